@@ -0,0 +1,14 @@
+package config
+
+import "time"
+
+//CacheConfig sizes the read-through cache in front of the database, the cache is disabled when Size is 0
+type CacheConfig struct {
+	Size int           `json:"size"`
+	TTL  time.Duration `json:"ttl"`
+}
+
+// Enabled reports whether the database should be wrapped with the cache
+func (c CacheConfig) Enabled() bool {
+	return c.Size > 0
+}
@@ -2,6 +2,8 @@ package config
 
 import (
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -11,16 +13,58 @@ var envMap = map[string]string{
 	characterDatabase:   defaultCharacterDatabase,
 	characterCollection: defaultCharacterCollection,
 	characterArchive:    defaultCharacterArchive,
+	characterRevisions:  defaultCharacterRevisions,
+	characterMigrations: defaultCharacterMigrations,
 	logLevel:            defaultlogLevel,
+	storageBackend:      defaultStorageBackend,
+	dataDirectory:       defaultDataDirectory,
+	dbReadTimeout:       defaultDBReadTimeout,
+	dbWriteTimeout:      defaultDBWriteTimeout,
+	adminToken:          "",
+	cacheSize:           defaultCacheSize,
+	cacheTTL:            defaultCacheTTL,
+	requireIfMatch:      defaultRequireIfMatch,
+	catalogFile:         "",
+
+	mongoURI:                    defaultMongoURI,
+	mongoUsername:               "",
+	mongoPassword:               "",
+	mongoAuthSource:             "",
+	mongoTLSCAFile:              "",
+	mongoTLSCertificateKeyFile:  "",
+	mongoReplicaSet:             "",
+	mongoMinPoolSize:            defaultMongoMinPoolSize,
+	mongoMaxPoolSize:            defaultMongoMaxPoolSize,
+	mongoConnectTimeout:         defaultMongoConnectTimeout,
+	mongoServerSelectionTimeout: defaultMongoServerSelectionTimeout,
+	mongoAppName:                defaultMongoAppName,
+
+	ensureIndexes:          defaultEnsureIndexes,
+	ensureUniqueIdentity:   defaultEnsureUniqueIdentity,
+	ensureValidator:        defaultEnsureValidator,
+	schemaValidationLevel:  defaultSchemaValidationLevel,
+	schemaValidationAction: defaultSchemaValidationAction,
 }
 
 //Config is the general struct for app configuration
 type Config struct {
-	Port                string       `json:"port"`
-	CharacterDatabase   string       `json:"characterDatabase"`
-	CharacterCollection string       `json:"characterCollection"`
-	CharacterArchive    string       `json:"characterArchive"`
-	LogLevel            logrus.Level `json:"log-level"`
+	Port                string        `json:"port"`
+	CharacterDatabase   string        `json:"characterDatabase"`
+	CharacterCollection string        `json:"characterCollection"`
+	CharacterArchive    string        `json:"characterArchive"`
+	CharacterRevisions  string        `json:"characterRevisions"`
+	CharacterMigrations string        `json:"characterMigrations"`
+	LogLevel            logrus.Level  `json:"log-level"`
+	StorageBackend      string        `json:"storageBackend"`
+	DataDirectory       string        `json:"dataDirectory"`
+	ReadTimeout         time.Duration `json:"readTimeout"`
+	WriteTimeout        time.Duration `json:"writeTimeout"`
+	AdminToken          string        `json:"-"`
+	RequireIfMatch      bool          `json:"requireIfMatch"`
+	CatalogFile         string        `json:"catalogFile"`
+	Cache               CacheConfig   `json:"cache"`
+	Mongo               MongoConfig   `json:"mongo"`
+	Schema              SchemaConfig  `json:"schema"`
 }
 
 //Accessor is the interface setup for any configuration accessor
@@ -42,12 +86,54 @@ func New(accessor Accessor) (c *Config, err error) {
 		logrus.Warnf("Cannot load log-level: %v", err)
 	}
 
+	readTimeout, err := parseDuration(dbReadTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	writeTimeout, err := parseDuration(dbWriteTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	ifMatch, err := parseBool(requireIfMatch)
+	if err != nil {
+		return nil, err
+	}
+
+	cache, err := loadCacheConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	mongo, err := loadMongoConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := loadSchemaConfig()
+	if err != nil {
+		return nil, err
+	}
+
 	config := Config{
 		Port:                envMap[port],
 		CharacterDatabase:   envMap[characterDatabase],
 		CharacterCollection: envMap[characterCollection],
 		CharacterArchive:    envMap[characterArchive],
+		CharacterRevisions:  envMap[characterRevisions],
+		CharacterMigrations: envMap[characterMigrations],
 		LogLevel:            currentLogLevel,
+		StorageBackend:      envMap[storageBackend],
+		DataDirectory:       envMap[dataDirectory],
+		ReadTimeout:         readTimeout,
+		WriteTimeout:        writeTimeout,
+		AdminToken:          envMap[adminToken],
+		RequireIfMatch:      ifMatch,
+		CatalogFile:         envMap[catalogFile],
+		Cache:               cache,
+		Mongo:               mongo,
+		Schema:              schema,
 	}
 	return &config, nil
 }
@@ -66,3 +152,108 @@ func loadEnvVars(accessor Accessor) error {
 
 	return nil
 }
+
+func loadCacheConfig() (CacheConfig, error) {
+	size, err := parseUint(cacheSize)
+	if err != nil {
+		return CacheConfig{}, err
+	}
+
+	ttl, err := parseDuration(cacheTTL)
+	if err != nil {
+		return CacheConfig{}, err
+	}
+
+	return CacheConfig{Size: int(size), TTL: ttl}, nil
+}
+
+func loadMongoConfig() (MongoConfig, error) {
+	minPoolSize, err := parseUint(mongoMinPoolSize)
+	if err != nil {
+		return MongoConfig{}, err
+	}
+
+	maxPoolSize, err := parseUint(mongoMaxPoolSize)
+	if err != nil {
+		return MongoConfig{}, err
+	}
+
+	connectTimeout, err := parseDuration(mongoConnectTimeout)
+	if err != nil {
+		return MongoConfig{}, err
+	}
+
+	serverSelectionTimeout, err := parseDuration(mongoServerSelectionTimeout)
+	if err != nil {
+		return MongoConfig{}, err
+	}
+
+	return MongoConfig{
+		URI:                    envMap[mongoURI],
+		Username:               envMap[mongoUsername],
+		Password:               envMap[mongoPassword],
+		AuthSource:             envMap[mongoAuthSource],
+		TLSCAFile:              envMap[mongoTLSCAFile],
+		TLSCertificateKeyFile:  envMap[mongoTLSCertificateKeyFile],
+		ReplicaSet:             envMap[mongoReplicaSet],
+		MinPoolSize:            minPoolSize,
+		MaxPoolSize:            maxPoolSize,
+		ConnectTimeout:         connectTimeout,
+		ServerSelectionTimeout: serverSelectionTimeout,
+		AppName:                envMap[mongoAppName],
+	}, nil
+}
+
+func loadSchemaConfig() (SchemaConfig, error) {
+	indexes, err := parseBool(ensureIndexes)
+	if err != nil {
+		return SchemaConfig{}, err
+	}
+
+	uniqueIdentity, err := parseBool(ensureUniqueIdentity)
+	if err != nil {
+		return SchemaConfig{}, err
+	}
+
+	validator, err := parseBool(ensureValidator)
+	if err != nil {
+		return SchemaConfig{}, err
+	}
+
+	schema := SchemaConfig{
+		EnsureIndexes:        indexes,
+		EnsureUniqueIdentity: uniqueIdentity,
+		EnsureValidator:      validator,
+		ValidationLevel:      envMap[schemaValidationLevel],
+		ValidationAction:     envMap[schemaValidationAction],
+	}
+
+	return schema, schema.Validate()
+}
+
+func parseBool(envKey string) (bool, error) {
+	value, err := strconv.ParseBool(envMap[envKey])
+	if err != nil {
+		return false, fmt.Errorf("error parsing environment variable %s: %q is not true or false", envKey, envMap[envKey])
+	}
+
+	return value, nil
+}
+
+func parseUint(envKey string) (uint64, error) {
+	value, err := strconv.ParseUint(envMap[envKey], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing environment variable %s: %q is not a whole number", envKey, envMap[envKey])
+	}
+
+	return value, nil
+}
+
+func parseDuration(envKey string) (time.Duration, error) {
+	value, err := time.ParseDuration(envMap[envKey])
+	if err != nil {
+		return 0, fmt.Errorf("error parsing environment variable %s: %q is not a duration such as 10s", envKey, envMap[envKey])
+	}
+
+	return value, nil
+}
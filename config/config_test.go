@@ -2,11 +2,41 @@ package config
 
 import (
 	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/geeksheik9/sheet-CRUD/config/mocks"
 )
 
+// dummyEnvValues holds valid values for the environment variables that are parsed rather than used as strings
+var dummyEnvValues = map[string]string{
+	dbReadTimeout:               "4s",
+	dbWriteTimeout:              "6s",
+	cacheSize:                   "100",
+	cacheTTL:                    "1m",
+	requireIfMatch:              "true",
+	mongoMinPoolSize:            "1",
+	mongoMaxPoolSize:            "5",
+	mongoConnectTimeout:         "2s",
+	mongoServerSelectionTimeout: "3s",
+	ensureIndexes:               "true",
+	ensureUniqueIdentity:        "false",
+	ensureValidator:             "true",
+	schemaValidationLevel:       "strict",
+	schemaValidationAction:      "warn",
+}
+
+func dummyEnvValue(envKey string) string {
+	if value, ok := dummyEnvValues[envKey]; ok {
+		return value
+	}
+
+	return "dummyEnvValue"
+}
+
 func TestConfig_NewNoError(t *testing.T) {
 	configAccessor := &mocks.ConfigAccessor{}
 
@@ -17,7 +47,7 @@ func TestConfig_NewNoError(t *testing.T) {
 		if envKey != port {
 			configAccessor.On("BindEnv", envKey).Return(nil)
 			configAccessor.On("IsSet", envKey).Return(true)
-			configAccessor.On("GetString", envKey).Return("dummyEnvValue")
+			configAccessor.On("GetString", envKey).Return(dummyEnvValue(envKey))
 		}
 	}
 
@@ -31,6 +61,26 @@ func TestConfig_NewNoError(t *testing.T) {
 	if c.Port != defaultPort {
 		t.Errorf("Environment variable PORT returned wrong value: got %v, want %v", c.Port, defaultPort)
 	}
+
+	if !c.Cache.Enabled() || c.Cache.Size != 100 || c.Cache.TTL != time.Minute {
+		t.Errorf("Cache config returned wrong value: got %v, want size 100 and ttl 1m", c.Cache)
+	}
+
+	if !c.RequireIfMatch {
+		t.Errorf("RequireIfMatch returned wrong value: got %v, want true", c.RequireIfMatch)
+	}
+
+	if c.CatalogFile != "dummyEnvValue" {
+		t.Errorf("CatalogFile returned wrong value: got %v, want dummyEnvValue", c.CatalogFile)
+	}
+
+	if c.Mongo.MaxPoolSize != 5 || c.Mongo.ServerSelectionTimeout != 3*time.Second {
+		t.Errorf("Mongo config returned wrong value: got %v, want max pool 5 and server selection 3s", c.Mongo)
+	}
+
+	if !c.Schema.EnsureIndexes || c.Schema.EnsureUniqueIdentity || c.Schema.ValidationLevel != "strict" || c.Schema.ValidationAction != "warn" {
+		t.Errorf("Schema config returned wrong value: got %v, want indexes without unique identity, strict and warn", c.Schema)
+	}
 }
 
 func TestConfig_NewWithError(t *testing.T) {
@@ -51,3 +101,85 @@ func TestConfig_NewWithError(t *testing.T) {
 		t.Errorf("New() returned wrong value: got %v, want %v", err, expectedErr)
 	}
 }
+
+func TestConfig_NewInvalidNumber(t *testing.T) {
+	configAccessor := &mocks.ConfigAccessor{}
+
+	for envKey := range envMap {
+		configAccessor.On("BindEnv", envKey).Return(nil)
+		if envKey == mongoMaxPoolSize {
+			configAccessor.On("IsSet", envKey).Return(true)
+			configAccessor.On("GetString", envKey).Return("lots")
+		} else {
+			configAccessor.On("IsSet", envKey).Return(false)
+		}
+	}
+	defer func() { envMap[mongoMaxPoolSize] = defaultMongoMaxPoolSize }()
+
+	expectedErr := `error parsing environment variable MONGO_MAX_POOL_SIZE: "lots" is not a whole number`
+	_, err := New(configAccessor)
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("New() returned wrong value: got %v, want %v", err, expectedErr)
+	}
+}
+
+func validMongoConfig() MongoConfig {
+	return MongoConfig{
+		URI:                    "mongodb://localhost:27017",
+		MinPoolSize:            0,
+		MaxPoolSize:            10,
+		ConnectTimeout:         time.Second,
+		ServerSelectionTimeout: time.Second,
+	}
+}
+
+func TestMongoConfig_Validate(t *testing.T) {
+	if err := validMongoConfig().Validate(); err != nil {
+		t.Errorf("Validate() returned wrong value: got %v, want <nil>", err)
+	}
+
+	caFile, _ := ioutil.TempFile("", "ca")
+	defer os.Remove(caFile.Name())
+	withTLS := validMongoConfig()
+	withTLS.TLSCAFile = caFile.Name()
+	if err := withTLS.Validate(); err != nil {
+		t.Errorf("Validate() with TLS returned wrong value: got %v, want <nil>", err)
+	}
+}
+
+func TestMongoConfig_ValidateErrors(t *testing.T) {
+	c := validMongoConfig()
+	c.URI = "localhost:27017"
+	c.Password = "secret"
+	c.MinPoolSize = 20
+	c.TLSCAFile = "/does/not/exist.pem"
+
+	err := c.Validate()
+	if err == nil {
+		t.Fatalf("Validate() returned wrong value: got <nil>, want error")
+	}
+
+	for _, expected := range []string{mongoURI, mongoPassword, mongoMinPoolSize, mongoTLSCAFile} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Validate() returned wrong value: got %v, want mention of %v", err, expected)
+		}
+	}
+}
+
+func TestSchemaConfig_Validate(t *testing.T) {
+	c := SchemaConfig{ValidationLevel: "moderate", ValidationAction: "error"}
+	if err := c.Validate(); err != nil {
+		t.Errorf("Validate() returned wrong value: got %v, want <nil>", err)
+	}
+
+	c.ValidationLevel = "loose"
+	if err := c.Validate(); err == nil || !strings.Contains(err.Error(), schemaValidationLevel) {
+		t.Errorf("Validate() returned wrong value: got %v, want mention of %v", err, schemaValidationLevel)
+	}
+
+	c.ValidationLevel = "off"
+	c.ValidationAction = "ignore"
+	if err := c.Validate(); err == nil || !strings.Contains(err.Error(), schemaValidationAction) {
+		t.Errorf("Validate() returned wrong value: got %v, want mention of %v", err, schemaValidationAction)
+	}
+}
@@ -5,7 +5,44 @@ const (
 	characterDatabase   = "CHARACTER_DATABASE"
 	characterCollection = "CHARACTER_COLLECTION"
 	characterArchive    = "CHARACTER_ARCHIVE"
+	characterRevisions  = "CHARACTER_REVISIONS"
+	characterMigrations = "CHARACTER_MIGRATIONS"
 	logLevel            = "LOG_LEVEL"
+	storageBackend      = "STORAGE_BACKEND"
+	dataDirectory       = "DATA_DIRECTORY"
+	dbReadTimeout       = "DB_READ_TIMEOUT"
+	dbWriteTimeout      = "DB_WRITE_TIMEOUT"
+	adminToken          = "ADMIN_TOKEN"
+	cacheSize           = "CACHE_SIZE"
+	cacheTTL            = "CACHE_TTL"
+	requireIfMatch      = "REQUIRE_IF_MATCH"
+	catalogFile         = "CATALOG_FILE"
+
+	mongoURI                    = "MONGO_URI"
+	mongoUsername               = "MONGO_USERNAME"
+	mongoPassword               = "MONGO_PASSWORD"
+	mongoAuthSource             = "MONGO_AUTH_SOURCE"
+	mongoTLSCAFile              = "MONGO_TLS_CA_FILE"
+	mongoTLSCertificateKeyFile  = "MONGO_TLS_CERTIFICATE_KEY_FILE"
+	mongoReplicaSet             = "MONGO_REPLICA_SET"
+	mongoMinPoolSize            = "MONGO_MIN_POOL_SIZE"
+	mongoMaxPoolSize            = "MONGO_MAX_POOL_SIZE"
+	mongoConnectTimeout         = "MONGO_CONNECT_TIMEOUT"
+	mongoServerSelectionTimeout = "MONGO_SERVER_SELECTION_TIMEOUT"
+	mongoAppName                = "MONGO_APP_NAME"
+
+	ensureIndexes          = "ENSURE_INDEXES"
+	ensureUniqueIdentity   = "ENSURE_UNIQUE_IDENTITY"
+	ensureValidator        = "ENSURE_VALIDATOR"
+	schemaValidationLevel  = "SCHEMA_VALIDATION_LEVEL"
+	schemaValidationAction = "SCHEMA_VALIDATION_ACTION"
+)
+
+// Supported values for STORAGE_BACKEND
+const (
+	MongoBackend  = "mongo"
+	MemoryBackend = "memory"
+	FileBackend   = "file"
 )
 
 const (
@@ -13,5 +50,27 @@ const (
 	defaultCharacterDatabase   = "characters"
 	defaultCharacterCollection = "sheets"
 	defaultCharacterArchive    = "sheets_Archive"
+	defaultCharacterRevisions  = "sheets_Revisions"
+	defaultCharacterMigrations = "sheets_Migrations"
 	defaultlogLevel            = "trace"
+	defaultStorageBackend      = MongoBackend
+	defaultDataDirectory       = "./data"
+	defaultDBReadTimeout       = "10s"
+	defaultDBWriteTimeout      = "10s"
+	defaultCacheSize           = "0"
+	defaultCacheTTL            = "30s"
+	defaultRequireIfMatch      = "false"
+
+	defaultMongoURI                    = "mongodb://localhost:27017"
+	defaultMongoMinPoolSize            = "0"
+	defaultMongoMaxPoolSize            = "100"
+	defaultMongoConnectTimeout         = "10s"
+	defaultMongoServerSelectionTimeout = "30s"
+	defaultMongoAppName                = "sheet-crud"
+
+	defaultEnsureIndexes          = "true"
+	defaultEnsureUniqueIdentity   = "true"
+	defaultEnsureValidator        = "true"
+	defaultSchemaValidationLevel  = "moderate"
+	defaultSchemaValidationAction = "error"
 )
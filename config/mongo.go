@@ -0,0 +1,77 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+//MongoConfig is the connection specification for the MongoDB deployment
+type MongoConfig struct {
+	URI                    string        `json:"uri"`
+	Username               string        `json:"username"`
+	Password               string        `json:"-"`
+	AuthSource             string        `json:"authSource"`
+	TLSCAFile              string        `json:"tlsCAFile"`
+	TLSCertificateKeyFile  string        `json:"tlsCertificateKeyFile"`
+	ReplicaSet             string        `json:"replicaSet"`
+	MinPoolSize            uint64        `json:"minPoolSize"`
+	MaxPoolSize            uint64        `json:"maxPoolSize"`
+	ConnectTimeout         time.Duration `json:"connectTimeout"`
+	ServerSelectionTimeout time.Duration `json:"serverSelectionTimeout"`
+	AppName                string        `json:"appName"`
+}
+
+//TLSEnabled reports whether any TLS files were configured
+func (m MongoConfig) TLSEnabled() bool {
+	return m.TLSCAFile != "" || m.TLSCertificateKeyFile != ""
+}
+
+//Validate checks the connection specification and returns every problem found in a single error
+func (m MongoConfig) Validate() error {
+	problems := []string{}
+
+	if !strings.HasPrefix(m.URI, "mongodb://") && !strings.HasPrefix(m.URI, "mongodb+srv://") {
+		problems = append(problems, fmt.Sprintf("%s must start with mongodb:// or mongodb+srv://", mongoURI))
+	}
+
+	if m.Password != "" && m.Username == "" {
+		problems = append(problems, fmt.Sprintf("%s is set without %s", mongoPassword, mongoUsername))
+	}
+
+	if m.AuthSource != "" && m.Username == "" {
+		problems = append(problems, fmt.Sprintf("%s is set without %s", mongoAuthSource, mongoUsername))
+	}
+
+	if m.MaxPoolSize == 0 {
+		problems = append(problems, fmt.Sprintf("%s must be greater than 0", mongoMaxPoolSize))
+	} else if m.MinPoolSize > m.MaxPoolSize {
+		problems = append(problems, fmt.Sprintf("%s (%d) must not exceed %s (%d)", mongoMinPoolSize, m.MinPoolSize, mongoMaxPoolSize, m.MaxPoolSize))
+	}
+
+	if m.ConnectTimeout <= 0 {
+		problems = append(problems, fmt.Sprintf("%s must be greater than 0", mongoConnectTimeout))
+	}
+
+	if m.ServerSelectionTimeout <= 0 {
+		problems = append(problems, fmt.Sprintf("%s must be greater than 0", mongoServerSelectionTimeout))
+	}
+
+	files := [][2]string{{mongoTLSCAFile, m.TLSCAFile}, {mongoTLSCertificateKeyFile, m.TLSCertificateKeyFile}}
+	for _, file := range files {
+		if file[1] == "" {
+			continue
+		}
+		if _, err := os.Stat(file[1]); err != nil {
+			problems = append(problems, fmt.Sprintf("%s cannot be read: %v", file[0], err))
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New("invalid mongo configuration: " + strings.Join(problems, "; "))
+	}
+
+	return nil
+}
@@ -0,0 +1,29 @@
+package config
+
+import "fmt"
+
+//SchemaConfig decides which indexes and validation rules are put in place on the sheet collection at startup
+type SchemaConfig struct {
+	EnsureIndexes        bool   `json:"ensureIndexes"`
+	EnsureUniqueIdentity bool   `json:"ensureUniqueIdentity"`
+	EnsureValidator      bool   `json:"ensureValidator"`
+	ValidationLevel      string `json:"validationLevel"`
+	ValidationAction     string `json:"validationAction"`
+}
+
+//Validate checks that the validation level and action are ones mongo accepts
+func (s SchemaConfig) Validate() error {
+	switch s.ValidationLevel {
+	case "off", "strict", "moderate":
+	default:
+		return fmt.Errorf("%s must be off, strict or moderate, got %q", schemaValidationLevel, s.ValidationLevel)
+	}
+
+	switch s.ValidationAction {
+	case "error", "warn":
+	default:
+		return fmt.Errorf("%s must be error or warn, got %q", schemaValidationAction, s.ValidationAction)
+	}
+
+	return nil
+}
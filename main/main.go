@@ -9,13 +9,21 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/geeksheik9/sheet-CRUD/config"
+	"github.com/geeksheik9/sheet-CRUD/pkg/backup"
+	"github.com/geeksheik9/sheet-CRUD/pkg/cache"
+	"github.com/geeksheik9/sheet-CRUD/pkg/catalog"
 	"github.com/geeksheik9/sheet-CRUD/pkg/db"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/file"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/memory"
+	"github.com/geeksheik9/sheet-CRUD/pkg/events"
 	"github.com/geeksheik9/sheet-CRUD/pkg/handler"
 
 	"github.com/gorilla/mux"
@@ -37,31 +45,42 @@ func main() {
 		log.Fatalf("ERROR LOADING CONFIG: %v", err.Error())
 	}
 
-	timeout := time.Second * 5
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
+	database, closeDatabase := initializeDatabase(config)
+	defer closeDatabase()
 
-	client, err := db.InitializeClients(ctx)
-	if err != nil {
-		logrus.Warnf("Failed to intialize client with error: %v, trying again", err)
-		err = nil
-		ctx, cancel = context.WithTimeout(context.Background(), time.Second*60)
-		client, err = db.InitializeClients(ctx)
-		if err != nil {
-			logrus.Fatalf("Failed to initialize database client a second time with error: %v", err)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			migrate(database, os.Args[2:])
+			return
+		case "backup":
+			backupSheets(database, os.Args[2:])
+			return
+		case "restore":
+			restoreSheets(database, os.Args[2:])
+			return
 		}
+		log.Fatalf("Unknown command %v, expected migrate, backup or restore", os.Args[1])
 	}
+	warnPendingMigrations(database)
 
-	defer client.Disconnect(context.Background())
-
-	database := db.InitializeDatabases(client, config)
-	if database == nil {
-		log.Fatalf("Error no database from client %v", client)
+	if config.AdminToken == "" {
+		logrus.Info("ADMIN_TOKEN is not set, the admin routes are disabled")
 	}
 
 	characterService := handler.CharacterService{
-		Version:  version,
-		Database: database,
+		Version:        version,
+		Database:       database,
+		AdminToken:     config.AdminToken,
+		RequireIfMatch: config.RequireIfMatch,
+		Catalog:        loadCatalog(config.CatalogFile),
+	}
+	if source, ok := database.(interface{ Events() events.Source }); ok {
+		characterService.Events = source.Events()
+	}
+	if config.Cache.Enabled() {
+		logrus.Infof("Caching up to %v sheets for %v", config.Cache.Size, config.Cache.TTL)
+		characterService.Database = cache.New(database, config.Cache.Size, config.Cache.TTL)
 	}
 
 	r := mux.NewRouter().StrictSlash(true)
@@ -69,5 +88,220 @@ func main() {
 	r = characterService.Routes(r)
 	fmt.Printf("Sever listening on port %v\n", config.Port)
 	logrus.Info("END")
-	log.Fatal(http.ListenAndServe(":"+config.Port, cors.AllowAll().Handler(r)))
+	log.Fatal(http.ListenAndServe(":"+config.Port, allowAll().Handler(r)))
+}
+
+// initializeDatabase connects to the storage backend selected by STORAGE_BACKEND and returns a function that releases it
+func initializeDatabase(c *config.Config) (handler.CharacterDatabase, func()) {
+	switch c.StorageBackend {
+	case config.MemoryBackend:
+		logrus.Warn("Using in-memory storage, sheets will be lost when the service stops")
+		return memory.New(), func() {}
+	case config.FileBackend:
+		store, err := file.NewStore(c.DataDirectory)
+		if err != nil {
+			logrus.Fatalf("Failed to open data directory %v with error: %v", c.DataDirectory, err)
+		}
+
+		database, err := memory.NewWithStore(store)
+		if err != nil {
+			logrus.Fatalf("Failed to load sheets from %v with error: %v", c.DataDirectory, err)
+		}
+
+		logrus.Infof("Using file storage in %v", c.DataDirectory)
+		return database, func() {}
+	case config.MongoBackend:
+		err := c.Mongo.Validate()
+		if err != nil {
+			logrus.Fatalf("Cannot connect to mongo: %v", err)
+		}
+
+		timeout := time.Second * 5
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		client, err := db.InitializeClients(ctx, c.Mongo)
+		if err != nil {
+			logrus.Warnf("Failed to intialize client with error: %v, trying again", err)
+			err = nil
+			ctx, cancel = context.WithTimeout(context.Background(), time.Second*60)
+			defer cancel()
+			client, err = db.InitializeClients(ctx, c.Mongo)
+			if err != nil {
+				logrus.Fatalf("Failed to initialize database client a second time with error: %v", err)
+			}
+		}
+
+		database := db.InitializeDatabases(client, c)
+		if database == nil {
+			log.Fatalf("Error no database from client %v", client)
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		report, err := database.EnsureSchema(ctx, c.Schema)
+		if err != nil {
+			logrus.Warnf("Could not ensure the sheet collection schema: %v", err)
+		}
+		if len(report.Failures) > 0 {
+			logrus.Warnf("Sheet collection schema ensured with failures: %v", report)
+		} else {
+			logrus.Infof("Sheet collection schema ensured: %v", report)
+		}
+
+		return database, func() { client.Disconnect(context.Background()) }
+	}
+
+	log.Fatalf("Unknown %v storage backend, expected %v, %v or %v", c.StorageBackend, config.MongoBackend, config.FileBackend, config.MemoryBackend)
+	return nil, nil
+}
+
+// migrate runs the migrate subcommand, which brings the stored documents to a migration version.
+// Usage: sheet-CRUD migrate [-dry-run] [-to version]
+func migrate(database handler.CharacterDatabase, args []string) {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "report the documents each migration would change without changing them")
+	target := flags.Int("to", db.LatestVersion, "the version to migrate to, lower than the current version rolls migrations back")
+	flags.Parse(args)
+
+	store, ok := database.(db.MigrationStore)
+	if !ok {
+		log.Fatalf("The storage backend does not support migrations")
+	}
+
+	report, err := db.Migrate(context.Background(), store, db.Migrations, *target, *dryRun)
+	for _, step := range report.Steps {
+		logrus.Infof("Migration %v %v (%v): %v documents", step.Version, step.Direction, step.Description, step.Documents)
+	}
+	if err != nil {
+		log.Fatalf("Migration failed: %v", err)
+	}
+
+	if *dryRun {
+		logrus.Infof("Dry run from version %v to %v, nothing was changed", report.From, report.To)
+		return
+	}
+	logrus.Infof("Migrated from version %v to %v", report.From, report.To)
+}
+
+// backupSheets runs the backup subcommand, which writes a backup bundle of the live and archived sheets.
+// Usage: sheet-CRUD backup [-out file], the bundle goes to standard output when file is -
+func backupSheets(database handler.CharacterDatabase, args []string) {
+	flags := flag.NewFlagSet("backup", flag.ExitOnError)
+	out := flags.String("out", fmt.Sprintf("sheets-%v.tar.gz", time.Now().UTC().Format("20060102-150405")), "the file to write the bundle to, - for standard output")
+	flags.Parse(args)
+
+	w := os.Stdout
+	if *out != "-" {
+		file, err := os.Create(*out)
+		if err != nil {
+			log.Fatalf("Could not create backup file: %v", err)
+		}
+		w = file
+	}
+
+	manifest, err := backup.Write(context.Background(), database, w)
+	if w != os.Stdout {
+		if err == nil {
+			err = w.Sync()
+		}
+		if closeErr := w.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		log.Fatalf("Backup failed: %v", err)
+	}
+
+	logrus.Infof("Backed up %v sheets and %v archived sheets to %v",
+		manifest.Collections[backup.SheetsCollection], manifest.Collections[backup.ArchiveCollection], *out)
+}
+
+// restoreSheets runs the restore subcommand, which loads a backup bundle.
+// Usage: sheet-CRUD restore -in file [-policy skip|overwrite|new-ids], the bundle is read from standard input when file is -
+func restoreSheets(database handler.CharacterDatabase, args []string) {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	in := flags.String("in", "-", "the bundle to restore, - for standard input")
+	policyFlag := flags.String("policy", string(backup.Skip), "what to do with sheets that already exist: skip, overwrite or new-ids")
+	flags.Parse(args)
+
+	policy, err := backup.ParsePolicy(*policyFlag)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	r := os.Stdin
+	if *in != "-" {
+		file, err := os.Open(*in)
+		if err != nil {
+			log.Fatalf("Could not open backup file: %v", err)
+		}
+		defer file.Close()
+		r = file
+	}
+
+	report, err := backup.Restore(context.Background(), database, r, policy)
+	if err != nil {
+		log.Fatalf("Restore failed: %v", err)
+	}
+
+	for _, failure := range append(report.Sheets.Failures, report.Archive.Failures...) {
+		logrus.Warnf("Could not restore %v", failure)
+	}
+	logrus.Infof("Restored backup from %v with policy %v: %v", report.Manifest.CreatedAt.Format(time.RFC3339), policy, report)
+}
+
+// loadCatalog returns the catalog XP purchases are priced from, read from CATALOG_FILE when it is set and the built in one otherwise
+func loadCatalog(path string) *catalog.Catalog {
+	if path == "" {
+		return catalog.Default()
+	}
+
+	prices, err := catalog.Load(path)
+	if err != nil {
+		logrus.Fatalf("Failed to load the purchase catalog with error: %v", err)
+	}
+
+	logrus.Infof("Pricing purchases from %v", path)
+	return prices
+}
+
+// warnPendingMigrations logs a warning when the stored documents are behind the latest migration
+func warnPendingMigrations(database handler.CharacterDatabase) {
+	store, ok := database.(db.MigrationStore)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	report, err := db.Migrate(ctx, store, db.Migrations, db.LatestVersion, true)
+	if err != nil {
+		logrus.Warnf("Could not check for pending migrations: %v", err)
+		return
+	}
+
+	if len(report.Steps) > 0 {
+		logrus.Warnf("%v migrations are pending from version %v to %v, run the migrate command to apply them", len(report.Steps), report.From, report.To)
+	}
+}
+
+// allowAll is the cors.AllowAll policy that also lets browsers read the ETag of a sheet, which conditional writes send back in If-Match,
+// and the Link header of a list, which holds its first, prev, next and last pages
+func allowAll() *cors.Cors {
+	return cors.New(cors.Options{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{
+			http.MethodHead,
+			http.MethodGet,
+			http.MethodPost,
+			http.MethodPut,
+			http.MethodPatch,
+			http.MethodDelete,
+		},
+		AllowedHeaders:   []string{"*"},
+		ExposedHeaders:   []string{"ETag", "Link"},
+		AllowCredentials: false,
+	})
 }
@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// ArchivedForceCharacterSheet is a force character sheet that has been removed from the live collection
+// and kept in the archive so that it can be restored later
+// swagger:model
+type ArchivedForceCharacterSheet struct {
+	ForceCharacterSheet `bson:",inline"`
+	DeletedAt           time.Time `json:"deletedAt" bson:"deletedAt"`
+	DeletedBy           string    `json:"deletedBy" bson:"deletedBy"`
+}
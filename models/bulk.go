@@ -0,0 +1,46 @@
+package model
+
+import "encoding/json"
+
+// The operations a bulk request can run
+const (
+	BulkInsert = "insert"
+	BulkUpdate = "update"
+	BulkDelete = "delete"
+)
+
+// BulkRequest is a batch of sheet inserts, updates and deletes run in order by a single request.
+// An atomic request keeps every change or, when any operation fails, none of them
+// swagger:model
+type BulkRequest struct {
+	Atomic     bool            `json:"atomic"`
+	Operations []BulkOperation `json:"operations"`
+}
+
+// BulkOperation is one insert, update or delete of a bulk request. Updates and deletes name the sheet by ID,
+// inserts and updates carry the sheet in the same form as the single sheet endpoints
+// swagger:model
+type BulkOperation struct {
+	Op    string          `json:"op"`
+	ID    string          `json:"id,omitempty"`
+	Sheet json.RawMessage `json:"sheet,omitempty"`
+}
+
+// BulkResult is the outcome of one operation of a bulk request, with the HTTP status and problem the single sheet endpoint would have returned
+// swagger:model
+type BulkResult struct {
+	Index  int      `json:"index"`
+	Op     string   `json:"op"`
+	ID     string   `json:"id,omitempty"`
+	Status int      `json:"status"`
+	Error  *Problem `json:"error,omitempty"`
+}
+
+// BulkResponse holds the result of every operation of a bulk request in request order
+// swagger:model
+type BulkResponse struct {
+	Atomic    bool         `json:"atomic"`
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+	Results   []BulkResult `json:"results"`
+}
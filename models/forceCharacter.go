@@ -2,7 +2,10 @@ package model
 
 import "go.mongodb.org/mongo-driver/bson/primitive"
 
-// ForceCharacterSheet is the model for the FFG Star Wars character sheet
+// ForceCharacterSheet is the model for the FFG Star Wars character sheet.
+// Every sheet is a version of a character, the sheets of a character share its CharacterID and are numbered by CharacterVersion.
+// Version counts the edits of one sheet and guards against concurrent updates.
+// The elements of the skills, weapons, talents, critical injuries, armor and personal gear arrays have IDs of their own so they can be changed one at a time
 // swagger:model
 type ForceCharacterSheet struct {
 	ID                   primitive.ObjectID    `json:"_id" bson:"_id"`
@@ -12,7 +15,7 @@ type ForceCharacterSheet struct {
 	Career               string                `json:"career" bson:"career"`
 	SpecializationTrees  []SpecializationTrees `json:"specializationTrees" bson:"specializationTrees"`
 	SoakValue            int64                 `json:"soakValue" bson:"soakValue"`
-	Wounds               Amount                `json:"wound" bson:"wound"`
+	Wounds               Amount                `json:"wounds" bson:"wounds"`
 	Strain               Amount                `json:"strain" bson:"strain"`
 	Defense              DefenseStats          `json:"defense" bson:"defense"`
 	Characteristics      Characteristics       `json:"characteristics" bson:"characteristics"`
@@ -22,12 +25,14 @@ type ForceCharacterSheet struct {
 	AvailableXP          int64                 `json:"availableXP" bson:"availableXP"`
 	Motivation           Motivation            `json:"motivation" bson:"motivation"`
 	Morality             Morality              `json:"morality" bson:"morality"`
-	CharacterDescription CharacterDescription  `json:"charcaterDescription" bson:"characterDescription"`
+	CharacterDescription CharacterDescription  `json:"characterDescription" bson:"characterDescription"`
 	Equipment            Equipment             `json:"equipment" bson:"equipment"`
 	CriticalInjuries     []CriticalInjuries    `json:"criticalInjuries" bson:"criticalInjuries"`
 	Talents              []Talents             `json:"talents" bson:"talents"`
 	ForceRating          int64                 `json:"forceRating" bson:"forceRating"`
 	Version              int64                 `json:"version" bson:"version"`
+	CharacterID          primitive.ObjectID    `json:"characterId" bson:"characterId"`
+	CharacterVersion     int64                 `json:"characterVersion" bson:"characterVersion"`
 }
 
 // DefenseStats is a generic that holds a characters Defensive amount for ranged and melee damage
@@ -77,22 +82,24 @@ type Characteristics struct {
 // Skills is a subcategory of the FFG Star Wars character sheet that keeps track of different skills and their levels
 // swagger:model
 type Skills struct {
-	Name           string `json:"name" bson:"name"`
-	Characteristic string `json:"characteristic" bson:"characteristic"`
-	Career         bool   `json:"career" bson:"career"`
-	Level          int64  `json:"level" bson:"level"`
-	Description    string `json:"description" bson:"description"`
+	ID             primitive.ObjectID `json:"id" bson:"id"`
+	Name           string             `json:"name" bson:"name"`
+	Characteristic string             `json:"characteristic" bson:"characteristic"`
+	Career         bool               `json:"career" bson:"career"`
+	Level          int64              `json:"level" bson:"level"`
+	Description    string             `json:"description" bson:"description"`
 }
 
 // Weapons is a subcategory of the FFG Star Wars character sheet that keeps track of a characters weapon inventory
 // swagger:model
 type Weapons struct {
-	Name    string `json:"name" bson:"name"`
-	Skill   string `json:"skill" bson:"skill"`
-	Damage  string `json:"damage" bson:"damage"`
-	Crit    int64  `json:"crit" bson:"crit"`
-	Range   string `json:"range" bson:"range"`
-	Special string `json:"special" bson:"special"`
+	ID      primitive.ObjectID `json:"id" bson:"id"`
+	Name    string             `json:"name" bson:"name"`
+	Skill   string             `json:"skill" bson:"skill"`
+	Damage  string             `json:"damage" bson:"damage"`
+	Crit    int64              `json:"crit" bson:"crit"`
+	Range   string             `json:"range" bson:"range"`
+	Special string             `json:"special" bson:"special"`
 }
 
 // Motivation is a subcategory of the FFG Star Wars character sheet that keeps track of a characters motivation
@@ -106,7 +113,7 @@ type Motivation struct {
 // swagger:model
 type Morality struct {
 	EmotionalStrength string `json:"emotionalStrength" bson:"emotionalStrength"`
-	EmotionalWeakness string `json:"emotionalWeakness" bson:"emotionalWeekness"`
+	EmotionalWeakness string `json:"emotionalWeakness" bson:"emotionalWeakness"`
 	Conflict          int64  `json:"conflict" bson:"conflict"`
 	Morality          int64  `json:"morality" bson:"morality"`
 }
@@ -122,16 +129,18 @@ type Equipment struct {
 // Gear is the generic for any gear a character may carry for the FFG Star Wars character sheet
 // swagger:model
 type Gear struct {
-	Gear string `json:"gear" bson:"gear"`
+	ID   primitive.ObjectID `json:"id" bson:"id"`
+	Gear string             `json:"gear" bson:"gear"`
 }
 
 // Talents is a subcategory of the FFG Star Wars character sheet that keeps track of all talents acquired through skill trees
 // swagger:model
 type Talents struct {
-	Name        string       `json:"name" bson:"name"`
-	Page        int64        `json:"page" bson:"page"`
-	Description string       `json:"description" bson:"description"`
-	ForcePower  []ForcePower `json:"forcePower" bson:"forcePower"`
+	ID          primitive.ObjectID `json:"id" bson:"id"`
+	Name        string             `json:"name" bson:"name"`
+	Page        int64              `json:"page" bson:"page"`
+	Description string             `json:"description" bson:"description"`
+	ForcePower  []ForcePower       `json:"forcePower" bson:"forcePower"`
 }
 
 // ForcePower is a subcategory of the FFG Star Wars character sheet that keeps track of all Force abilities gained through the force tree
@@ -145,6 +154,7 @@ type ForcePower struct {
 // CriticalInjuries is a subcategory of the FFG Star Wars character sheet that keeps track of all critical injuries suffered
 // swagger:model
 type CriticalInjuries struct {
-	Severity int64 `json:"severity" bson:"severity"`
-	Result   bool  `json:"result" bson:"result"`
+	ID       primitive.ObjectID `json:"id" bson:"id"`
+	Severity int64              `json:"severity" bson:"severity"`
+	Result   bool               `json:"result" bson:"result"`
 }
@@ -0,0 +1,22 @@
+package model
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// HealthActionRequest is the body of a damage, strain or healing action, the amount of damage, strain or healing before soak
+// swagger:model
+type HealthActionRequest struct {
+	Amount int64 `json:"amount"`
+}
+
+// HealthState is the wounds and strain of a sheet after a damage, strain or healing action.
+// A threshold is exceeded once the current value is above it, Soaked is how much of the damage the soak value of the sheet stopped
+// swagger:model
+type HealthState struct {
+	ID                      primitive.ObjectID `json:"id"`
+	Version                 int64              `json:"version"`
+	Wounds                  Amount             `json:"wounds"`
+	Strain                  Amount             `json:"strain"`
+	WoundThresholdExceeded  bool               `json:"woundThresholdExceeded"`
+	StrainThresholdExceeded bool               `json:"strainThresholdExceeded"`
+	Soaked                  int64              `json:"soaked,omitempty"`
+}
@@ -0,0 +1,16 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MigrationRecord records a migration that was applied to the stored documents
+type MigrationRecord struct {
+	ID          primitive.ObjectID `json:"_id" bson:"_id"`
+	Version     int                `json:"version" bson:"version"`
+	Description string             `json:"description" bson:"description"`
+	AppliedAt   time.Time          `json:"appliedAt" bson:"appliedAt"`
+	Documents   int64              `json:"documents" bson:"documents"`
+}
@@ -0,0 +1,32 @@
+package model
+
+// The kinds of advancement XP can buy
+const (
+	PurchaseSkill          = "skill"
+	PurchaseCharacteristic = "characteristic"
+	PurchaseTalent         = "talent"
+	PurchaseForcePower     = "forcePower"
+)
+
+// PurchaseRequest is an advancement bought with XP. Name is the skill, characteristic, talent or force power bought.
+// Force powers give the upgrade bought, none for the power itself. The cost is worked out by the service
+// swagger:model
+type PurchaseRequest struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Upgrade     string `json:"upgrade,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// PurchaseResult is what an XP purchase cost and the sheet after it.
+// Rank is the new rank of a skill, rating of a characteristic or how many times a talent or force power upgrade has been bought
+// swagger:model
+type PurchaseResult struct {
+	Type        string              `json:"type"`
+	Name        string              `json:"name"`
+	Upgrade     string              `json:"upgrade,omitempty"`
+	Cost        int64               `json:"cost"`
+	Rank        int64               `json:"rank"`
+	AvailableXP int64               `json:"availableXP"`
+	Sheet       ForceCharacterSheet `json:"sheet"`
+}
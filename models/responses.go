@@ -2,6 +2,75 @@ package model
 
 //HealthCheckResponse returns the version of the api and whether or not the DB is queryable
 type HealthCheckResponse struct {
-	APIVersion string `json:"apiVersion"`
-	DBError    string `json:"dbError"`
+	APIVersion string      `json:"apiVersion"`
+	DBError    string      `json:"dbError"`
+	Cache      *CacheStats `json:"cache,omitempty"`
+}
+
+//CacheStats counts how the read-through cache in front of the database served sheet lookups
+type CacheStats struct {
+	Hits          uint64 `json:"hits"`
+	Misses        uint64 `json:"misses"`
+	Evictions     uint64 `json:"evictions"`
+	Expirations   uint64 `json:"expirations"`
+	Invalidations uint64 `json:"invalidations"`
+	Size          int    `json:"size"`
+	Capacity      int    `json:"capacity"`
+}
+
+//Problem is the RFC 7807 body of every error response, sent as application/problem+json.
+//Code is stable and meant for programs, Detail is meant for people and may change
+type Problem struct {
+	Type       string      `json:"type"`
+	Title      string      `json:"title"`
+	Status     int         `json:"status"`
+	Code       string      `json:"code"`
+	Detail     string      `json:"detail,omitempty"`
+	Violations []Violation `json:"violations,omitempty"`
+}
+
+//Violation is a problem with one field of a query or payload
+type Violation struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+//ConflictResponse is the problem returned when an update was made against a stale version of a sheet, along with the stored sheet
+type ConflictResponse struct {
+	Problem
+	Current *ForceCharacterSheet `json:"current"`
+}
+
+//ForceCharacterSheetPage is a page of force character sheets along with the information needed to fetch the other pages
+type ForceCharacterSheetPage struct {
+	Items      []ForceCharacterSheet `json:"items"`
+	Total      int64                 `json:"total"`
+	PageNumber int                   `json:"pageNumber"`
+	PageCount  int                   `json:"pageCount"`
+	Next       string                `json:"next,omitempty"`
+	Prev       string                `json:"prev,omitempty"`
+}
+
+//SearchHighlight is a snippet of a sheet field that matched a search, with the matching words wrapped in <em> tags
+type SearchHighlight struct {
+	Field   string `json:"field"`
+	Snippet string `json:"snippet"`
+}
+
+//SearchResult is a sheet that matched a search along with its rank and the text that matched
+type SearchResult struct {
+	Sheet      ForceCharacterSheet `json:"sheet"`
+	Score      float64             `json:"score"`
+	Highlights []SearchHighlight   `json:"highlights"`
+}
+
+//SearchPage is a page of search results in descending score order
+type SearchPage struct {
+	Query      string         `json:"query"`
+	Items      []SearchResult `json:"items"`
+	Total      int64          `json:"total"`
+	PageNumber int            `json:"pageNumber"`
+	PageCount  int            `json:"pageCount"`
+	Next       string         `json:"next,omitempty"`
+	Prev       string         `json:"prev,omitempty"`
 }
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SheetRevision is an immutable snapshot of a force character sheet taken every time the sheet changes
+// swagger:model
+type SheetRevision struct {
+	ID        primitive.ObjectID   `json:"_id" bson:"_id"`
+	SheetID   primitive.ObjectID   `json:"sheetId" bson:"sheetId"`
+	Version   int64                `json:"version" bson:"version"`
+	Timestamp time.Time            `json:"timestamp" bson:"timestamp"`
+	Summary   string               `json:"summary" bson:"summary"`
+	Sheet     *ForceCharacterSheet `json:"sheet,omitempty" bson:"sheet,omitempty"`
+}
+
+// FieldChange is a single field that differs between two revisions of a force character sheet
+// swagger:model
+type FieldChange struct {
+	Path string      `json:"path"`
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// RevisionDiff is the field by field difference between two revisions of a force character sheet
+// swagger:model
+type RevisionDiff struct {
+	SheetID primitive.ObjectID `json:"sheetId"`
+	From    int64              `json:"from"`
+	To      int64              `json:"to"`
+	Changes []FieldChange      `json:"changes"`
+}
@@ -2,32 +2,19 @@ package api
 
 import (
 	"encoding/json"
-	"errors"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
 
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	log "github.com/sirupsen/logrus"
 )
 
-// ErrorResponse
-// struct representation of what gets returned by the RespondWithError function.
-// swagger:model
-type ErrorResponse struct {
-	Error string `json:"error"`
-}
-
-// RespondWithError Utility function to convert an error message into a JSON response.
-func RespondWithError(w http.ResponseWriter, code int, msg string) {
-	// Clean up all quote marks for readability, marshal adds additional "\" escape char in the response JSON.
-	RespondWithJSON(w, code, map[string]string{"error": strings.Replace(msg, `"`, ``, -1)})
-}
-
 // RespondNoContent Utility function to send a response without any content.
 func RespondNoContent(w http.ResponseWriter, code int) {
 	if w != nil {
@@ -36,6 +23,14 @@ func RespondNoContent(w http.ResponseWriter, code int) {
 	}
 }
 
+// RespondNotModified Utility function to answer a conditional GET whose entity tag still matches, without a body.
+func RespondNotModified(w http.ResponseWriter, etag string) {
+	if w != nil {
+		w.Header().Set("ETag", etag)
+		w.WriteHeader(http.StatusNotModified)
+	}
+}
+
 // RespondWithJSON Utility function to convert the payload into a JSON response.
 // ORIGINAL:
 func RespondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
@@ -64,39 +59,16 @@ func GetJSONRequestBody(r *http.Request) (requestBodyJSON string) {
 func StringToObjectID(ID string) (primitive.ObjectID, error) {
 	objID, err := primitive.ObjectIDFromHex(ID)
 	if err != nil {
-		return primitive.ObjectID{}, err
+		return primitive.ObjectID{}, InvalidIDError(ID)
 	}
 
 	if objID.IsZero() {
-		return objID, errors.New("StringToObjectID() in api.go failed, object ID returned as zero and is not valid")
+		return objID, InvalidIDError(ID)
 	}
 
 	return objID, nil
 }
 
-// CheckError checks the err message and returns a code based on the message.
-func CheckError(err error) int {
-	var code int
-	// TODO: Check error returns and make this into a switch statement.
-	if err == nil {
-		code = http.StatusOK
-	} else if strings.Contains(err.Error(), "no documents in result") ||
-		strings.Contains(err.Error(), "out of bounds") ||
-		strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "matches instead of 1") {
-		code = http.StatusNotFound
-	} else if strings.Contains(err.Error(), "E11000 duplicate key error") ||
-		strings.Contains(err.Error(), "E11001 duplicate key error") {
-		code = http.StatusConflict
-	} else if strings.Contains(err.Error(), "E10334") ||
-		strings.Contains(err.Error(), "Invalid request payload, unable to marshal into json, err: ") || strings.Contains(err.Error(), "number of results instead of 1") {
-		code = http.StatusBadRequest
-	} else {
-		code = http.StatusInternalServerError
-	}
-
-	return code
-}
-
 //BuildQuery sets up the mongo query
 func BuildQuery(ID *primitive.ObjectID, name *string, other ...bson.M) bson.M {
 	conditions := []bson.M{}
@@ -122,34 +94,125 @@ func BuildQuery(ID *primitive.ObjectID, name *string, other ...bson.M) bson.M {
 	return c
 }
 
-//BuildFilter sets up the mongo filtering
-func BuildFilter(queryParams url.Values) (int, int, string, bson.M) {
-	filter := bson.M{}
+// DefaultPageCount is the page size used when no pageCount is requested
+const DefaultPageCount = 50
+
+// MaxPageCount is the largest page size a client may request
+const MaxPageCount = 500
+
+//BuildFilter sets up the mongo filtering.
+//Every query parameter other than the paging ones is a filter on a field of the schema, see ParseCondition for the syntax.
+//An after parameter holding a sheet ID switches to cursor pagination, returning sheets with a greater ID in ID order, so it can't be combined with sort
+func BuildFilter(queryParams url.Values, schema Schema) (int, int, bson.D, bson.M, error) {
+	pageNumber, pageCount, err := Paging(queryParams)
+	if err != nil {
+		return 0, 0, nil, nil, err
+	}
+
+	sort, err := ParseSort(queryParams.Get("sort"), schema)
+	if err != nil {
+		return 0, 0, nil, nil, err
+	}
+
 	filters := []bson.M{}
-	pageNumber := 0
-	pageCount := 10000
-	sort := "priority"
-	if len(queryParams) > 0 {
-		for queryParam, paramValue := range queryParams {
-			switch queryParam {
-			case "pageNumber":
-				pageNumber, _ = strconv.Atoi(paramValue[0])
-			case "pageCount":
-				pageCount, _ = strconv.Atoi(paramValue[0])
-			case "sort":
-				sort = paramValue[0]
-			default:
-				m := bson.M{queryParam: paramValue[0]}
-				filters = append(filters, m)
+	for queryParam, paramValues := range queryParams {
+		if reservedParams[queryParam] {
+			continue
+		}
+
+		for _, paramValue := range paramValues {
+			condition, err := ParseCondition(queryParam, paramValue, schema)
+			if err != nil {
+				return 0, 0, nil, nil, err
 			}
+
+			filters = append(filters, condition)
 		}
-		if len(filters) > 0 {
-			filter = bson.M{"$and": filters}
-		} else {
-			filter = nil
+	}
+
+	if after := queryParams.Get("after"); after != "" {
+		afterID, err := primitive.ObjectIDFromHex(after)
+		if err != nil {
+			return 0, 0, nil, nil, dberr.Invalid("invalid_query", "after", "invalid query: after must be a sheet ID: %v", err)
 		}
-		return pageNumber, pageCount, sort, filter
+		if queryParams.Get("sort") != "" {
+			return 0, 0, nil, nil, dberr.Invalid("invalid_query", "sort", "invalid query: sort can't be combined with after, cursor pages are in ID order")
+		}
+
+		filters = append(filters, bson.M{"_id": bson.M{"$gt": afterID}})
+		pageNumber = 0
+		sort = bson.D{{Key: "_id", Value: 1}}
+	}
+
+	if len(filters) == 0 {
+		return pageNumber, pageCount, sort, nil, nil
+	}
+
+	return pageNumber, pageCount, sort, bson.M{"$and": filters}, nil
+}
+
+//Paging returns the requested page number and page size, a page number of 0 means the first page
+func Paging(queryParams url.Values) (int, int, error) {
+	pageNumber := 0
+	pageCount := DefaultPageCount
+	var err error
+
+	if value := queryParams.Get("pageNumber"); value != "" {
+		pageNumber, err = strconv.Atoi(value)
+		if err != nil || pageNumber < 0 {
+			return 0, 0, dberr.Invalid("invalid_query", "pageNumber", "invalid query: pageNumber %q is not a whole number", value)
+		}
+	}
+
+	if value := queryParams.Get("pageCount"); value != "" {
+		pageCount, err = strconv.Atoi(value)
+		if err != nil {
+			return 0, 0, dberr.Invalid("invalid_query", "pageCount", "invalid query: pageCount %q is not a whole number", value)
+		}
+	}
+
+	if pageCount <= 0 {
+		pageCount = DefaultPageCount
+	}
+	if pageCount > MaxPageCount {
+		pageCount = MaxPageCount
+	}
+
+	return pageNumber, pageCount, nil
+}
+
+// reservedParams are the query parameters that control paging, sorting and projection rather than filter on a field
+var reservedParams = map[string]bool{
+	"pageNumber": true,
+	"pageCount":  true,
+	"sort":       true,
+	"after":      true,
+	"fields":     true,
+}
+
+// WithoutPagination returns a copy of the query parameters with the paging, sorting and cursor parameters removed,
+// leaving only the filters that decide how many sheets match in total
+func WithoutPagination(queryParams url.Values) url.Values {
+	filters := url.Values{}
+	for key, values := range queryParams {
+		if reservedParams[key] {
+			continue
+		}
+		filters[key] = values
+	}
+
+	return filters
+}
+
+// UserHeader is the request header that identifies the user performing an action
+const UserHeader = "X-User-Name"
+
+// RequestUser returns the user performing the request, or anonymous if none was provided
+func RequestUser(r *http.Request) string {
+	user := strings.TrimSpace(r.Header.Get(UserHeader))
+	if user == "" {
+		return "anonymous"
 	}
 
-	return pageNumber, pageCount, sort, nil
+	return user
 }
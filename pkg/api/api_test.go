@@ -2,13 +2,20 @@ package api
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/catalog"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -28,12 +35,15 @@ func Test_RespondWithError(t *testing.T) {
 	}
 }
 
-func Test_RespondWithError_RemoveQuotes(t *testing.T) {
+func Test_RespondWithError_Problem(t *testing.T) {
 	w := httptest.NewRecorder()
-	RespondWithError(w, http.StatusNotFound, `Te"st ""payl"o"ad`)
+	RespondWithError(w, http.StatusNotFound, `sheet "Mando" not found`)
 	body := w.Body.String()
-	if body != `{"error":"Test payload"}` {
-		t.Errorf("RespondNoContent():\n   expected error body:\n   got:      %s", body)
+	if body != `{"type":"about:blank","title":"Not Found","status":404,"code":"not_found","detail":"sheet \"Mando\" not found"}` {
+		t.Errorf("RespondWithError() error:\n   expected problem body:\n   got:      %s", body)
+	}
+	if w.Header().Get("Content-Type") != ProblemContentType {
+		t.Errorf("RespondWithError() error:\n   expected: %v\n   got:      %v", ProblemContentType, w.Header().Get("Content-Type"))
 	}
 }
 
@@ -41,8 +51,21 @@ func Test_RespondWithError_Empty(t *testing.T) {
 	w := httptest.NewRecorder()
 	RespondWithError(w, http.StatusNotFound, "")
 	body := w.Body.String()
-	if body != `{"error":""}` {
-		t.Errorf("RespondNoContent() error:\n   expected empty error body:\n   got:      %s", body)
+	if body != `{"type":"about:blank","title":"Not Found","status":404,"code":"not_found"}` {
+		t.Errorf("RespondWithError() error:\n   expected problem body without detail:\n   got:      %s", body)
+	}
+}
+
+func Test_RespondWithFailure(t *testing.T) {
+	w := httptest.NewRecorder()
+	_, err := ParseCondition("wounds.current[gte]", "lots", SheetSchema)
+	RespondWithFailure(w, err)
+
+	problem := model.Problem{}
+	json.NewDecoder(w.Body).Decode(&problem)
+	if w.Code != http.StatusBadRequest || problem.Code != "invalid_query" || problem.Status != http.StatusBadRequest ||
+		len(problem.Violations) != 1 || problem.Violations[0].Field != "wounds.current" {
+		t.Errorf("RespondWithFailure() error:\n   expected: invalid_query on wounds.current\n   got:      %v %+v", w.Code, problem)
 	}
 }
 
@@ -100,20 +123,53 @@ func Test_StringToObjectID_isID(t *testing.T) {
 }
 
 func TestCheckError(t *testing.T) {
-	if code := CheckError(nil); code != http.StatusOK {
-		t.Errorf("TestCheckError(),\n   expected: %v\n   got:      %v", http.StatusOK, code)
+	tests := []struct {
+		err      error
+		expected int
+	}{
+		{nil, http.StatusOK},
+		{dberr.SheetNotFound(primitive.NewObjectID()), http.StatusNotFound},
+		{fmt.Errorf("restore: %w", dberr.DuplicateSheet(primitive.NewObjectID())), http.StatusConflict},
+		{dberr.VersionConflict(primitive.NewObjectID(), 1, 2), http.StatusConflict},
+		{dberr.NoChange(primitive.NewObjectID(), 2), http.StatusUnprocessableEntity},
+		{InsufficientXPError(10, 5), http.StatusUnprocessableEntity},
+		{InvalidIDError("bad"), http.StatusBadRequest},
+		{dberr.New(dberr.ErrNotSupported, "transactions_not_supported", "no transactions"), http.StatusNotImplemented},
+		{dberr.Wrap(errors.New("server selection error"), dberr.ErrUnavailable, "unavailable", "down"), http.StatusServiceUnavailable},
+		{fmt.Errorf("server selection error: %w", context.DeadlineExceeded), http.StatusGatewayTimeout},
+		{context.Canceled, StatusClientClosedRequest},
+		{errors.New("sheet not found"), http.StatusInternalServerError},
 	}
-	if code := CheckError(errors.New("no documents in result")); code != http.StatusNotFound {
-		t.Errorf("TestCheckError(),\n   expected: %v\n   got:      %v", http.StatusNotFound, code)
+
+	for _, test := range tests {
+		if code := CheckError(test.err); code != test.expected {
+			t.Errorf("TestCheckError(%v),\n   expected: %v\n   got:      %v", test.err, test.expected, code)
+		}
 	}
-	if code := CheckError(errors.New("E11000 duplicate key error")); code != http.StatusConflict {
-		t.Errorf("TestCheckError(),\n   expected: %v\n   got:      %v", http.StatusConflict, code)
+}
+
+func TestPayloadError(t *testing.T) {
+	err := json.Unmarshal([]byte(`{"availableXP":"lots"}`), &model.ForceCharacterSheet{})
+	problem := ProblemFor(PayloadError(err))
+	if problem.Status != http.StatusBadRequest || problem.Code != "invalid_payload" || len(problem.Violations) != 1 || problem.Violations[0].Field != "availableXP" {
+		t.Errorf("PayloadError(),\n   expected: invalid_payload on availableXP\n   got:      %+v", problem)
 	}
-	if code := CheckError(errors.New("E10334")); code != http.StatusBadRequest {
-		t.Errorf("TestCheckError(),\n   expected: %v\n   got:      %v", http.StatusBadRequest, code)
+
+	problem = ProblemFor(PayloadError(errors.New("unexpected EOF")))
+	if problem.Status != http.StatusBadRequest || problem.Code != "invalid_payload" || problem.Violations != nil {
+		t.Errorf("PayloadError(),\n   expected: invalid_payload without violations\n   got:      %+v", problem)
 	}
-	if code := CheckError(errors.New("E1")); code != http.StatusInternalServerError {
-		t.Errorf("TestCheckError(),\n   expected: %v\n   got:      %v", http.StatusInternalServerError, code)
+}
+
+func TestProblemFor(t *testing.T) {
+	problem := ProblemFor(dberr.NotFound("sheet_not_found", "sheet %v not found", "abc"))
+	if problem.Status != http.StatusNotFound || problem.Code != "sheet_not_found" || problem.Detail != "sheet abc not found" {
+		t.Errorf("ProblemFor(),\n   expected: sheet_not_found with its detail\n   got:      %+v", problem)
+	}
+
+	problem = ProblemFor(errors.New("connection to mongodb://admin:secret@db:27017 refused"))
+	if problem.Status != http.StatusInternalServerError || problem.Code != "internal_error" || strings.Contains(problem.Detail, "secret") {
+		t.Errorf("ProblemFor(),\n   expected: internal_error with a generic detail\n   got:      %+v", problem)
 	}
 }
 
@@ -132,17 +188,20 @@ func TestBuildFilter_extraFilters(t *testing.T) {
 	queryParams := url.Values{}
 	queryParams.Set("pageCount", "5")
 	queryParams.Set("pageNumber", "1")
-	queryParams.Set("sort", "priority")
-	queryParams.Set("query", "query")
-	pageNumber, pageCount, sort, bson := BuildFilter(queryParams)
+	queryParams.Set("sort", "-forceRating,characterName")
+	queryParams.Set("playerName", "Jeff")
+	pageNumber, pageCount, sort, bson, err := BuildFilter(queryParams, SheetSchema)
+	if err != nil {
+		t.Errorf("Error building filters: got error: %v, expected <nil>", err)
+	}
 	if pageNumber != 1 {
 		t.Errorf("Error building filters: got page number: %v, expected: 1", pageNumber)
 	}
 	if pageCount != 5 {
 		t.Errorf("Error building filters: got page count: %v, expected: 5", pageCount)
 	}
-	if sort != "priority" {
-		t.Errorf("Error building filters: got sort: %v, expected: priority", sort)
+	if fmt.Sprint(sort) != "[{forceRating -1} {characterName 1} {_id 1}]" {
+		t.Errorf("Error building filters: got sort: %v, expected: [{forceRating -1} {characterName 1} {_id 1}]", sort)
 	}
 	if bson == nil {
 		t.Errorf("Error building filters: got query: <nil>, expected <not nil>")
@@ -153,16 +212,19 @@ func TestBuildFilter_noExtraFilters(t *testing.T) {
 	queryParams := url.Values{}
 	queryParams.Set("pageCount", "5")
 	queryParams.Set("pageNumber", "1")
-	queryParams.Set("sort", "priority")
-	pageNumber, pageCount, sort, bson := BuildFilter(queryParams)
+	queryParams.Set("sort", "characterName")
+	pageNumber, pageCount, sort, bson, err := BuildFilter(queryParams, SheetSchema)
+	if err != nil {
+		t.Errorf("Error building filters: got error: %v, expected <nil>", err)
+	}
 	if pageNumber != 1 {
 		t.Errorf("Error building filters: got page number: %v, expected: 1", pageNumber)
 	}
 	if pageCount != 5 {
 		t.Errorf("Error building filters: got page count: %v, expected: 5", pageCount)
 	}
-	if sort != "priority" {
-		t.Errorf("Error building filters: got sort: %v, expected: priority", sort)
+	if fmt.Sprint(sort) != "[{characterName 1} {_id 1}]" {
+		t.Errorf("Error building filters: got sort: %v, expected: [{characterName 1} {_id 1}]", sort)
 	}
 	if bson != nil {
 		t.Errorf("Error building filters: got query: %v, expected <nil>", bson)
@@ -171,17 +233,600 @@ func TestBuildFilter_noExtraFilters(t *testing.T) {
 
 func TestBuildFilter_emptyfilters(t *testing.T) {
 	queryParams := url.Values{}
-	pageNumber, pageCount, sort, bson := BuildFilter(queryParams)
+	pageNumber, pageCount, sort, bson, err := BuildFilter(queryParams, SheetSchema)
+	if err != nil {
+		t.Errorf("Error building filters: got error: %v, expected <nil>", err)
+	}
 	if pageNumber != 0 {
 		t.Errorf("Error building filters: got page number: %v, expected: 0", pageNumber)
 	}
-	if pageCount != 10000 {
-		t.Errorf("Error building filters: got page count: %v, expected: 10000", pageCount)
+	if pageCount != DefaultPageCount {
+		t.Errorf("Error building filters: got page count: %v, expected: %v", pageCount, DefaultPageCount)
 	}
-	if sort != "priority" {
-		t.Errorf("Error building filters: got sort: %v, expected: priority", sort)
+	if fmt.Sprint(sort) != "[{_id 1}]" {
+		t.Errorf("Error building filters: got sort: %v, expected: [{_id 1}]", sort)
 	}
 	if bson != nil {
 		t.Errorf("Error building filters: got query: %v, expected <nil>", bson)
 	}
 }
+
+func TestBuildFilter_maxPageCount(t *testing.T) {
+	queryParams := url.Values{}
+	queryParams.Set("pageCount", "100000")
+	_, pageCount, _, _, _ := BuildFilter(queryParams, SheetSchema)
+	if pageCount != MaxPageCount {
+		t.Errorf("Error building filters: got page count: %v, expected: %v", pageCount, MaxPageCount)
+	}
+}
+
+func TestBuildFilter_after(t *testing.T) {
+	after := primitive.NewObjectID()
+	queryParams := url.Values{}
+	queryParams.Set("after", after.Hex())
+	queryParams.Set("pageNumber", "3")
+	pageNumber, _, sort, filter, _ := BuildFilter(queryParams, SheetSchema)
+	if pageNumber != 0 || fmt.Sprint(sort) != "[{_id 1}]" {
+		t.Errorf("Error building filters: got page number %v sort %v, expected: 0 [{_id 1}]", pageNumber, sort)
+	}
+	expected := fmt.Sprint(bson.M{"$and": []bson.M{{"_id": bson.M{"$gt": after}}}})
+	if fmt.Sprint(filter) != expected {
+		t.Errorf("Error building filters: got query: %v, expected %v", filter, expected)
+	}
+}
+
+func TestBuildFilter_invalid(t *testing.T) {
+	tests := []url.Values{
+		{"priority": {"1"}},
+		{"forceRating": {"two"}},
+		{"characterName[gte]": {"a"}, "skills[eq]": {"a"}},
+		{"forceRating[like]": {"2"}},
+		{"characterName[between]": {"a"}},
+		{"characterName[regex]": {"("}},
+		{"characterName[regex]": {strings.Repeat("a", MaxRegexLength+1)}},
+		{"sort": {"-priority"}},
+		{"pageNumber": {"first"}},
+		{"after": {"not-an-id"}},
+		{"after": {"5f8a1c2b3d4e5f6071829304"}, "sort": {"characterName"}},
+	}
+
+	for _, queryParams := range tests {
+		_, _, _, _, err := BuildFilter(queryParams, SheetSchema)
+		if err == nil || CheckError(err) != http.StatusBadRequest {
+			t.Errorf("BuildFilter(%v) error:\ngot: %v\nexpected: an invalid query error", queryParams, err)
+		}
+	}
+}
+
+func TestParseCondition(t *testing.T) {
+	id := primitive.NewObjectID()
+	tests := []struct {
+		param    string
+		value    string
+		expected bson.M
+	}{
+		{"forceRating", "2", bson.M{"forceRating": int64(2)}},
+		{"characteristics.brawn[gte]", "3", bson.M{"characteristics.brawn": bson.M{"$gte": int64(3)}}},
+		{"skills.name", "athletics", bson.M{"skills.name": "athletics"}},
+		{"skills.career[ne]", "true", bson.M{"skills.career": bson.M{"$ne": true}}},
+		{"species[in]", "Human,Twi'lek", bson.M{"species": bson.M{"$in": []interface{}{"Human", "Twi'lek"}}}},
+		{"availableXP[nin]", "0,5", bson.M{"availableXP": bson.M{"$nin": []interface{}{int64(0), int64(5)}}}},
+		{"characterName[like]", "man.", bson.M{"characterName": bson.M{"$regex": `man\.`, "$options": "i"}}},
+		{"characterName[ieq]", "mando", bson.M{"characterName": bson.M{"$regex": "^mando$", "$options": "i"}}},
+		{"career[regex]", "^Man", bson.M{"career": bson.M{"$regex": "^Man"}}},
+		{"talents[exists]", "false", bson.M{"talents": bson.M{"$exists": false}}},
+		{"_id", id.Hex(), bson.M{"_id": id}},
+	}
+
+	for _, test := range tests {
+		condition, err := ParseCondition(test.param, test.value, SheetSchema)
+		if err != nil || fmt.Sprint(condition) != fmt.Sprint(test.expected) {
+			t.Errorf("ParseCondition(%v=%v) error:\ngot: %v %v\nexpected: %v", test.param, test.value, condition, err, test.expected)
+		}
+	}
+}
+
+func TestParseProjection(t *testing.T) {
+	tests := map[string]string{
+		"":                           "map[]",
+		"characterName, wounds":      "map[_id:1 characterName:1 wounds:1]",
+		"-talents,-weapons":          "map[talents:0 weapons:0]",
+		"characterName,-_id":         "map[_id:0 characterName:1]",
+		"skills.name":                "map[_id:1 skills.name:1]",
+		"charcaterDescription":       "error",
+		"characterName,-talents":     "error",
+		"characterDescription.hair,": "map[_id:1 characterDescription.hair:1]",
+	}
+
+	for value, expected := range tests {
+		projection, err := ParseProjection(value, SheetSchema)
+		got := fmt.Sprint(projection)
+		if err != nil {
+			got = "error"
+		}
+		if got != expected {
+			t.Errorf("ParseProjection(%q) error:\ngot: %v %v\nexpected: %v", value, projection, err, expected)
+		}
+	}
+}
+
+func TestProjection_Apply(t *testing.T) {
+	doc := map[string]interface{}{
+		"_id":           "1",
+		"characterName": "Mando",
+		"wounds":        map[string]interface{}{"threshold": 15, "current": 2},
+		"skills": []interface{}{
+			map[string]interface{}{"name": "athletics", "level": 1},
+			map[string]interface{}{"name": "cool", "level": 2},
+		},
+	}
+
+	included := Projection{"_id": 1, "wounds.current": 1, "skills.name": 1}.Apply(doc)
+	expected := "map[_id:1 skills:[map[name:athletics] map[name:cool]] wounds:map[current:2]]"
+	if fmt.Sprint(included) != expected {
+		t.Errorf("Projection.Apply() include error:\ngot: %v\nexpected: %v", included, expected)
+	}
+
+	excluded := Projection{"skills": 0, "wounds.threshold": 0}.Apply(doc)
+	expected = "map[_id:1 characterName:Mando wounds:map[current:2]]"
+	if fmt.Sprint(excluded) != expected {
+		t.Errorf("Projection.Apply() exclude error:\ngot: %v\nexpected: %v", excluded, expected)
+	}
+
+	if all := Projection(nil).Apply(doc); len(all) != len(doc) {
+		t.Errorf("Projection.Apply() nil error:\ngot: %v\nexpected: %v", all, doc)
+	}
+}
+
+func TestSparse(t *testing.T) {
+	sheet := model.ForceCharacterSheet{
+		ID:                   primitive.NewObjectID(),
+		CharacterName:        "Mando",
+		CharacterDescription: model.CharacterDescription{Hair: "brown"},
+	}
+
+	sparse, err := Sparse([]model.ForceCharacterSheet{sheet}, Projection{"_id": 1, "characterDescription.hair": 1}, SheetSchema)
+	expected := "[map[_id:" + sheet.ID.Hex() + " characterDescription:map[hair:brown]]]"
+	if err != nil || fmt.Sprint(sparse) != expected {
+		t.Errorf("Sparse() error:\ngot: %v %v\nexpected: %v", sparse, err, expected)
+	}
+}
+
+func TestNewSchema(t *testing.T) {
+	tests := map[string]FieldType{
+		"_id":                     ObjectIDField,
+		"characterName":           StringField,
+		"characteristics.brawn":   IntField,
+		"skills":                  ObjectField,
+		"skills.career":           BoolField,
+		"talents.forcePower.name": StringField,
+	}
+
+	for path, expected := range tests {
+		if fieldType, ok := SheetSchema.Lookup(path); !ok || fieldType != expected {
+			t.Errorf("NewSchema() error:\ngot: %v %v for %v\nexpected: %v", fieldType, ok, path, expected)
+		}
+	}
+
+	if fieldType, ok := ArchivedSheetSchema.Lookup("deletedAt"); !ok || fieldType != TimeField {
+		t.Errorf("NewSchema() error:\ngot: %v %v for deletedAt\nexpected: %v", fieldType, ok, TimeField)
+	}
+	if _, ok := SheetSchema.Lookup("deletedAt"); ok {
+		t.Errorf("NewSchema() error:\ngot: deletedAt in the sheet schema\nexpected: only in the archive schema")
+	}
+}
+
+func TestWithoutPagination(t *testing.T) {
+	queryParams := url.Values{}
+	queryParams.Set("pageCount", "5")
+	queryParams.Set("pageNumber", "2")
+	queryParams.Set("sort", "characterName")
+	queryParams.Set("after", primitive.NewObjectID().Hex())
+	queryParams.Set("playerName", "Jeff")
+	filters := WithoutPagination(queryParams)
+	if len(filters) != 1 || filters.Get("playerName") != "Jeff" {
+		t.Errorf("WithoutPagination() error:\ngot: %v\nexpected: map[playerName:[Jeff]]", filters)
+	}
+}
+
+func TestBuildPageLinks(t *testing.T) {
+	u, _ := url.Parse("/force-character-sheet?pageNumber=2&pageCount=10&playerName=Jeff")
+	links := BuildPageLinks(u, 10, 35, nil)
+	expected := PageLinks{
+		"first": "/force-character-sheet?pageCount=10&pageNumber=1&playerName=Jeff",
+		"prev":  "/force-character-sheet?pageCount=10&pageNumber=1&playerName=Jeff",
+		"next":  "/force-character-sheet?pageCount=10&pageNumber=3&playerName=Jeff",
+		"last":  "/force-character-sheet?pageCount=10&pageNumber=4&playerName=Jeff",
+	}
+	if fmt.Sprint(links) != fmt.Sprint(expected) {
+		t.Errorf("BuildPageLinks() error:\ngot: %v\nexpected: %v", links, expected)
+	}
+
+	w := httptest.NewRecorder()
+	SetLinkHeader(w, links)
+	if header := w.Header().Get("Link"); header != `<`+expected["first"]+`>; rel="first", <`+expected["prev"]+`>; rel="prev", <`+expected["next"]+`>; rel="next", <`+expected["last"]+`>; rel="last"` {
+		t.Errorf("SetLinkHeader() error:\ngot: %v", header)
+	}
+}
+
+func TestBuildPageLinks_after(t *testing.T) {
+	after := primitive.NewObjectID()
+	last := primitive.NewObjectID()
+	u, _ := url.Parse("/force-character-sheet?pageCount=2&after=" + after.Hex())
+
+	links := BuildPageLinks(u, 2, 10, &last)
+	if links["next"] != "/force-character-sheet?after="+last.Hex()+"&pageCount=2" || links["prev"] != "" {
+		t.Errorf("BuildPageLinks() error:\ngot: %v\nexpected: next after %v", links, last.Hex())
+	}
+
+	links = BuildPageLinks(u, 1, 10, &last)
+	if _, ok := links["next"]; ok {
+		t.Errorf("BuildPageLinks() error:\ngot: %v\nexpected: no next link on a short page", links)
+	}
+
+	u, _ = url.Parse("/force-character-sheet?pageNumber=3&pageCount=2&fields=characterName&playerName=Jeff&after=" + after.Hex())
+	links = BuildPageLinks(u, 2, 10, &last)
+	if first := "/force-character-sheet?fields=characterName&pageCount=2&playerName=Jeff"; links["first"] != first {
+		t.Errorf("BuildPageLinks() error:\ngot: %v\nexpected: %v", links["first"], first)
+	}
+	if next := "/force-character-sheet?after=" + last.Hex() + "&fields=characterName&pageCount=2&playerName=Jeff"; links["next"] != next {
+		t.Errorf("BuildPageLinks() error:\ngot: %v\nexpected: %v", links["next"], next)
+	}
+}
+
+func TestRequestUser(t *testing.T) {
+	r, _ := http.NewRequest("DELETE", "/any", nil)
+	if user := RequestUser(r); user != "anonymous" {
+		t.Errorf("RequestUser() error:\n   expected: anonymous\n   got:      %v", user)
+	}
+
+	r.Header.Set(UserHeader, " gm ")
+	if user := RequestUser(r); user != "gm" {
+		t.Errorf("RequestUser() error:\n   expected: gm\n   got:      %v", user)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	from := map[string]interface{}{
+		"_id":         "ignored",
+		"version":     1,
+		"availableXP": 25,
+		"skills":      []map[string]interface{}{{"name": "athletics", "level": 1}},
+	}
+	to := map[string]interface{}{
+		"_id":         "ignored",
+		"version":     2,
+		"availableXP": 10,
+		"skills":      []map[string]interface{}{{"name": "athletics", "level": 2}, {"name": "cool", "level": 1}},
+	}
+
+	changes, err := Diff(from, to)
+	if err != nil {
+		t.Errorf("Diff() error:\n   expected: <nil>\n   got:      %v", err)
+	}
+
+	paths := []string{}
+	for _, change := range changes {
+		paths = append(paths, change.Path)
+	}
+	expected := []string{"availableXP", "skills.0.level", "skills.1"}
+	if fmt.Sprint(paths) != fmt.Sprint(expected) {
+		t.Errorf("Diff() error:\n   expected: %v\n   got:      %v", expected, paths)
+	}
+
+	summary := ChangeSummary(changes)
+	if summary != "updated availableXP, skills" {
+		t.Errorf("ChangeSummary() error:\n   expected: updated availableXP, skills\n   got:      %v", summary)
+	}
+}
+
+func TestParseMergePatch(t *testing.T) {
+	patch, err := ParseMergePatch([]byte(`{"wounds":{"current":3},"notes":null,"skills":[{"name":"cool"}]}`))
+	if err != nil {
+		t.Fatalf("ParseMergePatch() error:\n   expected: <nil>\n   got:      %v", err)
+	}
+
+	doc, err := patch([]byte(`{"wounds":{"threshold":12,"current":0},"notes":"old","skills":[{"name":"athletics"}],"availableXP":9007199254740993}`))
+	expected := `{"availableXP":9007199254740993,"skills":[{"name":"cool"}],"wounds":{"current":3,"threshold":12}}`
+	if err != nil || string(doc) != expected {
+		t.Errorf("MergePatch() error:\n   expected: %v\n   got:      %s %v", expected, doc, err)
+	}
+
+	_, err = ParseMergePatch([]byte(`{"wounds":`))
+	if dberr.Code(err) != "invalid_patch" {
+		t.Errorf("ParseMergePatch() malformed error:\n   expected: invalid_patch\n   got:      %v", err)
+	}
+}
+
+func TestParseJSONPatch(t *testing.T) {
+	doc := `{"a":{"b~c":1,"d/e":[1,2,3]},"f":"x"}`
+	tests := []struct {
+		patch    string
+		expected string
+		code     string
+	}{
+		{`[{"op":"add","path":"/a/d~1e/1","value":9}]`, `{"a":{"b~c":1,"d/e":[1,9,2,3]},"f":"x"}`, ""},
+		{`[{"op":"add","path":"/a/d~1e/-","value":4}]`, `{"a":{"b~c":1,"d/e":[1,2,3,4]},"f":"x"}`, ""},
+		{`[{"op":"remove","path":"/a/b~0c"}]`, `{"a":{"d/e":[1,2,3]},"f":"x"}`, ""},
+		{`[{"op":"replace","path":"/f","value":{"g":null}}]`, `{"a":{"b~c":1,"d/e":[1,2,3]},"f":{"g":null}}`, ""},
+		{`[{"op":"move","from":"/f","path":"/a/f"}]`, `{"a":{"b~c":1,"d/e":[1,2,3],"f":"x"}}`, ""},
+		{`[{"op":"copy","from":"/a/d~1e/0","path":"/g"}]`, `{"a":{"b~c":1,"d/e":[1,2,3]},"f":"x","g":1}`, ""},
+		{`[{"op":"test","path":"/a/b~0c","value":1.0},{"op":"replace","path":"/a/b~0c","value":2}]`, `{"a":{"b~c":2,"d/e":[1,2,3]},"f":"x"}`, ""},
+		{`[{"op":"replace","path":"/f","value":"y"},{"op":"test","path":"/f","value":"x"}]`, "", "patch_test_failed"},
+		{`[{"op":"remove","path":"/missing"}]`, "", "patch_conflict"},
+		{`[{"op":"add","path":"/a/d~1e/5","value":1}]`, "", "patch_conflict"},
+		{`[{"op":"move","from":"/a","path":"/a/h"}]`, "", "patch_conflict"},
+	}
+
+	for _, test := range tests {
+		patch, err := ParseJSONPatch([]byte(test.patch))
+		if err != nil {
+			t.Errorf("ParseJSONPatch(%v) error:\n   expected: <nil>\n   got:      %v", test.patch, err)
+			continue
+		}
+
+		patched, err := patch([]byte(doc))
+		if string(patched) != test.expected || dberr.Code(err) != test.code {
+			t.Errorf("JSONPatch(%v) error:\n   expected: %v %v\n   got:      %s %v", test.patch, test.expected, test.code, patched, err)
+		}
+	}
+
+	for _, patch := range []string{`{"op":"add"}`, `[{"op":"jump","path":"/a"}]`, `[{"op":"add","path":"a","value":1}]`, `[{"op":"replace","path":"/a"}]`} {
+		if _, err := ParseJSONPatch([]byte(patch)); dberr.Code(err) != "invalid_patch" {
+			t.Errorf("ParseJSONPatch(%v) error:\n   expected: invalid_patch\n   got:      %v", patch, err)
+		}
+	}
+}
+
+func TestSheetETag(t *testing.T) {
+	if etag := SheetETag(3, nil); etag != `"3"` {
+		t.Errorf("SheetETag() error:\n   expected: \"3\"\n   got:      %v", etag)
+	}
+
+	first := SheetETag(3, Projection{"_id": 1, "characterName": 1})
+	second := SheetETag(3, Projection{"characterName": 1, "_id": 1})
+	other := SheetETag(3, Projection{"_id": 1, "playerName": 1})
+	if first != second || first == other || first == SheetETag(3, nil) {
+		t.Errorf("SheetETag() projection error:\n   expected: a tag per set of fields\n   got:      %v %v %v", first, second, other)
+	}
+}
+
+func TestMatchETag(t *testing.T) {
+	tests := []struct {
+		header   string
+		weak     bool
+		expected bool
+	}{
+		{`"3"`, false, true},
+		{`"2", "3"`, false, true},
+		{`*`, false, true},
+		{`"2"`, false, false},
+		{`W/"3"`, false, false},
+		{`W/"3"`, true, true},
+		{`"4",W/"3"`, true, true},
+	}
+
+	for _, test := range tests {
+		if matched := MatchETag(test.header, `"3"`, test.weak); matched != test.expected {
+			t.Errorf("MatchETag(%v, %v) error:\n   expected: %v\n   got:      %v", test.header, test.weak, test.expected, matched)
+		}
+	}
+}
+
+func TestProjection_Including(t *testing.T) {
+	included := Projection{"_id": 1, "characterName": 1}.Including("version")
+	if fmt.Sprint(included) != fmt.Sprint(Projection{"_id": 1, "characterName": 1, "version": 1}) {
+		t.Errorf("Including() error:\n   expected: version included\n   got:      %v", included)
+	}
+
+	excluded := Projection{"talents": 0, "version": 0}.Including("version")
+	if fmt.Sprint(excluded) != fmt.Sprint(Projection{"talents": 0}) {
+		t.Errorf("Including() error:\n   expected: version no longer excluded\n   got:      %v", excluded)
+	}
+
+	if Projection(nil).Including("version") != nil || (Projection{"version": 0}).Including("version") != nil {
+		t.Errorf("Including() error:\n   expected: <nil> for a projection of every field\n   got:      a projection")
+	}
+}
+
+func TestWithElementIDsFrom(t *testing.T) {
+	kept, taken := primitive.NewObjectID(), primitive.NewObjectID()
+	previous := model.ForceCharacterSheet{Weapons: []model.Weapons{{ID: kept, Name: "Blaster"}, {ID: taken, Name: "Vibroknife"}}}
+	sheet := model.ForceCharacterSheet{Weapons: []model.Weapons{{Name: "Blaster"}, {Name: "Vibroknife"}, {ID: taken, Name: "Lightsaber"}}}
+
+	withIDs := WithElementIDsFrom(sheet, previous)
+	if withIDs.Weapons[0].ID != kept || withIDs.Weapons[1].ID.IsZero() || withIDs.Weapons[1].ID == taken || withIDs.Weapons[2].ID != taken {
+		t.Errorf("WithElementIDsFrom() error:\n   expected: %v, a new ID, %v\n   got:      %+v", kept, taken, withIDs.Weapons)
+	}
+	if !sheet.Weapons[0].ID.IsZero() {
+		t.Errorf("WithElementIDsFrom() error:\n   expected: the sheet passed in left as it is\n   got:      %+v", sheet.Weapons)
+	}
+	if !MissingElementIDs(sheet) || MissingElementIDs(withIDs) || MissingElementIDs(model.ForceCharacterSheet{}) {
+		t.Errorf("MissingElementIDs() error:\n   expected: true only for the sheet without IDs\n   got:      %v %v", MissingElementIDs(sheet), MissingElementIDs(withIDs))
+	}
+}
+
+func TestSheetArray(t *testing.T) {
+	array, found := FindSheetArray("equipment/personalGear")
+	if !found || array.Path != "equipment.personalGear" {
+		t.Fatalf("FindSheetArray() error:\n   expected: equipment.personalGear\n   got:      %+v %v", array, found)
+	}
+	if _, found := FindSheetArray("wounds"); found {
+		t.Errorf("FindSheetArray() error:\n   expected: no array named wounds\n   got:      found")
+	}
+
+	sheet := model.ForceCharacterSheet{}
+	if elements := array.Elements(sheet).([]model.Gear); elements == nil || len(elements) != 0 {
+		t.Errorf("Elements() error:\n   expected: []\n   got:      %#v", elements)
+	}
+
+	first, second := primitive.NewObjectID(), primitive.NewObjectID()
+	element := array.NewElement()
+	SetElementID(element, first)
+	array.Append(&sheet, element)
+	array.Append(&sheet, model.Gear{ID: second, Gear: "Comlink"})
+
+	if !array.Replace(&sheet, first, model.Gear{ID: first, Gear: "Stimpack"}) || array.Replace(&sheet, primitive.NewObjectID(), model.Gear{}) {
+		t.Errorf("Replace() error:\n   expected: only the element with the ID replaced\n   got:      %+v", sheet.Equipment.PersonalGear)
+	}
+	if found, ok := array.FindElement(sheet, first); !ok || found.(model.Gear).Gear != "Stimpack" {
+		t.Errorf("FindElement() error:\n   expected: Stimpack\n   got:      %+v", found)
+	}
+
+	if !array.Remove(&sheet, first) || array.Remove(&sheet, first) || len(sheet.Equipment.PersonalGear) != 1 || sheet.Equipment.PersonalGear[0].ID != second {
+		t.Errorf("Remove() error:\n   expected: [Comlink]\n   got:      %+v", sheet.Equipment.PersonalGear)
+	}
+}
+
+func TestHealthAction_Apply(t *testing.T) {
+	tests := []struct {
+		action  HealthAction
+		amount  int64
+		wounds  int64
+		strain  int64
+		changed bool
+	}{
+		{TakeDamage, 5, 6, 2, true},
+		{TakeDamage, 3, 4, 2, false},
+		{SufferStrain, 3, 4, 5, true},
+		{SufferStrain, 0, 4, 2, false},
+		{RecoverStrain, 1, 4, 1, true},
+		{RecoverStrain, 5, 4, 0, true},
+		{HealWounds, 10, 0, 2, true},
+	}
+
+	for _, test := range tests {
+		sheet := model.ForceCharacterSheet{SoakValue: 3, Wounds: model.Amount{Current: 4}, Strain: model.Amount{Current: 2}}
+		changed := test.action.Apply(&sheet, test.amount)
+		if changed != test.changed || sheet.Wounds.Current != test.wounds || sheet.Strain.Current != test.strain {
+			t.Errorf("Apply() %v %v error:\n   expected: wounds %v strain %v changed %v\n   got:      wounds %v strain %v changed %v",
+				test.action.Name, test.amount, test.wounds, test.strain, test.changed, sheet.Wounds.Current, sheet.Strain.Current, changed)
+		}
+	}
+
+	if HealWounds.Apply(&model.ForceCharacterSheet{}, 2) {
+		t.Errorf("Apply() error:\n   expected: healing at 0 wounds to change nothing\n   got:      changed")
+	}
+}
+
+func TestHealthState(t *testing.T) {
+	sheet := model.ForceCharacterSheet{SoakValue: 3, Wounds: model.Amount{Threshold: 10, Current: 11}, Strain: model.Amount{Threshold: 10, Current: 10}}
+
+	state := HealthState(sheet, TakeDamage, 2)
+	if !state.WoundThresholdExceeded || state.StrainThresholdExceeded || state.Soaked != 2 {
+		t.Errorf("HealthState() error:\n   expected: wound threshold exceeded, 2 soaked\n   got:      %+v", state)
+	}
+
+	state = HealthState(sheet, SufferStrain, 2)
+	if state.Soaked != 0 {
+		t.Errorf("HealthState() error:\n   expected: nothing soaked for strain\n   got:      %+v", state)
+	}
+
+	if TakeDamage.Summary(5) != "took 5 damage" {
+		t.Errorf("Summary() error:\n   expected: took 5 damage\n   got:      %v", TakeDamage.Summary(5))
+	}
+}
+
+func TestValidatePurchase(t *testing.T) {
+	tests := []struct {
+		purchase model.PurchaseRequest
+		field    string
+	}{
+		{model.PurchaseRequest{Type: model.PurchaseSkill, Name: "Athletics"}, ""},
+		{model.PurchaseRequest{Type: "level", Name: "Athletics"}, "type"},
+		{model.PurchaseRequest{Type: model.PurchaseSkill, Name: " "}, "name"},
+		{model.PurchaseRequest{Type: model.PurchaseCharacteristic, Name: "Luck"}, "name"},
+		{model.PurchaseRequest{Type: model.PurchaseTalent, Name: "Grit"}, ""},
+	}
+
+	for _, test := range tests {
+		err := ValidatePurchase(test.purchase)
+		violations := dberr.Violations(err)
+		if (test.field == "" && err != nil) || (test.field != "" && (len(violations) != 1 || violations[0].Field != test.field)) {
+			t.Errorf("ValidatePurchase(%+v) error:\n   expected: a violation of %q\n   got:      %v", test.purchase, test.field, err)
+		}
+	}
+}
+
+func TestPurchase(t *testing.T) {
+	mockSheet := func() model.ForceCharacterSheet {
+		return model.ForceCharacterSheet{
+			AvailableXP:      100,
+			CharacterVersion: 1,
+			ForceRating:      1,
+			Characteristics:  model.Characteristics{Brawn: 2, Willpower: 5},
+			Skills:           []model.Skills{{Name: "Athletics", Career: true, Level: 1}, {Name: "Cool", Level: 0}, {Name: "Lightsaber", Career: true, Level: 5}},
+			Talents:          []model.Talents{{Name: "Grit"}, {Name: "Move", ForcePower: []model.ForcePower{{Name: "Strength"}}}, {Name: "Quick Draw"}},
+		}
+	}
+
+	tests := []struct {
+		purchase model.PurchaseRequest
+		cost     int64
+		rank     int64
+		code     string
+	}{
+		{model.PurchaseRequest{Type: model.PurchaseSkill, Name: "athletics"}, 10, 2, ""},
+		{model.PurchaseRequest{Type: model.PurchaseSkill, Name: "Cool"}, 10, 1, ""},
+		{model.PurchaseRequest{Type: model.PurchaseSkill, Name: "Lightsaber"}, 0, 6, "rank_cap_reached"},
+		{model.PurchaseRequest{Type: model.PurchaseSkill, Name: "Piloting"}, 0, 0, "skill_not_on_sheet"},
+		{model.PurchaseRequest{Type: model.PurchaseCharacteristic, Name: "Brawn"}, 30, 3, ""},
+		{model.PurchaseRequest{Type: model.PurchaseCharacteristic, Name: "Willpower"}, 0, 6, "rank_cap_reached"},
+		{model.PurchaseRequest{Type: model.PurchaseTalent, Name: "grit"}, 5, 2, ""},
+		{model.PurchaseRequest{Type: model.PurchaseTalent, Name: "Quick Draw"}, 0, 2, "talent_already_owned"},
+		{model.PurchaseRequest{Type: model.PurchaseTalent, Name: "Dedication"}, 25, 1, ""},
+		{model.PurchaseRequest{Type: model.PurchaseTalent, Name: "Force Choke"}, 0, 0, "not_in_catalog"},
+		{model.PurchaseRequest{Type: model.PurchaseForcePower, Name: "Sense"}, 10, 1, ""},
+		{model.PurchaseRequest{Type: model.PurchaseForcePower, Name: "Move"}, 0, 0, "force_power_already_owned"},
+		{model.PurchaseRequest{Type: model.PurchaseForcePower, Name: "Move", Upgrade: "Strength"}, 10, 2, ""},
+		{model.PurchaseRequest{Type: model.PurchaseForcePower, Name: "Move", Upgrade: "Duration"}, 0, 0, "not_in_catalog"},
+		{model.PurchaseRequest{Type: model.PurchaseForcePower, Name: "Enhance", Upgrade: "Range"}, 0, 0, "force_power_not_owned"},
+		{model.PurchaseRequest{Type: model.PurchaseForcePower, Name: "Bind"}, 0, 0, "not_in_catalog"},
+	}
+
+	for _, test := range tests {
+		sheet := mockSheet()
+		result, err := Purchase(&sheet, test.purchase, catalog.Default())
+		if dberr.Code(err) != test.code || result.Rank != test.rank || (err == nil && (result.Cost != test.cost || sheet.AvailableXP != 100-test.cost || result.AvailableXP != sheet.AvailableXP)) {
+			t.Errorf("Purchase(%+v) error:\n   expected: cost %v rank %v %v\n   got:      %+v %v", test.purchase, test.cost, test.rank, test.code, result, err)
+		}
+		if err != nil && sheet.AvailableXP != 100 {
+			t.Errorf("Purchase(%+v) error:\n   expected: nothing spent\n   got:      %v available", test.purchase, sheet.AvailableXP)
+		}
+	}
+
+	sheet := mockSheet()
+	_, _ = Purchase(&sheet, model.PurchaseRequest{Type: model.PurchaseSkill, Name: "Athletics"}, catalog.Default())
+	_, _ = Purchase(&sheet, model.PurchaseRequest{Type: model.PurchaseForcePower, Name: "Move", Upgrade: "Strength"}, catalog.Default())
+	if sheet.Skills[0].Level != 2 || len(sheet.Talents[1].ForcePower) != 2 || !sheet.Talents[1].ForcePower[1].Completed {
+		t.Errorf("Purchase() error:\n   expected: athletics at rank 2 and a second strength upgrade\n   got:      %+v %+v", sheet.Skills[0], sheet.Talents[1])
+	}
+
+	sheet = mockSheet()
+	sheet.AvailableXP = 25
+	_, err := Purchase(&sheet, model.PurchaseRequest{Type: model.PurchaseCharacteristic, Name: "brawn"}, catalog.Default())
+	if dberr.Code(err) != "insufficient_xp" || sheet.Characteristics.Brawn != 2 {
+		t.Errorf("Purchase() error:\n   expected: insufficient_xp\n   got:      %v brawn %v", err, sheet.Characteristics.Brawn)
+	}
+
+	sheet = mockSheet()
+	sheet.CharacterVersion = 2
+	_, err = Purchase(&sheet, model.PurchaseRequest{Type: model.PurchaseCharacteristic, Name: "brawn"}, catalog.Default())
+	if dberr.Code(err) != "characteristic_locked" {
+		t.Errorf("Purchase() error:\n   expected: characteristic_locked\n   got:      %v", err)
+	}
+
+	sheet = mockSheet()
+	sheet.ForceRating = 0
+	_, err = Purchase(&sheet, model.PurchaseRequest{Type: model.PurchaseForcePower, Name: "Sense"}, catalog.Default())
+	if dberr.Code(err) != "not_force_sensitive" {
+		t.Errorf("Purchase() error:\n   expected: not_force_sensitive\n   got:      %v", err)
+	}
+
+	sheet = mockSheet()
+	sheet.Talents = append(sheet.Talents, model.Talents{Name: "Enhance", ForcePower: []model.ForcePower{{Name: "Range"}}})
+	result, err := Purchase(&sheet, model.PurchaseRequest{Type: model.PurchaseForcePower, Name: "Enhance", Upgrade: "range"}, catalog.Default())
+	if dberr.Code(err) != "rank_cap_reached" || result.Rank != 2 {
+		t.Errorf("Purchase() error:\n   expected: rank_cap_reached at rank 2\n   got:      %+v %v", result, err)
+	}
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+)
+
+// ignoredDiffFields are bookkeeping fields that change on every update and are left out of diffs
+var ignoredDiffFields = map[string]bool{
+	"_id":     true,
+	"version": true,
+}
+
+// Diff compares two values by their JSON representation and returns every changed field path
+func Diff(from interface{}, to interface{}) ([]model.FieldChange, error) {
+	fromJSON, err := toJSONValue(from)
+	if err != nil {
+		return nil, err
+	}
+
+	toJSON, err := toJSONValue(to)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []model.FieldChange{}
+	diffValues("", fromJSON, toJSON, &changes)
+
+	return changes, nil
+}
+
+// ChangeSummary describes the top level fields touched by a set of changes
+func ChangeSummary(changes []model.FieldChange) string {
+	if len(changes) == 0 {
+		return "no changes"
+	}
+
+	fields := []string{}
+	seen := map[string]bool{}
+	for _, change := range changes {
+		field := strings.SplitN(change.Path, ".", 2)[0]
+		if !seen[field] {
+			seen[field] = true
+			fields = append(fields, field)
+		}
+	}
+
+	return "updated " + strings.Join(fields, ", ")
+}
+
+func toJSONValue(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var result interface{}
+	err = json.Unmarshal(data, &result)
+
+	return result, err
+}
+
+func diffValues(path string, from interface{}, to interface{}, changes *[]model.FieldChange) {
+	fromMap, fromIsMap := from.(map[string]interface{})
+	toMap, toIsMap := to.(map[string]interface{})
+	if fromIsMap && toIsMap {
+		keys := []string{}
+		for key := range fromMap {
+			keys = append(keys, key)
+		}
+		for key := range toMap {
+			if _, ok := fromMap[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if path == "" && ignoredDiffFields[key] {
+				continue
+			}
+			diffValues(joinPath(path, key), fromMap[key], toMap[key], changes)
+		}
+		return
+	}
+
+	fromSlice, fromIsSlice := from.([]interface{})
+	toSlice, toIsSlice := to.([]interface{})
+	if fromIsSlice && toIsSlice {
+		length := len(fromSlice)
+		if len(toSlice) > length {
+			length = len(toSlice)
+		}
+
+		for i := 0; i < length; i++ {
+			var fromElem, toElem interface{}
+			if i < len(fromSlice) {
+				fromElem = fromSlice[i]
+			}
+			if i < len(toSlice) {
+				toElem = toSlice[i]
+			}
+			diffValues(joinPath(path, strconv.Itoa(i)), fromElem, toElem, changes)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(from, to) {
+		*changes = append(*changes, model.FieldChange{
+			Path: path,
+			From: from,
+			To:   to,
+		})
+	}
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
@@ -0,0 +1,181 @@
+package api
+
+import (
+	"reflect"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SheetArray is an array of a sheet whose elements have IDs, so that they can be added, replaced and removed one at a time
+type SheetArray struct {
+	// Name is how routes refer to the array, such as weapons or equipment/armor
+	Name string
+	// Path is the dotted path of the array in a stored sheet, such as weapons or equipment.armor
+	Path string
+	// slice returns the settable slice of the array in a sheet
+	slice func(sheet *model.ForceCharacterSheet) reflect.Value
+}
+
+// SheetArrays are the arrays of a sheet whose elements have IDs
+var SheetArrays = []SheetArray{
+	{"skills", "skills", func(sheet *model.ForceCharacterSheet) reflect.Value { return reflect.ValueOf(&sheet.Skills).Elem() }},
+	{"weapons", "weapons", func(sheet *model.ForceCharacterSheet) reflect.Value { return reflect.ValueOf(&sheet.Weapons).Elem() }},
+	{"talents", "talents", func(sheet *model.ForceCharacterSheet) reflect.Value { return reflect.ValueOf(&sheet.Talents).Elem() }},
+	{"criticalInjuries", "criticalInjuries", func(sheet *model.ForceCharacterSheet) reflect.Value {
+		return reflect.ValueOf(&sheet.CriticalInjuries).Elem()
+	}},
+	{"equipment/armor", "equipment.armor", func(sheet *model.ForceCharacterSheet) reflect.Value {
+		return reflect.ValueOf(&sheet.Equipment.Armor).Elem()
+	}},
+	{"equipment/personalGear", "equipment.personalGear", func(sheet *model.ForceCharacterSheet) reflect.Value {
+		return reflect.ValueOf(&sheet.Equipment.PersonalGear).Elem()
+	}},
+}
+
+// FindSheetArray returns the sheet array with the route name
+func FindSheetArray(name string) (SheetArray, bool) {
+	for _, array := range SheetArrays {
+		if array.Name == name {
+			return array, true
+		}
+	}
+
+	return SheetArray{}, false
+}
+
+// NewElement returns a pointer to a new, empty element of the array, ready to decode a request body into
+func (a SheetArray) NewElement() interface{} {
+	return reflect.New(a.slice(&model.ForceCharacterSheet{}).Type().Elem()).Interface()
+}
+
+// Elements returns the elements of the array in a sheet, never nil
+func (a SheetArray) Elements(sheet model.ForceCharacterSheet) interface{} {
+	slice := a.slice(&sheet)
+	if slice.IsNil() {
+		return reflect.MakeSlice(slice.Type(), 0, 0).Interface()
+	}
+
+	return slice.Interface()
+}
+
+// FindElement returns the element of the array in a sheet with the ID
+func (a SheetArray) FindElement(sheet model.ForceCharacterSheet, ID primitive.ObjectID) (interface{}, bool) {
+	slice := a.slice(&sheet)
+	for i := 0; i < slice.Len(); i++ {
+		if idOf(slice.Index(i)) == ID {
+			return slice.Index(i).Interface(), true
+		}
+	}
+
+	return nil, false
+}
+
+// Append adds an element, or a pointer to one, to the end of the array in a sheet
+func (a SheetArray) Append(sheet *model.ForceCharacterSheet, element interface{}) {
+	slice := a.slice(sheet)
+	slice.Set(reflect.Append(slice, reflect.Indirect(reflect.ValueOf(element))))
+}
+
+// Replace puts an element, or a pointer to one, in place of the element of the array in a sheet with the ID and reports whether there was one
+func (a SheetArray) Replace(sheet *model.ForceCharacterSheet, ID primitive.ObjectID, element interface{}) bool {
+	slice := a.slice(sheet)
+	for i := 0; i < slice.Len(); i++ {
+		if idOf(slice.Index(i)) == ID {
+			slice.Index(i).Set(reflect.Indirect(reflect.ValueOf(element)))
+			return true
+		}
+	}
+
+	return false
+}
+
+// Remove takes the element with the ID out of the array in a sheet and reports whether there was one
+func (a SheetArray) Remove(sheet *model.ForceCharacterSheet, ID primitive.ObjectID) bool {
+	slice := a.slice(sheet)
+	for i := 0; i < slice.Len(); i++ {
+		if idOf(slice.Index(i)) == ID {
+			slice.Set(reflect.AppendSlice(slice.Slice(0, i), slice.Slice(i+1, slice.Len())))
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetElementID sets the ID of an element returned by NewElement
+func SetElementID(element interface{}, elementID primitive.ObjectID) {
+	reflect.ValueOf(element).Elem().FieldByName("ID").Set(reflect.ValueOf(elementID))
+}
+
+// WithElementIDs gives every element of the sheet arrays that has no ID yet a new one, the arrays of the sheet passed in are left as they are
+func WithElementIDs(sheet model.ForceCharacterSheet) model.ForceCharacterSheet {
+	return WithElementIDsFrom(sheet, model.ForceCharacterSheet{})
+}
+
+// WithElementIDsFrom gives every element of the sheet arrays that has no ID yet the ID of the element at the same place in the previous
+// version of the sheet, or a new one when that ID is taken or there is no such element. Clients that don't send element IDs back
+// so keep the IDs of the elements they left alone
+func WithElementIDsFrom(sheet model.ForceCharacterSheet, previous model.ForceCharacterSheet) model.ForceCharacterSheet {
+	for _, array := range SheetArrays {
+		slice := array.slice(&sheet)
+		if !missingIDs(slice) {
+			continue
+		}
+
+		elements := reflect.MakeSlice(slice.Type(), slice.Len(), slice.Len())
+		reflect.Copy(elements, slice)
+
+		taken := map[primitive.ObjectID]bool{}
+		for i := 0; i < elements.Len(); i++ {
+			taken[idOf(elements.Index(i))] = true
+		}
+
+		before := array.slice(&previous)
+		for i := 0; i < elements.Len(); i++ {
+			if !idOf(elements.Index(i)).IsZero() {
+				continue
+			}
+
+			id := primitive.NewObjectID()
+			if i < before.Len() && !idOf(before.Index(i)).IsZero() && !taken[idOf(before.Index(i))] {
+				id = idOf(before.Index(i))
+			}
+			taken[id] = true
+			elements.Index(i).FieldByName("ID").Set(reflect.ValueOf(id))
+		}
+		slice.Set(elements)
+	}
+
+	return sheet
+}
+
+// MissingElementIDs reports whether an element of the sheet arrays has no ID, as elements stored before they had IDs don't
+func MissingElementIDs(sheet model.ForceCharacterSheet) bool {
+	for _, array := range SheetArrays {
+		if array.MissingIDs(sheet) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MissingIDs reports whether an element of the array in a sheet has no ID
+func (a SheetArray) MissingIDs(sheet model.ForceCharacterSheet) bool {
+	return missingIDs(a.slice(&sheet))
+}
+
+func missingIDs(slice reflect.Value) bool {
+	for i := 0; i < slice.Len(); i++ {
+		if idOf(slice.Index(i)).IsZero() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func idOf(element reflect.Value) primitive.ObjectID {
+	return element.FieldByName("ID").Interface().(primitive.ObjectID)
+}
@@ -0,0 +1,23 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
+)
+
+// InvalidIDError returns the error used when a path or payload holds something that isn't a sheet ID
+func InvalidIDError(ID string) error {
+	return dberr.Invalid("invalid_id", "id", "%q is not a valid object ID", ID)
+}
+
+// PayloadError returns the error used when a request body can't be decoded, naming the field that was wrong when it is known
+func PayloadError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return dberr.Invalid("invalid_payload", typeErr.Field, "Invalid request payload, %v needs a %v but got a %v", typeErr.Field, typeErr.Type, typeErr.Value)
+	}
+
+	return dberr.Wrap(err, dberr.ErrValidation, "invalid_payload", "Invalid request payload")
+}
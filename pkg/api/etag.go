@@ -0,0 +1,52 @@
+package api
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SheetETag returns the strong entity tag of a sheet at a version, such as "3".
+// A representation limited to some fields by a projection gets a tag of its own, such as "3+9d5ed678"
+func SheetETag(version int64, projection Projection) string {
+	if len(projection) == 0 {
+		return `"` + strconv.FormatInt(version, 10) + `"`
+	}
+
+	fields := make([]string, 0, len(projection))
+	for field, value := range projection {
+		fields = append(fields, fmt.Sprintf("%v:%v", field, value))
+	}
+	sort.Strings(fields)
+
+	hash := fnv.New32a()
+	hash.Write([]byte(strings.Join(fields, ",")))
+
+	return fmt.Sprintf(`"%v+%08x"`, version, hash.Sum32())
+}
+
+// MatchETag reports whether an If-Match or If-None-Match header, a comma separated list of entity tags or *, matches an entity tag.
+// If-Match uses the strong comparison where weak tags never match, If-None-Match the weak comparison that ignores the W/ prefix
+func MatchETag(header string, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+			etag = strings.TrimPrefix(etag, "W/")
+		} else if strings.HasPrefix(candidate, "W/") {
+			continue
+		}
+
+		if candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,198 @@
+package api
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// filterParam splits a query parameter such as characteristics.brawn[gte] into its field path and operator
+var filterParam = regexp.MustCompile(`^([A-Za-z0-9_.]+)(?:\[([a-z]+)\])?$`)
+
+// MaxRegexLength is the longest pattern the regex operator accepts, like and ieq cover most text searches without a pattern
+const MaxRegexLength = 100
+
+// filterOperators maps the operators accepted in query parameters to their mongo equivalent
+var filterOperators = map[string]string{
+	"eq":     "$eq",
+	"ne":     "$ne",
+	"gt":     "$gt",
+	"gte":    "$gte",
+	"lt":     "$lt",
+	"lte":    "$lte",
+	"in":     "$in",
+	"nin":    "$nin",
+	"exists": "$exists",
+	"like":   "$regex",
+	"ieq":    "$regex",
+	"regex":  "$regex",
+}
+
+//ParseCondition turns a single query parameter into a mongo condition on a field of the schema.
+//The parameter is either field=value for an exact match or field[op]=value, where op is one of
+//eq, ne, gt, gte, lt, lte, in and nin (comma separated values), exists (true or false),
+//like (case insensitive substring), ieq (case insensitive exact match) or regex.
+//like and ieq escape the value, regex takes a pattern of up to MaxRegexLength characters that needs to compile.
+//Values are converted to the type of the field, and fields inside arrays match when any element matches
+func ParseCondition(param string, value string, schema Schema) (bson.M, error) {
+	parts := filterParam.FindStringSubmatch(param)
+	if parts == nil {
+		return nil, dberr.Invalid("invalid_query", param, "invalid query: malformed filter %q", param)
+	}
+
+	path, op := parts[1], parts[2]
+	if op == "" {
+		op = "eq"
+	}
+
+	fieldType, ok := schema.Lookup(path)
+	if !ok {
+		return nil, dberr.Invalid("invalid_query", path, "invalid query: unknown field %q", path)
+	}
+
+	operator, ok := filterOperators[op]
+	if !ok {
+		return nil, dberr.Invalid("invalid_query", path, "invalid query: unknown operator %q on field %q", op, path)
+	}
+
+	switch op {
+	case "exists":
+		exists, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, dberr.Invalid("invalid_query", path, "invalid query: %v[exists] must be true or false", path)
+		}
+		return bson.M{path: bson.M{operator: exists}}, nil
+	case "like", "ieq", "regex":
+		if fieldType != StringField {
+			return nil, dberr.Invalid("invalid_query", path, "invalid query: %v[%v] needs a text field", path, op)
+		}
+		if op == "regex" {
+			if len(value) > MaxRegexLength {
+				return nil, dberr.Invalid("invalid_query", path, "invalid query: %v[regex] is longer than %v characters, use %v[like] or %v[ieq] to search text", path, MaxRegexLength, path, path)
+			}
+			if _, err := regexp.Compile(value); err != nil {
+				return nil, dberr.Invalid("invalid_query", path, "invalid query: %v[regex] is not a valid regular expression: %v, use %v[like] or %v[ieq] to search text", path, err, path, path)
+			}
+		}
+		return bson.M{path: textCondition(op, value)}, nil
+	}
+
+	if fieldType == ObjectField {
+		return nil, dberr.Invalid("invalid_query", path, "invalid query: %v holds documents and can only be filtered with exists", path)
+	}
+
+	if op == "in" || op == "nin" {
+		values := []interface{}{}
+		for _, item := range strings.Split(value, ",") {
+			converted, err := convertValue(path, fieldType, item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, converted)
+		}
+		return bson.M{path: bson.M{operator: values}}, nil
+	}
+
+	converted, err := convertValue(path, fieldType, value)
+	if err != nil {
+		return nil, err
+	}
+
+	if op == "eq" {
+		return bson.M{path: converted}, nil
+	}
+
+	return bson.M{path: bson.M{operator: converted}}, nil
+}
+
+//ParseSort turns a comma separated list of fields into a mongo sort, a field prefixed with - sorts descending.
+//The _id field is always added last so that sheets with equal sort keys keep a stable order between pages
+func ParseSort(value string, schema Schema) (bson.D, error) {
+	sort := bson.D{}
+	hasID := false
+
+	for _, key := range strings.Split(value, ",") {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+
+		direction := 1
+		if strings.HasPrefix(key, "-") {
+			direction = -1
+			key = key[1:]
+		} else if strings.HasPrefix(key, "+") {
+			key = key[1:]
+		}
+
+		fieldType, ok := schema.Lookup(key)
+		if !ok {
+			return nil, dberr.Invalid("invalid_query", "sort", "invalid query: unknown sort field %q", key)
+		}
+		if fieldType == ObjectField {
+			return nil, dberr.Invalid("invalid_query", "sort", "invalid query: cannot sort on %v, it holds documents", key)
+		}
+
+		hasID = hasID || key == "_id"
+		sort = append(sort, bson.E{Key: key, Value: direction})
+	}
+
+	if !hasID {
+		sort = append(sort, bson.E{Key: "_id", Value: 1})
+	}
+
+	return sort, nil
+}
+
+func textCondition(op string, value string) bson.M {
+	switch op {
+	case "like":
+		return bson.M{"$regex": regexp.QuoteMeta(value), "$options": "i"}
+	case "ieq":
+		return bson.M{"$regex": "^" + regexp.QuoteMeta(value) + "$", "$options": "i"}
+	}
+
+	return bson.M{"$regex": value}
+}
+
+func convertValue(path string, fieldType FieldType, value string) (interface{}, error) {
+	switch fieldType {
+	case IntField:
+		converted, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, dberr.Invalid("invalid_query", path, "invalid query: %v needs a whole number, got %q", path, value)
+		}
+		return converted, nil
+	case FloatField:
+		converted, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, dberr.Invalid("invalid_query", path, "invalid query: %v needs a number, got %q", path, value)
+		}
+		return converted, nil
+	case BoolField:
+		converted, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, dberr.Invalid("invalid_query", path, "invalid query: %v needs true or false, got %q", path, value)
+		}
+		return converted, nil
+	case ObjectIDField:
+		converted, err := primitive.ObjectIDFromHex(value)
+		if err != nil {
+			return nil, dberr.Invalid("invalid_query", path, "invalid query: %v needs an object ID, got %q", path, value)
+		}
+		return converted, nil
+	case TimeField:
+		converted, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, dberr.Invalid("invalid_query", path, "invalid query: %v needs an RFC 3339 time, got %q", path, value)
+		}
+		return converted, nil
+	}
+
+	return value, nil
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"fmt"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
+)
+
+// HealthAction changes the current wounds or strain of a sheet by an amount, such as taking damage or healing
+type HealthAction struct {
+	// Name is how routes refer to the action, such as damage
+	Name string
+	// Path is the dotted path of the counter the action changes in a stored sheet, wounds.current or strain.current
+	Path string
+	// Soaked reports whether the soak value of the sheet is taken off the amount, as it is for damage
+	Soaked bool
+	// Recovers reports whether the action lowers the counter, which never goes below 0
+	Recovers bool
+	// summary is the format of the revision summary, given the amount
+	summary string
+	// amount returns the counter of a sheet the action changes
+	amount func(sheet *model.ForceCharacterSheet) *model.Amount
+}
+
+var (
+	// TakeDamage adds the damage left after soak to the wounds of a sheet
+	TakeDamage = HealthAction{"damage", "wounds.current", true, false, "took %v damage", wounds}
+	// SufferStrain adds to the strain of a sheet
+	SufferStrain = HealthAction{"suffer-strain", "strain.current", false, false, "suffered %v strain", strain}
+	// RecoverStrain takes off the strain of a sheet
+	RecoverStrain = HealthAction{"recover-strain", "strain.current", false, true, "recovered %v strain", strain}
+	// HealWounds takes off the wounds of a sheet
+	HealWounds = HealthAction{"heal", "wounds.current", false, true, "healed %v wounds", wounds}
+)
+
+func wounds(sheet *model.ForceCharacterSheet) *model.Amount {
+	return &sheet.Wounds
+}
+
+func strain(sheet *model.ForceCharacterSheet) *model.Amount {
+	return &sheet.Strain
+}
+
+// Summary returns the revision summary of the action, such as "took 3 damage"
+func (a HealthAction) Summary(amount int64) string {
+	return fmt.Sprintf(a.summary, amount)
+}
+
+// Change returns how much the action moves the counter of a sheet, after soak and without going below 0
+func (a HealthAction) Change(sheet model.ForceCharacterSheet, amount int64) int64 {
+	switch {
+	case a.Soaked && amount > sheet.SoakValue:
+		return amount - sheet.SoakValue
+	case a.Soaked:
+		return 0
+	case a.Recovers && a.amount(&sheet).Current <= 0:
+		return 0
+	case a.Recovers && amount > a.amount(&sheet).Current:
+		return -a.amount(&sheet).Current
+	case a.Recovers:
+		return -amount
+	default:
+		return amount
+	}
+}
+
+// Apply applies the action to a sheet and reports whether it changed anything
+func (a HealthAction) Apply(sheet *model.ForceCharacterSheet, amount int64) bool {
+	change := a.Change(*sheet, amount)
+	a.amount(sheet).Current += change
+
+	return change != 0
+}
+
+// HealthState returns the wounds and strain of a sheet after an action of an amount
+func HealthState(sheet model.ForceCharacterSheet, action HealthAction, amount int64) model.HealthState {
+	state := model.HealthState{
+		ID:                      sheet.ID,
+		Version:                 sheet.Version,
+		Wounds:                  sheet.Wounds,
+		Strain:                  sheet.Strain,
+		WoundThresholdExceeded:  sheet.Wounds.Current > sheet.Wounds.Threshold,
+		StrainThresholdExceeded: sheet.Strain.Current > sheet.Strain.Threshold,
+	}
+	if action.Soaked {
+		state.Soaked = sheet.SoakValue
+		if amount < state.Soaked {
+			state.Soaked = amount
+		}
+	}
+
+	return state
+}
+
+// HealthAmountError returns the error used when an action is asked for a negative amount
+func HealthAmountError(amount int64) error {
+	return dberr.Invalid("invalid_payload", "amount", "Invalid request payload, amount can't be negative but got %v", amount)
+}
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"fmt"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// StartLineage makes a sheet that isn't a version of a character yet the first version of its own character
+func StartLineage(sheet model.ForceCharacterSheet) model.ForceCharacterSheet {
+	if sheet.CharacterID.IsZero() {
+		sheet.CharacterID = sheet.ID
+	}
+
+	if sheet.CharacterVersion == 0 {
+		sheet.CharacterVersion = 1
+	}
+
+	return sheet
+}
+
+// LevelUp returns the next version of a character, forked from its latest sheet under a new ID with a fresh edit version
+func LevelUp(latest model.ForceCharacterSheet) model.ForceCharacterSheet {
+	next := StartLineage(latest)
+	next.ID = primitive.NewObjectID()
+	next.CharacterVersion++
+	next.Version = 1
+
+	return next
+}
+
+// LevelUpSummary is the revision summary recorded for the sheet a level up creates
+func LevelUpSummary(latest model.ForceCharacterSheet) string {
+	return fmt.Sprintf("leveled up from version %v (%v)", latest.CharacterVersion, latest.ID.Hex())
+}
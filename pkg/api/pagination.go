@@ -0,0 +1,95 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PageLinks are the navigation links of a list response keyed by their RFC 5988 relation
+type PageLinks map[string]string
+
+// BuildPageLinks returns the first, prev, next and last links for a list response.
+// In cursor mode, when the request has an after parameter, only first and next are returned and next continues after lastID.
+// The first link keeps the filters and the reserved parameters that shape a page but drops the ones that pick where it starts
+func BuildPageLinks(u *url.URL, returned int, total int64, lastID *primitive.ObjectID) PageLinks {
+	queryParams := u.Query()
+	pageNumber, pageCount, _ := Paging(queryParams)
+	links := PageLinks{}
+
+	if queryParams.Get("after") != "" {
+		links["first"] = pageURL(u, func(q url.Values) {
+			for key := range reservedParams {
+				if !pageShapeParams[key] {
+					q.Del(key)
+				}
+			}
+		})
+		if lastID != nil && returned == pageCount {
+			links["next"] = pageURL(u, func(q url.Values) {
+				q.Del("pageNumber")
+				q.Set("after", lastID.Hex())
+			})
+		}
+		return links
+	}
+
+	if pageNumber < 1 {
+		pageNumber = 1
+	}
+	lastPage := int((total + int64(pageCount) - 1) / int64(pageCount))
+	if lastPage < 1 {
+		lastPage = 1
+	}
+
+	setPage := func(page int) string {
+		return pageURL(u, func(q url.Values) {
+			q.Set("pageNumber", strconv.Itoa(page))
+			q.Set("pageCount", strconv.Itoa(pageCount))
+		})
+	}
+
+	links["first"] = setPage(1)
+	links["last"] = setPage(lastPage)
+	if pageNumber > 1 {
+		links["prev"] = setPage(pageNumber - 1)
+	}
+	if pageNumber < lastPage {
+		links["next"] = setPage(pageNumber + 1)
+	}
+
+	return links
+}
+
+// pageShapeParams are the reserved parameters that decide the size and fields of a page rather than where it starts
+var pageShapeParams = map[string]bool{
+	"pageCount": true,
+	"fields":    true,
+}
+
+// SetLinkHeader writes the links as an RFC 5988 Link header
+func SetLinkHeader(w http.ResponseWriter, links PageLinks) {
+	values := []string{}
+	for _, rel := range []string{"first", "prev", "next", "last"} {
+		if link, ok := links[rel]; ok {
+			values = append(values, fmt.Sprintf(`<%s>; rel="%s"`, link, rel))
+		}
+	}
+
+	if len(values) > 0 {
+		w.Header().Set("Link", strings.Join(values, ", "))
+	}
+}
+
+func pageURL(u *url.URL, change func(url.Values)) string {
+	queryParams := u.Query()
+	change(queryParams)
+
+	link := url.URL{Path: u.Path, RawQuery: queryParams.Encode()}
+
+	return link.String()
+}
@@ -0,0 +1,348 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
+)
+
+// The media types a PATCH request may use, a JSON Merge Patch (RFC 7386) or a JSON Patch (RFC 6902)
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+)
+
+// PatchOperation is a single operation of a JSON Patch, one of add, remove, replace, move, copy and test.
+// A missing value is nil while an explicit null is the raw message null
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Patch turns a JSON document into its patched form
+type Patch func(doc []byte) ([]byte, error)
+
+// ParseMergePatch returns the patch that merges a JSON Merge Patch into a document:
+// members of the patch replace those of the document, objects are merged recursively and null removes a member
+func ParseMergePatch(data []byte) (Patch, error) {
+	var patch interface{}
+	if err := unmarshalJSON(data, &patch); err != nil {
+		return nil, dberr.Wrap(err, dberr.ErrValidation, "invalid_patch", "invalid merge patch")
+	}
+
+	return func(doc []byte) ([]byte, error) {
+		var target interface{}
+		if err := unmarshalJSON(doc, &target); err != nil {
+			return nil, err
+		}
+
+		return json.Marshal(mergePatch(target, patch))
+	}, nil
+}
+
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = mergePatch(targetObject[key], value)
+	}
+
+	return targetObject
+}
+
+// ParseJSONPatch checks a JSON Patch and returns the patch that applies its operations in order.
+// The operations apply to the document as a whole, when one fails the document is left as it was
+func ParseJSONPatch(data []byte) (Patch, error) {
+	operations := []PatchOperation{}
+	if err := json.Unmarshal(data, &operations); err != nil {
+		return nil, dberr.Wrap(err, dberr.ErrValidation, "invalid_patch", "invalid JSON patch, expected an array of operations")
+	}
+
+	for i, operation := range operations {
+		field := "/" + strconv.Itoa(i)
+		switch operation.Op {
+		case "add", "replace", "test":
+			if operation.Value == nil {
+				return nil, dberr.Invalid("invalid_patch", field+"/value", "%v operation %v needs a value", operation.Op, i)
+			}
+		case "move", "copy":
+			if _, err := parsePointer(operation.From); err != nil {
+				return nil, dberr.Invalid("invalid_patch", field+"/from", "%v operation %v: %v", operation.Op, i, err)
+			}
+		case "remove":
+		default:
+			return nil, dberr.Invalid("invalid_patch", field+"/op", "unknown patch operation %q, expected add, remove, replace, move, copy or test", operation.Op)
+		}
+
+		if _, err := parsePointer(operation.Path); err != nil {
+			return nil, dberr.Invalid("invalid_patch", field+"/path", "%v operation %v: %v", operation.Op, i, err)
+		}
+	}
+
+	return func(doc []byte) ([]byte, error) {
+		var target interface{}
+		if err := unmarshalJSON(doc, &target); err != nil {
+			return nil, err
+		}
+
+		for _, operation := range operations {
+			var err error
+			target, err = applyOperation(target, operation)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return json.Marshal(target)
+	}, nil
+}
+
+func applyOperation(doc interface{}, operation PatchOperation) (interface{}, error) {
+	path, _ := parsePointer(operation.Path)
+
+	var value interface{}
+	if operation.Value != nil {
+		if err := unmarshalJSON(operation.Value, &value); err != nil {
+			return nil, dberr.Wrap(err, dberr.ErrValidation, "invalid_patch", "invalid value for "+operation.Path)
+		}
+	}
+
+	switch operation.Op {
+	case "add":
+		return addValue(doc, path, value, operation)
+	case "remove":
+		return removeValue(doc, path, operation)
+	case "replace":
+		doc, err := removeValue(doc, path, operation)
+		if err != nil {
+			return nil, err
+		}
+		return addValue(doc, path, value, operation)
+	case "move", "copy":
+		from, _ := parsePointer(operation.From)
+		moved, err := getValue(doc, from, operation)
+		if err != nil {
+			return nil, err
+		}
+		if operation.Op == "copy" {
+			return addValue(doc, path, deepCopy(moved), operation)
+		}
+		if len(path) > len(from) && reflect.DeepEqual(path[:len(from)], from) {
+			return nil, patchConflict(operation, "can't move a value into itself")
+		}
+		doc, err = removeValue(doc, from, operation)
+		if err != nil {
+			return nil, err
+		}
+		return addValue(doc, path, moved, operation)
+	case "test":
+		current, err := getValue(doc, path, operation)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(normalize(current), normalize(value)) {
+			return nil, dberr.Conflict("patch_test_failed", "patch test failed, %v is %s", operation.Path, mustMarshal(current))
+		}
+	}
+
+	return doc, nil
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) into its unescaped reference tokens, the empty pointer is the whole document
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, dberr.New(dberr.ErrValidation, "invalid_patch", "%q is not a JSON pointer", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+func getValue(doc interface{}, path []string, operation PatchOperation) (interface{}, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, patchConflict(operation, "no member "+token)
+			}
+			doc = value
+		case []interface{}:
+			index, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, patchConflict(operation, err.Error())
+			}
+			doc = node[index]
+		default:
+			return nil, patchConflict(operation, token+" is not inside an object or array")
+		}
+	}
+
+	return doc, nil
+}
+
+// updateParent finds the object or array holding the last token of the path and replaces it by what update returns,
+// rebuilding the arrays along the way since inserting into or removing from an array makes a new slice
+func updateParent(doc interface{}, path []string, operation PatchOperation, update func(parent interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return update(doc, path[0])
+	}
+
+	child, err := getValue(doc, path[:1], operation)
+	if err != nil {
+		return nil, err
+	}
+
+	child, err = updateParent(child, path[1:], operation, update)
+	if err != nil {
+		return nil, err
+	}
+
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		node[path[0]] = child
+	case []interface{}:
+		index, _ := arrayIndex(path[0], len(node)-1)
+		node[index] = child
+	}
+
+	return doc, nil
+}
+
+func addValue(doc interface{}, path []string, value interface{}, operation PatchOperation) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return updateParent(doc, path, operation, func(parent interface{}, token string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			node[token] = value
+			return node, nil
+		case []interface{}:
+			if token == "-" {
+				return append(node, value), nil
+			}
+			index, err := arrayIndex(token, len(node))
+			if err != nil {
+				return nil, patchConflict(operation, err.Error())
+			}
+			node = append(node, nil)
+			copy(node[index+1:], node[index:])
+			node[index] = value
+			return node, nil
+		}
+
+		return nil, patchConflict(operation, token+" is not inside an object or array")
+	})
+}
+
+func removeValue(doc interface{}, path []string, operation PatchOperation) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil
+	}
+
+	return updateParent(doc, path, operation, func(parent interface{}, token string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			if _, ok := node[token]; !ok {
+				return nil, patchConflict(operation, "no member "+token)
+			}
+			delete(node, token)
+			return node, nil
+		case []interface{}:
+			index, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, patchConflict(operation, err.Error())
+			}
+			return append(node[:index], node[index+1:]...), nil
+		}
+
+		return nil, patchConflict(operation, token+" is not inside an object or array")
+	})
+}
+
+// arrayIndex parses an array index token, which must be between 0 and max without leading zeros
+func arrayIndex(token string, max int) (int, error) {
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, dberr.New(dberr.ErrValidation, "invalid_patch", "%q is not an array index", token)
+	}
+	if index > max {
+		return 0, dberr.New(dberr.ErrValidation, "invalid_patch", "index %v is out of bounds", index)
+	}
+
+	return index, nil
+}
+
+func patchConflict(operation PatchOperation, reason string) error {
+	return dberr.Conflict("patch_conflict", "can't %v %v: %v", operation.Op, operation.Path, reason)
+}
+
+// unmarshalJSON decodes JSON keeping numbers as they were written, so that large whole numbers survive a round trip
+func unmarshalJSON(data []byte, out interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	return decoder.Decode(out)
+}
+
+// normalize turns the numbers of a decoded document into float64 so that 1 and 1.0 compare equal
+func normalize(value interface{}) interface{} {
+	switch node := value.(type) {
+	case json.Number:
+		number, _ := node.Float64()
+		return number
+	case map[string]interface{}:
+		normalized := map[string]interface{}{}
+		for key, child := range node {
+			normalized[key] = normalize(child)
+		}
+		return normalized
+	case []interface{}:
+		normalized := make([]interface{}, len(node))
+		for i, child := range node {
+			normalized[i] = normalize(child)
+		}
+		return normalized
+	}
+
+	return value
+}
+
+func deepCopy(value interface{}) interface{} {
+	var copied interface{}
+	unmarshalJSON(mustMarshal(value), &copied)
+
+	return copied
+}
+
+func mustMarshal(value interface{}) []byte {
+	data, _ := json.Marshal(value)
+	return data
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
+	log "github.com/sirupsen/logrus"
+)
+
+// ProblemContentType is the media type of every error response, see RFC 7807
+const ProblemContentType = "application/problem+json"
+
+// StatusClientClosedRequest is the status used when the client went away before the request finished
+const StatusClientClosedRequest = 499
+
+// statusCodes are the codes of problems built from a status alone, by RespondWithError or for untyped errors
+var statusCodes = map[int]string{
+	http.StatusBadRequest:          "bad_request",
+	http.StatusUnauthorized:        "unauthorized",
+	http.StatusForbidden:           "forbidden",
+	http.StatusNotFound:            "not_found",
+	http.StatusConflict:            "conflict",
+	http.StatusGone:                "gone",
+	http.StatusUnprocessableEntity: "no_change",
+	StatusClientClosedRequest:      "client_closed_request",
+	http.StatusInternalServerError: "internal_error",
+	http.StatusNotImplemented:      "not_supported",
+	http.StatusServiceUnavailable:  "unavailable",
+	http.StatusGatewayTimeout:      "timeout",
+}
+
+// CheckError returns the status code for an error from its kind, see package dberr.
+// Errors of no known kind are internal server errors
+func CheckError(err error) int {
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case errors.Is(err, context.Canceled):
+		return StatusClientClosedRequest
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, dberr.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, dberr.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, dberr.ErrPreconditionFailed):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, dberr.ErrPreconditionRequired):
+		return http.StatusPreconditionRequired
+	case errors.Is(err, dberr.ErrNoOp), errors.Is(err, dberr.ErrRuleViolation):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, dberr.ErrValidation):
+		return http.StatusBadRequest
+	case errors.Is(err, dberr.ErrNotSupported):
+		return http.StatusNotImplemented
+	case errors.Is(err, dberr.ErrUnavailable):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// NewProblem returns the problem for a status, a stable code and a detail, an empty code is filled in from the status
+func NewProblem(status int, code string, detail string) model.Problem {
+	if code == "" {
+		code = statusCodes[status]
+	}
+	if code == "" {
+		code = strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+	}
+
+	return model.Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Code:   code,
+		Detail: detail,
+	}
+}
+
+// internalErrorDetail is the detail of an internal server error, whose error is only logged as it can hold connection strings or stored data
+const internalErrorDetail = "The server failed to handle the request, the error has been logged"
+
+// ProblemFor returns the problem describing an error, with the status from CheckError and the code and violations the error carries.
+// An error of no known kind is logged and answered with a generic detail
+func ProblemFor(err error) model.Problem {
+	status := CheckError(err)
+	if status == http.StatusInternalServerError {
+		log.Errorf("Internal server error: %v", err)
+		return NewProblem(status, dberr.Code(err), internalErrorDetail)
+	}
+
+	problem := NewProblem(status, dberr.Code(err), err.Error())
+	problem.Violations = dberr.Violations(err)
+
+	return problem
+}
+
+// RespondWithProblem Utility function to convert a problem into an application/problem+json response.
+// The payload is the problem itself or a struct embedding it with extension members.
+func RespondWithProblem(w http.ResponseWriter, code int, payload interface{}) {
+	if w != nil {
+		response, err := json.Marshal(payload)
+		if err != nil {
+			log.Errorf("Error in RespondWithProblem marshal: %v", err)
+		}
+
+		w.Header().Set("Content-Type", ProblemContentType)
+		w.WriteHeader(code)
+		w.Write(response)
+	}
+}
+
+// RespondWithFailure Utility function to convert an error into a problem response, the status is picked by CheckError.
+func RespondWithFailure(w http.ResponseWriter, err error) {
+	problem := ProblemFor(err)
+	RespondWithProblem(w, problem.Status, problem)
+}
+
+// RespondWithError Utility function to convert a status and message into a problem response.
+func RespondWithError(w http.ResponseWriter, code int, msg string) {
+	RespondWithProblem(w, code, NewProblem(code, "", msg))
+}
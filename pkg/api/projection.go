@@ -0,0 +1,242 @@
+package api
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Projection selects the fields of a sheet to return, keyed by bson path with 1 to include a field or 0 to exclude it.
+// A nil projection returns every field
+type Projection bson.M
+
+//ParseProjection parses the fields query parameter, a comma separated list of fields to return such as
+//characterName,playerName,wounds or of fields to leave out such as -talents,-weapons.
+//Like a mongo projection the _id is returned unless -_id is given, and included and excluded fields can't be mixed
+func ParseProjection(value string, schema Schema) (Projection, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	projection := Projection{}
+	included, excluded := 0, 0
+
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		include := 1
+		if strings.HasPrefix(field, "-") {
+			include = 0
+			field = field[1:]
+		}
+
+		if _, ok := schema.Lookup(field); !ok {
+			return nil, dberr.Invalid("invalid_query", "fields", "invalid query: unknown field %q in fields", field)
+		}
+
+		projection[field] = include
+		if field == "_id" {
+			continue
+		}
+		if include == 1 {
+			included++
+		} else {
+			excluded++
+		}
+	}
+
+	if included > 0 && excluded > 0 {
+		return nil, dberr.Invalid("invalid_query", "fields", "invalid query: fields can't mix included and excluded fields")
+	}
+
+	if len(projection) == 0 {
+		return nil, nil
+	}
+
+	if _, ok := projection["_id"]; !ok && included > 0 {
+		projection["_id"] = 1
+	}
+
+	return projection, nil
+}
+
+// Apply trims a document down to the fields selected by the projection the same way a mongo projection does
+func (p Projection) Apply(doc map[string]interface{}) map[string]interface{} {
+	if len(p) == 0 {
+		return doc
+	}
+
+	include := p.includes()
+	tree := projectionTree{}
+	for path, value := range p {
+		if (value == 1) == include {
+			tree.add(strings.Split(path, "."))
+		}
+	}
+
+	if include {
+		result := tree.include(doc)
+		if p["_id"] == 0 {
+			delete(result, "_id")
+		}
+		return result
+	}
+
+	return tree.exclude(doc)
+}
+
+//Sparse returns the JSON form of a sheet, or of a slice of sheets, trimmed down to the fields selected by the projection
+func Sparse(value interface{}, projection Projection, schema Schema) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded interface{}
+	err = json.Unmarshal(data, &decoded)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonProjection := Projection{}
+	for path, include := range projection {
+		jsonProjection[schema.JSONPath(path)] = include
+	}
+
+	switch v := decoded.(type) {
+	case map[string]interface{}:
+		return jsonProjection.Apply(v), nil
+	case []interface{}:
+		for i, item := range v {
+			if doc, ok := item.(map[string]interface{}); ok {
+				v[i] = jsonProjection.Apply(doc)
+			}
+		}
+		return v, nil
+	}
+
+	return decoded, nil
+}
+
+// Including returns a copy of the projection that also selects the field, for a caller that needs the field even when the response leaves it out
+func (p Projection) Including(field string) Projection {
+	if len(p) == 0 {
+		return p
+	}
+
+	including := Projection{}
+	for path, value := range p {
+		including[path] = value
+	}
+
+	if p.includes() {
+		including[field] = 1
+	} else {
+		delete(including, field)
+	}
+
+	if len(including) == 0 {
+		return nil
+	}
+
+	return including
+}
+
+func (p Projection) includes() bool {
+	for path, value := range p {
+		if path != "_id" && value == 1 {
+			return true
+		}
+	}
+
+	return p["_id"] == 1 && len(p) == 1
+}
+
+// projectionTree holds the projected paths split on their dots, a leaf selects the whole field
+type projectionTree map[string]projectionTree
+
+func (t projectionTree) add(parts []string) {
+	child, ok := t[parts[0]]
+	if ok && len(child) == 0 {
+		return
+	}
+
+	if len(parts) == 1 {
+		t[parts[0]] = projectionTree{}
+		return
+	}
+
+	if !ok {
+		child = projectionTree{}
+		t[parts[0]] = child
+	}
+	child.add(parts[1:])
+}
+
+func (t projectionTree) include(doc map[string]interface{}) map[string]interface{} {
+	result := map[string]interface{}{}
+	for key, child := range t {
+		value, ok := doc[key]
+		if !ok {
+			continue
+		}
+
+		if len(child) == 0 {
+			result[key] = value
+			continue
+		}
+
+		if nested := child.apply(value, child.include); nested != nil {
+			result[key] = nested
+		}
+	}
+
+	return result
+}
+
+func (t projectionTree) exclude(doc map[string]interface{}) map[string]interface{} {
+	result := map[string]interface{}{}
+	for key, value := range doc {
+		child, ok := t[key]
+		if !ok {
+			result[key] = value
+			continue
+		}
+
+		if len(child) > 0 {
+			result[key] = child.apply(value, child.exclude)
+		}
+	}
+
+	return result
+}
+
+// apply projects an embedded document, or every document of an array, leaving other values as they are
+func (t projectionTree) apply(value interface{}, project func(map[string]interface{}) map[string]interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return project(v)
+	case bson.M:
+		return bson.M(project(v))
+	case []interface{}:
+		return t.applyAll(v, project)
+	case bson.A:
+		return bson.A(t.applyAll(v, project))
+	}
+
+	return value
+}
+
+func (t projectionTree) applyAll(values []interface{}, project func(map[string]interface{}) map[string]interface{}) []interface{} {
+	result := []interface{}{}
+	for _, value := range values {
+		result = append(result, t.apply(value, project))
+	}
+
+	return result
+}
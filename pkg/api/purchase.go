@@ -0,0 +1,223 @@
+package api
+
+import (
+	"strings"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/catalog"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The caps of the advancement rules
+const (
+	// MaxSkillRank is the highest rank a skill can be trained to
+	MaxSkillRank = 5
+	// MaxCreationCharacteristic is the highest rating a characteristic can be bought to at creation
+	MaxCreationCharacteristic = 5
+)
+
+// characteristic returns the characteristic of a sheet with the name, nil when there is none
+func characteristic(sheet *model.ForceCharacterSheet, name string) *int64 {
+	switch strings.ToLower(name) {
+	case "brawn":
+		return &sheet.Characteristics.Brawn
+	case "agility":
+		return &sheet.Characteristics.Agility
+	case "intellect":
+		return &sheet.Characteristics.Intellect
+	case "cunning":
+		return &sheet.Characteristics.Cunning
+	case "willpower":
+		return &sheet.Characteristics.Willpower
+	case "presence":
+		return &sheet.Characteristics.Presence
+	}
+
+	return nil
+}
+
+// ValidatePurchase checks that a purchase is well formed, before Purchase checks it against a sheet
+func ValidatePurchase(purchase model.PurchaseRequest) error {
+	switch purchase.Type {
+	case model.PurchaseSkill, model.PurchaseCharacteristic, model.PurchaseTalent, model.PurchaseForcePower:
+	default:
+		return dberr.Invalid("invalid_payload", "type", "Invalid request payload, type needs to be skill, characteristic, talent or forcePower but got %q", purchase.Type)
+	}
+
+	switch {
+	case strings.TrimSpace(purchase.Name) == "":
+		return dberr.Invalid("invalid_payload", "name", "Invalid request payload, name is required")
+	case purchase.Type == model.PurchaseCharacteristic && characteristic(&model.ForceCharacterSheet{}, purchase.Name) == nil:
+		return dberr.Invalid("invalid_payload", "name", "Invalid request payload, %q is not a characteristic", purchase.Name)
+	}
+
+	return nil
+}
+
+// Purchase buys an advancement for a sheet with its available XP at the costs of the rulebook: 5 × the new rank for a career skill and 5 more
+// for any other, 10 × the new rating for a characteristic, which can only be bought at creation on the first version of a character,
+// 5 × the tier for a talent and the cost from the power tree for a force power or upgrade. Talents and force powers are priced from the catalog,
+// a purchase the catalog can't price is refused. Nothing is changed when the purchase breaks a rule
+func Purchase(sheet *model.ForceCharacterSheet, purchase model.PurchaseRequest, prices *catalog.Catalog) (model.PurchaseResult, error) {
+	result := model.PurchaseResult{Type: purchase.Type, Name: purchase.Name, Upgrade: purchase.Upgrade}
+
+	var buy func()
+	switch purchase.Type {
+	case model.PurchaseSkill:
+		skill := findSkill(sheet, purchase.Name)
+		if skill == nil {
+			return result, purchaseError("skill_not_on_sheet", "the sheet has no skill %q", purchase.Name)
+		}
+
+		result.Rank = skill.Level + 1
+		if result.Rank > MaxSkillRank {
+			return result, RankCapError(skill.Name, MaxSkillRank)
+		}
+		result.Cost = 5 * result.Rank
+		if !skill.Career {
+			result.Cost += 5
+		}
+		buy = func() { skill.Level = result.Rank }
+
+	case model.PurchaseCharacteristic:
+		if sheet.CharacterVersion > 1 {
+			return result, purchaseError("characteristic_locked", "characteristics can only be bought at creation, on the first version of a character")
+		}
+
+		rating := characteristic(sheet, purchase.Name)
+		result.Rank = *rating + 1
+		if result.Rank > MaxCreationCharacteristic {
+			return result, RankCapError(purchase.Name, MaxCreationCharacteristic)
+		}
+		result.Cost = 10 * result.Rank
+		buy = func() { *rating = result.Rank }
+
+	case model.PurchaseTalent:
+		talent := prices.Talent(purchase.Name)
+		if talent == nil {
+			return result, NotInCatalogError("talent", purchase.Name)
+		}
+
+		result.Rank = countTalents(sheet, talent.Name) + 1
+		if result.Rank > 1 && !talent.Ranked {
+			return result, purchaseError("talent_already_owned", "%q is already on the sheet and isn't a ranked talent", talent.Name)
+		}
+		result.Cost = 5 * talent.Tier
+		buy = func() {
+			sheet.Talents = append(sheet.Talents, model.Talents{ID: primitive.NewObjectID(), Name: talent.Name, Description: purchase.Description})
+		}
+
+	case model.PurchaseForcePower:
+		if sheet.ForceRating < 1 {
+			return result, purchaseError("not_force_sensitive", "force powers need a force rating of at least 1")
+		}
+
+		listed := prices.ForcePower(purchase.Name)
+		if listed == nil {
+			return result, NotInCatalogError("force power", purchase.Name)
+		}
+
+		power := findTalent(sheet, listed.Name)
+		switch {
+		case purchase.Upgrade == "" && power != nil:
+			return result, purchaseError("force_power_already_owned", "%q is already on the sheet", listed.Name)
+		case purchase.Upgrade == "":
+			result.Rank = 1
+			result.Cost = listed.Cost
+			buy = func() {
+				sheet.Talents = append(sheet.Talents, model.Talents{
+					ID: primitive.NewObjectID(), Name: listed.Name, Description: purchase.Description, ForcePower: []model.ForcePower{},
+				})
+			}
+		default:
+			upgrade := listed.Upgrade(purchase.Upgrade)
+			if upgrade == nil {
+				return result, NotInCatalogError("upgrade of "+listed.Name, purchase.Upgrade)
+			}
+			if power == nil {
+				return result, purchaseError("force_power_not_owned", "%q needs to be bought before its upgrades", listed.Name)
+			}
+
+			result.Rank = countUpgrades(power, upgrade.Name) + 1
+			if result.Rank > int64(len(upgrade.Costs)) {
+				return result, RankCapError(listed.Name+" "+upgrade.Name, int64(len(upgrade.Costs)))
+			}
+			result.Cost = upgrade.Costs[result.Rank-1]
+			buy = func() {
+				power.ForcePower = append(power.ForcePower, model.ForcePower{Name: upgrade.Name, Description: purchase.Description, Completed: true})
+			}
+		}
+	}
+
+	if result.Cost > sheet.AvailableXP {
+		return result, InsufficientXPError(result.Cost, sheet.AvailableXP)
+	}
+
+	buy()
+	sheet.AvailableXP -= result.Cost
+	result.AvailableXP = sheet.AvailableXP
+
+	return result, nil
+}
+
+func findSkill(sheet *model.ForceCharacterSheet, name string) *model.Skills {
+	for i := range sheet.Skills {
+		if strings.EqualFold(sheet.Skills[i].Name, name) {
+			return &sheet.Skills[i]
+		}
+	}
+
+	return nil
+}
+
+func findTalent(sheet *model.ForceCharacterSheet, name string) *model.Talents {
+	for i := range sheet.Talents {
+		if strings.EqualFold(sheet.Talents[i].Name, name) {
+			return &sheet.Talents[i]
+		}
+	}
+
+	return nil
+}
+
+func countTalents(sheet *model.ForceCharacterSheet, name string) int64 {
+	var count int64
+	for _, talent := range sheet.Talents {
+		if strings.EqualFold(talent.Name, name) {
+			count++
+		}
+	}
+
+	return count
+}
+
+func countUpgrades(power *model.Talents, name string) int64 {
+	var count int64
+	for _, upgrade := range power.ForcePower {
+		if strings.EqualFold(upgrade.Name, name) {
+			count++
+		}
+	}
+
+	return count
+}
+
+func purchaseError(code string, format string, args ...interface{}) error {
+	return dberr.New(dberr.ErrRuleViolation, code, format, args...)
+}
+
+// InsufficientXPError returns the error used when a purchase costs more XP than the sheet has available
+func InsufficientXPError(cost int64, available int64) error {
+	return purchaseError("insufficient_xp", "the purchase costs %v XP but only %v is available", cost, available)
+}
+
+// NotInCatalogError returns the error used when the catalog has no price for a talent, force power or upgrade
+func NotInCatalogError(kind string, name string) error {
+	return purchaseError("not_in_catalog", "the catalog has no %v %q, so it can't be priced", kind, name)
+}
+
+// RankCapError returns the error used when a skill or characteristic is already at the highest rank it can be bought to
+func RankCapError(name string, max int64) error {
+	return purchaseError("rank_cap_reached", "%v is already at %v, the highest it can be bought to", name, max)
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FieldType is the kind of value stored in a field, it decides how query parameter values for the field are parsed
+type FieldType int
+
+const (
+	// StringField holds text
+	StringField FieldType = iota
+	// IntField holds whole numbers
+	IntField
+	// FloatField holds decimal numbers
+	FloatField
+	// BoolField holds true or false
+	BoolField
+	// ObjectIDField holds a mongo object ID
+	ObjectIDField
+	// TimeField holds a timestamp, queried in RFC 3339 format
+	TimeField
+	// ObjectField holds an embedded document or an array of them
+	ObjectField
+)
+
+// Schema maps the dotted bson path of every field in a model to its type and its path in the JSON form of the model.
+// Fields inside arrays use the path of the array, e.g. skills.name
+type Schema struct {
+	types     map[string]FieldType
+	jsonPaths map[string]string
+}
+
+// SheetSchema is the schema of the force character sheets in the live collection
+var SheetSchema = NewSchema(model.ForceCharacterSheet{})
+
+// ArchivedSheetSchema is the schema of the force character sheets in the archive collection
+var ArchivedSheetSchema = NewSchema(model.ArchivedForceCharacterSheet{})
+
+// NewSchema builds the schema of a model from its bson tags
+func NewSchema(value interface{}) Schema {
+	schema := Schema{
+		types:     map[string]FieldType{},
+		jsonPaths: map[string]string{},
+	}
+	addFields(schema, "", "", reflect.TypeOf(value))
+
+	return schema
+}
+
+// Lookup returns the type of the field at the path and whether the model has such a field
+func (s Schema) Lookup(path string) (FieldType, bool) {
+	fieldType, ok := s.types[path]
+
+	return fieldType, ok
+}
+
+// JSONPath returns the path of a field in the JSON form of the model, which differs from the bson path when the tags differ
+func (s Schema) JSONPath(path string) string {
+	if jsonPath, ok := s.jsonPaths[path]; ok {
+		return jsonPath
+	}
+
+	return path
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	objectIDType = reflect.TypeOf(primitive.ObjectID{})
+)
+
+func addFields(schema Schema, prefix string, jsonPrefix string, t reflect.Type) {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || t == timeType || t == objectIDType {
+		return
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name, inline := bsonName(field)
+		if name == "-" {
+			continue
+		}
+
+		if inline {
+			addFields(schema, prefix, jsonPrefix, field.Type)
+			continue
+		}
+
+		path := prefix + name
+		jsonPath := jsonPrefix + jsonName(field)
+		schema.types[path] = fieldType(field.Type)
+		schema.jsonPaths[path] = jsonPath
+		addFields(schema, path+".", jsonPath+".", field.Type)
+	}
+}
+
+func bsonName(field reflect.StructField) (string, bool) {
+	tag := strings.Split(field.Tag.Get("bson"), ",")
+	for _, option := range tag[1:] {
+		if option == "inline" {
+			return "", true
+		}
+	}
+
+	if tag[0] == "" {
+		return strings.ToLower(field.Name), false
+	}
+
+	return tag[0], false
+}
+
+func jsonName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" {
+		return field.Name
+	}
+
+	return name
+}
+
+func fieldType(t reflect.Type) FieldType {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		if t == objectIDType {
+			return ObjectIDField
+		}
+		t = t.Elem()
+	}
+
+	switch {
+	case t == objectIDType:
+		return ObjectIDField
+	case t == timeType:
+		return TimeField
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return IntField
+	case reflect.Float32, reflect.Float64:
+		return FloatField
+	case reflect.Bool:
+		return BoolField
+	case reflect.String:
+		return StringField
+	}
+
+	return ObjectField
+}
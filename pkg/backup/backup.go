@@ -0,0 +1,452 @@
+// Package backup dumps the live and archived character sheets to a portable bundle and restores them.
+// A bundle is a gzipped tar holding a manifest and one NDJSON file per collection, with each sheet in its API JSON form,
+// so a bundle taken from one storage backend can be restored into any other
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strconv"
+	"time"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/api"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Format and FormatVersion identify the bundles this package writes, restore refuses anything else
+const (
+	Format        = "sheet-crud-backup"
+	FormatVersion = 1
+)
+
+// The files of a bundle
+const (
+	ManifestFile = "manifest.json"
+	SheetsFile   = "sheets.ndjson"
+	ArchiveFile  = "archive.ndjson"
+)
+
+// The collections named in the manifest
+const (
+	SheetsCollection  = "sheets"
+	ArchiveCollection = "archive"
+)
+
+// Policy decides what restore does with a sheet whose ID already exists
+type Policy string
+
+const (
+	// Skip keeps the existing sheet
+	Skip Policy = "skip"
+	// Overwrite replaces the existing sheet with the one from the bundle
+	Overwrite Policy = "overwrite"
+	// NewIDs inserts the sheet from the bundle alongside the existing one under a new ID
+	NewIDs Policy = "new-ids"
+)
+
+//ParsePolicy parses a conflict policy, an empty value is Skip
+func ParsePolicy(value string) (Policy, error) {
+	switch Policy(value) {
+	case "":
+		return Skip, nil
+	case Skip, Overwrite, NewIDs:
+		return Policy(value), nil
+	}
+
+	return "", dberr.Invalid("invalid_query", "policy", "invalid query: unknown restore policy %q, expected %v, %v or %v", value, Skip, Overwrite, NewIDs)
+}
+
+// invalidBackup returns the error used when a bundle can't be read or doesn't match its manifest
+func invalidBackup(format string, args ...interface{}) error {
+	return dberr.New(dberr.ErrValidation, "invalid_backup", "invalid backup: "+format, args...)
+}
+
+//Database is the part of the character database a backup reads from and a restore writes to
+type Database interface {
+	GetForceCharacterSheets(ctx context.Context, query url.Values) ([]model.ForceCharacterSheet, error)
+	FindForceCharacterSheetByID(ctx context.Context, mongoID primitive.ObjectID, projection api.Projection) (*model.ForceCharacterSheet, error)
+	InsertForceCharacterSheet(ctx context.Context, sheet model.ForceCharacterSheet) error
+	UpdateForceCharacterSheetByID(ctx context.Context, sheet model.ForceCharacterSheet, mongoID primitive.ObjectID) error
+	GetArchivedForceCharacterSheets(ctx context.Context, query url.Values) ([]model.ArchivedForceCharacterSheet, error)
+	FindArchivedForceCharacterSheetByID(ctx context.Context, mongoID primitive.ObjectID) (*model.ArchivedForceCharacterSheet, error)
+	InsertArchivedForceCharacterSheet(ctx context.Context, sheet model.ArchivedForceCharacterSheet) error
+	PurgeArchivedForceCharacterSheetByID(ctx context.Context, mongoID primitive.ObjectID) error
+}
+
+//Manifest describes a bundle and how many sheets each of its collections holds
+type Manifest struct {
+	Format      string           `json:"format"`
+	Version     int              `json:"version"`
+	CreatedAt   time.Time        `json:"createdAt"`
+	Collections map[string]int64 `json:"collections"`
+}
+
+//CollectionReport counts what a restore did with the sheets of one collection
+type CollectionReport struct {
+	Inserted    int      `json:"inserted"`
+	Overwritten int      `json:"overwritten"`
+	Skipped     int      `json:"skipped"`
+	Failed      int      `json:"failed"`
+	Failures    []string `json:"failures"`
+}
+
+//RestoreReport describes what a restore did
+type RestoreReport struct {
+	Policy   Policy           `json:"policy"`
+	Manifest Manifest         `json:"manifest"`
+	Sheets   CollectionReport `json:"sheets"`
+	Archive  CollectionReport `json:"archive"`
+}
+
+// String summarises the report for the command line
+func (r RestoreReport) String() string {
+	summary := func(c CollectionReport) string {
+		return fmt.Sprintf("%v inserted, %v overwritten, %v skipped, %v failed", c.Inserted, c.Overwritten, c.Skipped, c.Failed)
+	}
+
+	return fmt.Sprintf("sheets: %v; archive: %v", summary(r.Sheets), summary(r.Archive))
+}
+
+//Write dumps every live and archived sheet to a bundle. Every sheet is read before anything is written,
+//so an error leaves the writer untouched
+func Write(ctx context.Context, database Database, w io.Writer) (Manifest, error) {
+	sheets := &bytes.Buffer{}
+	sheetCount, err := dumpSheets(ctx, database, json.NewEncoder(sheets))
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	archive := &bytes.Buffer{}
+	archiveCount, err := dumpArchive(ctx, database, json.NewEncoder(archive))
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	manifest := Manifest{
+		Format:    Format,
+		Version:   FormatVersion,
+		CreatedAt: time.Now().UTC(),
+		Collections: map[string]int64{
+			SheetsCollection:  sheetCount,
+			ArchiveCollection: archiveCount,
+		},
+	}
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{ManifestFile, manifestJSON},
+		{SheetsFile, sheets.Bytes()},
+		{ArchiveFile, archive.Bytes()},
+	}
+	for _, file := range files {
+		header := &tar.Header{
+			Name:    file.name,
+			Mode:    0644,
+			Size:    int64(len(file.data)),
+			ModTime: manifest.CreatedAt,
+		}
+		err := tw.WriteHeader(header)
+		if err != nil {
+			return manifest, err
+		}
+
+		_, err = tw.Write(file.data)
+		if err != nil {
+			return manifest, err
+		}
+	}
+
+	err = tw.Close()
+	if err != nil {
+		return manifest, err
+	}
+
+	return manifest, gz.Close()
+}
+
+// dumpSheets pages through the live sheets in _id order with the after cursor, which every backend supports
+func dumpSheets(ctx context.Context, database Database, encoder *json.Encoder) (int64, error) {
+	var count int64
+	query := url.Values{"pageCount": {strconv.Itoa(api.MaxPageCount)}}
+
+	for {
+		sheets, err := database.GetForceCharacterSheets(ctx, query)
+		if err != nil {
+			return count, err
+		}
+
+		for _, sheet := range sheets {
+			err := encoder.Encode(sheet)
+			if err != nil {
+				return count, err
+			}
+			count++
+		}
+
+		if len(sheets) < api.MaxPageCount {
+			return count, nil
+		}
+		query.Set("after", sheets[len(sheets)-1].ID.Hex())
+	}
+}
+
+func dumpArchive(ctx context.Context, database Database, encoder *json.Encoder) (int64, error) {
+	var count int64
+	query := url.Values{"pageCount": {strconv.Itoa(api.MaxPageCount)}}
+
+	for {
+		sheets, err := database.GetArchivedForceCharacterSheets(ctx, query)
+		if err != nil {
+			return count, err
+		}
+
+		for _, sheet := range sheets {
+			err := encoder.Encode(sheet)
+			if err != nil {
+				return count, err
+			}
+			count++
+		}
+
+		if len(sheets) < api.MaxPageCount {
+			return count, nil
+		}
+		query.Set("after", sheets[len(sheets)-1].ID.Hex())
+	}
+}
+
+//Restore loads a bundle into the database, resolving sheets whose ID already exists with the policy.
+//A sheet that can't be restored is counted as failed in the report and the restore carries on,
+//only an unreadable bundle or a canceled context stop it
+func Restore(ctx context.Context, database Database, r io.Reader, policy Policy) (RestoreReport, error) {
+	report := RestoreReport{
+		Policy:  policy,
+		Sheets:  CollectionReport{Failures: []string{}},
+		Archive: CollectionReport{Failures: []string{}},
+	}
+
+	files, err := readBundle(r)
+	if err != nil {
+		return report, err
+	}
+
+	manifest := Manifest{}
+	err = json.Unmarshal(files[ManifestFile], &manifest)
+	if err != nil {
+		return report, invalidBackup("unreadable %v: %v", ManifestFile, err)
+	}
+	if manifest.Format != Format || manifest.Version != FormatVersion {
+		return report, invalidBackup("expected format %v version %v, got %q version %v", Format, FormatVersion, manifest.Format, manifest.Version)
+	}
+	report.Manifest = manifest
+	lineages := lineages{}
+
+	err = eachLine(files[SheetsFile], manifest.Collections[SheetsCollection], func(line []byte) error {
+		sheet := model.ForceCharacterSheet{}
+		err := json.Unmarshal(line, &sheet)
+		if err != nil {
+			return invalidBackup("unreadable sheet in %v: %v", SheetsFile, err)
+		}
+
+		restoreSheet(ctx, database, sheet, policy, lineages, &report.Sheets)
+		return ctx.Err()
+	})
+	if err != nil {
+		return report, err
+	}
+
+	err = eachLine(files[ArchiveFile], manifest.Collections[ArchiveCollection], func(line []byte) error {
+		sheet := model.ArchivedForceCharacterSheet{}
+		err := json.Unmarshal(line, &sheet)
+		if err != nil {
+			return invalidBackup("unreadable sheet in %v: %v", ArchiveFile, err)
+		}
+
+		restoreArchived(ctx, database, sheet, policy, lineages, &report.Archive)
+		return ctx.Err()
+	})
+
+	return report, err
+}
+
+// readBundle reads every file of a bundle into memory, the sheets are only restored once the whole bundle is known to be readable
+func readBundle(r io.Reader) (map[string][]byte, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, invalidBackup("not a gzip file: %v", err)
+	}
+	defer gz.Close()
+
+	files := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, invalidBackup("%v", err)
+		}
+
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, invalidBackup("%v", err)
+		}
+		files[header.Name] = data
+	}
+
+	if _, ok := files[ManifestFile]; !ok {
+		return nil, invalidBackup("missing %v", ManifestFile)
+	}
+
+	return files, nil
+}
+
+// eachLine calls fn with every line of an NDJSON file after checking that it holds as many lines as the manifest says
+func eachLine(data []byte, expected int64, fn func(line []byte) error) error {
+	lines := [][]byte{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		lines = append(lines, append([]byte{}, scanner.Bytes()...))
+	}
+	if err := scanner.Err(); err != nil {
+		return invalidBackup("%v", err)
+	}
+
+	if int64(len(lines)) != expected {
+		return invalidBackup("the manifest lists %v sheets but the bundle holds %v", expected, len(lines))
+	}
+
+	for _, line := range lines {
+		err := fn(line)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func restoreSheet(ctx context.Context, database Database, sheet model.ForceCharacterSheet, policy Policy, lineages lineages, report *CollectionReport) {
+	fail := func(err error) {
+		report.Failed++
+		report.Failures = append(report.Failures, fmt.Sprintf("sheet %v: %v", sheet.ID.Hex(), err))
+	}
+
+	existing, err := database.FindForceCharacterSheetByID(ctx, sheet.ID, nil)
+	if err != nil && !errors.Is(err, dberr.ErrNotFound) {
+		fail(err)
+		return
+	}
+
+	switch {
+	case existing == nil:
+		err = database.InsertForceCharacterSheet(ctx, sheet)
+		if err == nil {
+			report.Inserted++
+		}
+	case policy == Overwrite:
+		// the update checks the stored version, the restored contents are then recorded as a new revision
+		sheet.Version = existing.Version
+		err = database.UpdateForceCharacterSheetByID(ctx, sheet, sheet.ID)
+		if err == nil {
+			report.Overwritten++
+		}
+	case policy == NewIDs:
+		sheet.ID = primitive.NewObjectID()
+		sheet.CharacterID = lineages.copyOf(sheet.CharacterID)
+		err = database.InsertForceCharacterSheet(ctx, sheet)
+		if err == nil {
+			report.Inserted++
+		}
+	default:
+		report.Skipped++
+	}
+
+	if err != nil {
+		fail(err)
+	}
+}
+
+func restoreArchived(ctx context.Context, database Database, sheet model.ArchivedForceCharacterSheet, policy Policy, lineages lineages, report *CollectionReport) {
+	fail := func(err error) {
+		report.Failed++
+		report.Failures = append(report.Failures, fmt.Sprintf("archived sheet %v: %v", sheet.ID.Hex(), err))
+	}
+
+	existing, err := database.FindArchivedForceCharacterSheetByID(ctx, sheet.ID)
+	if err != nil && !errors.Is(err, dberr.ErrNotFound) {
+		fail(err)
+		return
+	}
+
+	switch {
+	case existing == nil:
+		err = database.InsertArchivedForceCharacterSheet(ctx, sheet)
+		if err == nil {
+			report.Inserted++
+		}
+	case policy == Overwrite:
+		err = database.PurgeArchivedForceCharacterSheetByID(ctx, sheet.ID)
+		if err == nil {
+			err = database.InsertArchivedForceCharacterSheet(ctx, sheet)
+		}
+		if err == nil {
+			report.Overwritten++
+		}
+	case policy == NewIDs:
+		sheet.ID = primitive.NewObjectID()
+		sheet.CharacterID = lineages.copyOf(sheet.CharacterID)
+		err = database.InsertArchivedForceCharacterSheet(ctx, sheet)
+		if err == nil {
+			report.Inserted++
+		}
+	default:
+		report.Skipped++
+	}
+
+	if err != nil {
+		fail(err)
+	}
+}
+
+// lineages maps the characters of the sheets a restore copied under new IDs to the new characters the copies belong to,
+// so that copied versions of one character stay together without joining the existing character
+type lineages map[primitive.ObjectID]primitive.ObjectID
+
+// copyOf returns the character a copy of a sheet of the character belongs to, a sheet without a character starts its own
+func (l lineages) copyOf(characterID primitive.ObjectID) primitive.ObjectID {
+	if characterID.IsZero() {
+		return characterID
+	}
+
+	if _, found := l[characterID]; !found {
+		l[characterID] = primitive.NewObjectID()
+	}
+
+	return l[characterID]
+}
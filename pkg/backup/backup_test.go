@@ -0,0 +1,104 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/memory"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func mockCharacter(name string) model.ForceCharacterSheet {
+	return model.ForceCharacterSheet{
+		ID:            primitive.NewObjectID(),
+		CharacterName: name,
+		PlayerName:    "Ben",
+		Wounds:        model.Amount{Threshold: 12, Current: 3},
+		Version:       1,
+	}
+}
+
+func TestWriteAndRestore(t *testing.T) {
+	ctx := context.Background()
+	source := memory.New()
+
+	kanan := mockCharacter("Kanan")
+	ezra := mockCharacter("Ezra")
+	source.InsertForceCharacterSheet(ctx, kanan)
+	source.InsertForceCharacterSheet(ctx, ezra)
+	source.InsertArchivedForceCharacterSheet(ctx, model.ArchivedForceCharacterSheet{
+		ForceCharacterSheet: mockCharacter("Caleb"),
+		DeletedAt:           time.Now().UTC(),
+		DeletedBy:           "gm",
+	})
+
+	bundle := &bytes.Buffer{}
+	manifest, err := Write(ctx, source, bundle)
+	if err != nil || manifest.Collections[SheetsCollection] != 2 || manifest.Collections[ArchiveCollection] != 1 {
+		t.Fatalf("Write() error:\n   expected: 2 sheets and 1 archived sheet\n   got:      %+v %v", manifest, err)
+	}
+
+	target := memory.New()
+	report, err := Restore(ctx, target, bytes.NewReader(bundle.Bytes()), Skip)
+	if err != nil || report.Sheets.Inserted != 2 || report.Archive.Inserted != 1 {
+		t.Fatalf("Restore() error:\n   expected: everything inserted\n   got:      %+v %v", report, err)
+	}
+
+	restored, err := target.FindForceCharacterSheetByID(ctx, kanan.ID, nil)
+	if err != nil || restored.Wounds.Current != 3 {
+		t.Errorf("Restore() error:\n   expected: Kanan with 3 wounds\n   got:      %+v %v", restored, err)
+	}
+
+	report, err = Restore(ctx, target, bytes.NewReader(bundle.Bytes()), Skip)
+	if err != nil || report.Sheets.Skipped != 2 || report.Archive.Skipped != 1 {
+		t.Errorf("Restore() skip error:\n   expected: everything skipped\n   got:      %+v %v", report, err)
+	}
+
+	changed := *restored
+	changed.Wounds.Current = 9
+	target.UpdateForceCharacterSheetByID(ctx, changed, kanan.ID)
+
+	report, err = Restore(ctx, target, bytes.NewReader(bundle.Bytes()), Overwrite)
+	if err != nil || report.Sheets.Overwritten != 2 || report.Archive.Overwritten != 1 || report.Sheets.Failed != 0 {
+		t.Errorf("Restore() overwrite error:\n   expected: everything overwritten\n   got:      %+v %v", report, err)
+	}
+	restored, _ = target.FindForceCharacterSheetByID(ctx, kanan.ID, nil)
+	if restored.Wounds.Current != 3 {
+		t.Errorf("Restore() overwrite error:\n   expected: 3 wounds\n   got:      %v", restored.Wounds.Current)
+	}
+
+	report, err = Restore(ctx, target, bytes.NewReader(bundle.Bytes()), NewIDs)
+	if err != nil || report.Sheets.Inserted != 2 || report.Archive.Inserted != 1 {
+		t.Errorf("Restore() new-ids error:\n   expected: everything inserted\n   got:      %+v %v", report, err)
+	}
+	sheets, _ := target.GetForceCharacterSheets(ctx, url.Values{"characterName": {"Kanan"}})
+	if len(sheets) != 2 {
+		t.Fatalf("Restore() new-ids error:\n   expected: 2 copies of Kanan\n   got:      %v", len(sheets))
+	}
+	if sheets[0].CharacterID == sheets[1].CharacterID {
+		t.Errorf("Restore() new-ids error:\n   expected: the copy of Kanan in a character of its own\n   got:      %v", sheets[0].CharacterID)
+	}
+}
+
+func TestRestore_InvalidBundle(t *testing.T) {
+	_, err := Restore(context.Background(), memory.New(), strings.NewReader("not a bundle"), Skip)
+	if err == nil || !strings.Contains(err.Error(), "invalid backup") {
+		t.Errorf("Restore() error:\n   expected: invalid backup\n   got:      %v", err)
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	tests := map[string]Policy{"": Skip, "skip": Skip, "overwrite": Overwrite, "new-ids": NewIDs, "merge": ""}
+
+	for value, expected := range tests {
+		policy, err := ParsePolicy(value)
+		if policy != expected || (expected == "") != (err != nil) {
+			t.Errorf("ParsePolicy(%q) error:\n   expected: %v\n   got:      %v %v", value, expected, policy, err)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+// Package cache is a read-through cache in front of the character database.
+// It keeps the most recently read sheets by ID for a limited time so that repeated lookups don't wait on the database
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/api"
+	"github.com/geeksheik9/sheet-CRUD/pkg/handler"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//CharacterDB caches the sheets read by ID from the database it wraps, every other call goes straight through.
+//The least recently used sheet is evicted once size sheets are cached and a sheet is read again from the database once it is older than ttl,
+//a ttl of 0 keeps sheets until they are evicted or invalidated. Writes through the cache invalidate the sheets they touch,
+//writes by other instances are only seen once the cached sheet expires
+type CharacterDB struct {
+	handler.CharacterDatabase
+
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[primitive.ObjectID]*list.Element
+	recency *list.List
+	stats   model.CacheStats
+	now     func() time.Time
+	// generation changes on every invalidation, a read that raced with one doesn't fill the cache
+	generation uint64
+}
+
+type entry struct {
+	mongoID primitive.ObjectID
+	sheet   model.ForceCharacterSheet
+	expires time.Time
+}
+
+//New wraps the database with a cache of up to size sheets kept for ttl
+func New(database handler.CharacterDatabase, size int, ttl time.Duration) *CharacterDB {
+	return &CharacterDB{
+		CharacterDatabase: database,
+		size:              size,
+		ttl:               ttl,
+		entries:           map[primitive.ObjectID]*list.Element{},
+		recency:           list.New(),
+		now:               time.Now,
+	}
+}
+
+//Stats returns the hit, miss and eviction counts since the cache was created
+func (c *CharacterDB) Stats() model.CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Size = c.recency.Len()
+	stats.Capacity = c.size
+
+	return stats
+}
+
+//FindForceCharacterSheetByID returns the cached sheet or reads it from the database and caches it.
+//Projected reads are applied to the whole cached sheet, so they share the cache with whole sheet reads
+func (c *CharacterDB) FindForceCharacterSheetByID(ctx context.Context, mongoID primitive.ObjectID, projection api.Projection) (*model.ForceCharacterSheet, error) {
+	logrus.Debugf("BEGIN - cache FindForceCharacterSheetByID: %v", mongoID)
+
+	sheet, generation, found := c.get(mongoID)
+	if !found {
+		fetched, err := c.CharacterDatabase.FindForceCharacterSheetByID(ctx, mongoID, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		c.put(mongoID, *fetched, generation)
+		sheet = *fetched
+	}
+
+	return project(sheet, projection)
+}
+
+//UpdateForceCharacterSheetByID updates the sheet in the database and drops it from the cache
+func (c *CharacterDB) UpdateForceCharacterSheetByID(ctx context.Context, sheet model.ForceCharacterSheet, mongoID primitive.ObjectID) error {
+	defer c.Invalidate(mongoID)
+	return c.CharacterDatabase.UpdateForceCharacterSheetByID(ctx, sheet, mongoID)
+}
+
+//InsertSheetElement adds the element in the database and drops the sheet from the cache
+func (c *CharacterDB) InsertSheetElement(ctx context.Context, mongoID primitive.ObjectID, array api.SheetArray, element interface{}, expected int64) (*model.ForceCharacterSheet, error) {
+	defer c.Invalidate(mongoID)
+	return c.CharacterDatabase.InsertSheetElement(ctx, mongoID, array, element, expected)
+}
+
+//UpdateSheetElement replaces the element in the database and drops the sheet from the cache
+func (c *CharacterDB) UpdateSheetElement(ctx context.Context, mongoID primitive.ObjectID, array api.SheetArray, elementID primitive.ObjectID, element interface{}, expected int64) (*model.ForceCharacterSheet, error) {
+	defer c.Invalidate(mongoID)
+	return c.CharacterDatabase.UpdateSheetElement(ctx, mongoID, array, elementID, element, expected)
+}
+
+//DeleteSheetElement removes the element in the database and drops the sheet from the cache
+func (c *CharacterDB) DeleteSheetElement(ctx context.Context, mongoID primitive.ObjectID, array api.SheetArray, elementID primitive.ObjectID, expected int64) (*model.ForceCharacterSheet, error) {
+	defer c.Invalidate(mongoID)
+	return c.CharacterDatabase.DeleteSheetElement(ctx, mongoID, array, elementID, expected)
+}
+
+//ApplyHealthAction changes the wounds or strain in the database and drops the sheet from the cache
+func (c *CharacterDB) ApplyHealthAction(ctx context.Context, mongoID primitive.ObjectID, action api.HealthAction, amount int64, expected int64) (*model.ForceCharacterSheet, error) {
+	defer c.Invalidate(mongoID)
+	return c.CharacterDatabase.ApplyHealthAction(ctx, mongoID, action, amount, expected)
+}
+
+//DeleteForceCharacterSheetByID archives the sheet in the database and drops it from the cache
+func (c *CharacterDB) DeleteForceCharacterSheetByID(ctx context.Context, mongoID primitive.ObjectID, deletedBy string) error {
+	defer c.Invalidate(mongoID)
+	return c.CharacterDatabase.DeleteForceCharacterSheetByID(ctx, mongoID, deletedBy)
+}
+
+//RestoreForceCharacterSheetByID restores the archived sheet in the database and drops it from the cache
+func (c *CharacterDB) RestoreForceCharacterSheetByID(ctx context.Context, mongoID primitive.ObjectID) error {
+	defer c.Invalidate(mongoID)
+	return c.CharacterDatabase.RestoreForceCharacterSheetByID(ctx, mongoID)
+}
+
+//RevertForceCharacterSheetByID reverts the sheet in the database and drops it from the cache
+func (c *CharacterDB) RevertForceCharacterSheetByID(ctx context.Context, mongoID primitive.ObjectID, version int64) error {
+	defer c.Invalidate(mongoID)
+	return c.CharacterDatabase.RevertForceCharacterSheetByID(ctx, mongoID, version)
+}
+
+//RunInTransaction runs fn in a transaction of the database and empties the cache once it ends,
+//sheets read while it ran may hold writes that were rolled back or miss writes that were not committed yet
+func (c *CharacterDB) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	defer c.Purge()
+	return c.CharacterDatabase.RunInTransaction(ctx, fn)
+}
+
+//Invalidate drops a sheet from the cache
+func (c *CharacterDB) Invalidate(mongoID primitive.ObjectID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	if element, found := c.entries[mongoID]; found {
+		c.remove(element)
+		c.stats.Invalidations++
+	}
+}
+
+//Purge drops every sheet from the cache
+func (c *CharacterDB) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.stats.Invalidations += uint64(c.recency.Len())
+	c.entries = map[primitive.ObjectID]*list.Element{}
+	c.recency.Init()
+}
+
+// get returns the cached sheet, or the generation a read from the database must still be in to fill the cache
+func (c *CharacterDB) get(mongoID primitive.ObjectID) (model.ForceCharacterSheet, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, found := c.entries[mongoID]
+	if found && c.ttl > 0 && c.now().After(element.Value.(*entry).expires) {
+		c.remove(element)
+		c.stats.Expirations++
+		found = false
+	}
+
+	if !found {
+		c.stats.Misses++
+		return model.ForceCharacterSheet{}, c.generation, false
+	}
+
+	c.stats.Hits++
+	c.recency.MoveToFront(element)
+
+	return element.Value.(*entry).sheet, c.generation, true
+}
+
+func (c *CharacterDB) put(mongoID primitive.ObjectID, sheet model.ForceCharacterSheet, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation || c.size <= 0 {
+		return
+	}
+
+	cached := &entry{mongoID: mongoID, sheet: sheet, expires: c.now().Add(c.ttl)}
+	if element, found := c.entries[mongoID]; found {
+		element.Value = cached
+		c.recency.MoveToFront(element)
+		return
+	}
+
+	c.entries[mongoID] = c.recency.PushFront(cached)
+	for c.recency.Len() > c.size {
+		c.remove(c.recency.Back())
+		c.stats.Evictions++
+	}
+}
+
+func (c *CharacterDB) remove(element *list.Element) {
+	c.recency.Remove(element)
+	delete(c.entries, element.Value.(*entry).mongoID)
+}
+
+// project returns a copy of the cached sheet with only the fields of the projection, so callers can't change the cached sheet
+func project(sheet model.ForceCharacterSheet, projection api.Projection) (*model.ForceCharacterSheet, error) {
+	data, err := bson.Marshal(sheet)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := bson.M{}
+	err = bson.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err = bson.Marshal(projection.Apply(doc))
+	if err != nil {
+		return nil, err
+	}
+
+	projected := model.ForceCharacterSheet{}
+	err = bson.Unmarshal(data, &projected)
+	if err != nil {
+		return nil, err
+	}
+
+	return &projected, nil
+}
@@ -0,0 +1,164 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/api"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/memory"
+	"github.com/geeksheik9/sheet-CRUD/pkg/handler"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var _ handler.CharacterDatabase = New(memory.New(), 1, 0)
+
+// countingDB counts the sheet lookups that reach the database
+type countingDB struct {
+	handler.CharacterDatabase
+	finds int
+}
+
+func (d *countingDB) FindForceCharacterSheetByID(ctx context.Context, mongoID primitive.ObjectID, projection api.Projection) (*model.ForceCharacterSheet, error) {
+	d.finds++
+	return d.CharacterDatabase.FindForceCharacterSheetByID(ctx, mongoID, projection)
+}
+
+func setup(t *testing.T, size int, ttl time.Duration, names ...string) (*CharacterDB, *countingDB, []model.ForceCharacterSheet) {
+	database := &countingDB{CharacterDatabase: memory.New()}
+	sheets := []model.ForceCharacterSheet{}
+	for _, name := range names {
+		sheet := model.ForceCharacterSheet{
+			ID:            primitive.NewObjectID(),
+			CharacterName: name,
+			Skills:        []model.Skills{{Name: "athletics", Level: 1}},
+			Version:       1,
+		}
+		err := database.InsertForceCharacterSheet(context.Background(), sheet)
+		if err != nil {
+			t.Fatalf("InsertForceCharacterSheet() error:\n   expected: <nil>\n   got:      %v", err)
+		}
+		sheets = append(sheets, sheet)
+	}
+
+	return New(database, size, ttl), database, sheets
+}
+
+func TestCharacterDB_FindForceCharacterSheetByID(t *testing.T) {
+	ctx := context.Background()
+	c, database, sheets := setup(t, 10, time.Minute, "Mando")
+
+	first, err := c.FindForceCharacterSheetByID(ctx, sheets[0].ID, nil)
+	if err != nil || first.CharacterName != "Mando" {
+		t.Fatalf("FindForceCharacterSheetByID() error:\n   expected: Mando\n   got:      %v %v", first, err)
+	}
+	first.Skills[0].Level = 5
+
+	second, _ := c.FindForceCharacterSheetByID(ctx, sheets[0].ID, nil)
+	projected, _ := c.FindForceCharacterSheetByID(ctx, sheets[0].ID, api.Projection{"skills": 0})
+
+	if database.finds != 1 {
+		t.Errorf("FindForceCharacterSheetByID() error:\n   expected: 1 database lookup\n   got:      %v", database.finds)
+	}
+	if second.Skills[0].Level != 1 {
+		t.Errorf("FindForceCharacterSheetByID() error:\n   expected: cached sheet unchanged by the caller\n   got:      %v", second.Skills)
+	}
+	if projected.CharacterName != "Mando" || projected.Skills != nil {
+		t.Errorf("FindForceCharacterSheetByID() projection error:\n   expected: Mando without skills\n   got:      %v", projected)
+	}
+
+	stats := c.Stats()
+	if stats.Hits != 2 || stats.Misses != 1 || stats.Size != 1 || stats.Capacity != 10 {
+		t.Errorf("Stats() error:\n   expected: 2 hits, 1 miss, 1 of 10 cached\n   got:      %+v", stats)
+	}
+
+	_, err = c.FindForceCharacterSheetByID(ctx, primitive.NewObjectID(), nil)
+	if api.CheckError(err) != 404 || c.Stats().Size != 1 {
+		t.Errorf("FindForceCharacterSheetByID() missing sheet error:\n   expected: not found and not cached\n   got:      %v %+v", err, c.Stats())
+	}
+}
+
+func TestCharacterDB_Eviction(t *testing.T) {
+	ctx := context.Background()
+	c, database, sheets := setup(t, 2, 0, "Mando", "Grogu", "Cara")
+
+	c.FindForceCharacterSheetByID(ctx, sheets[0].ID, nil)
+	c.FindForceCharacterSheetByID(ctx, sheets[1].ID, nil)
+	c.FindForceCharacterSheetByID(ctx, sheets[0].ID, nil)
+	// Grogu is the least recently used and makes room for Cara
+	c.FindForceCharacterSheetByID(ctx, sheets[2].ID, nil)
+	c.FindForceCharacterSheetByID(ctx, sheets[0].ID, nil)
+	c.FindForceCharacterSheetByID(ctx, sheets[1].ID, nil)
+
+	stats := c.Stats()
+	if database.finds != 4 || stats.Evictions != 2 || stats.Size != 2 {
+		t.Errorf("FindForceCharacterSheetByID() eviction error:\n   expected: 4 database lookups and 2 evictions\n   got:      %v %+v", database.finds, stats)
+	}
+}
+
+func TestCharacterDB_Expiry(t *testing.T) {
+	ctx := context.Background()
+	c, database, sheets := setup(t, 10, time.Minute, "Mando")
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	c.FindForceCharacterSheetByID(ctx, sheets[0].ID, nil)
+	now = now.Add(59 * time.Second)
+	c.FindForceCharacterSheetByID(ctx, sheets[0].ID, nil)
+	now = now.Add(2 * time.Second)
+	c.FindForceCharacterSheetByID(ctx, sheets[0].ID, nil)
+
+	if database.finds != 2 || c.Stats().Expirations != 1 {
+		t.Errorf("FindForceCharacterSheetByID() expiry error:\n   expected: 2 database lookups and 1 expiration\n   got:      %v %+v", database.finds, c.Stats())
+	}
+}
+
+func TestCharacterDB_Invalidation(t *testing.T) {
+	ctx := context.Background()
+	c, _, sheets := setup(t, 10, time.Minute, "Mando")
+
+	c.FindForceCharacterSheetByID(ctx, sheets[0].ID, nil)
+	sheets[0].CharacterName = "Din Djarin"
+	err := c.UpdateForceCharacterSheetByID(ctx, sheets[0], sheets[0].ID)
+	if err != nil {
+		t.Fatalf("UpdateForceCharacterSheetByID() error:\n   expected: <nil>\n   got:      %v", err)
+	}
+
+	updated, _ := c.FindForceCharacterSheetByID(ctx, sheets[0].ID, nil)
+	if updated.CharacterName != "Din Djarin" || updated.Version != 2 {
+		t.Errorf("UpdateForceCharacterSheetByID() invalidation error:\n   expected: Din Djarin version 2\n   got:      %v version %v", updated.CharacterName, updated.Version)
+	}
+
+	c.DeleteForceCharacterSheetByID(ctx, sheets[0].ID, "gm")
+	_, err = c.FindForceCharacterSheetByID(ctx, sheets[0].ID, nil)
+	if api.CheckError(err) != 404 {
+		t.Errorf("DeleteForceCharacterSheetByID() invalidation error:\n   expected: not found\n   got:      %v", err)
+	}
+
+	c.RestoreForceCharacterSheetByID(ctx, sheets[0].ID)
+	c.FindForceCharacterSheetByID(ctx, sheets[0].ID, nil)
+	c.RunInTransaction(ctx, func(ctx context.Context) error { return nil })
+	if stats := c.Stats(); stats.Size != 0 || stats.Invalidations != 3 {
+		t.Errorf("RunInTransaction() invalidation error:\n   expected: empty cache after 3 invalidations\n   got:      %+v", stats)
+	}
+}
+
+func TestCharacterDB_StaleFill(t *testing.T) {
+	ctx := context.Background()
+	c, _, sheets := setup(t, 10, time.Minute, "Mando")
+
+	// a read that started before an invalidation must not cache what it read
+	_, generation, _ := c.get(sheets[0].ID)
+	c.Invalidate(sheets[0].ID)
+	c.put(sheets[0].ID, sheets[0], generation)
+
+	if c.Stats().Size != 0 {
+		t.Errorf("put() error:\n   expected: stale read not cached\n   got:      %+v", c.Stats())
+	}
+
+	c.FindForceCharacterSheetByID(ctx, sheets[0].ID, nil)
+	if c.Stats().Size != 1 {
+		t.Errorf("FindForceCharacterSheetByID() error:\n   expected: sheet cached\n   got:      %+v", c.Stats())
+	}
+}
@@ -0,0 +1,181 @@
+// Package catalog holds the talents and force powers XP can buy and what they cost, so purchases are priced by the service rather than by the client.
+// The built in catalog is a starter set, a deployment can replace it with its own JSON file of the same shape
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// MaxTalentTier is the tier of the last row of a talent tree
+const MaxTalentTier = 5
+
+// Talent is a talent XP can buy. A talent costs 5 × its tier, ranked talents can be bought more than once
+type Talent struct {
+	Name   string `json:"name"`
+	Tier   int64  `json:"tier"`
+	Ranked bool   `json:"ranked,omitempty"`
+}
+
+// Upgrade is an upgrade of a force power tree. Costs holds the cost of each time it can be bought, in the order they are bought
+type Upgrade struct {
+	Name  string  `json:"name"`
+	Costs []int64 `json:"costs"`
+}
+
+// ForcePower is a force power XP can buy, Cost is the cost of the basic power
+type ForcePower struct {
+	Name     string    `json:"name"`
+	Cost     int64     `json:"cost"`
+	Upgrades []Upgrade `json:"upgrades"`
+}
+
+// Catalog is the talents and force powers XP can buy
+type Catalog struct {
+	Talents     []Talent     `json:"talents"`
+	ForcePowers []ForcePower `json:"forcePowers"`
+}
+
+// Default returns the built in catalog
+func Default() *Catalog {
+	return &Catalog{
+		Talents: []Talent{
+			{Name: "Dedication", Tier: 5, Ranked: true},
+			{Name: "Durable", Tier: 1, Ranked: true},
+			{Name: "Grit", Tier: 1, Ranked: true},
+			{Name: "Parry", Tier: 1, Ranked: true},
+			{Name: "Quick Draw", Tier: 1},
+			{Name: "Rapid Reaction", Tier: 1, Ranked: true},
+			{Name: "Reflect", Tier: 1, Ranked: true},
+			{Name: "Toughened", Tier: 1, Ranked: true},
+		},
+		ForcePowers: []ForcePower{
+			{Name: "Enhance", Cost: 10, Upgrades: []Upgrade{
+				{Name: "Control", Costs: []int64{10, 10, 15, 15, 20}},
+				{Name: "Range", Costs: []int64{10}},
+			}},
+			{Name: "Move", Cost: 10, Upgrades: []Upgrade{
+				{Name: "Control", Costs: []int64{10, 10, 15}},
+				{Name: "Magnitude", Costs: []int64{5, 10, 15}},
+				{Name: "Range", Costs: []int64{5, 10, 15}},
+				{Name: "Strength", Costs: []int64{10, 10, 15, 15}},
+			}},
+			{Name: "Sense", Cost: 10, Upgrades: []Upgrade{
+				{Name: "Control", Costs: []int64{10, 10, 20}},
+				{Name: "Duration", Costs: []int64{10}},
+				{Name: "Magnitude", Costs: []int64{10, 10}},
+				{Name: "Range", Costs: []int64{10, 15}},
+				{Name: "Strength", Costs: []int64{10, 15}},
+			}},
+		},
+	}
+}
+
+// Load reads a catalog from a JSON file and checks that everything in it can be priced
+func Load(path string) (*Catalog, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	catalog := Catalog{}
+	err = json.Unmarshal(data, &catalog)
+	if err != nil {
+		return nil, fmt.Errorf("error reading catalog %v: %v", path, err)
+	}
+
+	err = catalog.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("error reading catalog %v: %v", path, err)
+	}
+
+	return &catalog, nil
+}
+
+// Validate checks that every talent has a name and a tier between 1 and MaxTalentTier and every force power and upgrade a name and positive costs
+func (c *Catalog) Validate() error {
+	talents := map[string]bool{}
+	for _, talent := range c.Talents {
+		key := strings.ToLower(talent.Name)
+		switch {
+		case strings.TrimSpace(talent.Name) == "":
+			return fmt.Errorf("a talent has no name")
+		case talents[key]:
+			return fmt.Errorf("talent %q is listed more than once", talent.Name)
+		case talent.Tier < 1 || talent.Tier > MaxTalentTier:
+			return fmt.Errorf("talent %q needs a tier between 1 and %v but has %v", talent.Name, MaxTalentTier, talent.Tier)
+		}
+		talents[key] = true
+	}
+
+	powers := map[string]bool{}
+	for _, power := range c.ForcePowers {
+		key := strings.ToLower(power.Name)
+		switch {
+		case strings.TrimSpace(power.Name) == "":
+			return fmt.Errorf("a force power has no name")
+		case powers[key]:
+			return fmt.Errorf("force power %q is listed more than once", power.Name)
+		case power.Cost <= 0:
+			return fmt.Errorf("force power %q needs a positive cost but has %v", power.Name, power.Cost)
+		}
+		powers[key] = true
+
+		upgrades := map[string]bool{}
+		for _, upgrade := range power.Upgrades {
+			key := strings.ToLower(upgrade.Name)
+			switch {
+			case strings.TrimSpace(upgrade.Name) == "":
+				return fmt.Errorf("an upgrade of %q has no name", power.Name)
+			case upgrades[key]:
+				return fmt.Errorf("upgrade %q of %q is listed more than once", upgrade.Name, power.Name)
+			case len(upgrade.Costs) == 0:
+				return fmt.Errorf("upgrade %q of %q has no costs", upgrade.Name, power.Name)
+			}
+			upgrades[key] = true
+
+			for _, cost := range upgrade.Costs {
+				if cost <= 0 {
+					return fmt.Errorf("upgrade %q of %q needs positive costs but has %v", upgrade.Name, power.Name, cost)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Talent returns the talent with the name, ignoring case, nil when the catalog has none
+func (c *Catalog) Talent(name string) *Talent {
+	for i := range c.Talents {
+		if strings.EqualFold(c.Talents[i].Name, name) {
+			return &c.Talents[i]
+		}
+	}
+
+	return nil
+}
+
+// ForcePower returns the force power with the name, ignoring case, nil when the catalog has none
+func (c *Catalog) ForcePower(name string) *ForcePower {
+	for i := range c.ForcePowers {
+		if strings.EqualFold(c.ForcePowers[i].Name, name) {
+			return &c.ForcePowers[i]
+		}
+	}
+
+	return nil
+}
+
+// Upgrade returns the upgrade of the force power with the name, ignoring case, nil when the power has none
+func (p *ForcePower) Upgrade(name string) *Upgrade {
+	for i := range p.Upgrades {
+		if strings.EqualFold(p.Upgrades[i].Name, name) {
+			return &p.Upgrades[i]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package catalog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDefault(t *testing.T) {
+	c := Default()
+
+	err := c.Validate()
+	if err != nil {
+		t.Errorf("Default() error:\n   expected: a valid catalog\n   got:      %v", err)
+	}
+
+	if talent := c.Talent("grit"); talent == nil || talent.Tier != 1 || !talent.Ranked {
+		t.Errorf("Talent() error:\n   expected: grit at tier 1 and ranked\n   got:      %+v", talent)
+	}
+	if talent := c.Talent("Force Choke"); talent != nil {
+		t.Errorf("Talent() error:\n   expected: nil\n   got:      %+v", talent)
+	}
+
+	power := c.ForcePower("MOVE")
+	if power == nil || power.Cost != 10 || power.Upgrade("strength") == nil || power.Upgrade("Duration") != nil {
+		t.Errorf("ForcePower() error:\n   expected: move for 10 with a strength upgrade and no duration upgrade\n   got:      %+v", power)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "catalog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name  string
+		data  string
+		valid bool
+	}{
+		{"valid", `{"talents":[{"name":"Grit","tier":1,"ranked":true}],"forcePowers":[{"name":"Bind","cost":10,"upgrades":[{"name":"Range","costs":[5,10]}]}]}`, true},
+		{"malformed", `{"talents":`, false},
+		{"tier", `{"talents":[{"name":"Grit","tier":6}]}`, false},
+		{"duplicate", `{"talents":[{"name":"Grit","tier":1},{"name":"grit","tier":2}]}`, false},
+		{"power cost", `{"forcePowers":[{"name":"Bind"}]}`, false},
+		{"upgrade costs", `{"forcePowers":[{"name":"Bind","cost":10,"upgrades":[{"name":"Range","costs":[]}]}]}`, false},
+		{"upgrade cost", `{"forcePowers":[{"name":"Bind","cost":10,"upgrades":[{"name":"Range","costs":[5,0]}]}]}`, false},
+	}
+
+	for _, test := range tests {
+		path := filepath.Join(dir, test.name+".json")
+		err := ioutil.WriteFile(path, []byte(test.data), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c, err := Load(path)
+		if (err == nil) != test.valid {
+			t.Errorf("Load(%v) error:\n   expected: valid %v\n   got:      %v", test.name, test.valid, err)
+		}
+		if test.valid && (c == nil || c.ForcePower("bind").Upgrade("range").Costs[1] != 10) {
+			t.Errorf("Load(%v) error:\n   expected: bind with a second range upgrade for 10\n   got:      %+v", test.name, c)
+		}
+	}
+
+	_, err = Load(filepath.Join(dir, "missing.json"))
+	if err == nil {
+		t.Errorf("Load(missing) error:\n   expected: an error\n   got:      nil")
+	}
+}
@@ -0,0 +1,179 @@
+package db
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/api"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
+	"github.com/sirupsen/logrus"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//GetArchivedForceCharacterSheets returns all FFG Star Wars Force sensitive character sheets that reside in the archive
+func (d *CharacterDB) GetArchivedForceCharacterSheets(ctx context.Context, queryParams url.Values) ([]model.ArchivedForceCharacterSheet, error) {
+	logrus.Debug("BEGIN - GetArchivedForceCharacterSheets")
+
+	ctx, cancel := withTimeout(ctx, d.readTimeout)
+	defer cancel()
+
+	archive := d.client.Database(d.databaseName).Collection(d.archiveName)
+
+	pageNumber, pageCount, sort, filter, err := api.BuildFilter(queryParams, api.ArchivedSheetSchema)
+	if err != nil {
+		return nil, translate(err)
+	}
+
+	skip := 0
+	if pageNumber > 0 {
+		skip = (pageNumber - 1) * pageCount
+	}
+
+	opts := options.Find().
+		SetMaxTime(30 * time.Second).
+		SetSkip(int64(skip)).
+		SetLimit(int64(pageCount)).
+		SetSort(sort)
+
+	cur, err := archive.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, translate(err)
+	}
+	defer cur.Close(ctx)
+
+	matches := []model.ArchivedForceCharacterSheet{}
+
+	for cur.Next(ctx) {
+		elem := model.ArchivedForceCharacterSheet{}
+		err := cur.Decode(&elem)
+		if err != nil {
+			return nil, translate(err)
+		}
+
+		matches = append(matches, elem)
+	}
+
+	return matches, translate(cur.Err())
+}
+
+//FindArchivedForceCharacterSheetByID finds a specific archived force character sheet by a provided ID
+func (d *CharacterDB) FindArchivedForceCharacterSheetByID(ctx context.Context, mongoID primitive.ObjectID) (*model.ArchivedForceCharacterSheet, error) {
+	logrus.Debugf("BEGIN - FindArchivedForceCharacterSheetByID: %v", mongoID)
+
+	ctx, cancel := withTimeout(ctx, d.readTimeout)
+	defer cancel()
+
+	archive := d.client.Database(d.databaseName).Collection(d.archiveName)
+
+	sheet := model.ArchivedForceCharacterSheet{}
+
+	err := archive.FindOne(ctx, bson.M{"_id": mongoID}).Decode(&sheet)
+	if err == mongo.ErrNoDocuments {
+		return nil, dberr.ArchivedSheetNotFound(mongoID)
+	}
+	if err != nil {
+		return nil, translate(err)
+	}
+
+	return &sheet, nil
+}
+
+//InsertArchivedForceCharacterSheet inserts an archived force character sheet straight into the archive collection, used to restore backups
+func (d *CharacterDB) InsertArchivedForceCharacterSheet(ctx context.Context, sheet model.ArchivedForceCharacterSheet) error {
+	logrus.Debugf("BEGIN - InsertArchivedForceCharacterSheet: %v", sheet.ID)
+
+	ctx, cancel := withTimeout(ctx, d.writeTimeout)
+	defer cancel()
+
+	archive := d.client.Database(d.databaseName).Collection(d.archiveName)
+	sheet.ForceCharacterSheet = api.StartLineage(sheet.ForceCharacterSheet)
+
+	_, err := archive.InsertOne(ctx, sheet)
+
+	return translate(err)
+}
+
+//RestoreForceCharacterSheetByID moves a specific archived force character sheet back into the live collection
+func (d *CharacterDB) RestoreForceCharacterSheetByID(ctx context.Context, mongoID primitive.ObjectID) error {
+	logrus.Debugf("BEGIN - RestoreForceCharacterSheetByID: %v", mongoID)
+
+	ctx, cancel := withTimeout(ctx, d.writeTimeout)
+	defer cancel()
+
+	collection := d.client.Database(d.databaseName).Collection(d.collectionName)
+	archive := d.client.Database(d.databaseName).Collection(d.archiveName)
+
+	return d.atomically(ctx, func(ctx context.Context) error {
+		archived := model.ArchivedForceCharacterSheet{}
+		err := archive.FindOne(ctx, bson.M{"_id": mongoID}).Decode(&archived)
+		if err == mongo.ErrNoDocuments {
+			return dberr.ArchivedSheetNotFound(mongoID)
+		}
+		if err != nil {
+			return translate(err)
+		}
+
+		return d.moveSheet(ctx, mongoID, func(ctx context.Context) error {
+			_, err := collection.InsertOne(ctx, archived.ForceCharacterSheet)
+			return err
+		}, archive, collection)
+	})
+}
+
+// collectionDeleter is the part of a collection a move between the live and archive collections deletes from
+type collectionDeleter interface {
+	DeleteOne(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error)
+}
+
+// moveSheet moves a sheet between the live and archive collections, insert writes it to the destination and it is then deleted from the source.
+// In a transaction a failed delete rolls the insert back with it. Without one the copy written to the destination is deleted again,
+// so the sheet is left where it was rather than in both collections
+func (d *CharacterDB) moveSheet(ctx context.Context, mongoID primitive.ObjectID, insert func(ctx context.Context) error, source collectionDeleter, destination collectionDeleter) error {
+	err := insert(ctx)
+	if err != nil {
+		return translate(err)
+	}
+
+	_, err = source.DeleteOne(ctx, bson.M{"_id": mongoID})
+	if err == nil || mongo.SessionFromContext(ctx) != nil {
+		return translate(err)
+	}
+
+	// the move may have failed on its deadline, so the copy is taken back with a deadline of its own
+	undoCtx, cancel := withTimeout(context.Background(), d.writeTimeout)
+	defer cancel()
+
+	_, undoErr := destination.DeleteOne(undoCtx, bson.M{"_id": mongoID})
+	if undoErr != nil {
+		logrus.Errorf("Sheet %v is left in both collections, taking back its copy after a failed move failed: %v", mongoID.Hex(), undoErr)
+	}
+
+	return translate(err)
+}
+
+//PurgeArchivedForceCharacterSheetByID permanently deletes a specific archived force character sheet
+func (d *CharacterDB) PurgeArchivedForceCharacterSheetByID(ctx context.Context, mongoID primitive.ObjectID) error {
+	logrus.Debugf("BEGIN - PurgeArchivedForceCharacterSheetByID: %v", mongoID)
+
+	ctx, cancel := withTimeout(ctx, d.writeTimeout)
+	defer cancel()
+
+	archive := d.client.Database(d.databaseName).Collection(d.archiveName)
+
+	result, err := archive.DeleteOne(ctx, bson.M{"_id": mongoID})
+	if err != nil {
+		return translate(err)
+	}
+
+	if result.DeletedCount != 1 {
+		return dberr.ArchivedSheetNotFound(mongoID)
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// fakeDeleter counts the deletes made on it and fails them with err
+type fakeDeleter struct {
+	deletes int
+	err     error
+}
+
+func (f *fakeDeleter) DeleteOne(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
+	f.deletes++
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	return &mongo.DeleteResult{DeletedCount: 1}, nil
+}
+
+func TestCharacterDB_moveSheet(t *testing.T) {
+	failed := errors.New("connection reset")
+	tests := []struct {
+		name        string
+		insertErr   error
+		sourceErr   error
+		sourceDels  int
+		destDels    int
+		expectedErr error
+	}{
+		{"moved", nil, nil, 1, 0, nil},
+		{"insert fails", failed, nil, 0, 0, failed},
+		{"delete fails", nil, failed, 1, 1, failed},
+	}
+
+	d := &CharacterDB{}
+	for _, test := range tests {
+		source, destination := &fakeDeleter{err: test.sourceErr}, &fakeDeleter{}
+		err := d.moveSheet(context.Background(), primitive.NewObjectID(), func(ctx context.Context) error {
+			return test.insertErr
+		}, source, destination)
+
+		if err != test.expectedErr || source.deletes != test.sourceDels || destination.deletes != test.destDels {
+			t.Errorf("moveSheet() %v error:\n   expected: %v with %v source and %v destination deletes\n   got:      %v with %v and %v",
+				test.name, test.expectedErr, test.sourceDels, test.destDels, err, source.deletes, destination.deletes)
+		}
+	}
+}
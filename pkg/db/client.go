@@ -2,6 +2,10 @@ package db
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
 
 	"github.com/geeksheik9/sheet-CRUD/config"
 
@@ -9,12 +13,20 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-// InitializeClients returns a mongo client.
-func InitializeClients(ctx context.Context) (*mongo.Client, error) {
+// InitializeClients validates the mongo configuration and returns a connected mongo client.
+func InitializeClients(ctx context.Context, mongoConfig config.MongoConfig) (*mongo.Client, error) {
 
-	options := options.Client().ApplyURI("mongodb://192.168.145.181:27017")
+	err := mongoConfig.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	options, err := clientOptions(mongoConfig)
+	if err != nil {
+		return nil, err
+	}
 
-	err := options.Validate()
+	err = options.Validate()
 	if err != nil {
 		return nil, err
 	}
@@ -32,6 +44,68 @@ func InitializeClients(ctx context.Context) (*mongo.Client, error) {
 	return client, err
 }
 
+// clientOptions builds the driver options from the configuration, settings in the configuration override the URI
+func clientOptions(mongoConfig config.MongoConfig) (*options.ClientOptions, error) {
+	opts := options.Client().
+		ApplyURI(mongoConfig.URI).
+		SetAppName(mongoConfig.AppName).
+		SetMinPoolSize(mongoConfig.MinPoolSize).
+		SetMaxPoolSize(mongoConfig.MaxPoolSize).
+		SetConnectTimeout(mongoConfig.ConnectTimeout).
+		SetServerSelectionTimeout(mongoConfig.ServerSelectionTimeout)
+
+	if mongoConfig.ReplicaSet != "" {
+		opts.SetReplicaSet(mongoConfig.ReplicaSet)
+	}
+
+	if mongoConfig.Username != "" {
+		opts.SetAuth(options.Credential{
+			Username:    mongoConfig.Username,
+			Password:    mongoConfig.Password,
+			PasswordSet: mongoConfig.Password != "",
+			AuthSource:  mongoConfig.AuthSource,
+		})
+	}
+
+	if mongoConfig.TLSEnabled() {
+		tlsConfig, err := tlsConfig(mongoConfig)
+		if err != nil {
+			return nil, err
+		}
+		opts.SetTLSConfig(tlsConfig)
+	}
+
+	return opts, nil
+}
+
+// tlsConfig loads the CA bundle used to verify the server and the client certificate used to authenticate
+func tlsConfig(mongoConfig config.MongoConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+
+	if mongoConfig.TLSCAFile != "" {
+		ca, err := ioutil.ReadFile(mongoConfig.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading MONGO_TLS_CA_FILE: %v", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("error reading MONGO_TLS_CA_FILE: %v contains no PEM certificates", mongoConfig.TLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if mongoConfig.TLSCertificateKeyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(mongoConfig.TLSCertificateKeyFile, mongoConfig.TLSCertificateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading MONGO_TLS_CERTIFICATE_KEY_FILE: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
+
 // InitializeDatabases Factory for the dao implementation. Returns a dao connected to the designated MongoDB database for DB operations.
 // The database connection is made using configuration in the config.go file
 func InitializeDatabases(client *mongo.Client, config *config.Config) *CharacterDB {
@@ -41,6 +115,10 @@ func InitializeDatabases(client *mongo.Client, config *config.Config) *Character
 		databaseName:   config.CharacterDatabase,
 		collectionName: config.CharacterCollection,
 		archiveName:    config.CharacterArchive,
+		revisionsName:  config.CharacterRevisions,
+		migrationsName: config.CharacterMigrations,
+		readTimeout:    config.ReadTimeout,
+		writeTimeout:   config.WriteTimeout,
 	}
 
 	return database
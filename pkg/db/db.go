@@ -2,13 +2,12 @@ package db
 
 import (
 	"context"
-	"errors"
 	"net/url"
-	"strconv"
 	"time"
 
 	model "github.com/geeksheik9/sheet-CRUD/models"
 	"github.com/geeksheik9/sheet-CRUD/pkg/api"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
 	"github.com/sirupsen/logrus"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -24,35 +23,70 @@ type CharacterDB struct {
 	databaseName   string
 	collectionName string
 	archiveName    string
+	revisionsName  string
+	migrationsName string
+	readTimeout    time.Duration
+	writeTimeout   time.Duration
+}
+
+// withTimeout bounds an operation by the configured timeout on top of any deadline already on the context
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, timeout)
 }
 
 //Ping checks that the database is running
-func (d *CharacterDB) Ping() error {
-	err := d.client.Ping(context.Background(), readpref.Primary())
+func (d *CharacterDB) Ping(ctx context.Context) error {
+	ctx, cancel := withTimeout(ctx, d.readTimeout)
+	defer cancel()
+
+	err := d.client.Ping(ctx, readpref.Primary())
 	if err != nil {
 		logrus.Errorf("ERROR connectiong to database %v", err)
 	}
-	return err
+	return translate(err)
 }
 
 //InsertForceCharacterSheet inserts the FFG Star Wars Force sensitive character sheet into the database
-func (d *CharacterDB) InsertForceCharacterSheet(sheet model.ForceCharacterSheet) error {
+func (d *CharacterDB) InsertForceCharacterSheet(ctx context.Context, sheet model.ForceCharacterSheet) error {
 	logrus.Debug("BEGIN - InsertForceCharacterSheet")
 
+	ctx, cancel := withTimeout(ctx, d.writeTimeout)
+	defer cancel()
+
 	collection := d.client.Database(d.databaseName).Collection(d.collectionName)
+	sheet = api.WithElementIDs(api.StartLineage(sheet))
 
-	_, err := collection.InsertOne(context.Background(), sheet)
+	_, err := collection.InsertOne(ctx, sheet)
+	if err != nil {
+		return translate(err)
+	}
 
-	return err
+	return d.insertRevision(ctx, sheet, "created")
 }
 
 //GetForceCharacterSheets returns all FFG Star Wars Force sensitive character sheets that reside in the database
-func (d *CharacterDB) GetForceCharacterSheets(queryParams url.Values) ([]model.ForceCharacterSheet, error) {
+func (d *CharacterDB) GetForceCharacterSheets(ctx context.Context, queryParams url.Values) ([]model.ForceCharacterSheet, error) {
 	logrus.Debug("BEGIN - GetForceCharacterSheets")
 
+	ctx, cancel := withTimeout(ctx, d.readTimeout)
+	defer cancel()
+
 	collection := d.client.Database(d.databaseName).Collection(d.collectionName)
 
-	pageNumber, pageCount, sort, filter := api.BuildFilter(queryParams)
+	pageNumber, pageCount, sort, filter, err := api.BuildFilter(queryParams, api.SheetSchema)
+	if err != nil {
+		return nil, translate(err)
+	}
+
+	projection, err := api.ParseProjection(queryParams.Get("fields"), api.SheetSchema)
+	if err != nil {
+		return nil, translate(err)
+	}
+
 	skip := 0
 	if pageNumber > 0 {
 		skip = (pageNumber - 1) * pageCount
@@ -62,83 +96,236 @@ func (d *CharacterDB) GetForceCharacterSheets(queryParams url.Values) ([]model.F
 		SetMaxTime(30 * time.Second).
 		SetSkip(int64(skip)).
 		SetLimit(int64(pageCount)).
-		SetSort(bson.D{{
-			Key:   sort,
-			Value: 1,
-		}})
+		SetSort(sort)
+	if projection != nil {
+		opts.SetProjection(projection)
+	}
 
-	cur, err := collection.Find(context.Background(), filter, opts)
+	cur, err := collection.Find(ctx, filter, opts)
 	if err != nil {
-		return nil, err
+		return nil, translate(err)
 	}
+	defer cur.Close(ctx)
 
 	matches := []model.ForceCharacterSheet{}
 
-	for cur.Next(context.Background()) {
+	for cur.Next(ctx) {
 		elem := model.ForceCharacterSheet{}
 		err := cur.Decode(&elem)
 		if err != nil {
-			return nil, err
+			return nil, translate(err)
 		}
 
 		matches = append(matches, elem)
 	}
 
-	return matches, nil
+	return matches, translate(cur.Err())
+}
+
+//CountForceCharacterSheets returns how many force character sheets match the filters of the query parameters, ignoring pagination
+func (d *CharacterDB) CountForceCharacterSheets(ctx context.Context, queryParams url.Values) (int64, error) {
+	logrus.Debug("BEGIN - CountForceCharacterSheets")
+
+	ctx, cancel := withTimeout(ctx, d.readTimeout)
+	defer cancel()
+
+	collection := d.client.Database(d.databaseName).Collection(d.collectionName)
+
+	_, _, _, filter, err := api.BuildFilter(api.WithoutPagination(queryParams), api.SheetSchema)
+	if err != nil {
+		return 0, translate(err)
+	}
+
+	count, err := collection.CountDocuments(ctx, filter)
+
+	return count, translate(err)
 }
 
-//FindForceCharacterSheetByID finds a specific force character sheet by a provided ID
-func (d *CharacterDB) FindForceCharacterSheetByID(mongoID primitive.ObjectID) (*model.ForceCharacterSheet, error) {
+//FindForceCharacterSheetByID finds a specific force character sheet by a provided ID.
+//Only the fields selected by the projection are read, a nil projection reads the whole sheet
+func (d *CharacterDB) FindForceCharacterSheetByID(ctx context.Context, mongoID primitive.ObjectID, projection api.Projection) (*model.ForceCharacterSheet, error) {
 	logrus.Debugf("BEGIN - FindForceCharacterSheet: %v", mongoID)
 
+	ctx, cancel := withTimeout(ctx, d.readTimeout)
+	defer cancel()
+
 	collection := d.client.Database(d.databaseName).Collection(d.collectionName)
 	query := api.BuildQuery(&mongoID, nil)
 
+	opts := options.FindOne()
+	if projection != nil {
+		opts.SetProjection(projection)
+	}
+
 	sheet := model.ForceCharacterSheet{}
 
-	err := collection.FindOne(context.Background(), query).Decode(&sheet)
+	err := collection.FindOne(ctx, query, opts).Decode(&sheet)
+	if err == mongo.ErrNoDocuments {
+		return nil, dberr.SheetNotFound(mongoID)
+	}
 	if err != nil {
-		return nil, err
+		return nil, translate(err)
 	}
 
-	return &sheet, err
+	return &sheet, nil
 }
 
-//UpdateForceCharacterSheetByID updates a specific force character sheet by provided ID
-func (d *CharacterDB) UpdateForceCharacterSheetByID(sheet model.ForceCharacterSheet, mongoID primitive.ObjectID) error {
+//FindLatestForceCharacterSheet finds the latest sheet with the character and player name, the highest character version and then the most edited.
+//With ignoreCase the names are compared with a case insensitive collation
+func (d *CharacterDB) FindLatestForceCharacterSheet(ctx context.Context, characterName string, playerName string, ignoreCase bool) (*model.ForceCharacterSheet, error) {
+	logrus.Debugf("BEGIN - FindLatestForceCharacterSheet: %v %v", characterName, playerName)
+
+	ctx, cancel := withTimeout(ctx, d.readTimeout)
+	defer cancel()
+
+	collection := d.client.Database(d.databaseName).Collection(d.collectionName)
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"characterName": characterName, "playerName": playerName}}},
+		{{Key: "$sort", Value: bson.D{{Key: "characterVersion", Value: -1}, {Key: "version", Value: -1}, {Key: "_id", Value: -1}}}},
+		{{Key: "$limit", Value: 1}},
+	}
+
+	opts := options.Aggregate()
+	if ignoreCase {
+		opts.SetCollation(&options.Collation{Locale: "en", Strength: 2})
+	}
+
+	cur, err := collection.Aggregate(ctx, pipeline, opts)
+	if err != nil {
+		return nil, translate(err)
+	}
+	defer cur.Close(ctx)
+
+	if !cur.Next(ctx) {
+		if err := cur.Err(); err != nil {
+			return nil, translate(err)
+		}
+		return nil, dberr.LatestNotFound(characterName, playerName)
+	}
+
+	sheet := model.ForceCharacterSheet{}
+	err = cur.Decode(&sheet)
+	if err != nil {
+		return nil, translate(err)
+	}
+
+	return &sheet, nil
+}
+
+//UpdateForceCharacterSheetByID updates a specific force character sheet by provided ID.
+//The update only applies if the stored version matches the version on the sheet, the stored version is then incremented
+func (d *CharacterDB) UpdateForceCharacterSheetByID(ctx context.Context, sheet model.ForceCharacterSheet, mongoID primitive.ObjectID) error {
 	logrus.Debugf("BEGIN - UpdateForceCharacterSheetByID: %v", mongoID)
 
+	ctx, cancel := withTimeout(ctx, d.writeTimeout)
+	defer cancel()
+
+	return d.updateForceCharacterSheet(ctx, sheet, mongoID, "")
+}
+
+// updateForceCharacterSheet applies a versioned update and records a revision with the given summary,
+// if no summary is given one is built from the fields that changed
+func (d *CharacterDB) updateForceCharacterSheet(ctx context.Context, sheet model.ForceCharacterSheet, mongoID primitive.ObjectID, summary string) error {
 	collection := d.client.Database(d.databaseName).Collection(d.collectionName)
 
-	result, err := collection.UpdateOne(context.Background(), bson.M{"_id": mongoID}, bson.D{{
-		Key:   "$set",
-		Value: sheet,
-	}})
+	if api.MissingElementIDs(sheet) {
+		// the update below only applies to this version, so the IDs read here are the ones the update replaces
+		stored := model.ForceCharacterSheet{}
+		err := collection.FindOne(ctx, bson.M{"_id": mongoID, "version": sheet.Version}).Decode(&stored)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return translate(err)
+		}
+		sheet = api.WithElementIDsFrom(sheet, stored)
+	}
+
+	fields, err := sheetFields(sheet)
 	if err != nil {
-		return err
+		return translate(err)
 	}
 
-	matched := strconv.FormatInt(result.MatchedCount, 10)
-	modified := strconv.FormatInt(result.ModifiedCount, 10)
+	previous := model.ForceCharacterSheet{}
+	err = collection.FindOneAndUpdate(ctx, bson.M{"_id": mongoID, "version": sheet.Version}, bson.D{
+		{Key: "$set", Value: fields},
+		{Key: "$inc", Value: bson.M{"version": 1}},
+	}, options.FindOneAndUpdate().SetReturnDocument(options.Before)).Decode(&previous)
+	if err == mongo.ErrNoDocuments {
+		current := model.ForceCharacterSheet{}
+		err = collection.FindOne(ctx, bson.M{"_id": mongoID}).Decode(&current)
+		if err == nil {
+			return dberr.VersionConflict(mongoID, sheet.Version, current.Version)
+		}
+		return dberr.SheetNotFound(mongoID)
+	}
+	if err != nil {
+		return translate(err)
+	}
+
+	sheet.ID = mongoID
+	sheet.Version = previous.Version + 1
+	sheet.CharacterID = previous.CharacterID
+	sheet.CharacterVersion = previous.CharacterVersion
+
+	if summary == "" {
+		changes, err := api.Diff(previous, sheet)
+		if err != nil {
+			return translate(err)
+		}
+		summary = api.ChangeSummary(changes)
+	}
+
+	return d.insertRevision(ctx, sheet, summary)
+}
 
-	if result.MatchedCount != 1 {
-		return errors.New("Could not update sheet. Tried to update " + mongoID.Hex() + " got " + matched + " matches instead of 1")
+// sheetFields returns the fields of a sheet that may be replaced by an update, leaving out the ID, version and lineage
+func sheetFields(sheet model.ForceCharacterSheet) (bson.M, error) {
+	data, err := bson.Marshal(sheet)
+	if err != nil {
+		return nil, translate(err)
 	}
 
-	if result.ModifiedCount != 1 {
-		return errors.New("Could not update sheet. Tried to updated " + mongoID.Hex() + " tried to update " + modified + " number of results instead of 1")
+	fields := bson.M{}
+	err = bson.Unmarshal(data, &fields)
+	if err != nil {
+		return nil, translate(err)
 	}
 
-	return nil
+	delete(fields, "_id")
+	delete(fields, "version")
+	delete(fields, "characterId")
+	delete(fields, "characterVersion")
+
+	return fields, nil
 }
 
-//DeleteForceCharacterSheetByID deletes a specific force character sheet by provided ID
-func (d *CharacterDB) DeleteForceCharacterSheetByID(mongoID primitive.ObjectID) error {
+//DeleteForceCharacterSheetByID moves a specific force character sheet by provided ID into the archive collection
+func (d *CharacterDB) DeleteForceCharacterSheetByID(ctx context.Context, mongoID primitive.ObjectID, deletedBy string) error {
 	logrus.Debugf("BEGIN - DeleteForceCharacterSheetByID: %v", mongoID)
 
+	ctx, cancel := withTimeout(ctx, d.writeTimeout)
+	defer cancel()
+
 	collection := d.client.Database(d.databaseName).Collection(d.collectionName)
+	archive := d.client.Database(d.databaseName).Collection(d.archiveName)
 
-	_, err := collection.DeleteOne(context.Background(), bson.M{"_id": mongoID})
+	return d.atomically(ctx, func(ctx context.Context) error {
+		sheet := model.ForceCharacterSheet{}
+		err := collection.FindOne(ctx, bson.M{"_id": mongoID}).Decode(&sheet)
+		if err == mongo.ErrNoDocuments {
+			return dberr.SheetNotFound(mongoID)
+		}
+		if err != nil {
+			return translate(err)
+		}
+
+		archived := model.ArchivedForceCharacterSheet{
+			ForceCharacterSheet: sheet,
+			DeletedAt:           time.Now().UTC(),
+			DeletedBy:           deletedBy,
+		}
 
-	return err
+		return d.moveSheet(ctx, mongoID, func(ctx context.Context) error {
+			_, err := archive.ReplaceOne(ctx, bson.M{"_id": mongoID}, archived, options.Replace().SetUpsert(true))
+			return err
+		}, collection, archive)
+	})
 }
@@ -0,0 +1,107 @@
+// Package dberr holds the errors returned by the sheet storage backends. Every error is of one of a few kinds,
+// checked with errors.Is against the sentinels below, and carries a stable code and a detail that API responses
+// are built from. The errors of the sheet operations every backend shares are built by the constructors in sheet.go.
+// It is kept apart from package db so that the request parsing in package api can return them too, package api only maps them to statuses and problems
+package dberr
+
+import (
+	"errors"
+	"fmt"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+)
+
+var (
+	// ErrNotFound is the kind of error returned when a sheet, revision or character doesn't exist
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is the kind of error returned when a write clashes with the stored data, a duplicate key or a stale version
+	ErrConflict = errors.New("conflict")
+	// ErrNoOp is the kind of error returned when a write would leave the stored data as it is
+	ErrNoOp = errors.New("no change")
+	// ErrValidation is the kind of error returned when a query, payload or ID is malformed
+	ErrValidation = errors.New("invalid request")
+	// ErrUnavailable is the kind of error returned when the database can't be reached
+	ErrUnavailable = errors.New("unavailable")
+	// ErrNotSupported is the kind of error returned when the storage backend or deployment lacks a feature
+	ErrNotSupported = errors.New("not supported")
+	// ErrPreconditionFailed is the kind of error returned when a conditional request doesn't match the stored sheet
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// ErrPreconditionRequired is the kind of error returned when a write must be conditional but isn't
+	ErrPreconditionRequired = errors.New("precondition required")
+	// ErrRuleViolation is the kind of error returned when a change breaks a rule of the game, such as spending XP a character doesn't have
+	ErrRuleViolation = errors.New("rule violation")
+)
+
+// Error is an error of a kind with a stable code, a detail meant for the client and the violations that caused it
+type Error struct {
+	Kind       error
+	Code       string
+	Detail     string
+	Violations []model.Violation
+	Err        error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Detail + ": " + e.Err.Error()
+	}
+
+	return e.Detail
+}
+
+// Is reports the error as its kind, so that errors.Is(err, ErrNotFound) holds for every not found error
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+// Unwrap returns the error that caused this one, if any
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New returns an error of the kind with the code and a detail formatted from the arguments
+func New(kind error, code string, format string, args ...interface{}) *Error {
+	return &Error{Kind: kind, Code: code, Detail: fmt.Sprintf(format, args...)}
+}
+
+// Wrap returns an error of the kind with the code and detail that was caused by err
+func Wrap(err error, kind error, code string, detail string) *Error {
+	return &Error{Kind: kind, Code: code, Detail: detail, Err: err}
+}
+
+// Invalid returns a validation error with the code and detail that blames a single field
+func Invalid(code string, field string, format string, args ...interface{}) *Error {
+	detail := fmt.Sprintf(format, args...)
+
+	return &Error{Kind: ErrValidation, Code: code, Detail: detail, Violations: []model.Violation{{Field: field, Message: detail}}}
+}
+
+// NotFound returns a not found error with the code and a detail formatted from the arguments
+func NotFound(code string, format string, args ...interface{}) *Error {
+	return New(ErrNotFound, code, format, args...)
+}
+
+// Conflict returns a conflict error with the code and a detail formatted from the arguments
+func Conflict(code string, format string, args ...interface{}) *Error {
+	return New(ErrConflict, code, format, args...)
+}
+
+// Code returns the stable code of err, or an empty string when it isn't one of these errors
+func Code(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+
+	return ""
+}
+
+// Violations returns the field violations carried by err
+func Violations(err error) []model.Violation {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Violations
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package dberr
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestError_Is(t *testing.T) {
+	err := fmt.Errorf("restore: %w", NotFound("sheet_not_found", "sheet %v not found", "abc"))
+	if !errors.Is(err, ErrNotFound) || errors.Is(err, ErrConflict) {
+		t.Errorf("errors.Is() error:\n   expected: only %v\n   got:      %v", ErrNotFound, err)
+	}
+	if Code(err) != "sheet_not_found" || err.Error() != "restore: sheet abc not found" {
+		t.Errorf("Code() error:\n   expected: sheet_not_found\n   got:      %v %v", Code(err), err)
+	}
+
+	cause := errors.New("connection refused")
+	wrapped := Wrap(cause, ErrUnavailable, "unavailable", "the database is unavailable")
+	if !errors.Is(wrapped, cause) || !errors.Is(wrapped, ErrUnavailable) || wrapped.Error() != "the database is unavailable: connection refused" {
+		t.Errorf("Wrap() error:\n   expected: unavailable caused by %v\n   got:      %v", cause, wrapped)
+	}
+
+	if Code(cause) != "" || Violations(cause) != nil {
+		t.Errorf("Code() error:\n   expected: no code or violations\n   got:      %q %v", Code(cause), Violations(cause))
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	err := Invalid("invalid_query", "pageCount", "pageCount %q is not a whole number", "ten")
+	violations := Violations(err)
+	if !errors.Is(err, ErrValidation) || len(violations) != 1 || violations[0].Field != "pageCount" || violations[0].Message != err.Detail {
+		t.Errorf("Invalid() error:\n   expected: a violation of pageCount\n   got:      %v %v", err, violations)
+	}
+}
+
+func TestSheetErrors(t *testing.T) {
+	id := primitive.NewObjectID()
+	tests := []struct {
+		err  error
+		kind error
+		code string
+	}{
+		{SheetNotFound(id), ErrNotFound, "sheet_not_found"},
+		{ArchivedSheetNotFound(id), ErrNotFound, "archived_sheet_not_found"},
+		{RevisionNotFound(id, 2), ErrNotFound, "revision_not_found"},
+		{LatestNotFound("Kanan", "Ben"), ErrNotFound, "sheet_not_found"},
+		{CharacterNotFound(id), ErrNotFound, "character_not_found"},
+		{CharacterVersionNotFound(id, 2), ErrNotFound, "character_version_not_found"},
+		{ElementNotFound(id, "skills", id), ErrNotFound, "element_not_found"},
+		{DuplicateSheet(id), ErrConflict, "duplicate_key"},
+		{VersionConflict(id, 1, 2), ErrConflict, "version_conflict"},
+		{NoChange(id, 2), ErrNoOp, "no_change"},
+		{PreconditionFailed(id, `"1"`), ErrPreconditionFailed, "precondition_failed"},
+		{PreconditionRequired(), ErrPreconditionRequired, "precondition_required"},
+	}
+
+	for _, test := range tests {
+		if !errors.Is(test.err, test.kind) || Code(test.err) != test.code {
+			t.Errorf("sheet error %v:\n   expected: %v %v\n   got:      %v", test.err, test.kind, test.code, Code(test.err))
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package dberr
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// SheetNotFound returns the error used when no live sheet has the ID
+func SheetNotFound(ID primitive.ObjectID) error {
+	return NotFound("sheet_not_found", "sheet %v not found", ID.Hex())
+}
+
+// ArchivedSheetNotFound returns the error used when no archived sheet has the ID
+func ArchivedSheetNotFound(ID primitive.ObjectID) error {
+	return NotFound("archived_sheet_not_found", "archived sheet %v not found", ID.Hex())
+}
+
+// RevisionNotFound returns the error used when a sheet has no revision with the version
+func RevisionNotFound(ID primitive.ObjectID, version int64) error {
+	return NotFound("revision_not_found", "revision %v of sheet %v not found", version, ID.Hex())
+}
+
+// LatestNotFound returns the error used when no sheet has the character and player name
+func LatestNotFound(characterName string, playerName string) error {
+	return NotFound("sheet_not_found", "sheet of character %q played by %q not found", characterName, playerName)
+}
+
+// CharacterNotFound returns the error used when no sheet belongs to the character
+func CharacterNotFound(characterID primitive.ObjectID) error {
+	return NotFound("character_not_found", "character %v not found", characterID.Hex())
+}
+
+// CharacterVersionNotFound returns the error used when the character has no sheet with the version
+func CharacterVersionNotFound(characterID primitive.ObjectID, characterVersion int64) error {
+	return NotFound("character_version_not_found", "version %v of character %v not found", characterVersion, characterID.Hex())
+}
+
+// ElementNotFound returns the error used when an array of a sheet has no element with the ID
+func ElementNotFound(ID primitive.ObjectID, array string, elementID primitive.ObjectID) error {
+	return NotFound("element_not_found", "%v %v not found on sheet %v", array, elementID.Hex(), ID.Hex())
+}
+
+// DuplicateSheet returns the error used when a sheet is inserted under an ID that is already taken
+func DuplicateSheet(ID primitive.ObjectID) error {
+	return Conflict("duplicate_key", "sheet %v already exists", ID.Hex())
+}
+
+// VersionConflict returns the error used when an update was made against a stale version of a sheet
+func VersionConflict(ID primitive.ObjectID, expected int64, current int64) error {
+	return Conflict("version_conflict", "Could not update sheet. version conflict on %v: expected version %v but found %v", ID.Hex(), expected, current)
+}
+
+// NoChange returns the error used when a revert would leave a sheet as it is
+func NoChange(ID primitive.ObjectID, version int64) error {
+	return New(ErrNoOp, "no_change", "sheet %v is already at version %v", ID.Hex(), version)
+}
+
+// PreconditionFailed returns the error used when the If-Match header of a request doesn't match the stored sheet
+func PreconditionFailed(ID primitive.ObjectID, ifMatch string) error {
+	return New(ErrPreconditionFailed, "precondition_failed", "If-Match %v does not match the current ETag of sheet %v", ifMatch, ID.Hex())
+}
+
+// PreconditionRequired returns the error used when a write has no If-Match header but one is required
+func PreconditionRequired() error {
+	return New(ErrPreconditionRequired, "precondition_required", "an If-Match header with the ETag of the sheet is required")
+}
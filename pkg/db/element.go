@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/api"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
+	"github.com/sirupsen/logrus"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//InsertSheetElement appends an element to an array of a sheet with $push, so concurrent additions to the same sheet all land.
+//The element must already have its ID, an expected version other than 0 is matched in the same update. The sheet is returned as it is after the change
+func (d *CharacterDB) InsertSheetElement(ctx context.Context, mongoID primitive.ObjectID, array api.SheetArray, element interface{}, expected int64) (*model.ForceCharacterSheet, error) {
+	logrus.Debugf("BEGIN - InsertSheetElement: %v %v", mongoID, array.Name)
+
+	ctx, cancel := withTimeout(ctx, d.writeTimeout)
+	defer cancel()
+
+	collection := d.client.Database(d.databaseName).Collection(d.collectionName)
+
+	// nil slices are stored as null and $push fails on anything but an array, so such an array is made empty first
+	_, err := collection.UpdateOne(ctx, withVersion(bson.M{"_id": mongoID, array.Path: bson.M{"$not": bson.M{"$type": "array"}}}, expected), bson.M{
+		"$set": bson.M{array.Path: bson.A{}},
+	})
+	if err != nil {
+		return nil, translate(err)
+	}
+
+	sheet, err := d.updateAtomically(ctx, mongoID, withVersion(bson.M{"_id": mongoID}, expected), bson.D{
+		{Key: "$push", Value: bson.M{array.Path: element}},
+		{Key: "$inc", Value: bson.M{"version": 1}},
+	}, "added to "+array.Name)
+
+	return sheet, d.unmatchedError(ctx, err, mongoID, expected, nil)
+}
+
+//UpdateSheetElement replaces the element of an array of a sheet that has the ID with a positional $set, leaving the rest of the sheet as it is.
+//An expected version other than 0 is matched in the same update
+func (d *CharacterDB) UpdateSheetElement(ctx context.Context, mongoID primitive.ObjectID, array api.SheetArray, elementID primitive.ObjectID, element interface{}, expected int64) (*model.ForceCharacterSheet, error) {
+	logrus.Debugf("BEGIN - UpdateSheetElement: %v %v %v", mongoID, array.Name, elementID)
+
+	ctx, cancel := withTimeout(ctx, d.writeTimeout)
+	defer cancel()
+
+	sheet, err := d.updateAtomically(ctx, mongoID, withVersion(bson.M{"_id": mongoID, array.Path + ".id": elementID}, expected), bson.D{
+		{Key: "$set", Value: bson.M{array.Path + ".$": element}},
+		{Key: "$inc", Value: bson.M{"version": 1}},
+	}, "updated "+array.Name)
+
+	return sheet, d.unmatchedError(ctx, err, mongoID, expected, dberr.ElementNotFound(mongoID, array.Name, elementID))
+}
+
+//DeleteSheetElement removes the element of an array of a sheet that has the ID with $pull, an expected version other than 0 is matched in the same update
+func (d *CharacterDB) DeleteSheetElement(ctx context.Context, mongoID primitive.ObjectID, array api.SheetArray, elementID primitive.ObjectID, expected int64) (*model.ForceCharacterSheet, error) {
+	logrus.Debugf("BEGIN - DeleteSheetElement: %v %v %v", mongoID, array.Name, elementID)
+
+	ctx, cancel := withTimeout(ctx, d.writeTimeout)
+	defer cancel()
+
+	sheet, err := d.updateAtomically(ctx, mongoID, withVersion(bson.M{"_id": mongoID, array.Path + ".id": elementID}, expected), bson.D{
+		{Key: "$pull", Value: bson.M{array.Path: bson.M{"id": elementID}}},
+		{Key: "$inc", Value: bson.M{"version": 1}},
+	}, "removed from "+array.Name)
+
+	return sheet, d.unmatchedError(ctx, err, mongoID, expected, dberr.ElementNotFound(mongoID, array.Name, elementID))
+}
+
+// withVersion adds the version a sheet is expected to be at to the filter of an update, an expected version of 0 matches any
+func withVersion(filter bson.M, expected int64) bson.M {
+	if expected != 0 {
+		filter["version"] = expected
+	}
+
+	return filter
+}
+
+// updateAtomically applies a single update to a sheet, which must bump its version, and records a revision of the sheet it leaves
+func (d *CharacterDB) updateAtomically(ctx context.Context, mongoID primitive.ObjectID, filter bson.M, update interface{}, summary string) (*model.ForceCharacterSheet, error) {
+	collection := d.client.Database(d.databaseName).Collection(d.collectionName)
+
+	sheet := model.ForceCharacterSheet{}
+	err := collection.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&sheet)
+	if err == mongo.ErrNoDocuments {
+		return nil, dberr.SheetNotFound(mongoID)
+	}
+	if err != nil {
+		return nil, translate(err)
+	}
+
+	err = d.insertRevision(ctx, sheet, summary)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sheet, nil
+}
+
+// unmatchedError tells why an update of a sheet matched nothing: the sheet is missing, it isn't at the expected version or,
+// when missing isn't nil, it has no part the update needed
+func (d *CharacterDB) unmatchedError(ctx context.Context, err error, mongoID primitive.ObjectID, expected int64, missing error) error {
+	if dberr.Code(err) != "sheet_not_found" {
+		return err
+	}
+
+	collection := d.client.Database(d.databaseName).Collection(d.collectionName)
+	current := model.ForceCharacterSheet{}
+	findErr := collection.FindOne(ctx, bson.M{"_id": mongoID}, options.FindOne().SetProjection(bson.M{"version": 1})).Decode(&current)
+	switch {
+	case findErr != nil:
+		return err
+	case expected != 0 && current.Version != expected:
+		return dberr.VersionConflict(mongoID, expected, current.Version)
+	case missing != nil:
+		return missing
+	}
+
+	return err
+}
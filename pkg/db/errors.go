@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/topology"
+)
+
+// mongo server error codes that translate picks out
+const (
+	codeMaxTimeExpired     = 50
+	codeValidationFailed   = 121
+	codeDuplicateKey       = 11000
+	codeDuplicateKeyLegacy = 11001
+	codeObjectTooLarge     = 10334
+)
+
+// translate turns the errors of the mongo driver into the errors of package dberr so that callers can tell them apart
+// with errors.Is, errors that are already typed or that come from the context are returned as they are
+func translate(err error) error {
+	var typed *dberr.Error
+	if err == nil || errors.As(err, &typed) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return dberr.Wrap(err, dberr.ErrNotFound, "not_found", "no matching document")
+	}
+
+	if translated := translateCode(err, serverCode(err)); translated != nil {
+		return translated
+	}
+
+	if unreachable(err) {
+		return dberr.Wrap(err, dberr.ErrUnavailable, "unavailable", "the database is unavailable")
+	}
+
+	return err
+}
+
+// serverCode returns the code of the first server error in err, or 0 when there is none
+func serverCode(err error) int {
+	var commandErr mongo.CommandError
+	if errors.As(err, &commandErr) {
+		return int(commandErr.Code)
+	}
+
+	var writeErr mongo.WriteException
+	if errors.As(err, &writeErr) && len(writeErr.WriteErrors) > 0 {
+		return writeErr.WriteErrors[0].Code
+	}
+
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) && len(bulkErr.WriteErrors) > 0 {
+		return bulkErr.WriteErrors[0].Code
+	}
+
+	return 0
+}
+
+// translateCode returns the dberr error for a mongo server error code, or nil when the code has none
+func translateCode(err error, code int) error {
+	switch code {
+	case codeMaxTimeExpired:
+		return dberr.Wrap(err, context.DeadlineExceeded, "timeout", "the database operation timed out")
+	case codeValidationFailed:
+		return dberr.Wrap(err, dberr.ErrValidation, "schema_violation", "the sheet does not match the collection schema")
+	case codeDuplicateKey, codeDuplicateKeyLegacy:
+		return dberr.Wrap(err, dberr.ErrConflict, "duplicate_key", "a sheet with the same key already exists")
+	case codeObjectTooLarge:
+		return dberr.Wrap(err, dberr.ErrValidation, "sheet_too_large", "the sheet is larger than a document may be")
+	}
+
+	return nil
+}
+
+// unreachable reports whether err comes from failing to reach the database rather than from the operation itself
+func unreachable(err error) bool {
+	var connectionErr topology.ConnectionError
+	var commandErr mongo.CommandError
+	if errors.Is(err, mongo.ErrClientDisconnected) || errors.As(err, &connectionErr) ||
+		(errors.As(err, &commandErr) && commandErr.HasErrorLabel("NetworkError")) {
+		return true
+	}
+
+	// the driver formats server selection failures into a plain error, so they can only be recognised by their message
+	return strings.HasPrefix(err.Error(), "server selection error")
+}
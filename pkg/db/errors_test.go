@@ -0,0 +1,52 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestTranslate(t *testing.T) {
+	duplicate := mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000, Message: "E11000 duplicate key error"}}}
+	tests := []struct {
+		name string
+		err  error
+		kind error
+		code string
+	}{
+		{name: "no documents", err: mongo.ErrNoDocuments, kind: dberr.ErrNotFound, code: "not_found"},
+		{name: "duplicate key", err: duplicate, kind: dberr.ErrConflict, code: "duplicate_key"},
+		{name: "bulk duplicate key", err: mongo.BulkWriteException{WriteErrors: []mongo.BulkWriteError{{WriteError: mongo.WriteError{Code: 11001}}}}, kind: dberr.ErrConflict, code: "duplicate_key"},
+		{name: "schema validation", err: mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 121}}}, kind: dberr.ErrValidation, code: "schema_violation"},
+		{name: "too large", err: mongo.CommandError{Code: 10334}, kind: dberr.ErrValidation, code: "sheet_too_large"},
+		{name: "max time", err: mongo.CommandError{Code: 50, Name: "MaxTimeMSExpired"}, kind: context.DeadlineExceeded, code: "timeout"},
+		{name: "network", err: mongo.CommandError{Labels: []string{"NetworkError"}}, kind: dberr.ErrUnavailable, code: "unavailable"},
+		{name: "disconnected", err: mongo.ErrClientDisconnected, kind: dberr.ErrUnavailable, code: "unavailable"},
+		{name: "server selection", err: errors.New("server selection error: server selection timeout"), kind: dberr.ErrUnavailable, code: "unavailable"},
+		{name: "wrapped", err: fmt.Errorf("decode: %w", mongo.ErrNoDocuments), kind: dberr.ErrNotFound, code: "not_found"},
+		{name: "typed", err: dberr.NotFound("sheet_not_found", "sheet not found"), kind: dberr.ErrNotFound, code: "sheet_not_found"},
+		{name: "deadline", err: context.DeadlineExceeded, kind: context.DeadlineExceeded, code: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := translate(test.err)
+			if !errors.Is(err, test.kind) || dberr.Code(err) != test.code {
+				t.Errorf("translate() error:\n   expected: %v %v\n   got:      %v %v", test.kind, test.code, err, dberr.Code(err))
+			}
+		})
+	}
+
+	if err := translate(nil); err != nil {
+		t.Errorf("translate() error:\n   expected: <nil>\n   got:      %v", err)
+	}
+
+	other := errors.New("something else")
+	if err := translate(other); err != other {
+		t.Errorf("translate() error:\n   expected: %v\n   got:      %v", other, err)
+	}
+}
@@ -0,0 +1,10 @@
+package db
+
+import (
+	"github.com/geeksheik9/sheet-CRUD/pkg/events"
+)
+
+//Events returns the feed of changes to the sheet collection, read from its change stream so that it includes the writes of every instance
+func (d *CharacterDB) Events() events.Source {
+	return events.NewChangeStream(d.client.Database(d.databaseName).Collection(d.collectionName))
+}
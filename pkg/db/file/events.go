@@ -0,0 +1,81 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/geeksheik9/sheet-CRUD/pkg/events"
+	"github.com/sirupsen/logrus"
+)
+
+// eventsFile is the journal of the recent sheet events, one JSON event per line, kept beside the collection directories
+const eventsFile = "events.log"
+
+//LoadEvents reads the journaled events so that the event feed carries on from them after a restart.
+//A line left incomplete by an interrupted write is skipped
+func (s *Store) LoadEvents() ([]events.Event, error) {
+	file, err := os.Open(filepath.Join(s.directory, eventsFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var journaled []events.Event
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		event := events.Event{}
+		err = json.Unmarshal(scanner.Bytes(), &event)
+		if err != nil {
+			logrus.Warnf("Skipping an unreadable line of %v: %v", eventsFile, err)
+			continue
+		}
+		journaled = append(journaled, event)
+	}
+
+	return journaled, scanner.Err()
+}
+
+//AppendEvent adds the event to the journal and flushes it to disk before returning
+func (s *Store) AppendEvent(event events.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(filepath.Join(s.directory, eventsFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(append(data, '\n'))
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+//CompactEvents rewrites the journal with only the events still kept for resuming
+func (s *Store) CompactEvents(history []events.Event) error {
+	var buffer bytes.Buffer
+	for _, event := range history {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		buffer.Write(data)
+		buffer.WriteByte('\n')
+	}
+
+	return writeFileAtomic(filepath.Join(s.directory, eventsFile), buffer.Bytes())
+}
@@ -0,0 +1,176 @@
+// Package file stores character sheets as a directory of JSON documents on local disk.
+// It backs the in-memory database so that a single table can run without mongo and keep its sheets between sessions
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	documentExtension = ".json"
+	tempPrefix        = ".tmp-"
+)
+
+//Store keeps one extended JSON document per file in a sub directory per collection
+type Store struct {
+	directory string
+}
+
+//NewStore returns a store rooted at the directory, creating it if it does not exist
+func NewStore(directory string) (*Store, error) {
+	err := os.MkdirAll(directory, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Store{directory: directory}, nil
+}
+
+//Load reads every document in the store. Temporary files left behind by an interrupted write are removed
+func (s *Store) Load() (map[string]map[primitive.ObjectID]bson.M, error) {
+	collections := map[string]map[primitive.ObjectID]bson.M{}
+
+	dirs, err := ioutil.ReadDir(s.directory)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+
+		docs, err := s.loadCollection(dir.Name())
+		if err != nil {
+			return nil, err
+		}
+		collections[dir.Name()] = docs
+	}
+
+	return collections, nil
+}
+
+func (s *Store) loadCollection(collection string) (map[primitive.ObjectID]bson.M, error) {
+	docs := map[primitive.ObjectID]bson.M{}
+	directory := filepath.Join(s.directory, collection)
+
+	files, err := ioutil.ReadDir(directory)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		path := filepath.Join(directory, file.Name())
+
+		if strings.HasPrefix(file.Name(), tempPrefix) {
+			logrus.Warnf("Removing incomplete write %v", path)
+			_ = os.Remove(path)
+			continue
+		}
+
+		if file.IsDir() || filepath.Ext(file.Name()) != documentExtension {
+			continue
+		}
+
+		mongoID, err := primitive.ObjectIDFromHex(strings.TrimSuffix(file.Name(), documentExtension))
+		if err != nil {
+			logrus.Warnf("Skipping %v, the file name is not a document ID", path)
+			continue
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		doc := bson.M{}
+		err = bson.UnmarshalExtJSON(data, false, &doc)
+		if err != nil {
+			return nil, err
+		}
+
+		docs[mongoID] = doc
+	}
+
+	return docs, nil
+}
+
+//Save writes the document so that the file on disk always holds either the old or the new document in full
+func (s *Store) Save(collection string, mongoID primitive.ObjectID, doc bson.M) error {
+	data, err := bson.MarshalExtJSON(doc, false, false)
+	if err != nil {
+		return err
+	}
+
+	directory := filepath.Join(s.directory, collection)
+	err = os.MkdirAll(directory, 0755)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(filepath.Join(directory, mongoID.Hex()+documentExtension), data)
+}
+
+//Delete removes the document, deleting a document that does not exist is not an error
+func (s *Store) Delete(collection string, mongoID primitive.ObjectID) error {
+	directory := filepath.Join(s.directory, collection)
+
+	err := os.Remove(filepath.Join(directory, mongoID.Hex()+documentExtension))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return syncDirectory(directory)
+}
+
+// writeFileAtomic writes to a temporary file that is flushed to disk and then renamed over the destination
+func writeFileAtomic(path string, data []byte) error {
+	directory := filepath.Dir(path)
+
+	tmp, err := ioutil.TempFile(directory, tempPrefix)
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	err = os.Rename(tmp.Name(), path)
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	return syncDirectory(directory)
+}
+
+// syncDirectory flushes the directory entry so that a rename or remove survives a crash
+func syncDirectory(directory string) error {
+	dir, err := os.Open(directory)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	return dir.Sync()
+}
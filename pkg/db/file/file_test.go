@@ -0,0 +1,158 @@
+package file
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/memory"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func tempDirectory(t *testing.T) string {
+	directory, err := ioutil.TempDir("", "sheet-crud-file")
+	if err != nil {
+		t.Fatalf("TempDir() error:\n   expected: <nil>\n   got:      %v", err)
+	}
+
+	return directory
+}
+
+func TestStore_SaveLoadDelete(t *testing.T) {
+	directory := tempDirectory(t)
+	defer os.RemoveAll(directory)
+
+	store, err := NewStore(directory)
+	if err != nil {
+		t.Fatalf("NewStore() error:\n   expected: <nil>\n   got:      %v", err)
+	}
+
+	mongoID := primitive.NewObjectID()
+	err = store.Save("sheets", mongoID, bson.M{"_id": mongoID, "characterName": "Mando", "availableXP": int64(25)})
+	if err != nil {
+		t.Errorf("Save() error:\n   expected: <nil>\n   got:      %v", err)
+	}
+
+	_ = ioutil.WriteFile(filepath.Join(directory, "sheets", tempPrefix+"123"), []byte("{partial"), 0644)
+
+	collections, err := store.Load()
+	if err != nil {
+		t.Errorf("Load() error:\n   expected: <nil>\n   got:      %v", err)
+	}
+	doc := collections["sheets"][mongoID]
+	if doc["characterName"] != "Mando" || doc["_id"] != mongoID {
+		t.Errorf("Load() error:\n   expected: Mando %v\n   got:      %v", mongoID, doc)
+	}
+	if _, err := os.Stat(filepath.Join(directory, "sheets", tempPrefix+"123")); !os.IsNotExist(err) {
+		t.Errorf("Load() error:\n   expected: incomplete write removed\n   got:      %v", err)
+	}
+
+	err = store.Delete("sheets", mongoID)
+	if err != nil {
+		t.Errorf("Delete() error:\n   expected: <nil>\n   got:      %v", err)
+	}
+	err = store.Delete("sheets", mongoID)
+	if err != nil {
+		t.Errorf("Delete() missing document error:\n   expected: <nil>\n   got:      %v", err)
+	}
+
+	collections, _ = store.Load()
+	if len(collections["sheets"]) != 0 {
+		t.Errorf("Load() after delete error:\n   expected: no documents\n   got:      %v", collections["sheets"])
+	}
+}
+
+func TestStore_PersistsBetweenRestarts(t *testing.T) {
+	ctx := context.Background()
+	directory := tempDirectory(t)
+	defer os.RemoveAll(directory)
+
+	store, _ := NewStore(directory)
+	database, err := memory.NewWithStore(store)
+	if err != nil {
+		t.Fatalf("NewWithStore() error:\n   expected: <nil>\n   got:      %v", err)
+	}
+
+	sheet := model.ForceCharacterSheet{
+		ID:            primitive.NewObjectID(),
+		CharacterName: "Mando",
+		PlayerName:    "Ben",
+		Version:       1,
+	}
+	_ = database.InsertForceCharacterSheet(ctx, sheet)
+	sheet.AvailableXP = 15
+	_ = database.UpdateForceCharacterSheetByID(ctx, sheet, sheet.ID)
+
+	other := sheet
+	other.ID = primitive.NewObjectID()
+	_ = database.InsertForceCharacterSheet(ctx, other)
+	_ = database.DeleteForceCharacterSheetByID(ctx, other.ID, "gm")
+
+	restarted, err := memory.NewWithStore(store)
+	if err != nil {
+		t.Fatalf("NewWithStore() restart error:\n   expected: <nil>\n   got:      %v", err)
+	}
+
+	found, err := restarted.FindForceCharacterSheetByID(ctx, sheet.ID, nil)
+	if err != nil || found.AvailableXP != 15 || found.Version != 2 {
+		t.Errorf("FindForceCharacterSheetByID() after restart error:\n   expected: 15 XP at version 2\n   got:      %v %v", found, err)
+	}
+
+	archived, err := restarted.FindArchivedForceCharacterSheetByID(ctx, other.ID)
+	if err != nil || archived.DeletedBy != "gm" {
+		t.Errorf("FindArchivedForceCharacterSheetByID() after restart error:\n   expected: deleted by gm\n   got:      %v %v", archived, err)
+	}
+
+	revisions, _ := restarted.GetForceCharacterSheetRevisions(ctx, sheet.ID)
+	if len(revisions) != 2 {
+		t.Errorf("GetForceCharacterSheetRevisions() after restart error:\n   expected: 2 revisions\n   got:      %v", revisions)
+	}
+}
+
+func TestStore_EventsResumeAfterRestart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	directory := tempDirectory(t)
+	defer os.RemoveAll(directory)
+
+	store, _ := NewStore(directory)
+	database, _ := memory.NewWithStore(store)
+
+	sheet := model.ForceCharacterSheet{ID: primitive.NewObjectID(), CharacterName: "Mando", PlayerName: "Ben", Version: 1}
+	_ = database.InsertForceCharacterSheet(ctx, sheet)
+
+	// the token of the update to version 2, issued before the restart
+	subscription, _ := database.Events().Subscribe(ctx, "")
+	sheet.AvailableXP = 15
+	_ = database.UpdateForceCharacterSheetByID(ctx, sheet, sheet.ID)
+	token := (<-subscription.Events()).ID
+
+	reopened, _ := NewStore(directory)
+	restarted, err := memory.NewWithStore(reopened)
+	if err != nil {
+		t.Fatalf("NewWithStore() restart error:\n   expected: <nil>\n   got:      %v", err)
+	}
+
+	sheet.Version = 2
+	sheet.AvailableXP = 20
+	_ = restarted.UpdateForceCharacterSheetByID(ctx, sheet, sheet.ID)
+
+	resumed, err := restarted.Events().Subscribe(ctx, token)
+	if err != nil {
+		t.Fatalf("Subscribe() after restart error:\n   expected: <nil>\n   got:      %v", err)
+	}
+
+	select {
+	case event := <-resumed.Events():
+		if event.SheetID != sheet.ID || event.Version != 3 || event.Sheet == nil || event.Sheet.AvailableXP != 20 {
+			t.Errorf("Subscribe() after restart error:\n   expected: the update to version 3 made after the restart\n   got:      %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Subscribe() after restart error:\n   expected: the update made after the restart\n   got:      no event")
+	}
+}
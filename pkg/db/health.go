@@ -0,0 +1,68 @@
+package db
+
+import (
+	"context"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/api"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
+	"github.com/sirupsen/logrus"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//ApplyHealthAction changes the wounds or strain of a sheet with a single update, so concurrent hits on the same sheet are all counted.
+//An action that changes nothing, such as damage the soak value stops, writes no new version. An expected version other than 0 is matched in the same update
+//and fails with a version conflict when the sheet is at another. The sheet is returned as it is after the action
+func (d *CharacterDB) ApplyHealthAction(ctx context.Context, mongoID primitive.ObjectID, action api.HealthAction, amount int64, expected int64) (*model.ForceCharacterSheet, error) {
+	logrus.Debugf("BEGIN - ApplyHealthAction: %v %v %v", mongoID, action.Name, amount)
+
+	if amount > 0 {
+		writeCtx, cancel := withTimeout(ctx, d.writeTimeout)
+		defer cancel()
+
+		filter, update := HealthUpdate(mongoID, action, amount, expected)
+		sheet, err := d.updateAtomically(writeCtx, mongoID, filter, update, action.Summary(amount))
+		if dberr.Code(err) != "sheet_not_found" {
+			return sheet, err
+		}
+	}
+
+	// nothing matched, either the sheet is missing, it isn't at the expected version or the action leaves it as it is
+	sheet, err := d.FindForceCharacterSheetByID(ctx, mongoID, nil)
+	if err == nil && expected != 0 && sheet.Version != expected {
+		return nil, dberr.VersionConflict(mongoID, expected, sheet.Version)
+	}
+
+	return sheet, err
+}
+
+// HealthUpdate returns the filter and update applying an action of a positive amount to a sheet. The filter only matches a sheet the action changes
+// and, when the expected version isn't 0, that is at that version.
+// Damage and recovery depend on the soak value or current value of the sheet, so they are update pipelines that read both in the same write
+func HealthUpdate(mongoID primitive.ObjectID, action api.HealthAction, amount int64, expected int64) (bson.M, interface{}) {
+	counter := "$" + action.Path
+	version := bson.M{"$add": bson.A{"$version", 1}}
+
+	switch {
+	case action.Soaked:
+		return withVersion(bson.M{"_id": mongoID, "$expr": bson.M{"$gt": bson.A{amount, "$soakValue"}}}, expected), bson.A{
+			bson.M{"$set": bson.M{
+				action.Path: bson.M{"$add": bson.A{counter, bson.M{"$subtract": bson.A{amount, "$soakValue"}}}},
+				"version":   version,
+			}},
+		}
+	case action.Recovers:
+		return withVersion(bson.M{"_id": mongoID, action.Path: bson.M{"$gt": 0}}, expected), bson.A{
+			bson.M{"$set": bson.M{
+				action.Path: bson.M{"$max": bson.A{0, bson.M{"$subtract": bson.A{counter, amount}}}},
+				"version":   version,
+			}},
+		}
+	default:
+		return withVersion(bson.M{"_id": mongoID}, expected), bson.D{
+			{Key: "$inc", Value: bson.M{action.Path: amount, "version": 1}},
+		}
+	}
+}
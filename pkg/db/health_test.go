@@ -0,0 +1,65 @@
+package db
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/geeksheik9/sheet-CRUD/pkg/api"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestHealthUpdate(t *testing.T) {
+	id, _ := primitive.ObjectIDFromHex("5e5d82a1802cc20001cb9b9c")
+
+	tests := []struct {
+		action   api.HealthAction
+		expected int64
+		filter   string
+		update   string
+	}{
+		{
+			api.TakeDamage,
+			0,
+			`{"$expr":{"$gt":[5,"$soakValue"]},"_id":{"$oid":"5e5d82a1802cc20001cb9b9c"}}`,
+			`{"u":[{"$set":{"version":{"$add":["$version",1]},"wounds.current":{"$add":["$wounds.current",{"$subtract":[5,"$soakValue"]}]}}}]}`,
+		},
+		{
+			api.SufferStrain,
+			0,
+			`{"_id":{"$oid":"5e5d82a1802cc20001cb9b9c"}}`,
+			`{"u":{"$inc":{"strain.current":5,"version":1}}}`,
+		},
+		{
+			api.HealWounds,
+			0,
+			`{"_id":{"$oid":"5e5d82a1802cc20001cb9b9c"},"wounds.current":{"$gt":0}}`,
+			`{"u":[{"$set":{"version":{"$add":["$version",1]},"wounds.current":{"$max":[0,{"$subtract":["$wounds.current",5]}]}}}]}`,
+		},
+		{
+			api.RecoverStrain,
+			7,
+			`{"_id":{"$oid":"5e5d82a1802cc20001cb9b9c"},"strain.current":{"$gt":0},"version":7}`,
+			`{"u":[{"$set":{"version":{"$add":["$version",1]},"strain.current":{"$max":[0,{"$subtract":["$strain.current",5]}]}}}]}`,
+		},
+	}
+
+	for _, test := range tests {
+		filter, update := HealthUpdate(id, test.action, 5, test.expected)
+
+		filterJSON, _ := bson.MarshalExtJSON(filter, false, false)
+		updateJSON, _ := bson.MarshalExtJSON(bson.D{{Key: "u", Value: update}}, false, false)
+		if !sameJSON(filterJSON, test.filter) || !sameJSON(updateJSON, test.update) {
+			t.Errorf("HealthUpdate() %v error:\n   expected: %v %v\n   got:      %v %v", test.action.Name, test.filter, test.update, string(filterJSON), string(updateJSON))
+		}
+	}
+}
+
+func sameJSON(got []byte, expected string) bool {
+	var gotValue, expectedValue interface{}
+	_ = json.Unmarshal(got, &gotValue)
+	_ = json.Unmarshal([]byte(expected), &expectedValue)
+
+	return reflect.DeepEqual(gotValue, expectedValue)
+}
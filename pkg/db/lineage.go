@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/api"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//GetForceCharacterSheetVersions returns every live sheet of a character ordered by character version
+func (d *CharacterDB) GetForceCharacterSheetVersions(ctx context.Context, characterID primitive.ObjectID) ([]model.ForceCharacterSheet, error) {
+	logrus.Debugf("BEGIN - GetForceCharacterSheetVersions: %v", characterID)
+
+	ctx, cancel := withTimeout(ctx, d.readTimeout)
+	defer cancel()
+
+	collection := d.client.Database(d.databaseName).Collection(d.collectionName)
+
+	opts := options.Find().SetSort(bson.D{{Key: "characterVersion", Value: 1}, {Key: "_id", Value: 1}})
+	cur, err := collection.Find(ctx, bson.M{"characterId": characterID}, opts)
+	if err != nil {
+		return nil, translate(err)
+	}
+	defer cur.Close(ctx)
+
+	sheets := []model.ForceCharacterSheet{}
+	for cur.Next(ctx) {
+		sheet := model.ForceCharacterSheet{}
+		err := cur.Decode(&sheet)
+		if err != nil {
+			return nil, translate(err)
+		}
+
+		sheets = append(sheets, sheet)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, translate(err)
+	}
+
+	if len(sheets) == 0 {
+		return nil, dberr.CharacterNotFound(characterID)
+	}
+
+	return sheets, nil
+}
+
+//FindForceCharacterSheetVersion finds the sheet of a character with the given character version
+func (d *CharacterDB) FindForceCharacterSheetVersion(ctx context.Context, characterID primitive.ObjectID, characterVersion int64) (*model.ForceCharacterSheet, error) {
+	logrus.Debugf("BEGIN - FindForceCharacterSheetVersion: %v %v", characterID, characterVersion)
+
+	ctx, cancel := withTimeout(ctx, d.readTimeout)
+	defer cancel()
+
+	collection := d.client.Database(d.databaseName).Collection(d.collectionName)
+
+	sheet := model.ForceCharacterSheet{}
+	err := collection.FindOne(ctx, bson.M{"characterId": characterID, "characterVersion": characterVersion}).Decode(&sheet)
+	if err == mongo.ErrNoDocuments {
+		return nil, dberr.CharacterVersionNotFound(characterID, characterVersion)
+	}
+	if err != nil {
+		return nil, translate(err)
+	}
+
+	return &sheet, nil
+}
+
+//LevelUpForceCharacterSheet forks the next version of a character from its latest sheet and returns it, the earlier sheets are kept as they are.
+//Two level ups of the same character at once are told apart by the unique lineage index, without it both may get the same version
+func (d *CharacterDB) LevelUpForceCharacterSheet(ctx context.Context, characterID primitive.ObjectID) (*model.ForceCharacterSheet, error) {
+	logrus.Debugf("BEGIN - LevelUpForceCharacterSheet: %v", characterID)
+
+	ctx, cancel := withTimeout(ctx, d.writeTimeout)
+	defer cancel()
+
+	collection := d.client.Database(d.databaseName).Collection(d.collectionName)
+
+	latest := model.ForceCharacterSheet{}
+	opts := options.FindOne().SetSort(bson.D{{Key: "characterVersion", Value: -1}})
+	err := collection.FindOne(ctx, bson.M{"characterId": characterID}, opts).Decode(&latest)
+	if err == mongo.ErrNoDocuments {
+		return nil, dberr.CharacterNotFound(characterID)
+	}
+	if err != nil {
+		return nil, translate(err)
+	}
+
+	next := api.LevelUp(latest)
+
+	_, err = collection.InsertOne(ctx, next)
+	if err != nil {
+		return nil, translate(err)
+	}
+
+	return &next, d.insertRevision(ctx, next, api.LevelUpSummary(latest))
+}
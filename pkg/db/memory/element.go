@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"context"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/api"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
+	"github.com/sirupsen/logrus"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//InsertSheetElement appends an element to an array of a sheet, the element must already have its ID.
+//An expected version other than 0 must be that of the sheet. The sheet is returned as it is after the change
+func (d *CharacterDB) InsertSheetElement(ctx context.Context, mongoID primitive.ObjectID, array api.SheetArray, element interface{}, expected int64) (*model.ForceCharacterSheet, error) {
+	logrus.Debugf("BEGIN - memory InsertSheetElement: %v %v", mongoID, array.Name)
+
+	return d.changeAtomically(ctx, mongoID, expected, "added to "+array.Name, func(sheet *model.ForceCharacterSheet) (bool, error) {
+		array.Append(sheet, element)
+		return true, nil
+	})
+}
+
+//UpdateSheetElement replaces the element of an array of a sheet that has the ID, leaving the rest of the sheet as it is
+func (d *CharacterDB) UpdateSheetElement(ctx context.Context, mongoID primitive.ObjectID, array api.SheetArray, elementID primitive.ObjectID, element interface{}, expected int64) (*model.ForceCharacterSheet, error) {
+	logrus.Debugf("BEGIN - memory UpdateSheetElement: %v %v %v", mongoID, array.Name, elementID)
+
+	return d.changeAtomically(ctx, mongoID, expected, "updated "+array.Name, func(sheet *model.ForceCharacterSheet) (bool, error) {
+		if !array.Replace(sheet, elementID, element) {
+			return false, dberr.ElementNotFound(mongoID, array.Name, elementID)
+		}
+		return true, nil
+	})
+}
+
+//DeleteSheetElement removes the element of an array of a sheet that has the ID
+func (d *CharacterDB) DeleteSheetElement(ctx context.Context, mongoID primitive.ObjectID, array api.SheetArray, elementID primitive.ObjectID, expected int64) (*model.ForceCharacterSheet, error) {
+	logrus.Debugf("BEGIN - memory DeleteSheetElement: %v %v %v", mongoID, array.Name, elementID)
+
+	return d.changeAtomically(ctx, mongoID, expected, "removed from "+array.Name, func(sheet *model.ForceCharacterSheet) (bool, error) {
+		if !array.Remove(sheet, elementID) {
+			return false, dberr.ElementNotFound(mongoID, array.Name, elementID)
+		}
+		return true, nil
+	})
+}
+
+// changeAtomically applies a change to a sheet under the write lock and, when the change reports it changed something, bumps its version and records a revision.
+// Holding the lock for the whole change is what makes it atomic, the way the single updates of the mongo backend are. An expected version other than 0
+// that isn't the version of the sheet fails with a version conflict before the change is applied
+func (d *CharacterDB) changeAtomically(ctx context.Context, mongoID primitive.ObjectID, expected int64, summary string, change func(sheet *model.ForceCharacterSheet) (bool, error)) (*model.ForceCharacterSheet, error) {
+	unlock := d.lock(ctx)
+	defer unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sheet := model.ForceCharacterSheet{}
+	err := d.get(sheetsCollection, mongoID, &sheet)
+	if err != nil {
+		return nil, err
+	}
+	if expected != 0 && sheet.Version != expected {
+		return nil, dberr.VersionConflict(mongoID, expected, sheet.Version)
+	}
+
+	changed, err := change(&sheet)
+	if err != nil || !changed {
+		return &sheet, err
+	}
+	sheet.Version++
+
+	err = d.put(sheetsCollection, mongoID, sheet)
+	if err != nil {
+		return nil, err
+	}
+
+	err = d.insertRevision(sheet, summary)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sheet, nil
+}
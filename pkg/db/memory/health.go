@@ -0,0 +1,21 @@
+package memory
+
+import (
+	"context"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/api"
+	"github.com/sirupsen/logrus"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//ApplyHealthAction changes the wounds or strain of a sheet, an action that changes nothing writes no new version.
+//An expected version other than 0 must be that of the sheet. The sheet is returned as it is after the action
+func (d *CharacterDB) ApplyHealthAction(ctx context.Context, mongoID primitive.ObjectID, action api.HealthAction, amount int64, expected int64) (*model.ForceCharacterSheet, error) {
+	logrus.Debugf("BEGIN - memory ApplyHealthAction: %v %v %v", mongoID, action.Name, amount)
+
+	return d.changeAtomically(ctx, mongoID, expected, action.Summary(amount), func(sheet *model.ForceCharacterSheet) (bool, error) {
+		return action.Apply(sheet, amount), nil
+	})
+}
@@ -0,0 +1,107 @@
+package memory
+
+import (
+	"context"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/api"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/query"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//GetForceCharacterSheetVersions returns every live sheet of a character ordered by character version
+func (d *CharacterDB) GetForceCharacterSheetVersions(ctx context.Context, characterID primitive.ObjectID) ([]model.ForceCharacterSheet, error) {
+	logrus.Debugf("BEGIN - memory GetForceCharacterSheetVersions: %v", characterID)
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return d.versions(characterID)
+}
+
+//FindForceCharacterSheetVersion finds the sheet of a character with the given character version
+func (d *CharacterDB) FindForceCharacterSheetVersion(ctx context.Context, characterID primitive.ObjectID, characterVersion int64) (*model.ForceCharacterSheet, error) {
+	logrus.Debugf("BEGIN - memory FindForceCharacterSheetVersion: %v %v", characterID, characterVersion)
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	docs, err := match(d.collections[sheetsCollection], bson.M{"characterId": characterID, "characterVersion": characterVersion})
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, dberr.CharacterVersionNotFound(characterID, characterVersion)
+	}
+
+	sheet := model.ForceCharacterSheet{}
+	err = fromDocument(docs[0], &sheet)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sheet, nil
+}
+
+//LevelUpForceCharacterSheet forks the next version of a character from its latest sheet and returns it, the earlier sheets are kept as they are
+func (d *CharacterDB) LevelUpForceCharacterSheet(ctx context.Context, characterID primitive.ObjectID) (*model.ForceCharacterSheet, error) {
+	logrus.Debugf("BEGIN - memory LevelUpForceCharacterSheet: %v", characterID)
+
+	unlock := d.lock(ctx)
+	defer unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sheets, err := d.versions(characterID)
+	if err != nil {
+		return nil, err
+	}
+
+	latest := sheets[len(sheets)-1]
+	next := api.LevelUp(latest)
+
+	err = d.put(sheetsCollection, next.ID, next)
+	if err != nil {
+		return nil, err
+	}
+
+	return &next, d.insertRevision(next, api.LevelUpSummary(latest))
+}
+
+// versions returns the sheets of a character ordered by character version, the caller must hold a lock
+func (d *CharacterDB) versions(characterID primitive.ObjectID) ([]model.ForceCharacterSheet, error) {
+	docs, err := match(d.collections[sheetsCollection], bson.M{"characterId": characterID})
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, dberr.CharacterNotFound(characterID)
+	}
+	query.Sort(docs, bson.D{{Key: "characterVersion", Value: 1}, {Key: "_id", Value: 1}})
+
+	sheets := []model.ForceCharacterSheet{}
+	for _, doc := range docs {
+		sheet := model.ForceCharacterSheet{}
+		err := fromDocument(doc, &sheet)
+		if err != nil {
+			return nil, err
+		}
+
+		sheets = append(sheets, sheet)
+	}
+
+	return sheets, nil
+}
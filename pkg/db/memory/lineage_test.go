@@ -0,0 +1,57 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/geeksheik9/sheet-CRUD/pkg/api"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCharacterDB_LevelUpForceCharacterSheet(t *testing.T) {
+	ctx := context.Background()
+	d := New()
+	sheet := mockCharacter("Kanan Jarrus", "Ben")
+	d.InsertForceCharacterSheet(ctx, sheet)
+
+	second, err := d.LevelUpForceCharacterSheet(ctx, sheet.ID)
+	if err != nil || second.ID == sheet.ID || second.CharacterID != sheet.ID || second.CharacterVersion != 2 || second.Version != 1 {
+		t.Fatalf("LevelUpForceCharacterSheet() error:\n   expected: version 2 of %v under a new ID\n   got:      %+v %v", sheet.ID, second, err)
+	}
+
+	// an update of the new version can't move it out of the character
+	second.CharacterVersion = 9
+	second.CharacterID = primitive.NilObjectID
+	second.AvailableXP = 15
+	d.UpdateForceCharacterSheetByID(ctx, *second, second.ID)
+
+	third, _ := d.LevelUpForceCharacterSheet(ctx, sheet.ID)
+	if third.CharacterVersion != 3 || third.AvailableXP != 15 {
+		t.Errorf("LevelUpForceCharacterSheet() error:\n   expected: version 3 copied from version 2\n   got:      %+v", third)
+	}
+
+	versions, err := d.GetForceCharacterSheetVersions(ctx, sheet.ID)
+	if err != nil || len(versions) != 3 || versions[0].ID != sheet.ID || versions[1].ID != second.ID || versions[2].ID != third.ID {
+		t.Errorf("GetForceCharacterSheetVersions() error:\n   expected: versions 1 to 3 in order\n   got:      %+v %v", versions, err)
+	}
+
+	found, err := d.FindForceCharacterSheetVersion(ctx, sheet.ID, 2)
+	if err != nil || found.ID != second.ID || found.AvailableXP != 15 {
+		t.Errorf("FindForceCharacterSheetVersion() error:\n   expected: %v\n   got:      %+v %v", second.ID, found, err)
+	}
+
+	latest, err := d.FindLatestForceCharacterSheet(ctx, "Kanan Jarrus", "Ben", false)
+	if err != nil || latest.ID != third.ID {
+		t.Errorf("FindLatestForceCharacterSheet() error:\n   expected: %v\n   got:      %+v %v", third.ID, latest, err)
+	}
+
+	_, err = d.FindForceCharacterSheetVersion(ctx, sheet.ID, 4)
+	if api.CheckError(err) != 404 {
+		t.Errorf("FindForceCharacterSheetVersion() error:\n   expected: not found\n   got:      %v", err)
+	}
+
+	_, err = d.LevelUpForceCharacterSheet(ctx, primitive.NewObjectID())
+	if api.CheckError(err) != 404 {
+		t.Errorf("LevelUpForceCharacterSheet() error:\n   expected: not found\n   got:      %v", err)
+	}
+}
@@ -0,0 +1,710 @@
+// Package memory is an in-memory implementation of the character database that is safe for concurrent use.
+// It honours the same query parameters as the mongo backend and is meant for local development, demos and tests
+package memory
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/api"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/query"
+	"github.com/geeksheik9/sheet-CRUD/pkg/events"
+	"github.com/sirupsen/logrus"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	sheetsCollection     = "sheets"
+	archiveCollection    = "archive"
+	revisionsCollection  = "revisions"
+	migrationsCollection = "migrations"
+)
+
+//Store persists the documents of the in-memory database so that they survive a restart.
+//Documents are grouped by collection and keyed by their ID
+type Store interface {
+	Load() (map[string]map[primitive.ObjectID]bson.M, error)
+	Save(collection string, mongoID primitive.ObjectID, doc bson.M) error
+	Delete(collection string, mongoID primitive.ObjectID) error
+}
+
+//CharacterDB is the in-memory data access object for the Star Wars FFG character sheets
+type CharacterDB struct {
+	mu          sync.RWMutex
+	collections map[string]map[primitive.ObjectID]bson.M
+	store       Store
+	broker      *events.Broker
+
+	// txMu is held by a running transaction and by every write outside it, see RunInTransaction
+	txMu          sync.Mutex
+	inTransaction bool
+	journal       []undo
+	pending       []events.Event
+}
+
+//New returns an empty in-memory character database
+func New() *CharacterDB {
+	return &CharacterDB{
+		collections: map[string]map[primitive.ObjectID]bson.M{
+			sheetsCollection:     {},
+			archiveCollection:    {},
+			revisionsCollection:  {},
+			migrationsCollection: {},
+		},
+		broker: events.NewBroker(events.DefaultHistory),
+	}
+}
+
+//NewWithStore returns an in-memory character database loaded from the store that writes every change through to it.
+//A store that is also an events.Journal keeps the event feed too, so its resume tokens survive a restart
+func NewWithStore(store Store) (*CharacterDB, error) {
+	d := New()
+
+	if journal, ok := store.(events.Journal); ok {
+		broker, err := events.NewJournaledBroker(events.DefaultHistory, journal)
+		if err != nil {
+			return nil, err
+		}
+		d.broker = broker
+	}
+
+	collections, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	for name, docs := range collections {
+		if _, known := d.collections[name]; !known {
+			logrus.Warnf("Ignoring unknown collection %v in store", name)
+			continue
+		}
+		for mongoID, doc := range docs {
+			d.collections[name][mongoID] = doc
+		}
+	}
+
+	d.store = store
+
+	return d, nil
+}
+
+//Events returns the feed of changes to the sheets, published as they are written
+func (d *CharacterDB) Events() events.Source {
+	return d.broker
+}
+
+//Ping checks that the database is running, the in-memory database is always available
+func (d *CharacterDB) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+//InsertForceCharacterSheet inserts the FFG Star Wars Force sensitive character sheet into the database
+func (d *CharacterDB) InsertForceCharacterSheet(ctx context.Context, sheet model.ForceCharacterSheet) error {
+	logrus.Debug("BEGIN - memory InsertForceCharacterSheet")
+
+	unlock := d.lock(ctx)
+	defer unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, found := d.collections[sheetsCollection][sheet.ID]; found {
+		return duplicateError(sheet.ID)
+	}
+	sheet = api.WithElementIDs(api.StartLineage(sheet))
+
+	err := d.put(sheetsCollection, sheet.ID, sheet)
+	if err != nil {
+		return err
+	}
+
+	return d.insertRevision(sheet, "created")
+}
+
+//GetForceCharacterSheets returns all FFG Star Wars Force sensitive character sheets that match the query parameters
+func (d *CharacterDB) GetForceCharacterSheets(ctx context.Context, queryParams url.Values) ([]model.ForceCharacterSheet, error) {
+	logrus.Debug("BEGIN - memory GetForceCharacterSheets")
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	docs, err := find(d.collections[sheetsCollection], queryParams, api.SheetSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	projection, err := api.ParseProjection(queryParams.Get("fields"), api.SheetSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []model.ForceCharacterSheet{}
+	for _, doc := range docs {
+		elem := model.ForceCharacterSheet{}
+		err := fromDocument(projection.Apply(doc), &elem)
+		if err != nil {
+			return nil, err
+		}
+
+		matches = append(matches, elem)
+	}
+
+	return matches, nil
+}
+
+//CountForceCharacterSheets returns how many force character sheets match the filters of the query parameters, ignoring pagination
+func (d *CharacterDB) CountForceCharacterSheets(ctx context.Context, queryParams url.Values) (int64, error) {
+	logrus.Debug("BEGIN - memory CountForceCharacterSheets")
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	_, _, _, filter, err := api.BuildFilter(api.WithoutPagination(queryParams), api.SheetSchema)
+	if err != nil {
+		return 0, err
+	}
+
+	matches, err := match(d.collections[sheetsCollection], filter)
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(len(matches)), nil
+}
+
+//FindForceCharacterSheetByID finds a specific force character sheet by a provided ID.
+//Only the fields selected by the projection are returned, a nil projection returns the whole sheet
+func (d *CharacterDB) FindForceCharacterSheetByID(ctx context.Context, mongoID primitive.ObjectID, projection api.Projection) (*model.ForceCharacterSheet, error) {
+	logrus.Debugf("BEGIN - memory FindForceCharacterSheetByID: %v", mongoID)
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	doc, ok := d.collections[sheetsCollection][mongoID]
+	if !ok {
+		return nil, dberr.SheetNotFound(mongoID)
+	}
+
+	sheet := model.ForceCharacterSheet{}
+	err := fromDocument(projection.Apply(doc), &sheet)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sheet, nil
+}
+
+//FindLatestForceCharacterSheet finds the latest sheet with the character and player name, the highest character version and then the most edited.
+//With ignoreCase the names are compared without regard to case
+func (d *CharacterDB) FindLatestForceCharacterSheet(ctx context.Context, characterName string, playerName string, ignoreCase bool) (*model.ForceCharacterSheet, error) {
+	logrus.Debugf("BEGIN - memory FindLatestForceCharacterSheet: %v %v", characterName, playerName)
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	equal := func(a string, b string) bool { return a == b }
+	if ignoreCase {
+		equal = strings.EqualFold
+	}
+
+	var latest *model.ForceCharacterSheet
+	for _, doc := range d.collections[sheetsCollection] {
+		name, _ := doc["characterName"].(string)
+		player, _ := doc["playerName"].(string)
+		if !equal(name, characterName) || !equal(player, playerName) {
+			continue
+		}
+
+		sheet := model.ForceCharacterSheet{}
+		err := fromDocument(doc, &sheet)
+		if err != nil {
+			return nil, err
+		}
+
+		if latest == nil || laterThan(sheet, *latest) {
+			latest = &sheet
+		}
+	}
+
+	if latest == nil {
+		return nil, dberr.LatestNotFound(characterName, playerName)
+	}
+
+	return latest, nil
+}
+
+//UpdateForceCharacterSheetByID updates a specific force character sheet by provided ID.
+//The update only applies if the stored version matches the version on the sheet, the stored version is then incremented
+func (d *CharacterDB) UpdateForceCharacterSheetByID(ctx context.Context, sheet model.ForceCharacterSheet, mongoID primitive.ObjectID) error {
+	logrus.Debugf("BEGIN - memory UpdateForceCharacterSheetByID: %v", mongoID)
+
+	unlock := d.lock(ctx)
+	defer unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return d.update(sheet, mongoID, "")
+}
+
+// update applies a versioned update and records a revision, the caller must hold the write lock
+func (d *CharacterDB) update(sheet model.ForceCharacterSheet, mongoID primitive.ObjectID, summary string) error {
+	previous := model.ForceCharacterSheet{}
+	err := d.get(sheetsCollection, mongoID, &previous)
+	if err != nil {
+		return err
+	}
+
+	if previous.Version != sheet.Version {
+		return dberr.VersionConflict(mongoID, sheet.Version, previous.Version)
+	}
+
+	sheet = api.WithElementIDsFrom(sheet, previous)
+	sheet.ID = mongoID
+	sheet.Version = previous.Version + 1
+	sheet.CharacterID = previous.CharacterID
+	sheet.CharacterVersion = previous.CharacterVersion
+
+	if summary == "" {
+		changes, err := api.Diff(previous, sheet)
+		if err != nil {
+			return err
+		}
+		summary = api.ChangeSummary(changes)
+	}
+
+	err = d.put(sheetsCollection, mongoID, sheet)
+	if err != nil {
+		return err
+	}
+
+	return d.insertRevision(sheet, summary)
+}
+
+//DeleteForceCharacterSheetByID moves a specific force character sheet by provided ID into the archive
+func (d *CharacterDB) DeleteForceCharacterSheetByID(ctx context.Context, mongoID primitive.ObjectID, deletedBy string) error {
+	logrus.Debugf("BEGIN - memory DeleteForceCharacterSheetByID: %v", mongoID)
+
+	unlock := d.lock(ctx)
+	defer unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	sheet := model.ForceCharacterSheet{}
+	err := d.get(sheetsCollection, mongoID, &sheet)
+	if err != nil {
+		return err
+	}
+
+	archived := model.ArchivedForceCharacterSheet{
+		ForceCharacterSheet: sheet,
+		DeletedAt:           time.Now().UTC(),
+		DeletedBy:           deletedBy,
+	}
+
+	err = d.put(archiveCollection, mongoID, archived)
+	if err != nil {
+		return err
+	}
+
+	return d.remove(sheetsCollection, mongoID)
+}
+
+//GetArchivedForceCharacterSheets returns all archived force character sheets that match the query parameters
+func (d *CharacterDB) GetArchivedForceCharacterSheets(ctx context.Context, queryParams url.Values) ([]model.ArchivedForceCharacterSheet, error) {
+	logrus.Debug("BEGIN - memory GetArchivedForceCharacterSheets")
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	docs, err := find(d.collections[archiveCollection], queryParams, api.ArchivedSheetSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []model.ArchivedForceCharacterSheet{}
+	for _, doc := range docs {
+		elem := model.ArchivedForceCharacterSheet{}
+		err := fromDocument(doc, &elem)
+		if err != nil {
+			return nil, err
+		}
+
+		matches = append(matches, elem)
+	}
+
+	return matches, nil
+}
+
+//FindArchivedForceCharacterSheetByID finds a specific archived force character sheet by a provided ID
+func (d *CharacterDB) FindArchivedForceCharacterSheetByID(ctx context.Context, mongoID primitive.ObjectID) (*model.ArchivedForceCharacterSheet, error) {
+	logrus.Debugf("BEGIN - memory FindArchivedForceCharacterSheetByID: %v", mongoID)
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sheet := model.ArchivedForceCharacterSheet{}
+	err := d.get(archiveCollection, mongoID, &sheet)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sheet, nil
+}
+
+//InsertArchivedForceCharacterSheet inserts an archived force character sheet straight into the archive, used to restore backups
+func (d *CharacterDB) InsertArchivedForceCharacterSheet(ctx context.Context, sheet model.ArchivedForceCharacterSheet) error {
+	logrus.Debugf("BEGIN - memory InsertArchivedForceCharacterSheet: %v", sheet.ID)
+
+	unlock := d.lock(ctx)
+	defer unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, found := d.collections[archiveCollection][sheet.ID]; found {
+		return duplicateError(sheet.ID)
+	}
+	sheet.ForceCharacterSheet = api.StartLineage(sheet.ForceCharacterSheet)
+
+	return d.put(archiveCollection, sheet.ID, sheet)
+}
+
+//RestoreForceCharacterSheetByID moves a specific archived force character sheet back into the live collection
+func (d *CharacterDB) RestoreForceCharacterSheetByID(ctx context.Context, mongoID primitive.ObjectID) error {
+	logrus.Debugf("BEGIN - memory RestoreForceCharacterSheetByID: %v", mongoID)
+
+	unlock := d.lock(ctx)
+	defer unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	archived := model.ArchivedForceCharacterSheet{}
+	err := d.get(archiveCollection, mongoID, &archived)
+	if err != nil {
+		return err
+	}
+
+	if _, found := d.collections[sheetsCollection][mongoID]; found {
+		return duplicateError(mongoID)
+	}
+
+	err = d.put(sheetsCollection, mongoID, archived.ForceCharacterSheet)
+	if err != nil {
+		return err
+	}
+
+	return d.remove(archiveCollection, mongoID)
+}
+
+//PurgeArchivedForceCharacterSheetByID permanently deletes a specific archived force character sheet
+func (d *CharacterDB) PurgeArchivedForceCharacterSheetByID(ctx context.Context, mongoID primitive.ObjectID) error {
+	logrus.Debugf("BEGIN - memory PurgeArchivedForceCharacterSheetByID: %v", mongoID)
+
+	unlock := d.lock(ctx)
+	defer unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, found := d.collections[archiveCollection][mongoID]; !found {
+		return dberr.ArchivedSheetNotFound(mongoID)
+	}
+
+	return d.remove(archiveCollection, mongoID)
+}
+
+//GetForceCharacterSheetRevisions returns the revision history of a specific force character sheet without the sheet snapshots
+func (d *CharacterDB) GetForceCharacterSheetRevisions(ctx context.Context, mongoID primitive.ObjectID) ([]model.SheetRevision, error) {
+	logrus.Debugf("BEGIN - memory GetForceCharacterSheetRevisions: %v", mongoID)
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	revisions, err := d.revisions(mongoID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range revisions {
+		revisions[i].Sheet = nil
+	}
+
+	return revisions, nil
+}
+
+//FindForceCharacterSheetRevision finds a specific revision of a force character sheet by version
+func (d *CharacterDB) FindForceCharacterSheetRevision(ctx context.Context, mongoID primitive.ObjectID, version int64) (*model.SheetRevision, error) {
+	logrus.Debugf("BEGIN - memory FindForceCharacterSheetRevision: %v version %v", mongoID, version)
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return d.findRevision(mongoID, version)
+}
+
+//RevertForceCharacterSheetByID restores a force character sheet to the contents of an earlier revision,
+//the revert is stored as a new revision
+func (d *CharacterDB) RevertForceCharacterSheetByID(ctx context.Context, mongoID primitive.ObjectID, version int64) error {
+	logrus.Debugf("BEGIN - memory RevertForceCharacterSheetByID: %v to version %v", mongoID, version)
+
+	unlock := d.lock(ctx)
+	defer unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	revision, err := d.findRevision(mongoID, version)
+	if err != nil {
+		return err
+	}
+
+	current := model.ForceCharacterSheet{}
+	err = d.get(sheetsCollection, mongoID, &current)
+	if err != nil {
+		return err
+	}
+
+	if revision.Version == current.Version {
+		return dberr.NoChange(mongoID, version)
+	}
+
+	sheet := *revision.Sheet
+	sheet.Version = current.Version
+
+	return d.update(sheet, mongoID, "reverted to version "+strconv.FormatInt(version, 10))
+}
+
+func (d *CharacterDB) insertRevision(sheet model.ForceCharacterSheet, summary string) error {
+	revision := model.SheetRevision{
+		ID:        primitive.NewObjectID(),
+		SheetID:   sheet.ID,
+		Version:   sheet.Version,
+		Timestamp: time.Now().UTC(),
+		Summary:   summary,
+		Sheet:     &sheet,
+	}
+
+	return d.put(revisionsCollection, revision.ID, revision)
+}
+
+func (d *CharacterDB) revisions(mongoID primitive.ObjectID) ([]model.SheetRevision, error) {
+	docs, err := match(d.collections[revisionsCollection], bson.M{"sheetId": mongoID})
+	if err != nil {
+		return nil, err
+	}
+	query.Sort(docs, bson.D{{Key: "version", Value: 1}})
+
+	revisions := []model.SheetRevision{}
+	for _, doc := range docs {
+		revision := model.SheetRevision{}
+		err := fromDocument(doc, &revision)
+		if err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, nil
+}
+
+func (d *CharacterDB) findRevision(mongoID primitive.ObjectID, version int64) (*model.SheetRevision, error) {
+	revisions, err := d.revisions(mongoID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range revisions {
+		if revisions[i].Version == version && revisions[i].Sheet != nil {
+			return &revisions[i], nil
+		}
+	}
+
+	return nil, dberr.RevisionNotFound(mongoID, version)
+}
+
+// laterThan orders sheets like the mongo backend, by character version, then edit version and finally ID
+func laterThan(a model.ForceCharacterSheet, b model.ForceCharacterSheet) bool {
+	if a.CharacterVersion != b.CharacterVersion {
+		return a.CharacterVersion > b.CharacterVersion
+	}
+	if a.Version != b.Version {
+		return a.Version > b.Version
+	}
+
+	return a.ID.Hex() > b.ID.Hex()
+}
+
+// get decodes a stored document into out, returning a not found error when the ID is missing
+func (d *CharacterDB) get(collection string, mongoID primitive.ObjectID, out interface{}) error {
+	doc, found := d.collections[collection][mongoID]
+	if !found {
+		if collection == archiveCollection {
+			return dberr.ArchivedSheetNotFound(mongoID)
+		}
+		return dberr.SheetNotFound(mongoID)
+	}
+
+	return fromDocument(doc, out)
+}
+
+// put stores a copy of the value so later changes by the caller do not leak into the database,
+// the store is written first so memory never holds a change that was not persisted
+func (d *CharacterDB) put(collection string, mongoID primitive.ObjectID, value interface{}) error {
+	doc, err := query.ToDocument(value)
+	if err != nil {
+		return err
+	}
+
+	d.record(collection, mongoID)
+
+	if d.store != nil {
+		err = d.store.Save(collection, mongoID, doc)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, existed := d.collections[collection][mongoID]
+	d.collections[collection][mongoID] = doc
+
+	if collection == sheetsCollection {
+		event := events.Event{Type: events.Created, SheetID: mongoID, Sheet: &model.ForceCharacterSheet{}}
+		if existed {
+			event.Type = events.Updated
+		}
+		err = fromDocument(doc, event.Sheet)
+		if err != nil {
+			return err
+		}
+		event.Version = event.Sheet.Version
+		d.publish(event)
+	}
+
+	return nil
+}
+
+func (d *CharacterDB) remove(collection string, mongoID primitive.ObjectID) error {
+	d.record(collection, mongoID)
+
+	if d.store != nil {
+		err := d.store.Delete(collection, mongoID)
+		if err != nil {
+			return err
+		}
+	}
+
+	doc, existed := d.collections[collection][mongoID]
+	delete(d.collections[collection], mongoID)
+
+	if collection == sheetsCollection && existed {
+		event := events.Event{Type: events.Deleted, SheetID: mongoID}
+		if version, ok := doc["version"].(int64); ok {
+			event.Version = version
+		}
+		d.publish(event)
+	}
+
+	return nil
+}
+
+// find applies the filter, sort and pagination of the query parameters to a collection the same way the mongo backend does
+func find(collection map[primitive.ObjectID]bson.M, queryParams url.Values, schema api.Schema) ([]bson.M, error) {
+	pageNumber, pageCount, sort, filter, err := api.BuildFilter(queryParams, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	skip := 0
+	if pageNumber > 0 {
+		skip = (pageNumber - 1) * pageCount
+	}
+
+	matches, err := match(collection, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	query.Sort(matches, sort)
+
+	return query.Page(matches, skip, pageCount), nil
+}
+
+func match(collection map[primitive.ObjectID]bson.M, filter bson.M) ([]bson.M, error) {
+	matches := []bson.M{}
+	for _, doc := range collection {
+		matched, err := query.Match(doc, filter)
+		if err != nil {
+			return nil, err
+		}
+
+		if matched {
+			matches = append(matches, doc)
+		}
+	}
+
+	return matches, nil
+}
+
+func fromDocument(doc bson.M, out interface{}) error {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	return bson.Unmarshal(data, out)
+}
+
+func duplicateError(mongoID primitive.ObjectID) error {
+	return dberr.DuplicateSheet(mongoID)
+}
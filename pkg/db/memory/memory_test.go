@@ -0,0 +1,673 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/api"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func mockCharacter(name string, player string) model.ForceCharacterSheet {
+	return model.ForceCharacterSheet{
+		ID:            primitive.NewObjectID(),
+		CharacterName: name,
+		PlayerName:    player,
+		Skills:        []model.Skills{{Name: "athletics", Level: 1}},
+		Version:       1,
+	}
+}
+
+func TestCharacterDB_InsertAndFind(t *testing.T) {
+	ctx := context.Background()
+	d := New()
+	sheet := mockCharacter("Mando", "Ben")
+
+	err := d.InsertForceCharacterSheet(ctx, sheet)
+	if err != nil {
+		t.Errorf("InsertForceCharacterSheet() error:\n   expected: <nil>\n   got:      %v", err)
+	}
+
+	err = d.InsertForceCharacterSheet(ctx, sheet)
+	if !errors.Is(err, dberr.ErrConflict) || dberr.Code(err) != "duplicate_key" {
+		t.Errorf("InsertForceCharacterSheet() duplicate error:\n   expected: duplicate_key\n   got:      %v", err)
+	}
+
+	found, err := d.FindForceCharacterSheetByID(ctx, sheet.ID, nil)
+	if err != nil || found.CharacterName != "Mando" {
+		t.Errorf("FindForceCharacterSheetByID() error:\n   expected: Mando\n   got:      %v %v", found, err)
+	}
+
+	found.Skills[0].Level = 5
+	again, _ := d.FindForceCharacterSheetByID(ctx, sheet.ID, nil)
+	if again.Skills[0].Level != 1 {
+		t.Errorf("FindForceCharacterSheetByID() returned shared state:\n   expected: 1\n   got:      %v", again.Skills[0].Level)
+	}
+
+	_, err = d.FindForceCharacterSheetByID(ctx, primitive.NewObjectID(), nil)
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("FindForceCharacterSheetByID() error:\n   expected: not found\n   got:      %v", err)
+	}
+}
+
+func TestCharacterDB_GetForceCharacterSheets(t *testing.T) {
+	ctx := context.Background()
+	d := New()
+	for _, name := range []string{"c", "a", "b"} {
+		_ = d.InsertForceCharacterSheet(ctx, mockCharacter(name, "Ben"))
+	}
+	_ = d.InsertForceCharacterSheet(ctx, mockCharacter("z", "Ana"))
+
+	query := url.Values{}
+	query.Set("playerName", "Ben")
+	query.Set("sort", "characterName")
+	query.Set("pageCount", "2")
+	query.Set("pageNumber", "2")
+
+	sheets, err := d.GetForceCharacterSheets(ctx, query)
+	if err != nil {
+		t.Errorf("GetForceCharacterSheets() error:\n   expected: <nil>\n   got:      %v", err)
+	}
+	if len(sheets) != 1 || sheets[0].CharacterName != "c" {
+		t.Errorf("GetForceCharacterSheets() error:\n   expected: [c]\n   got:      %v", sheets)
+	}
+
+	query = url.Values{}
+	query.Set("skills.name", "athletics")
+	sheets, _ = d.GetForceCharacterSheets(ctx, query)
+	if len(sheets) != 4 {
+		t.Errorf("GetForceCharacterSheets() error:\n   expected: 4 sheets\n   got:      %v", len(sheets))
+	}
+}
+
+func TestCharacterDB_TypedFilters(t *testing.T) {
+	ctx := context.Background()
+	d := New()
+	for i, name := range []string{"Mando", "Grogu", "Bo-Katan"} {
+		sheet := mockCharacter(name, "Ben")
+		sheet.ForceRating = int64(i)
+		_ = d.InsertForceCharacterSheet(ctx, sheet)
+	}
+
+	query := url.Values{}
+	query.Set("forceRating[gte]", "1")
+	query.Set("sort", "-forceRating")
+	sheets, err := d.GetForceCharacterSheets(ctx, query)
+	if err != nil || len(sheets) != 2 || sheets[0].CharacterName != "Bo-Katan" || sheets[1].CharacterName != "Grogu" {
+		t.Errorf("GetForceCharacterSheets() error:\n   expected: [Bo-Katan Grogu]\n   got:      %v %v", sheets, err)
+	}
+
+	query = url.Values{}
+	query.Set("characterName[like]", "MAN")
+	sheets, _ = d.GetForceCharacterSheets(ctx, query)
+	if len(sheets) != 1 || sheets[0].CharacterName != "Mando" {
+		t.Errorf("GetForceCharacterSheets() error:\n   expected: [Mando]\n   got:      %v", sheets)
+	}
+
+	query = url.Values{}
+	query.Set("color", "green")
+	if _, err := d.GetForceCharacterSheets(ctx, query); err == nil || !strings.Contains(err.Error(), "invalid query") {
+		t.Errorf("GetForceCharacterSheets() error:\n   expected: invalid query\n   got:      %v", err)
+	}
+}
+
+func TestCharacterDB_Projection(t *testing.T) {
+	ctx := context.Background()
+	d := New()
+	sheet := mockCharacter("Mando", "Ben")
+	_ = d.InsertForceCharacterSheet(ctx, sheet)
+
+	query := url.Values{}
+	query.Set("fields", "characterName")
+	sheets, err := d.GetForceCharacterSheets(ctx, query)
+	if err != nil || len(sheets) != 1 || sheets[0].ID != sheet.ID || sheets[0].CharacterName != "Mando" || sheets[0].PlayerName != "" || len(sheets[0].Skills) != 0 {
+		t.Errorf("GetForceCharacterSheets() fields error:\n   expected: only the _id and characterName\n   got:      %v %v", sheets, err)
+	}
+
+	found, err := d.FindForceCharacterSheetByID(ctx, sheet.ID, api.Projection{"skills": 0})
+	if err != nil || found.PlayerName != "Ben" || len(found.Skills) != 0 {
+		t.Errorf("FindForceCharacterSheetByID() projection error:\n   expected: the sheet without skills\n   got:      %v %v", found, err)
+	}
+}
+
+func TestCharacterDB_SearchForceCharacterSheets(t *testing.T) {
+	ctx := context.Background()
+	d := New()
+
+	kanan := mockCharacter("Kanan", "Ben")
+	kanan.Weapons = []model.Weapons{{Name: "Lightsaber"}}
+	ezra := mockCharacter("Ezra", "Ben")
+	ezra.Talents = []model.Talents{{Name: "Lightsaber Training"}}
+	hera := mockCharacter("Hera", "Ann")
+	for _, sheet := range []model.ForceCharacterSheet{kanan, ezra, hera} {
+		d.InsertForceCharacterSheet(ctx, sheet)
+	}
+
+	results, total, err := d.SearchForceCharacterSheets(ctx, "lightsabers", url.Values{})
+	if err != nil || total != 2 || len(results) != 2 || results[0].Sheet.CharacterName != "Ezra" {
+		t.Errorf("SearchForceCharacterSheets() error:\n   expected: Ezra ranked above Kanan\n   got:      %v %v %v", results, total, err)
+	}
+
+	results, total, err = d.SearchForceCharacterSheets(ctx, "lightsaber", url.Values{"characterName": {"Kanan"}, "pageCount": {"1"}})
+	if err != nil || total != 1 || len(results) != 1 || results[0].Sheet.CharacterName != "Kanan" {
+		t.Errorf("SearchForceCharacterSheets() filtered error:\n   expected: Kanan\n   got:      %v %v %v", results, total, err)
+	}
+
+	results, total, err = d.SearchForceCharacterSheets(ctx, "lightsaber", url.Values{"pageNumber": {"3"}, "pageCount": {"1"}})
+	if err != nil || total != 2 || len(results) != 0 {
+		t.Errorf("SearchForceCharacterSheets() past the last page error:\n   expected: no results of 2\n   got:      %v %v %v", results, total, err)
+	}
+}
+
+func TestCharacterDB_CountAndCursor(t *testing.T) {
+	ctx := context.Background()
+	d := New()
+	for _, name := range []string{"a", "b", "c"} {
+		_ = d.InsertForceCharacterSheet(ctx, mockCharacter(name, "Ben"))
+	}
+
+	query := url.Values{}
+	query.Set("playerName", "Ben")
+	query.Set("pageCount", "2")
+
+	first, _ := d.GetForceCharacterSheets(ctx, query)
+	total, err := d.CountForceCharacterSheets(ctx, query)
+	if err != nil || total != 3 || len(first) != 2 {
+		t.Fatalf("CountForceCharacterSheets() error:\n   expected: 3 total and 2 sheets\n   got:      %v %v %v", total, len(first), err)
+	}
+
+	query.Set("after", first[1].ID.Hex())
+	rest, _ := d.GetForceCharacterSheets(ctx, query)
+	if len(rest) != 1 || rest[0].ID.Hex() <= first[1].ID.Hex() {
+		t.Errorf("GetForceCharacterSheets() after error:\n   expected: the last sheet\n   got:      %v", rest)
+	}
+
+	total, _ = d.CountForceCharacterSheets(ctx, query)
+	if total != 3 {
+		t.Errorf("CountForceCharacterSheets() error:\n   expected: 3 ignoring the cursor\n   got:      %v", total)
+	}
+}
+
+func TestCharacterDB_UpdateForceCharacterSheetByID(t *testing.T) {
+	ctx := context.Background()
+	d := New()
+	sheet := mockCharacter("Mando", "Ben")
+	_ = d.InsertForceCharacterSheet(ctx, sheet)
+
+	sheet.AvailableXP = 10
+	err := d.UpdateForceCharacterSheetByID(ctx, sheet, sheet.ID)
+	if err != nil {
+		t.Errorf("UpdateForceCharacterSheetByID() error:\n   expected: <nil>\n   got:      %v", err)
+	}
+
+	err = d.UpdateForceCharacterSheetByID(ctx, sheet, sheet.ID)
+	if err == nil || !strings.Contains(err.Error(), "version conflict") {
+		t.Errorf("UpdateForceCharacterSheetByID() stale error:\n   expected: version conflict\n   got:      %v", err)
+	}
+
+	err = d.UpdateForceCharacterSheetByID(ctx, sheet, primitive.NewObjectID())
+	if !errors.Is(err, dberr.ErrNotFound) {
+		t.Errorf("UpdateForceCharacterSheetByID() missing error:\n   expected: %v\n   got:      %v", dberr.ErrNotFound, err)
+	}
+
+	revisions, _ := d.GetForceCharacterSheetRevisions(ctx, sheet.ID)
+	if len(revisions) != 2 || revisions[1].Summary != "updated availableXP" || revisions[1].Sheet != nil {
+		t.Errorf("GetForceCharacterSheetRevisions() error:\n   expected: created and updated availableXP\n   got:      %v", revisions)
+	}
+
+	err = d.RevertForceCharacterSheetByID(ctx, sheet.ID, 1)
+	if err != nil {
+		t.Errorf("RevertForceCharacterSheetByID() error:\n   expected: <nil>\n   got:      %v", err)
+	}
+
+	current, _ := d.FindForceCharacterSheetByID(ctx, sheet.ID, nil)
+	if current.Version != 3 || current.AvailableXP != 0 {
+		t.Errorf("RevertForceCharacterSheetByID() error:\n   expected: version 3 with 0 XP\n   got:      %v %v", current.Version, current.AvailableXP)
+	}
+}
+
+func TestCharacterDB_ArchiveLifecycle(t *testing.T) {
+	ctx := context.Background()
+	d := New()
+	sheet := mockCharacter("Mando", "Ben")
+	_ = d.InsertForceCharacterSheet(ctx, sheet)
+
+	err := d.DeleteForceCharacterSheetByID(ctx, sheet.ID, "gm")
+	if err != nil {
+		t.Errorf("DeleteForceCharacterSheetByID() error:\n   expected: <nil>\n   got:      %v", err)
+	}
+
+	archived, err := d.FindArchivedForceCharacterSheetByID(ctx, sheet.ID)
+	if err != nil || archived.DeletedBy != "gm" || archived.DeletedAt.IsZero() {
+		t.Errorf("FindArchivedForceCharacterSheetByID() error:\n   expected: deleted by gm\n   got:      %v %v", archived, err)
+	}
+
+	err = d.RestoreForceCharacterSheetByID(ctx, sheet.ID)
+	if err != nil {
+		t.Errorf("RestoreForceCharacterSheetByID() error:\n   expected: <nil>\n   got:      %v", err)
+	}
+
+	if _, err := d.FindForceCharacterSheetByID(ctx, sheet.ID, nil); err != nil {
+		t.Errorf("FindForceCharacterSheetByID() after restore error:\n   expected: <nil>\n   got:      %v", err)
+	}
+
+	_ = d.DeleteForceCharacterSheetByID(ctx, sheet.ID, "gm")
+	err = d.PurgeArchivedForceCharacterSheetByID(ctx, sheet.ID)
+	if err != nil {
+		t.Errorf("PurgeArchivedForceCharacterSheetByID() error:\n   expected: <nil>\n   got:      %v", err)
+	}
+
+	err = d.PurgeArchivedForceCharacterSheetByID(ctx, sheet.ID)
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("PurgeArchivedForceCharacterSheetByID() error:\n   expected: not found\n   got:      %v", err)
+	}
+}
+
+func TestCharacterDB_ConcurrentUpdates(t *testing.T) {
+	ctx := context.Background()
+	d := New()
+	sheet := mockCharacter("Mando", "Ben")
+	_ = d.InsertForceCharacterSheet(ctx, sheet)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(xp int64) {
+			defer wg.Done()
+			update := sheet
+			update.AvailableXP = xp
+			if d.UpdateForceCharacterSheetByID(ctx, update, sheet.ID) == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}(int64(i))
+	}
+	wg.Wait()
+
+	if succeeded != 1 {
+		t.Errorf("UpdateForceCharacterSheetByID() concurrent error:\n   expected: 1 successful update\n   got:      %v", succeeded)
+	}
+}
+
+func TestCharacterDB_CanceledContext(t *testing.T) {
+	d := New()
+	sheet := mockCharacter("Mando", "Ben")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := d.InsertForceCharacterSheet(ctx, sheet)
+	if err != context.Canceled {
+		t.Errorf("InsertForceCharacterSheet() error:\n   expected: %v\n   got:      %v", context.Canceled, err)
+	}
+
+	_, err = d.FindForceCharacterSheetByID(context.Background(), sheet.ID, nil)
+	if err == nil {
+		t.Errorf("FindForceCharacterSheetByID() error:\n   expected: not found after canceled insert\n   got:      <nil>")
+	}
+}
+
+func TestCharacterDB_Migrate(t *testing.T) {
+	d := New()
+	ctx := context.Background()
+	mongoID := primitive.NewObjectID()
+	d.collections[sheetsCollection][mongoID] = bson.M{
+		"_id":           mongoID,
+		"characterName": "Mando",
+		"playerName":    "Ben",
+		"wound":         bson.M{"threshold": int64(15), "current": int64(2)},
+		"morality":      bson.M{"emotionalWeekness": "coldness"},
+		"weapons":       bson.A{bson.M{"name": "Amban Rifle"}},
+	}
+
+	report, err := db.Migrate(ctx, d, db.Migrations, db.LatestVersion, true)
+	if err != nil || len(report.Steps) != 4 || report.Steps[0].Documents != 1 || report.Steps[1].Documents != 1 || report.Steps[2].Documents != 1 || report.Steps[3].Documents != 1 {
+		t.Errorf("Migrate() dry run error:\n   expected: 4 steps changing 1 document each\n   got:      %+v %v", report, err)
+	}
+	if _, found := d.collections[sheetsCollection][mongoID]["wound"]; !found {
+		t.Errorf("Migrate() dry run error:\n   expected: wound left in place\n   got:      %v", d.collections[sheetsCollection][mongoID])
+	}
+
+	report, err = db.Migrate(ctx, d, db.Migrations, db.LatestVersion, false)
+	if err != nil || report.From != 0 || report.To != 4 || len(report.Steps) != 4 {
+		t.Errorf("Migrate() error:\n   expected: from 0 to 4 in 4 steps\n   got:      %+v %v", report, err)
+	}
+
+	sheet, err := d.FindForceCharacterSheetByID(ctx, mongoID, nil)
+	if err != nil || sheet.Wounds.Current != 2 || sheet.Morality.EmotionalWeakness != "coldness" || sheet.CharacterID != mongoID || sheet.CharacterVersion != 1 {
+		t.Errorf("Migrate() error:\n   expected: migrated wounds, emotional weakness and lineage\n   got:      %+v %v", sheet, err)
+	}
+	if err == nil && (len(sheet.Weapons) != 1 || sheet.Weapons[0].ID.IsZero() || sheet.Weapons[0].Name != "Amban Rifle") {
+		t.Errorf("Migrate() error:\n   expected: the Amban Rifle with an ID\n   got:      %+v", sheet.Weapons)
+	}
+
+	report, err = db.Migrate(ctx, d, db.Migrations, db.LatestVersion, false)
+	if err != nil || len(report.Steps) != 0 {
+		t.Errorf("Migrate() again error:\n   expected: no steps\n   got:      %+v %v", report, err)
+	}
+
+	report, err = db.Migrate(ctx, d, db.Migrations, 1, false)
+	if err != nil || len(report.Steps) != 3 || report.Steps[0].Version != 4 || report.Steps[2].Version != 2 || report.Steps[2].Direction != "down" {
+		t.Errorf("Migrate() rollback error:\n   expected: migrations 4, 3 and 2 rolled back\n   got:      %+v %v", report, err)
+	}
+	if _, found := d.collections[sheetsCollection][mongoID]["wound"]; !found {
+		t.Errorf("Migrate() rollback error:\n   expected: wound restored\n   got:      %v", d.collections[sheetsCollection][mongoID])
+	}
+	if _, found := d.collections[sheetsCollection][mongoID]["characterId"]; found {
+		t.Errorf("Migrate() rollback error:\n   expected: lineage removed\n   got:      %v", d.collections[sheetsCollection][mongoID])
+	}
+
+	records, err := d.AppliedMigrations(ctx)
+	if err != nil || len(records) != 1 || records[0].Version != 1 {
+		t.Errorf("AppliedMigrations() error:\n   expected: migration 1\n   got:      %+v %v", records, err)
+	}
+}
+
+func TestCharacterDB_RunInTransaction(t *testing.T) {
+	ctx := context.Background()
+	d := New()
+	sheet := mockCharacter("Mando", "Ben")
+	d.InsertForceCharacterSheet(ctx, sheet)
+
+	failure := errors.New("test error")
+	err := d.RunInTransaction(ctx, func(ctx context.Context) error {
+		err := d.DeleteForceCharacterSheetByID(ctx, sheet.ID, "gm")
+		if err != nil {
+			return err
+		}
+		return failure
+	})
+	if err != failure {
+		t.Errorf("RunInTransaction() error:\n   expected: %v\n   got:      %v", failure, err)
+	}
+
+	_, err = d.FindForceCharacterSheetByID(ctx, sheet.ID, nil)
+	if err != nil || len(d.collections[archiveCollection]) != 0 {
+		t.Errorf("RunInTransaction() rollback error:\n   expected: sheet live and not archived\n   got:      %v %v", err, d.collections[archiveCollection])
+	}
+
+	err = d.RunInTransaction(ctx, func(ctx context.Context) error {
+		return d.DeleteForceCharacterSheetByID(ctx, sheet.ID, "gm")
+	})
+	if _, findErr := d.FindArchivedForceCharacterSheetByID(ctx, sheet.ID); err != nil || findErr != nil {
+		t.Errorf("RunInTransaction() commit error:\n   expected: sheet archived\n   got:      %v %v", err, findErr)
+	}
+}
+
+func TestCharacterDB_Events(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	d := New()
+	subscription, err := d.Events().Subscribe(ctx, "")
+	if err != nil {
+		t.Fatalf("Subscribe() error:\n   expected: <nil>\n   got:      %v", err)
+	}
+
+	sheet := mockCharacter("Mando", "Ben")
+	d.InsertForceCharacterSheet(ctx, sheet)
+	sheet.Version = 1
+	d.UpdateForceCharacterSheetByID(ctx, sheet, sheet.ID)
+	d.RunInTransaction(ctx, func(ctx context.Context) error {
+		d.DeleteForceCharacterSheetByID(ctx, sheet.ID, "gm")
+		return errors.New("test error")
+	})
+	d.DeleteForceCharacterSheetByID(ctx, sheet.ID, "gm")
+	cancel()
+
+	got := []string{}
+	for event := range subscription.Events() {
+		if event.SheetID != sheet.ID {
+			t.Errorf("Events() error:\n   expected: events of %v\n   got:      %+v", sheet.ID, event)
+		}
+		got = append(got, fmt.Sprintf("%v %v", event.Type, event.Version))
+	}
+
+	expected := []string{"created 1", "updated 2", "deleted 2"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Events() error:\n   expected: %v\n   got:      %v", expected, got)
+	}
+}
+
+func TestCharacterDB_FindLatestForceCharacterSheet(t *testing.T) {
+	ctx := context.Background()
+	d := New()
+
+	for version := int64(1); version <= 3; version++ {
+		sheet := mockCharacter("Kanan Jarrus", "Ben")
+		sheet.Version = version
+		d.InsertForceCharacterSheet(ctx, sheet)
+	}
+	other := mockCharacter("Kanan Jarrus", "Cara")
+	other.Version = 7
+	d.InsertForceCharacterSheet(ctx, other)
+
+	tests := []struct {
+		name       string
+		character  string
+		player     string
+		ignoreCase bool
+		expected   int64
+	}{
+		{name: "exact", character: "Kanan Jarrus", player: "Ben", expected: 3},
+		{name: "ignore case", character: "kanan jarrus", player: "BEN", ignoreCase: true, expected: 3},
+		{name: "case differs", character: "kanan jarrus", player: "Ben", expected: 0},
+		{name: "unknown player", character: "Kanan Jarrus", player: "Grogu", expected: 0},
+	}
+
+	for _, test := range tests {
+		sheet, err := d.FindLatestForceCharacterSheet(ctx, test.character, test.player, test.ignoreCase)
+		if test.expected == 0 {
+			if api.CheckError(err) != 404 {
+				t.Errorf("FindLatestForceCharacterSheet() %v error:\n   expected: not found\n   got:      %v %v", test.name, sheet, err)
+			}
+			continue
+		}
+
+		if err != nil || sheet.Version != test.expected {
+			t.Errorf("FindLatestForceCharacterSheet() %v error:\n   expected: version %v\n   got:      %v %v", test.name, test.expected, sheet, err)
+		}
+	}
+}
+
+func TestCharacterDB_SheetElements(t *testing.T) {
+	ctx := context.Background()
+	d := New()
+	sheet := mockCharacter("Mando", "Ben")
+	_ = d.InsertForceCharacterSheet(ctx, sheet)
+	weapons, _ := api.FindSheetArray("weapons")
+
+	weapon := model.Weapons{ID: primitive.NewObjectID(), Name: "Blaster"}
+	current, err := d.InsertSheetElement(ctx, sheet.ID, weapons, &weapon, 1)
+	if err != nil || current.Version != 2 || len(current.Weapons) != 1 || current.Weapons[0] != weapon {
+		t.Errorf("InsertSheetElement() error:\n   expected: [%+v] at version 2\n   got:      %+v %v", weapon, current, err)
+	}
+
+	weapon.Damage = "6"
+	_, err = d.UpdateSheetElement(ctx, sheet.ID, weapons, weapon.ID, &weapon, 1)
+	if dberr.Code(err) != "version_conflict" {
+		t.Errorf("UpdateSheetElement() stale version error:\n   expected: version_conflict\n   got:      %v", err)
+	}
+
+	current, err = d.UpdateSheetElement(ctx, sheet.ID, weapons, weapon.ID, &weapon, 0)
+	if err != nil || current.Version != 3 || current.Weapons[0].Damage != "6" {
+		t.Errorf("UpdateSheetElement() error:\n   expected: damage 6 at version 3\n   got:      %+v %v", current, err)
+	}
+
+	current, err = d.DeleteSheetElement(ctx, sheet.ID, weapons, weapon.ID, 3)
+	if err != nil || current.Version != 4 || len(current.Weapons) != 0 {
+		t.Errorf("DeleteSheetElement() error:\n   expected: no weapons at version 4\n   got:      %+v %v", current, err)
+	}
+
+	_, err = d.DeleteSheetElement(ctx, sheet.ID, weapons, weapon.ID, 0)
+	if dberr.Code(err) != "element_not_found" {
+		t.Errorf("DeleteSheetElement() missing element error:\n   expected: element_not_found\n   got:      %v", err)
+	}
+
+	_, err = d.InsertSheetElement(ctx, primitive.NewObjectID(), weapons, &weapon, 0)
+	if dberr.Code(err) != "sheet_not_found" {
+		t.Errorf("InsertSheetElement() missing sheet error:\n   expected: sheet_not_found\n   got:      %v", err)
+	}
+
+	revisions, _ := d.GetForceCharacterSheetRevisions(ctx, sheet.ID)
+	summaries := []string{}
+	for _, revision := range revisions {
+		summaries = append(summaries, revision.Summary)
+	}
+	if fmt.Sprint(summaries[1:]) != "[added to weapons updated weapons removed from weapons]" {
+		t.Errorf("GetForceCharacterSheetRevisions() error:\n   expected: added to, updated and removed from weapons\n   got:      %v", summaries)
+	}
+}
+
+func TestCharacterDB_ApplyHealthAction(t *testing.T) {
+	ctx := context.Background()
+	d := New()
+	sheet := mockCharacter("Mando", "Ben")
+	sheet.SoakValue = 3
+	_ = d.InsertForceCharacterSheet(ctx, sheet)
+
+	current, err := d.ApplyHealthAction(ctx, sheet.ID, api.TakeDamage, 5, 1)
+	if err != nil || current.Wounds.Current != 2 || current.Version != 2 {
+		t.Errorf("ApplyHealthAction() error:\n   expected: 2 wounds at version 2\n   got:      %+v %v", current, err)
+	}
+
+	_, err = d.ApplyHealthAction(ctx, sheet.ID, api.SufferStrain, 1, 1)
+	if dberr.Code(err) != "version_conflict" {
+		t.Errorf("ApplyHealthAction() stale version error:\n   expected: version_conflict\n   got:      %v", err)
+	}
+
+	current, err = d.ApplyHealthAction(ctx, sheet.ID, api.TakeDamage, 3, 0)
+	if err != nil || current.Wounds.Current != 2 || current.Version != 2 {
+		t.Errorf("ApplyHealthAction() soaked error:\n   expected: 2 wounds still at version 2\n   got:      %+v %v", current, err)
+	}
+
+	_, err = d.ApplyHealthAction(ctx, primitive.NewObjectID(), api.HealWounds, 1, 0)
+	if dberr.Code(err) != "sheet_not_found" {
+		t.Errorf("ApplyHealthAction() missing sheet error:\n   expected: sheet_not_found\n   got:      %v", err)
+	}
+}
+
+func TestCharacterDB_Migrate_RenameKeepsNewerField(t *testing.T) {
+	d := New()
+	ctx := context.Background()
+	mongoID, revisionID := primitive.NewObjectID(), primitive.NewObjectID()
+	// written after the rename was deployed but before the migration ran, wounds is fresh and wound is stale
+	d.collections[sheetsCollection][mongoID] = bson.M{
+		"_id":    mongoID,
+		"wound":  bson.M{"threshold": int64(15), "current": int64(2)},
+		"wounds": bson.M{"threshold": int64(15), "current": int64(9)},
+	}
+	d.collections[revisionsCollection][revisionID] = bson.M{
+		"_id":   revisionID,
+		"sheet": bson.M{"_id": mongoID, "wound": bson.M{"current": int64(2)}, "wounds": bson.M{"current": int64(9)}},
+	}
+	migrations := []db.Migration{db.RenameField(1, "wound", "wounds")}
+
+	_, err := db.Migrate(ctx, d, migrations, db.LatestVersion, false)
+	if err != nil {
+		t.Fatalf("Migrate() error:\n   expected: <nil>\n   got:      %v", err)
+	}
+
+	sheet := d.collections[sheetsCollection][mongoID]
+	if _, found := sheet["wound"]; found || sheet["wounds"].(bson.M)["current"] != int64(9) {
+		t.Errorf("Migrate() error:\n   expected: the fresh wounds kept and the stale wound dropped\n   got:      %v", sheet)
+	}
+	revision := d.collections[revisionsCollection][revisionID]["sheet"].(bson.M)
+	if _, found := revision["wound"]; found || revision["wounds"].(bson.M)["current"] != int64(9) {
+		t.Errorf("Migrate() revision error:\n   expected: the fresh wounds kept and the stale wound dropped\n   got:      %v", revision)
+	}
+
+	d.collections[sheetsCollection][mongoID]["wound"] = bson.M{"threshold": int64(15), "current": int64(4)}
+	_, err = db.Migrate(ctx, d, migrations, 0, false)
+	sheet = d.collections[sheetsCollection][mongoID]
+	if _, found := sheet["wounds"]; err != nil || found || sheet["wound"].(bson.M)["current"] != int64(4) {
+		t.Errorf("Migrate() rollback error:\n   expected: the fresh wound kept and the stale wounds dropped\n   got:      %v %v", sheet, err)
+	}
+}
+
+func TestCharacterDB_Migrate_LinksLegacyVersions(t *testing.T) {
+	d := New()
+	ctx := context.Background()
+	first, second, archived, other, revisionID := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	legacy := func(id primitive.ObjectID, character string, version int64) bson.M {
+		return bson.M{"_id": id, "characterName": character, "playerName": "Ben", "version": version}
+	}
+	// the baseline kept a sheet for each version of a character, the second version was inserted before the first
+	d.collections[sheetsCollection][second] = legacy(second, "Kanan", 2)
+	d.collections[sheetsCollection][first] = legacy(first, "Kanan", 1)
+	d.collections[sheetsCollection][other] = legacy(other, "Ezra", 1)
+	d.collections[archiveCollection][archived] = legacy(archived, "Kanan", 3)
+	d.collections[revisionsCollection][revisionID] = bson.M{"_id": revisionID, "sheet": legacy(second, "Kanan", 2)}
+
+	_, err := db.Migrate(ctx, d, []db.Migration{db.StartLineages(1)}, db.LatestVersion, false)
+	if err != nil {
+		t.Fatalf("Migrate() error:\n   expected: <nil>\n   got:      %v", err)
+	}
+
+	versions, err := d.GetForceCharacterSheetVersions(ctx, first)
+	if err != nil || len(versions) != 2 || versions[0].ID != first || versions[0].CharacterVersion != 1 || versions[1].ID != second || versions[1].CharacterVersion != 2 {
+		t.Errorf("Migrate() error:\n   expected: both Kanan sheets in one lineage numbered by version\n   got:      %+v %v", versions, err)
+	}
+
+	archive := d.collections[archiveCollection][archived]
+	if archive["characterId"] != first || archive["characterVersion"] != int64(3) {
+		t.Errorf("Migrate() archive error:\n   expected: the archived Kanan as version 3 of the same character\n   got:      %v", archive)
+	}
+	revision := d.collections[revisionsCollection][revisionID]["sheet"].(bson.M)
+	if revision["characterId"] != first || revision["characterVersion"] != int64(2) {
+		t.Errorf("Migrate() revision error:\n   expected: the revision in the lineage of its sheet\n   got:      %v", revision)
+	}
+
+	latest, err := d.FindLatestForceCharacterSheet(ctx, "Kanan", "Ben", false)
+	if err != nil || latest.ID != second || latest.CharacterID != first {
+		t.Errorf("FindLatestForceCharacterSheet() error:\n   expected: the second Kanan sheet in the shared lineage\n   got:      %+v %v", latest, err)
+	}
+
+	ezra, err := d.FindForceCharacterSheetByID(ctx, other, nil)
+	if err != nil || ezra.CharacterID != other || ezra.CharacterVersion != 1 {
+		t.Errorf("Migrate() error:\n   expected: Ezra as a character of its own\n   got:      %+v %v", ezra, err)
+	}
+}
+
+func TestCharacterDB_Migrate_AssignsElementIDs(t *testing.T) {
+	d := New()
+	ctx := context.Background()
+	sheetID, revisionID, rifleID := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	d.collections[archiveCollection][sheetID] = bson.M{
+		"_id":       sheetID,
+		"version":   int64(2),
+		"weapons":   bson.A{bson.M{"id": rifleID, "name": "Amban Rifle"}, bson.M{"name": "Vibro-knife"}},
+		"talents":   bson.A{bson.M{"name": "Grit"}},
+		"equipment": bson.M{"armor": bson.A{bson.M{"id": primitive.NewObjectID(), "name": "Beskar"}}},
+	}
+	d.collections[revisionsCollection][revisionID] = bson.M{"_id": revisionID, "sheet": bson.M{"_id": sheetID, "version": int64(1), "talents": bson.A{bson.M{"name": "Grit"}}}}
+
+	report, err := db.Migrate(ctx, d, []db.Migration{db.AssignElementIDs(1)}, db.LatestVersion, false)
+	if err != nil || len(report.Steps) != 1 || report.Steps[0].Documents != 2 {
+		t.Errorf("Migrate() error:\n   expected: the archived sheet and the revision changed\n   got:      %+v %v", report, err)
+	}
+
+	archived, err := d.FindArchivedForceCharacterSheetByID(ctx, sheetID)
+	if err != nil || api.MissingElementIDs(archived.ForceCharacterSheet) || archived.Weapons[0].ID != rifleID || archived.Weapons[1].Name != "Vibro-knife" || archived.Version != 2 {
+		t.Errorf("Migrate() archive error:\n   expected: every element with an ID, the rifle keeping its own, at version 2\n   got:      %+v %v", archived, err)
+	}
+
+	revision := d.collections[revisionsCollection][revisionID]["sheet"].(bson.M)
+	talents, _ := revision["talents"].(bson.A)
+	if len(talents) != 1 || talents[0].(bson.M)["id"] == nil {
+		t.Errorf("Migrate() revision error:\n   expected: Grit with an ID\n   got:      %v", revision)
+	}
+}
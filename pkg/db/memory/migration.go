@@ -0,0 +1,200 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/query"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//CountDocuments counts the documents of a collection that match the filter
+func (d *CharacterDB) CountDocuments(ctx context.Context, collection string, filter bson.M) (int64, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	docs, err := d.migrationCollection(collection)
+	if err != nil {
+		return 0, err
+	}
+
+	matches, err := match(docs, filter)
+
+	return int64(len(matches)), err
+}
+
+//FindDocuments returns copies of the documents of a collection that match the filter
+func (d *CharacterDB) FindDocuments(ctx context.Context, collection string, filter bson.M) ([]bson.M, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	docs, err := d.migrationCollection(collection)
+	if err != nil {
+		return nil, err
+	}
+
+	matches, err := match(docs, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	found := []bson.M{}
+	for _, doc := range matches {
+		copied, err := query.ToDocument(doc)
+		if err != nil {
+			return nil, err
+		}
+
+		found = append(found, copied)
+	}
+
+	return found, nil
+}
+
+//UpdateDocuments applies the update to every document of a collection that matches the filter and returns how many changed
+func (d *CharacterDB) UpdateDocuments(ctx context.Context, collection string, filter bson.M, update bson.M) (int64, error) {
+	unlock := d.lock(ctx)
+	defer unlock()
+
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	docs, err := d.migrationCollection(collection)
+	if err != nil {
+		return 0, err
+	}
+
+	matches, err := match(docs, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	var modified int64
+	for _, doc := range matches {
+		updated, err := query.ToDocument(doc)
+		if err != nil {
+			return modified, err
+		}
+
+		changed, err := query.Update(updated, update)
+		if err != nil {
+			return modified, err
+		}
+		if !changed {
+			continue
+		}
+
+		mongoID, err := documentID(updated)
+		if err != nil {
+			return modified, err
+		}
+
+		err = d.put(collection, mongoID, updated)
+		if err != nil {
+			return modified, err
+		}
+		modified++
+	}
+
+	return modified, nil
+}
+
+//AppliedMigrations returns the migrations recorded as applied in version order
+func (d *CharacterDB) AppliedMigrations(ctx context.Context) ([]model.MigrationRecord, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	docs, err := match(d.collections[migrationsCollection], nil)
+	if err != nil {
+		return nil, err
+	}
+	query.Sort(docs, bson.D{{Key: "version", Value: 1}})
+
+	records := []model.MigrationRecord{}
+	for _, doc := range docs {
+		record := model.MigrationRecord{}
+		err := fromDocument(doc, &record)
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+//RecordMigration records that a migration was applied
+func (d *CharacterDB) RecordMigration(ctx context.Context, record model.MigrationRecord) error {
+	unlock := d.lock(ctx)
+	defer unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return d.put(migrationsCollection, record.ID, record)
+}
+
+//RemoveMigration removes the record of an applied migration after it was rolled back
+func (d *CharacterDB) RemoveMigration(ctx context.Context, version int) error {
+	unlock := d.lock(ctx)
+	defer unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	docs, err := match(d.collections[migrationsCollection], bson.M{"version": version})
+	if err != nil {
+		return err
+	}
+
+	for _, doc := range docs {
+		mongoID, err := documentID(doc)
+		if err != nil {
+			return err
+		}
+
+		err = d.remove(migrationsCollection, mongoID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// migrationCollection returns the documents of a collection migrations may change, the migrations record itself is left out
+func (d *CharacterDB) migrationCollection(name string) (map[primitive.ObjectID]bson.M, error) {
+	switch name {
+	case sheetsCollection, archiveCollection, revisionsCollection:
+		return d.collections[name], nil
+	}
+
+	return nil, fmt.Errorf("unknown collection %v", name)
+}
+
+func documentID(doc bson.M) (primitive.ObjectID, error) {
+	mongoID, ok := doc["_id"].(primitive.ObjectID)
+	if !ok {
+		return primitive.NilObjectID, fmt.Errorf("document has no object ID: %v", doc["_id"])
+	}
+
+	return mongoID, nil
+}
@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"context"
+	"net/url"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/api"
+	"github.com/geeksheik9/sheet-CRUD/pkg/search"
+	"github.com/sirupsen/logrus"
+)
+
+//SearchForceCharacterSheets finds the force character sheets matching any word of the text, ranked by the search package
+//in place of mongo's text score. The other query parameters filter and page the results like GetForceCharacterSheets
+func (d *CharacterDB) SearchForceCharacterSheets(ctx context.Context, text string, queryParams url.Values) ([]model.SearchResult, int64, error) {
+	logrus.Debug("BEGIN - memory SearchForceCharacterSheets")
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	pageNumber, pageCount, _, filter, err := api.BuildFilter(queryParams, api.SheetSchema)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	docs, err := match(d.collections[sheetsCollection], filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	terms := search.Terms(text)
+	results := []model.SearchResult{}
+	for _, doc := range docs {
+		sheet := model.ForceCharacterSheet{}
+		err := fromDocument(doc, &sheet)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if score := search.Score(sheet, terms); score > 0 {
+			results = append(results, model.SearchResult{Sheet: sheet, Score: score})
+		}
+	}
+
+	search.Rank(results)
+
+	skip := 0
+	if pageNumber > 0 {
+		skip = (pageNumber - 1) * pageCount
+	}
+	total := int64(len(results))
+	if skip > len(results) {
+		skip = len(results)
+	}
+	end := skip + pageCount
+	if end > len(results) {
+		end = len(results)
+	}
+
+	return results[skip:end], total, nil
+}
@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/geeksheik9/sheet-CRUD/pkg/events"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// txKey marks the context of the writes that belong to the running transaction
+type txKey struct{}
+
+// undo is how a document was before a transaction changed it, a nil document didn't exist
+type undo struct {
+	collection string
+	mongoID    primitive.ObjectID
+	doc        bson.M
+}
+
+//RunInTransaction runs fn so that either every write it makes through the context it is given is kept or, when it returns an error, none are.
+//Transactions run one at a time and writes outside them wait until they finish, reads don't wait and may see the writes of a running transaction
+func (d *CharacterDB) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(txKey{}) != nil {
+		return fn(ctx)
+	}
+
+	d.txMu.Lock()
+	defer d.txMu.Unlock()
+
+	d.mu.Lock()
+	d.inTransaction = true
+	d.journal = []undo{}
+	d.pending = []events.Event{}
+	d.mu.Unlock()
+
+	err := fn(context.WithValue(ctx, txKey{}, true))
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err == nil {
+		pending := d.pending
+		d.endTransaction()
+		for _, event := range pending {
+			d.broker.Publish(event)
+		}
+		return nil
+	}
+
+	// the rollback runs inside the transaction so that its writes are neither journaled nor published
+	defer d.endTransaction()
+
+	journal := d.journal
+	logrus.Debugf("Rolling back %v changes of a failed transaction", len(journal))
+	for i := len(journal) - 1; i >= 0; i-- {
+		change := journal[i]
+
+		var rollbackErr error
+		if change.doc == nil {
+			rollbackErr = d.remove(change.collection, change.mongoID)
+		} else {
+			rollbackErr = d.put(change.collection, change.mongoID, change.doc)
+		}
+		if rollbackErr != nil {
+			return fmt.Errorf("%w, and rolling the transaction back failed: %v", err, rollbackErr)
+		}
+	}
+
+	return err
+}
+
+func (d *CharacterDB) endTransaction() {
+	d.inTransaction = false
+	d.journal = nil
+	d.pending = nil
+}
+
+// lock takes the write lock. Writes outside a transaction also wait for any running transaction to finish
+func (d *CharacterDB) lock(ctx context.Context) func() {
+	if ctx.Value(txKey{}) != nil {
+		d.mu.Lock()
+		return d.mu.Unlock
+	}
+
+	d.txMu.Lock()
+	d.mu.Lock()
+
+	return func() {
+		d.mu.Unlock()
+		d.txMu.Unlock()
+	}
+}
+
+// record keeps how a document was before each change of the running transaction, they are undone in reverse order on rollback
+func (d *CharacterDB) record(collection string, mongoID primitive.ObjectID) {
+	if !d.inTransaction {
+		return
+	}
+
+	d.journal = append(d.journal, undo{
+		collection: collection,
+		mongoID:    mongoID,
+		doc:        d.collections[collection][mongoID],
+	})
+}
+
+// publish sends the event of a change to the sheets, the events of a transaction wait until it commits
+func (d *CharacterDB) publish(event events.Event) {
+	if d.inTransaction {
+		d.pending = append(d.pending, event)
+		return
+	}
+
+	d.broker.Publish(event)
+}
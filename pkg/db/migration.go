@@ -0,0 +1,570 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/api"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/query"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// The collections a migration can change. The mongo backend maps them to the configured collection names
+const (
+	SheetsCollection    = "sheets"
+	ArchiveCollection   = "archive"
+	RevisionsCollection = "revisions"
+)
+
+// LatestVersion is the migration target that applies every pending migration
+const LatestVersion = -1
+
+//MigrationStore is a database that migrations can run against, it records which migrations were applied
+type MigrationStore interface {
+	CountDocuments(ctx context.Context, collection string, filter bson.M) (int64, error)
+	FindDocuments(ctx context.Context, collection string, filter bson.M) ([]bson.M, error)
+	UpdateDocuments(ctx context.Context, collection string, filter bson.M, update bson.M) (int64, error)
+	AppliedMigrations(ctx context.Context) ([]model.MigrationRecord, error)
+	RecordMigration(ctx context.Context, record model.MigrationRecord) error
+	RemoveMigration(ctx context.Context, version int) error
+}
+
+//Change is an update applied to every document of a collection that matches the filter.
+//Updates are limited to $set, $unset, $rename and $copy so that every backend can apply them, see query.Update
+type Change struct {
+	Collection string
+	Filter     bson.M
+	Update     bson.M
+}
+
+//Migration is a versioned change to the stored documents along with the changes that undo it.
+//Plan, when set, works out more changes for Up from the stored documents, for a migration whose change to a document depends on others
+type Migration struct {
+	Version     int
+	Description string
+	Up          []Change
+	Down        []Change
+	Plan        func(ctx context.Context, store MigrationStore) ([]Change, error)
+}
+
+//Migrations are every migration in version order. Add a migration with the next version whenever
+//a change to models renames or restructures a stored field
+var Migrations = []Migration{
+	RenameField(1, "morality.emotionalWeekness", "morality.emotionalWeakness"),
+	RenameField(2, "wound", "wounds"),
+	StartLineages(3),
+	AssignElementIDs(4),
+}
+
+//RenameField returns a migration that renames a field of every live, archived and revision sheet.
+//A sheet that already has the new field was written since the rename was deployed, so its new field is kept and the stale old one dropped.
+//Rolling back does the same the other way round
+func RenameField(version int, from string, to string) Migration {
+	return Migration{
+		Version:     version,
+		Description: fmt.Sprintf("rename %v to %v", from, to),
+		Up:          renameChanges(from, to),
+		Down:        renameChanges(to, from),
+	}
+}
+
+func renameChanges(from string, to string) []Change {
+	changes := []Change{}
+	for _, target := range []struct{ collection, prefix string }{
+		{SheetsCollection, ""},
+		{ArchiveCollection, ""},
+		{RevisionsCollection, "sheet."},
+	} {
+		source, destination := target.prefix+from, target.prefix+to
+
+		// $rename overwrites the destination, so it only runs where there is none
+		changes = append(changes, Change{
+			Collection: target.collection,
+			Filter:     bson.M{source: bson.M{"$exists": true}, destination: bson.M{"$exists": false}},
+			Update:     bson.M{"$rename": bson.M{source: destination}},
+		}, Change{
+			Collection: target.collection,
+			Filter:     bson.M{source: bson.M{"$exists": true}, destination: bson.M{"$exists": true}},
+			Update:     bson.M{"$unset": bson.M{source: ""}},
+		})
+	}
+
+	return changes
+}
+
+//StartLineages returns a migration that links the sheets without a character lineage into characters. The legacy sheets of a player and character name,
+//live, archived or only left in revisions, become the versions of one character numbered in the order of their version, and the character takes the ID of its first sheet
+func StartLineages(version int) Migration {
+	unset := func(collection string, prefix string) Change {
+		return Change{
+			Collection: collection,
+			Filter:     bson.M{prefix + "characterId": bson.M{"$exists": true}},
+			Update:     bson.M{"$unset": bson.M{prefix + "characterId": "", prefix + "characterVersion": ""}},
+		}
+	}
+
+	return Migration{
+		Version:     version,
+		Description: "link the sheets of every character into a lineage",
+		Plan:        planLineages,
+		Down:        []Change{unset(SheetsCollection, ""), unset(ArchiveCollection, ""), unset(RevisionsCollection, "sheet.")},
+	}
+}
+
+// legacySheet is a sheet from before lineages, identified by its player and character name
+type legacySheet struct {
+	id        primitive.ObjectID
+	player    interface{}
+	character interface{}
+	version   interface{}
+}
+
+// planLineages groups the legacy sheets by player and character name and returns the changes giving each the lineage of its group
+func planLineages(ctx context.Context, store MigrationStore) ([]Change, error) {
+	sheets := map[primitive.ObjectID]*legacySheet{}
+	order := []*legacySheet{}
+	add := func(doc bson.M) {
+		id, ok := doc["_id"].(primitive.ObjectID)
+		if !ok {
+			return
+		}
+
+		sheet, found := sheets[id]
+		if !found {
+			sheet = &legacySheet{id: id, player: doc["playerName"], character: doc["characterName"], version: doc["version"]}
+			sheets[id] = sheet
+			order = append(order, sheet)
+		}
+		if query.Compare(doc["version"], sheet.version) > 0 {
+			sheet.version = doc["version"]
+		}
+	}
+
+	// live and archived sheets come first so their names win over older revisions
+	for _, collection := range []string{SheetsCollection, ArchiveCollection} {
+		docs, err := store.FindDocuments(ctx, collection, bson.M{"characterId": bson.M{"$exists": false}})
+		if err != nil {
+			return nil, err
+		}
+		for _, doc := range docs {
+			add(doc)
+		}
+	}
+
+	revisions, err := store.FindDocuments(ctx, RevisionsCollection, bson.M{"sheet.characterId": bson.M{"$exists": false}})
+	if err != nil {
+		return nil, err
+	}
+	for _, revision := range revisions {
+		switch sheet := revision["sheet"].(type) {
+		case bson.M:
+			add(sheet)
+		case primitive.D:
+			add(sheet.Map())
+		}
+	}
+
+	characters := map[string][]*legacySheet{}
+	keys := []string{}
+	for _, sheet := range order {
+		key := fmt.Sprintf("%v\x00%v", sheet.player, sheet.character)
+		if _, found := characters[key]; !found {
+			keys = append(keys, key)
+		}
+		characters[key] = append(characters[key], sheet)
+	}
+	sort.Strings(keys)
+
+	changes := []Change{}
+	for _, key := range keys {
+		versions := characters[key]
+		sort.SliceStable(versions, func(i, j int) bool {
+			if c := query.Compare(versions[i].version, versions[j].version); c != 0 {
+				return c < 0
+			}
+			return query.Compare(versions[i].id, versions[j].id) < 0
+		})
+
+		characterID := versions[0].id
+		for i, sheet := range versions {
+			for _, target := range []struct{ collection, prefix string }{
+				{SheetsCollection, ""},
+				{ArchiveCollection, ""},
+				{RevisionsCollection, "sheet."},
+			} {
+				changes = append(changes, Change{
+					Collection: target.collection,
+					Filter:     bson.M{target.prefix + "_id": sheet.id, target.prefix + "characterId": bson.M{"$exists": false}},
+					Update: bson.M{"$set": bson.M{
+						target.prefix + "characterId":      characterID,
+						target.prefix + "characterVersion": int64(i + 1),
+					}},
+				})
+			}
+		}
+	}
+
+	return changes, nil
+}
+
+//AssignElementIDs returns a migration that gives an ID to every element of the sheet arrays of the live, archived and revision sheets
+//stored before elements had them, so the element routes can address them without writing on a read. Older code ignores the IDs, so rolling back leaves them
+func AssignElementIDs(version int) Migration {
+	return Migration{
+		Version:     version,
+		Description: "give every element of the sheet arrays an ID",
+		Plan:        planElementIDs,
+		Down:        []Change{},
+	}
+}
+
+// planElementIDs returns a change for each sheet with elements missing an ID that sets the arrays holding them, only while the sheet is at the version read
+func planElementIDs(ctx context.Context, store MigrationStore) ([]Change, error) {
+	changes := []Change{}
+	for _, target := range []struct{ collection, prefix string }{
+		{SheetsCollection, ""},
+		{ArchiveCollection, ""},
+		{RevisionsCollection, "sheet."},
+	} {
+		docs, err := store.FindDocuments(ctx, target.collection, bson.M{})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, doc := range docs {
+			stored := doc
+			if target.prefix != "" {
+				switch sheet := doc["sheet"].(type) {
+				case bson.M:
+					stored = sheet
+				case primitive.D:
+					stored = sheet.Map()
+				default:
+					continue
+				}
+			}
+
+			sheet := model.ForceCharacterSheet{}
+			data, err := bson.Marshal(stored)
+			if err == nil {
+				err = bson.Unmarshal(data, &sheet)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("error reading %v %v: %v", target.collection, doc["_id"], err)
+			}
+			if !api.MissingElementIDs(sheet) {
+				continue
+			}
+
+			withIDs, err := query.ToDocument(api.WithElementIDs(sheet))
+			if err != nil {
+				return nil, err
+			}
+
+			set := bson.M{}
+			for _, array := range api.SheetArrays {
+				if array.MissingIDs(sheet) {
+					set[target.prefix+array.Path] = fieldAt(withIDs, array.Path)
+				}
+			}
+
+			changes = append(changes, Change{
+				Collection: target.collection,
+				// a null version also matches the sheets stored before they had one
+				Filter: bson.M{"_id": doc["_id"], target.prefix + "version": stored["version"]},
+				Update: bson.M{"$set": set},
+			})
+		}
+	}
+
+	return changes, nil
+}
+
+// fieldAt returns the value at a dotted path of a document, nil when there is none
+func fieldAt(doc bson.M, path string) interface{} {
+	parts := strings.Split(path, ".")
+	for _, part := range parts[:len(parts)-1] {
+		doc, _ = doc[part].(bson.M)
+	}
+
+	return doc[parts[len(parts)-1]]
+}
+
+//MigrationStep is a migration that was applied or rolled back, or in a dry run would have been
+type MigrationStep struct {
+	Version     int    `json:"version"`
+	Description string `json:"description"`
+	Direction   string `json:"direction"`
+	Documents   int64  `json:"documents"`
+}
+
+//MigrationReport describes what Migrate did, or in a dry run what it would do
+type MigrationReport struct {
+	DryRun bool            `json:"dryRun"`
+	From   int             `json:"from"`
+	To     int             `json:"to"`
+	Steps  []MigrationStep `json:"steps"`
+}
+
+//Migrate brings the stored documents to the target version. Pending migrations up to the target are applied in version order
+//and applied migrations after the target are rolled back in reverse order, use LatestVersion to apply everything.
+//In a dry run nothing is changed and each step reports how many documents it would change
+func Migrate(ctx context.Context, store MigrationStore, migrations []Migration, target int, dryRun bool) (MigrationReport, error) {
+	sorted := append([]Migration{}, migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	records, err := store.AppliedMigrations(ctx)
+	if err != nil {
+		return MigrationReport{}, err
+	}
+
+	applied := map[int]bool{}
+	report := MigrationReport{DryRun: dryRun, Steps: []MigrationStep{}}
+	for _, record := range records {
+		applied[record.Version] = true
+		if record.Version > report.From {
+			report.From = record.Version
+		}
+	}
+
+	if target == LatestVersion && len(sorted) > 0 {
+		target = sorted[len(sorted)-1].Version
+	}
+	report.To = target
+
+	for i := len(sorted) - 1; i >= 0; i-- {
+		migration := sorted[i]
+		if migration.Version <= target || !applied[migration.Version] {
+			continue
+		}
+
+		step, err := runMigration(ctx, store, migration, "down", dryRun)
+		report.Steps = append(report.Steps, step)
+		if err != nil {
+			return report, err
+		}
+	}
+
+	for _, migration := range sorted {
+		if migration.Version > target || applied[migration.Version] {
+			continue
+		}
+
+		step, err := runMigration(ctx, store, migration, "up", dryRun)
+		report.Steps = append(report.Steps, step)
+		if err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+func runMigration(ctx context.Context, store MigrationStore, migration Migration, direction string, dryRun bool) (MigrationStep, error) {
+	step := MigrationStep{
+		Version:     migration.Version,
+		Description: migration.Description,
+		Direction:   direction,
+	}
+
+	changes := migration.Up
+	if direction == "down" {
+		changes = migration.Down
+	} else if migration.Plan != nil {
+		planned, err := migration.Plan(ctx, store)
+		if err != nil {
+			return step, fmt.Errorf("migration %v %v failed to plan: %v", migration.Version, direction, err)
+		}
+		changes = append(append([]Change{}, changes...), planned...)
+	}
+
+	for _, change := range changes {
+		var documents int64
+		var err error
+		if dryRun {
+			documents, err = store.CountDocuments(ctx, change.Collection, change.Filter)
+		} else {
+			documents, err = store.UpdateDocuments(ctx, change.Collection, change.Filter, change.Update)
+		}
+		if err != nil {
+			return step, fmt.Errorf("migration %v %v failed on %v: %v", migration.Version, direction, change.Collection, err)
+		}
+		step.Documents += documents
+	}
+
+	if dryRun {
+		return step, nil
+	}
+
+	logrus.Infof("Migration %v %v (%v) changed %v documents", migration.Version, direction, migration.Description, step.Documents)
+
+	if direction == "down" {
+		return step, store.RemoveMigration(ctx, migration.Version)
+	}
+
+	return step, store.RecordMigration(ctx, model.MigrationRecord{
+		ID:          primitive.NewObjectID(),
+		Version:     migration.Version,
+		Description: migration.Description,
+		AppliedAt:   time.Now().UTC(),
+		Documents:   step.Documents,
+	})
+}
+
+// migrationCollection returns the mongo collection behind a migration collection name
+func (d *CharacterDB) migrationCollection(name string) (*mongo.Collection, error) {
+	database := d.client.Database(d.databaseName)
+
+	switch name {
+	case SheetsCollection:
+		return database.Collection(d.collectionName), nil
+	case ArchiveCollection:
+		return database.Collection(d.archiveName), nil
+	case RevisionsCollection:
+		return database.Collection(d.revisionsName), nil
+	}
+
+	return nil, fmt.Errorf("unknown collection %v", name)
+}
+
+//CountDocuments counts the documents of a collection that match the filter.
+//Migrations can take much longer than a request so they are bounded by the context alone
+func (d *CharacterDB) CountDocuments(ctx context.Context, collection string, filter bson.M) (int64, error) {
+	c, err := d.migrationCollection(collection)
+	if err != nil {
+		return 0, err
+	}
+
+	return c.CountDocuments(ctx, filter)
+}
+
+//FindDocuments returns the documents of a collection that match the filter
+func (d *CharacterDB) FindDocuments(ctx context.Context, collection string, filter bson.M) ([]bson.M, error) {
+	c, err := d.migrationCollection(collection)
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := c.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	docs := []bson.M{}
+	for cur.Next(ctx) {
+		doc := bson.M{}
+		err := cur.Decode(&doc)
+		if err != nil {
+			return nil, err
+		}
+
+		docs = append(docs, doc)
+	}
+
+	return docs, cur.Err()
+}
+
+//UpdateDocuments applies the update to every document of a collection that matches the filter and returns how many changed
+func (d *CharacterDB) UpdateDocuments(ctx context.Context, collection string, filter bson.M, update bson.M) (int64, error) {
+	c, err := d.migrationCollection(collection)
+	if err != nil {
+		return 0, err
+	}
+
+	var result *mongo.UpdateResult
+	if _, copies := update["$copy"]; copies {
+		result, err = c.UpdateMany(ctx, filter, updatePipeline(update))
+	} else {
+		result, err = c.UpdateMany(ctx, filter, update)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// updatePipeline turns an update with $copy into an update pipeline, which can refer to the fields of the document.
+// Update pipelines need mongo 4.2
+func updatePipeline(update bson.M) bson.A {
+	set := bson.M{}
+	unset := bson.A{}
+
+	for operator, fields := range update {
+		for path, value := range fields.(bson.M) {
+			switch operator {
+			case "$set":
+				set[path] = bson.M{"$literal": value}
+			case "$copy":
+				set[path] = "$" + value.(string)
+			case "$rename":
+				set[value.(string)] = "$" + path
+				unset = append(unset, path)
+			case "$unset":
+				unset = append(unset, path)
+			}
+		}
+	}
+
+	pipeline := bson.A{}
+	if len(set) > 0 {
+		pipeline = append(pipeline, bson.M{"$set": set})
+	}
+	if len(unset) > 0 {
+		pipeline = append(pipeline, bson.M{"$unset": unset})
+	}
+
+	return pipeline
+}
+
+//AppliedMigrations returns the migrations recorded as applied in version order
+func (d *CharacterDB) AppliedMigrations(ctx context.Context) ([]model.MigrationRecord, error) {
+	migrations := d.client.Database(d.databaseName).Collection(d.migrationsName)
+
+	opts := options.Find().SetSort(bson.D{{Key: "version", Value: 1}})
+	cur, err := migrations.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	records := []model.MigrationRecord{}
+	for cur.Next(ctx) {
+		record := model.MigrationRecord{}
+		err := cur.Decode(&record)
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, record)
+	}
+
+	return records, cur.Err()
+}
+
+//RecordMigration records that a migration was applied
+func (d *CharacterDB) RecordMigration(ctx context.Context, record model.MigrationRecord) error {
+	migrations := d.client.Database(d.databaseName).Collection(d.migrationsName)
+
+	_, err := migrations.InsertOne(ctx, record)
+
+	return err
+}
+
+//RemoveMigration removes the record of an applied migration after it was rolled back
+func (d *CharacterDB) RemoveMigration(ctx context.Context, version int) error {
+	migrations := d.client.Database(d.databaseName).Collection(d.migrationsName)
+
+	_, err := migrations.DeleteMany(ctx, bson.M{"version": version})
+
+	return err
+}
@@ -1,9 +1,12 @@
 package mocks
 
 import (
+	"context"
 	"net/url"
 
 	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/api"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -12,34 +15,220 @@ type MockCharacterDB struct {
 	SheetsToReturn []model.ForceCharacterSheet
 	SheetToReturn  *model.ForceCharacterSheet
 	ErrorToReturn  error
+
+	ArchivedSheetsToReturn []model.ArchivedForceCharacterSheet
+	ArchivedSheetToReturn  *model.ArchivedForceCharacterSheet
+
+	RevisionsToReturn []model.SheetRevision
+	RevisionToReturn  *model.SheetRevision
 }
 
 //GetForceCharacterSheets is the mock implementation for testing
-func (db *MockCharacterDB) GetForceCharacterSheets(query url.Values) ([]model.ForceCharacterSheet, error) {
+func (db *MockCharacterDB) GetForceCharacterSheets(ctx context.Context, query url.Values) ([]model.ForceCharacterSheet, error) {
+	if _, _, _, _, err := api.BuildFilter(query, api.SheetSchema); err != nil {
+		return nil, err
+	}
+
 	return db.SheetsToReturn, db.ErrorToReturn
 }
 
+//CountForceCharacterSheets is the mock implementation for testing
+func (db *MockCharacterDB) CountForceCharacterSheets(ctx context.Context, query url.Values) (int64, error) {
+	return int64(len(db.SheetsToReturn)), db.ErrorToReturn
+}
+
+//SearchForceCharacterSheets is the mock implementation for testing, every sheet to return matches with a score of 1
+func (db *MockCharacterDB) SearchForceCharacterSheets(ctx context.Context, text string, query url.Values) ([]model.SearchResult, int64, error) {
+	if _, _, _, _, err := api.BuildFilter(query, api.SheetSchema); err != nil {
+		return nil, 0, err
+	}
+
+	results := []model.SearchResult{}
+	for _, sheet := range db.SheetsToReturn {
+		results = append(results, model.SearchResult{Sheet: sheet, Score: 1})
+	}
+
+	return results, int64(len(results)), db.ErrorToReturn
+}
+
 //FindForceCharacterSheetByID is the mock implementation for testing
-func (db *MockCharacterDB) FindForceCharacterSheetByID(mongoID primitive.ObjectID) (*model.ForceCharacterSheet, error) {
+func (db *MockCharacterDB) FindForceCharacterSheetByID(ctx context.Context, mongoID primitive.ObjectID, projection api.Projection) (*model.ForceCharacterSheet, error) {
 	return db.SheetToReturn, db.ErrorToReturn
 }
 
-//UpdateForceCharacterSheetByID is the mock implementation for testing
-func (db *MockCharacterDB) UpdateForceCharacterSheetByID(sheet model.ForceCharacterSheet, mongoID primitive.ObjectID) error {
-	return db.ErrorToReturn
+//FindLatestForceCharacterSheet is the mock implementation for testing
+func (db *MockCharacterDB) FindLatestForceCharacterSheet(ctx context.Context, characterName string, playerName string, ignoreCase bool) (*model.ForceCharacterSheet, error) {
+	return db.SheetToReturn, db.ErrorToReturn
+}
+
+//GetForceCharacterSheetVersions is the mock implementation for testing
+func (db *MockCharacterDB) GetForceCharacterSheetVersions(ctx context.Context, characterID primitive.ObjectID) ([]model.ForceCharacterSheet, error) {
+	return db.SheetsToReturn, db.ErrorToReturn
+}
+
+//FindForceCharacterSheetVersion is the mock implementation for testing
+func (db *MockCharacterDB) FindForceCharacterSheetVersion(ctx context.Context, characterID primitive.ObjectID, characterVersion int64) (*model.ForceCharacterSheet, error) {
+	return db.SheetToReturn, db.ErrorToReturn
+}
+
+//LevelUpForceCharacterSheet is the mock implementation for testing, the next version is forked from SheetToReturn
+func (db *MockCharacterDB) LevelUpForceCharacterSheet(ctx context.Context, characterID primitive.ObjectID) (*model.ForceCharacterSheet, error) {
+	if db.ErrorToReturn != nil {
+		return nil, db.ErrorToReturn
+	}
+
+	next := api.LevelUp(*db.SheetToReturn)
+
+	return &next, nil
+}
+
+//UpdateForceCharacterSheetByID is the mock implementation for testing.
+//When SheetToReturn is set the update is compared against its version and increments it like the database does
+func (db *MockCharacterDB) UpdateForceCharacterSheetByID(ctx context.Context, sheet model.ForceCharacterSheet, mongoID primitive.ObjectID) error {
+	if db.ErrorToReturn != nil {
+		return db.ErrorToReturn
+	}
+
+	if db.SheetToReturn != nil {
+		if db.SheetToReturn.Version != sheet.Version {
+			return dberr.VersionConflict(mongoID, sheet.Version, db.SheetToReturn.Version)
+		}
+		sheet.ID = mongoID
+		sheet.Version = db.SheetToReturn.Version + 1
+		*db.SheetToReturn = sheet
+	}
+
+	return nil
+}
+
+//InsertSheetElement is the mock implementation for testing, the element is appended to SheetToReturn
+func (db *MockCharacterDB) InsertSheetElement(ctx context.Context, mongoID primitive.ObjectID, array api.SheetArray, element interface{}, expected int64) (*model.ForceCharacterSheet, error) {
+	if err := db.checkVersion(mongoID, expected); err != nil {
+		return nil, err
+	}
+
+	array.Append(db.SheetToReturn, element)
+	db.SheetToReturn.Version++
+
+	return db.SheetToReturn, nil
+}
+
+//UpdateSheetElement is the mock implementation for testing, the element of SheetToReturn with the ID is replaced
+func (db *MockCharacterDB) UpdateSheetElement(ctx context.Context, mongoID primitive.ObjectID, array api.SheetArray, elementID primitive.ObjectID, element interface{}, expected int64) (*model.ForceCharacterSheet, error) {
+	if err := db.checkVersion(mongoID, expected); err != nil {
+		return nil, err
+	}
+
+	if !array.Replace(db.SheetToReturn, elementID, element) {
+		return nil, dberr.ElementNotFound(mongoID, array.Name, elementID)
+	}
+	db.SheetToReturn.Version++
+
+	return db.SheetToReturn, nil
+}
+
+//DeleteSheetElement is the mock implementation for testing, the element of SheetToReturn with the ID is removed
+func (db *MockCharacterDB) DeleteSheetElement(ctx context.Context, mongoID primitive.ObjectID, array api.SheetArray, elementID primitive.ObjectID, expected int64) (*model.ForceCharacterSheet, error) {
+	if err := db.checkVersion(mongoID, expected); err != nil {
+		return nil, err
+	}
+
+	if !array.Remove(db.SheetToReturn, elementID) {
+		return nil, dberr.ElementNotFound(mongoID, array.Name, elementID)
+	}
+	db.SheetToReturn.Version++
+
+	return db.SheetToReturn, nil
+}
+
+// checkVersion returns ErrorToReturn, or a version conflict when an expected version isn't that of SheetToReturn
+func (db *MockCharacterDB) checkVersion(mongoID primitive.ObjectID, expected int64) error {
+	if db.ErrorToReturn != nil {
+		return db.ErrorToReturn
+	}
+	if expected != 0 && db.SheetToReturn.Version != expected {
+		return dberr.VersionConflict(mongoID, expected, db.SheetToReturn.Version)
+	}
+
+	return nil
+}
+
+//ApplyHealthAction is the mock implementation for testing, the action is applied to SheetToReturn
+func (db *MockCharacterDB) ApplyHealthAction(ctx context.Context, mongoID primitive.ObjectID, action api.HealthAction, amount int64, expected int64) (*model.ForceCharacterSheet, error) {
+	if err := db.checkVersion(mongoID, expected); err != nil {
+		return nil, err
+	}
+
+	if action.Apply(db.SheetToReturn, amount) {
+		db.SheetToReturn.Version++
+	}
+
+	return db.SheetToReturn, nil
 }
 
 //InsertForceCharacterSheet is the mock implementation for testing
-func (db *MockCharacterDB) InsertForceCharacterSheet(sheet model.ForceCharacterSheet) error {
+func (db *MockCharacterDB) InsertForceCharacterSheet(ctx context.Context, sheet model.ForceCharacterSheet) error {
 	return db.ErrorToReturn
 }
 
 //DeleteForceCharacterSheetByID is the mock implementation for testing
-func (db *MockCharacterDB) DeleteForceCharacterSheetByID(mongoID primitive.ObjectID) error {
+func (db *MockCharacterDB) DeleteForceCharacterSheetByID(ctx context.Context, mongoID primitive.ObjectID, deletedBy string) error {
+	return db.ErrorToReturn
+}
+
+//GetArchivedForceCharacterSheets is the mock implementation for testing
+func (db *MockCharacterDB) GetArchivedForceCharacterSheets(ctx context.Context, query url.Values) ([]model.ArchivedForceCharacterSheet, error) {
+	return db.ArchivedSheetsToReturn, db.ErrorToReturn
+}
+
+//FindArchivedForceCharacterSheetByID is the mock implementation for testing
+func (db *MockCharacterDB) FindArchivedForceCharacterSheetByID(ctx context.Context, mongoID primitive.ObjectID) (*model.ArchivedForceCharacterSheet, error) {
+	return db.ArchivedSheetToReturn, db.ErrorToReturn
+}
+
+//InsertArchivedForceCharacterSheet is the mock implementation for testing
+func (db *MockCharacterDB) InsertArchivedForceCharacterSheet(ctx context.Context, sheet model.ArchivedForceCharacterSheet) error {
 	return db.ErrorToReturn
 }
 
+//RestoreForceCharacterSheetByID is the mock implementation for testing
+func (db *MockCharacterDB) RestoreForceCharacterSheetByID(ctx context.Context, mongoID primitive.ObjectID) error {
+	return db.ErrorToReturn
+}
+
+//PurgeArchivedForceCharacterSheetByID is the mock implementation for testing
+func (db *MockCharacterDB) PurgeArchivedForceCharacterSheetByID(ctx context.Context, mongoID primitive.ObjectID) error {
+	return db.ErrorToReturn
+}
+
+//RunInTransaction is the mock implementation for testing, it runs fn without a transaction
+func (db *MockCharacterDB) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
 //Ping is the mock implementation for testing
-func (db *MockCharacterDB) Ping() error {
+func (db *MockCharacterDB) Ping(ctx context.Context) error {
+	return db.ErrorToReturn
+}
+
+//GetForceCharacterSheetRevisions is the mock implementation for testing
+func (db *MockCharacterDB) GetForceCharacterSheetRevisions(ctx context.Context, mongoID primitive.ObjectID) ([]model.SheetRevision, error) {
+	return db.RevisionsToReturn, db.ErrorToReturn
+}
+
+//FindForceCharacterSheetRevision is the mock implementation for testing.
+//It returns the matching version from RevisionsToReturn, falling back to RevisionToReturn
+func (db *MockCharacterDB) FindForceCharacterSheetRevision(ctx context.Context, mongoID primitive.ObjectID, version int64) (*model.SheetRevision, error) {
+	for i := range db.RevisionsToReturn {
+		if db.RevisionsToReturn[i].Version == version {
+			return &db.RevisionsToReturn[i], db.ErrorToReturn
+		}
+	}
+
+	return db.RevisionToReturn, db.ErrorToReturn
+}
+
+//RevertForceCharacterSheetByID is the mock implementation for testing
+func (db *MockCharacterDB) RevertForceCharacterSheetByID(ctx context.Context, mongoID primitive.ObjectID, version int64) error {
 	return db.ErrorToReturn
 }
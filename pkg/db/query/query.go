@@ -0,0 +1,490 @@
+// Package query evaluates mongo style filters and sorts against documents held in memory,
+// so that backends without mongo honour the same query parameters as the mongo backend
+package query
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ToDocument converts a value into its bson document form so that it can be matched and sorted
+func ToDocument(value interface{}) (bson.M, error) {
+	data, err := bson.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := bson.M{}
+	err = bson.Unmarshal(data, &doc)
+
+	return doc, err
+}
+
+// Match reports whether the document satisfies the filter. A nil or empty filter matches every document.
+// As in mongo a field the document doesn't have equals null, so {field: null} and {field: {$in: [null]}} match it
+func Match(doc bson.M, filter bson.M) (bool, error) {
+	for key, condition := range filter {
+		var matched bool
+		var err error
+
+		switch key {
+		case "$and":
+			matched, err = matchAll(doc, condition, true)
+		case "$or":
+			matched, err = matchAll(doc, condition, false)
+		default:
+			matched, err = matchField(fieldValues(doc, key), condition)
+		}
+
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+func matchAll(doc bson.M, conditions interface{}, all bool) (bool, error) {
+	filters, err := toFilters(conditions)
+	if err != nil {
+		return false, err
+	}
+
+	for _, filter := range filters {
+		matched, err := Match(doc, filter)
+		if err != nil {
+			return false, err
+		}
+		if matched != all {
+			return !all, nil
+		}
+	}
+
+	return all, nil
+}
+
+func toFilters(conditions interface{}) ([]bson.M, error) {
+	switch c := conditions.(type) {
+	case []bson.M:
+		return c, nil
+	case []interface{}:
+		filters := []bson.M{}
+		for _, condition := range c {
+			filter, ok := asMap(condition)
+			if !ok {
+				return nil, dberr.New(dberr.ErrValidation, "invalid_query", "invalid query: expected a document but got %T", condition)
+			}
+			filters = append(filters, filter)
+		}
+		return filters, nil
+	case bson.A:
+		return toFilters([]interface{}(c))
+	}
+
+	return nil, dberr.New(dberr.ErrValidation, "invalid_query", "invalid query: expected an array of documents but got %T", conditions)
+}
+
+// matchField checks the candidate values of a field against either a literal or an operator document
+func matchField(values []interface{}, condition interface{}) (bool, error) {
+	operators, ok := asMap(condition)
+	if !ok || !isOperatorDocument(operators) {
+		return matchAny(values, func(value interface{}) bool { return Compare(value, condition) == 0 && comparable(value, condition) }), nil
+	}
+
+	for operator, operand := range operators {
+		matched, err := matchOperator(values, operator, operand, operators)
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+func matchOperator(values []interface{}, operator string, operand interface{}, operators bson.M) (bool, error) {
+	switch operator {
+	case "$eq":
+		return matchField(values, operand)
+	case "$ne":
+		matched, err := matchField(values, operand)
+		return !matched, err
+	case "$gt":
+		return matchAny(values, func(v interface{}) bool { return comparable(v, operand) && Compare(v, operand) > 0 }), nil
+	case "$gte":
+		return matchAny(values, func(v interface{}) bool { return comparable(v, operand) && Compare(v, operand) >= 0 }), nil
+	case "$lt":
+		return matchAny(values, func(v interface{}) bool { return comparable(v, operand) && Compare(v, operand) < 0 }), nil
+	case "$lte":
+		return matchAny(values, func(v interface{}) bool { return comparable(v, operand) && Compare(v, operand) <= 0 }), nil
+	case "$in", "$nin":
+		list, ok := asSlice(operand)
+		if !ok {
+			return false, dberr.New(dberr.ErrValidation, "invalid_query", "invalid query: %v requires an array", operator)
+		}
+		matched := false
+		for _, candidate := range list {
+			if m, _ := matchField(values, candidate); m {
+				matched = true
+				break
+			}
+		}
+		return matched == (operator == "$in"), nil
+	case "$exists":
+		exists, _ := operand.(bool)
+		return matchAny(values, func(v interface{}) bool { return v != missing{} }) == exists, nil
+	case "$regex":
+		pattern, err := buildRegex(operand, operators["$options"])
+		if err != nil {
+			return false, err
+		}
+		return matchAny(values, func(v interface{}) bool {
+			s, ok := v.(string)
+			return ok && pattern.MatchString(s)
+		}), nil
+	case "$options":
+		return true, nil
+	case "$elemMatch":
+		filter, ok := asMap(operand)
+		if !ok {
+			return false, dberr.New(dberr.ErrValidation, "invalid_query", "invalid query: $elemMatch requires a document")
+		}
+		for _, value := range values {
+			doc, ok := asMap(value)
+			if !ok {
+				continue
+			}
+			matched, err := Match(doc, filter)
+			if err != nil {
+				return false, err
+			}
+			if matched {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	return false, dberr.New(dberr.ErrValidation, "invalid_query", "invalid query: unsupported operator %v", operator)
+}
+
+func buildRegex(pattern interface{}, options interface{}) (*regexp.Regexp, error) {
+	var expression string
+	switch p := pattern.(type) {
+	case string:
+		expression = p
+	case primitive.Regex:
+		expression = p.Pattern
+		if options == nil {
+			options = p.Options
+		}
+	default:
+		return nil, dberr.New(dberr.ErrValidation, "invalid_query", "invalid query: $regex requires a string")
+	}
+
+	if flags, ok := options.(string); ok && strings.Contains(flags, "i") {
+		expression = "(?i)" + expression
+	}
+
+	return regexp.Compile(expression)
+}
+
+func matchAny(values []interface{}, predicate func(interface{}) bool) bool {
+	for _, value := range values {
+		if predicate(value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isOperatorDocument(doc bson.M) bool {
+	if len(doc) == 0 {
+		return false
+	}
+
+	for key := range doc {
+		if !strings.HasPrefix(key, "$") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// missing stands in for a field a document doesn't have, it compares equal to null but doesn't exist
+type missing struct{}
+
+// fieldValues returns the values Lookup finds at a dotted path, along with missing when the document, or any document in an array on the path, doesn't have it
+func fieldValues(doc bson.M, path string) []interface{} {
+	parts := strings.Split(path, ".")
+	values := lookup(doc, parts)
+	if absent(doc, parts) {
+		values = append(values, missing{})
+	}
+
+	return values
+}
+
+func absent(value interface{}, parts []string) bool {
+	if len(parts) == 0 {
+		return false
+	}
+
+	if doc, ok := asMap(value); ok {
+		child, found := doc[parts[0]]
+		return !found || absent(child, parts[1:])
+	}
+
+	if list, ok := asSlice(value); ok {
+		for _, elem := range list {
+			if _, ok := asMap(elem); ok && absent(elem, parts) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// Lookup returns every value found at a dotted path, descending into arrays the same way mongo does.
+// Arrays found at the end of the path are returned along with each of their elements
+func Lookup(doc bson.M, path string) []interface{} {
+	return lookup(doc, strings.Split(path, "."))
+}
+
+func lookup(value interface{}, parts []string) []interface{} {
+	if len(parts) == 0 {
+		if list, ok := asSlice(value); ok {
+			return append([]interface{}{value}, list...)
+		}
+		return []interface{}{value}
+	}
+
+	if doc, ok := asMap(value); ok {
+		child, found := doc[parts[0]]
+		if !found {
+			return nil
+		}
+		return lookup(child, parts[1:])
+	}
+
+	if list, ok := asSlice(value); ok {
+		values := []interface{}{}
+		for _, elem := range list {
+			values = append(values, lookup(elem, parts)...)
+		}
+		return values
+	}
+
+	return nil
+}
+
+// Sort orders documents by the given keys, a value of -1 sorts that key descending
+func Sort(docs []bson.M, keys bson.D) {
+	sort.SliceStable(docs, func(i, j int) bool {
+		for _, key := range keys {
+			a := first(Lookup(docs[i], key.Key))
+			b := first(Lookup(docs[j], key.Key))
+			c := Compare(a, b)
+			if c == 0 {
+				continue
+			}
+			if direction(key.Value) < 0 {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+}
+
+// Page returns the documents for the given skip and limit, a limit of zero or less returns everything after skip
+func Page(docs []bson.M, skip int, limit int) []bson.M {
+	if skip >= len(docs) {
+		return []bson.M{}
+	}
+	if skip < 0 {
+		skip = 0
+	}
+
+	docs = docs[skip:]
+	if limit > 0 && limit < len(docs) {
+		docs = docs[:limit]
+	}
+
+	return docs
+}
+
+func first(values []interface{}) interface{} {
+	if len(values) == 0 {
+		return nil
+	}
+
+	return values[0]
+}
+
+func direction(value interface{}) int {
+	switch v := value.(type) {
+	case int:
+		return v
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+
+	return 1
+}
+
+// typeOrder follows the mongo comparison order between types so mixed values sort predictably
+func typeOrder(value interface{}) int {
+	if _, ok := toFloat(value); ok {
+		return 2
+	}
+
+	switch value.(type) {
+	case nil, missing:
+		return 0
+	case string:
+		return 3
+	case bson.M, primitive.D, map[string]interface{}:
+		return 4
+	case primitive.A, []interface{}:
+		return 5
+	case primitive.ObjectID:
+		return 7
+	case bool:
+		return 8
+	case time.Time, primitive.DateTime:
+		return 9
+	}
+
+	return 10
+}
+
+func comparable(a interface{}, b interface{}) bool {
+	return typeOrder(a) == typeOrder(b)
+}
+
+// Compare orders two values, numbers of any width compare by value
+func Compare(a interface{}, b interface{}) int {
+	orderA, orderB := typeOrder(a), typeOrder(b)
+	if orderA != orderB {
+		return orderA - orderB
+	}
+
+	if x, ok := toFloat(a); ok {
+		y, _ := toFloat(b)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+
+	switch x := a.(type) {
+	case nil, missing:
+		return 0
+	case string:
+		return strings.Compare(x, b.(string))
+	case bool:
+		y := b.(bool)
+		if x == y {
+			return 0
+		}
+		if !x {
+			return -1
+		}
+		return 1
+	case primitive.ObjectID:
+		y := b.(primitive.ObjectID)
+		return bytes.Compare(x[:], y[:])
+	case time.Time, primitive.DateTime:
+		return compareTimes(toTime(a), toTime(b))
+	}
+
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func compareTimes(a time.Time, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+
+	return 0
+}
+
+func toTime(value interface{}) time.Time {
+	switch v := value.(type) {
+	case time.Time:
+		return v
+	case primitive.DateTime:
+		return v.Time()
+	}
+
+	return time.Time{}
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+
+	return 0, false
+}
+
+func asMap(value interface{}) (bson.M, bool) {
+	switch v := value.(type) {
+	case bson.M:
+		return v, true
+	case map[string]interface{}:
+		return bson.M(v), true
+	case primitive.D:
+		return v.Map(), true
+	}
+
+	return nil, false
+}
+
+func asSlice(value interface{}) ([]interface{}, bool) {
+	switch v := value.(type) {
+	case primitive.A:
+		return []interface{}(v), true
+	case []interface{}:
+		return v, true
+	case []string:
+		list := []interface{}{}
+		for _, s := range v {
+			list = append(list, s)
+		}
+		return list, true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,245 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type testSkill struct {
+	Name  string `bson:"name"`
+	Level int64  `bson:"level"`
+}
+
+type testSheet struct {
+	Name   string      `bson:"characterName"`
+	XP     int64       `bson:"availableXP"`
+	Skills []testSkill `bson:"skills"`
+}
+
+func testDocument(t *testing.T, sheet testSheet) bson.M {
+	doc, err := ToDocument(sheet)
+	if err != nil {
+		t.Fatalf("ToDocument() error:\n   expected: <nil>\n   got:      %v", err)
+	}
+
+	return doc
+}
+
+func TestMatch(t *testing.T) {
+	doc := testDocument(t, testSheet{
+		Name:   "Mando",
+		XP:     25,
+		Skills: []testSkill{{Name: "athletics", Level: 2}, {Name: "cool", Level: 1}},
+	})
+
+	tests := []struct {
+		name     string
+		filter   bson.M
+		expected bool
+	}{
+		{"nil filter", nil, true},
+		{"equal", bson.M{"characterName": "Mando"}, true},
+		{"not equal", bson.M{"characterName": "Grogu"}, false},
+		{"and", bson.M{"$and": []bson.M{{"characterName": "Mando"}, {"availableXP": int64(25)}}}, true},
+		{"number widths", bson.M{"availableXP": 25}, true},
+		{"string does not match number", bson.M{"availableXP": "25"}, false},
+		{"array element", bson.M{"skills.name": "cool"}, true},
+		{"gte", bson.M{"skills.level": bson.M{"$gte": 2}}, true},
+		{"lt", bson.M{"availableXP": bson.M{"$lt": 10}}, false},
+		{"in", bson.M{"characterName": bson.M{"$in": []interface{}{"Grogu", "Mando"}}}, true},
+		{"nin", bson.M{"characterName": bson.M{"$nin": []interface{}{"Mando"}}}, false},
+		{"regex", bson.M{"characterName": bson.M{"$regex": "^man", "$options": "i"}}, true},
+		{"exists", bson.M{"priority": bson.M{"$exists": false}}, true},
+		{"elemMatch", bson.M{"skills": bson.M{"$elemMatch": bson.M{"name": "cool", "level": bson.M{"$gt": 1}}}}, false},
+	}
+
+	for _, test := range tests {
+		matched, err := Match(doc, test.filter)
+		if err != nil {
+			t.Errorf("Match() %v error:\n   expected: <nil>\n   got:      %v", test.name, err)
+		}
+		if matched != test.expected {
+			t.Errorf("Match() %v error:\n   expected: %v\n   got:      %v", test.name, test.expected, matched)
+		}
+	}
+}
+
+// nullDocuments are the documents the null filters are checked against, keyed by _id
+var nullDocuments = []bson.M{
+	{"_id": 1, "characterName": "Mando"},
+	{"_id": 2, "characterName": "Grogu", "priority": nil},
+	{"_id": 3, "characterName": "Cara", "priority": 2},
+	{"_id": 4, "characterName": "Din", "skills": bson.A{bson.M{"name": "cool"}, bson.M{"level": 1}}},
+}
+
+// nullFilters are filters on null with the _ids mongo returns for them from nullDocuments
+var nullFilters = []struct {
+	name     string
+	filter   bson.M
+	expected []int
+}{
+	{"null", bson.M{"priority": nil}, []int{1, 2, 4}},
+	{"eq null", bson.M{"priority": bson.M{"$eq": nil}}, []int{1, 2, 4}},
+	{"ne null", bson.M{"priority": bson.M{"$ne": nil}}, []int{3}},
+	{"in null", bson.M{"priority": bson.M{"$in": bson.A{nil, 2}}}, []int{1, 2, 3, 4}},
+	{"nin null", bson.M{"priority": bson.M{"$nin": bson.A{nil}}}, []int{3}},
+	{"exists", bson.M{"priority": bson.M{"$exists": true}}, []int{2, 3}},
+	{"array element null", bson.M{"skills.name": nil}, []int{1, 2, 3, 4}},
+	{"array element", bson.M{"skills.name": "cool"}, []int{4}},
+	{"array element ne null", bson.M{"skills.name": bson.M{"$ne": nil}}, []int{}},
+}
+
+func TestMatch_Null(t *testing.T) {
+	for _, test := range nullFilters {
+		matched := []int{}
+		for _, doc := range nullDocuments {
+			ok, err := Match(doc, test.filter)
+			if err != nil {
+				t.Errorf("Match() %v error:\n   expected: <nil>\n   got:      %v", test.name, err)
+			}
+			if ok {
+				matched = append(matched, doc["_id"].(int))
+			}
+		}
+
+		if fmt.Sprint(matched) != fmt.Sprint(test.expected) {
+			t.Errorf("Match() %v error:\n   expected: %v\n   got:      %v", test.name, test.expected, matched)
+		}
+	}
+}
+
+// TestMatch_NullMongo runs the null filters against a mongo server, when MONGO_TEST_URI names one, to check they match the same documents as Match
+func TestMatch_NullMongo(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("Connect() error:\n   expected: <nil>\n   got:      %v", err)
+	}
+	defer client.Disconnect(ctx)
+
+	collection := client.Database("sheet_crud_test").Collection("null_" + primitive.NewObjectID().Hex())
+	defer collection.Drop(ctx)
+
+	documents := []interface{}{}
+	for _, doc := range nullDocuments {
+		documents = append(documents, doc)
+	}
+	_, err = collection.InsertMany(ctx, documents)
+	if err != nil {
+		t.Fatalf("InsertMany() error:\n   expected: <nil>\n   got:      %v", err)
+	}
+
+	for _, test := range nullFilters {
+		cursor, err := collection.Find(ctx, test.filter, options.Find().SetSort(bson.M{"_id": 1}))
+		if err != nil {
+			t.Fatalf("Find() %v error:\n   expected: <nil>\n   got:      %v", test.name, err)
+		}
+
+		found := []struct {
+			ID int `bson:"_id"`
+		}{}
+		err = cursor.All(ctx, &found)
+		if err != nil {
+			t.Fatalf("All() %v error:\n   expected: <nil>\n   got:      %v", test.name, err)
+		}
+
+		matched := []int{}
+		for _, doc := range found {
+			matched = append(matched, doc.ID)
+		}
+		if fmt.Sprint(matched) != fmt.Sprint(test.expected) {
+			t.Errorf("Find() %v error:\n   expected: %v\n   got:      %v", test.name, test.expected, matched)
+		}
+	}
+}
+
+func TestMatch_UnsupportedOperator(t *testing.T) {
+	doc := testDocument(t, testSheet{Name: "Mando"})
+
+	_, err := Match(doc, bson.M{"characterName": bson.M{"$where": "true"}})
+	if err == nil {
+		t.Errorf("Match() error:\n   expected: <error>\n   got:      <nil>")
+	}
+}
+
+func TestSortAndPage(t *testing.T) {
+	docs := []bson.M{
+		testDocument(t, testSheet{Name: "b", XP: 10}),
+		testDocument(t, testSheet{Name: "a", XP: 10}),
+		testDocument(t, testSheet{Name: "c", XP: 30}),
+	}
+
+	Sort(docs, bson.D{{Key: "availableXP", Value: -1}, {Key: "characterName", Value: 1}})
+
+	names := ""
+	for _, doc := range docs {
+		names += doc["characterName"].(string)
+	}
+	if names != "cab" {
+		t.Errorf("Sort() error:\n   expected: cab\n   got:      %v", names)
+	}
+
+	page := Page(docs, 1, 1)
+	if len(page) != 1 || page[0]["characterName"] != "a" {
+		t.Errorf("Page() error:\n   expected: [a]\n   got:      %v", page)
+	}
+
+	if page := Page(docs, 5, 1); len(page) != 0 {
+		t.Errorf("Page() error:\n   expected: []\n   got:      %v", page)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	doc := bson.M{
+		"characterName": "Mando",
+		"wound":         bson.M{"threshold": 15, "current": 2},
+		"morality":      bson.M{"emotionalWeekness": "coldness"},
+	}
+
+	changed, err := Update(doc, bson.M{
+		"$rename": bson.M{"wound": "wounds", "morality.emotionalWeekness": "morality.emotionalWeakness"},
+		"$set":    bson.M{"career.name": "Bounty Hunter"},
+		"$unset":  bson.M{"missing": ""},
+	})
+	if err != nil || !changed {
+		t.Errorf("Update() error:\n   expected: changed <nil>\n   got:      %v %v", changed, err)
+	}
+
+	expected := "map[career:map[name:Bounty Hunter] characterName:Mando morality:map[emotionalWeakness:coldness] wounds:map[current:2 threshold:15]]"
+	if fmt.Sprint(doc) != expected {
+		t.Errorf("Update() error:\n   expected: %v\n   got:      %v", expected, doc)
+	}
+
+	changed, err = Update(doc, bson.M{"$rename": bson.M{"wound": "wounds"}, "$set": bson.M{"characterName": "Mando"}})
+	if err != nil || changed {
+		t.Errorf("Update() unchanged error:\n   expected: unchanged <nil>\n   got:      %v %v", changed, err)
+	}
+
+	changed, err = Update(doc, bson.M{"$copy": bson.M{"nickname": "characterName", "other": "missing"}})
+	if err != nil || !changed || doc["nickname"] != "Mando" || doc["other"] != nil {
+		t.Errorf("Update() copy error:\n   expected: nickname Mando and no other\n   got:      %v %v %v", changed, err, doc)
+	}
+
+	if _, err := Update(doc, bson.M{"$inc": bson.M{"availableXP": 5}}); err == nil {
+		t.Errorf("Update() unsupported operator error:\n   expected: <error>\n   got:      <nil>")
+	}
+
+	if _, err := Update(doc, bson.M{"$set": bson.M{"characterName.first": "Din"}}); err == nil {
+		t.Errorf("Update() through a value error:\n   expected: <error>\n   got:      <nil>")
+	}
+}
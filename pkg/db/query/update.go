@@ -0,0 +1,139 @@
+package query
+
+import (
+	"strings"
+
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+//Update applies a mongo style update made of $set, $unset and $rename operators to the document in place
+//and reports whether the document changed. Paths may be dotted but can't descend into arrays.
+//$copy sets each field to the value of another field of the same document, like a $set stage of a mongo update pipeline
+//that refers to a field, a missing field is not copied
+func Update(doc bson.M, update bson.M) (bool, error) {
+	changed := false
+
+	for operator, fields := range update {
+		values, ok := asMap(fields)
+		if !ok {
+			return false, dberr.New(dberr.ErrValidation, "invalid_update", "invalid update: %v needs a document of fields", operator)
+		}
+
+		for path, value := range values {
+			var didChange bool
+			var err error
+
+			switch operator {
+			case "$set":
+				didChange, err = setPath(doc, path, value)
+			case "$unset":
+				_, didChange, err = unsetPath(doc, path)
+			case "$rename":
+				to, ok := value.(string)
+				if !ok {
+					return false, dberr.New(dberr.ErrValidation, "invalid_update", "invalid update: $rename of %v needs a field name", path)
+				}
+				didChange, err = renamePath(doc, path, to)
+			case "$copy":
+				from, ok := value.(string)
+				if !ok {
+					return false, dberr.New(dberr.ErrValidation, "invalid_update", "invalid update: $copy to %v needs a field name", path)
+				}
+				didChange, err = copyPath(doc, from, path)
+			default:
+				return false, dberr.New(dberr.ErrValidation, "invalid_update", "invalid update: unsupported operator %v", operator)
+			}
+
+			if err != nil {
+				return false, err
+			}
+			changed = changed || didChange
+		}
+	}
+
+	return changed, nil
+}
+
+func setPath(doc bson.M, path string, value interface{}) (bool, error) {
+	parent, key, err := parentOf(doc, path, true)
+	if err != nil {
+		return false, err
+	}
+
+	if current, found := parent[key]; found && Compare(current, value) == 0 {
+		return false, nil
+	}
+
+	parent[key] = value
+
+	return true, nil
+}
+
+func unsetPath(doc bson.M, path string) (interface{}, bool, error) {
+	parent, key, err := parentOf(doc, path, false)
+	if err != nil || parent == nil {
+		return nil, false, err
+	}
+
+	value, found := parent[key]
+	if !found {
+		return nil, false, nil
+	}
+	delete(parent, key)
+
+	return value, true, nil
+}
+
+func renamePath(doc bson.M, from string, to string) (bool, error) {
+	value, found, err := unsetPath(doc, from)
+	if err != nil || !found {
+		return false, err
+	}
+
+	_, err = setPath(doc, to, value)
+
+	return true, err
+}
+
+func copyPath(doc bson.M, from string, to string) (bool, error) {
+	parent, key, err := parentOf(doc, from, false)
+	if err != nil || parent == nil {
+		return false, err
+	}
+
+	value, found := parent[key]
+	if !found {
+		return false, nil
+	}
+
+	return setPath(doc, to, value)
+}
+
+// parentOf returns the document holding the last field of the path, creating the documents on the way when create is set
+func parentOf(doc bson.M, path string, create bool) (bson.M, string, error) {
+	parts := strings.Split(path, ".")
+	current := doc
+
+	for _, part := range parts[:len(parts)-1] {
+		child, found := current[part]
+		if !found {
+			if !create {
+				return nil, "", nil
+			}
+			next := bson.M{}
+			current[part] = next
+			current = next
+			continue
+		}
+
+		next, ok := asMap(child)
+		if !ok {
+			return nil, "", dberr.New(dberr.ErrValidation, "invalid_update", "invalid update: %v is not a document at %v", path, part)
+		}
+		current[part] = next
+		current = next
+	}
+
+	return current, parts[len(parts)-1], nil
+}
@@ -0,0 +1,122 @@
+package db
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
+	"github.com/sirupsen/logrus"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// insertRevision stores an immutable snapshot of the sheet as it is after a change
+func (d *CharacterDB) insertRevision(ctx context.Context, sheet model.ForceCharacterSheet, summary string) error {
+	revisions := d.client.Database(d.databaseName).Collection(d.revisionsName)
+
+	revision := model.SheetRevision{
+		ID:        primitive.NewObjectID(),
+		SheetID:   sheet.ID,
+		Version:   sheet.Version,
+		Timestamp: time.Now().UTC(),
+		Summary:   summary,
+		Sheet:     &sheet,
+	}
+
+	_, err := revisions.InsertOne(ctx, revision)
+
+	return translate(err)
+}
+
+//GetForceCharacterSheetRevisions returns the revision history of a specific force character sheet without the sheet snapshots
+func (d *CharacterDB) GetForceCharacterSheetRevisions(ctx context.Context, mongoID primitive.ObjectID) ([]model.SheetRevision, error) {
+	logrus.Debugf("BEGIN - GetForceCharacterSheetRevisions: %v", mongoID)
+
+	ctx, cancel := withTimeout(ctx, d.readTimeout)
+	defer cancel()
+
+	revisions := d.client.Database(d.databaseName).Collection(d.revisionsName)
+
+	opts := options.Find().
+		SetMaxTime(30 * time.Second).
+		SetProjection(bson.M{"sheet": 0}).
+		SetSort(bson.D{{
+			Key:   "version",
+			Value: 1,
+		}})
+
+	cur, err := revisions.Find(ctx, bson.M{"sheetId": mongoID}, opts)
+	if err != nil {
+		return nil, translate(err)
+	}
+	defer cur.Close(ctx)
+
+	matches := []model.SheetRevision{}
+
+	for cur.Next(ctx) {
+		elem := model.SheetRevision{}
+		err := cur.Decode(&elem)
+		if err != nil {
+			return nil, translate(err)
+		}
+
+		matches = append(matches, elem)
+	}
+
+	return matches, translate(cur.Err())
+}
+
+//FindForceCharacterSheetRevision finds a specific revision of a force character sheet by version
+func (d *CharacterDB) FindForceCharacterSheetRevision(ctx context.Context, mongoID primitive.ObjectID, version int64) (*model.SheetRevision, error) {
+	logrus.Debugf("BEGIN - FindForceCharacterSheetRevision: %v version %v", mongoID, version)
+
+	ctx, cancel := withTimeout(ctx, d.readTimeout)
+	defer cancel()
+
+	revisions := d.client.Database(d.databaseName).Collection(d.revisionsName)
+
+	revision := model.SheetRevision{}
+
+	err := revisions.FindOne(ctx, bson.M{"sheetId": mongoID, "version": version}).Decode(&revision)
+	if err == mongo.ErrNoDocuments {
+		return nil, dberr.RevisionNotFound(mongoID, version)
+	}
+	if err != nil {
+		return nil, translate(err)
+	}
+
+	return &revision, nil
+}
+
+//RevertForceCharacterSheetByID restores a force character sheet to the contents of an earlier revision,
+//the revert is stored as a new revision
+func (d *CharacterDB) RevertForceCharacterSheetByID(ctx context.Context, mongoID primitive.ObjectID, version int64) error {
+	logrus.Debugf("BEGIN - RevertForceCharacterSheetByID: %v to version %v", mongoID, version)
+
+	ctx, cancel := withTimeout(ctx, d.writeTimeout)
+	defer cancel()
+
+	revision, err := d.FindForceCharacterSheetRevision(ctx, mongoID, version)
+	if err != nil {
+		return translate(err)
+	}
+
+	current, err := d.FindForceCharacterSheetByID(ctx, mongoID, nil)
+	if err != nil {
+		return translate(err)
+	}
+
+	if revision.Version == current.Version {
+		return dberr.NoChange(mongoID, version)
+	}
+
+	sheet := *revision.Sheet
+	sheet.Version = current.Version
+
+	return d.updateForceCharacterSheet(ctx, sheet, mongoID, "reverted to version "+strconv.FormatInt(version, 10))
+}
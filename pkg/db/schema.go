@@ -0,0 +1,232 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/geeksheik9/sheet-CRUD/config"
+	"github.com/geeksheik9/sheet-CRUD/pkg/search"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// namespaceNotFound is the mongo error code for a collection that doesn't exist yet
+const namespaceNotFound = 26
+
+// TextIndex is the name of the text index full text search runs on
+const TextIndex = "sheet_text"
+
+// IdentityIndex is the name of the index on the logical identity of a character, its player and character name, ordered by character version and edits.
+// It isn't unique, a character keeps a sheet for each of its versions
+const IdentityIndex = "character_identity"
+
+// LineageIndex is the name of the unique index on the sheets of a character, its character ID and character version
+const LineageIndex = "character_lineage"
+
+//SchemaReport describes what EnsureSchema changed on the sheet collection
+type SchemaReport struct {
+	CreatedIndexes  []string `json:"createdIndexes"`
+	ExistingIndexes []string `json:"existingIndexes"`
+	Validator       string   `json:"validator"`
+	Failures        []string `json:"failures"`
+}
+
+// String summarises the report for the startup log
+func (r SchemaReport) String() string {
+	summary := fmt.Sprintf("created indexes [%v], existing indexes [%v], validator %v",
+		strings.Join(r.CreatedIndexes, ", "), strings.Join(r.ExistingIndexes, ", "), r.Validator)
+	if len(r.Failures) > 0 {
+		summary += fmt.Sprintf(", failures [%v]", strings.Join(r.Failures, "; "))
+	}
+
+	return summary
+}
+
+//SheetIndexes returns the indexes the sheet collection should have under the schema configuration
+func SheetIndexes(schema config.SchemaConfig) []mongo.IndexModel {
+	indexes := []mongo.IndexModel{}
+
+	if schema.EnsureIndexes {
+		indexes = append(indexes,
+			mongo.IndexModel{
+				Keys:    bson.D{{Key: "playerName", Value: 1}},
+				Options: options.Index().SetName("playerName_1"),
+			},
+			mongo.IndexModel{
+				Keys:    bson.D{{Key: "characterName", Value: 1}},
+				Options: options.Index().SetName("characterName_1"),
+			},
+			textIndex(),
+			mongo.IndexModel{
+				Keys: bson.D{
+					{Key: "playerName", Value: 1}, {Key: "characterName", Value: 1}, {Key: "characterVersion", Value: -1}, {Key: "version", Value: -1},
+				},
+				Options: options.Index().SetName(IdentityIndex),
+			},
+		)
+	}
+
+	if schema.EnsureUniqueIdentity {
+		// sheets from before lineages have neither field until the lineage migration has run, so they are left out of the lineage index
+		indexes = append(indexes, mongo.IndexModel{
+			Keys: bson.D{{Key: "characterId", Value: 1}, {Key: "characterVersion", Value: 1}},
+			Options: options.Index().SetName(LineageIndex).SetUnique(true).
+				SetPartialFilterExpression(bson.M{"characterId": bson.M{"$exists": true}}),
+		})
+	}
+
+	return indexes
+}
+
+// textIndex indexes the searched fields with their search weights, a collection can only have one text index
+func textIndex() mongo.IndexModel {
+	keys := bson.D{}
+	weights := bson.D{}
+	for _, field := range search.Fields {
+		keys = append(keys, bson.E{Key: field.Path, Value: "text"})
+		weights = append(weights, bson.E{Key: field.Path, Value: field.Weight})
+	}
+
+	return mongo.IndexModel{
+		Keys:    keys,
+		Options: options.Index().SetName(TextIndex).SetWeights(weights),
+	}
+}
+
+//EnsureSchema creates the missing indexes on the sheet collection and applies the $jsonSchema validator.
+//Existing indexes and validators are never removed, and a step that fails is recorded in the report without stopping the others
+func (d *CharacterDB) EnsureSchema(ctx context.Context, schema config.SchemaConfig) (SchemaReport, error) {
+	logrus.Debug("BEGIN - EnsureSchema")
+
+	report := SchemaReport{
+		CreatedIndexes:  []string{},
+		ExistingIndexes: []string{},
+		Validator:       "skipped",
+		Failures:        []string{},
+	}
+
+	database := d.client.Database(d.databaseName)
+
+	if schema.EnsureValidator {
+		status, err := d.ensureValidator(ctx, database, schema)
+		if err != nil {
+			report.Failures = append(report.Failures, fmt.Sprintf("validator: %v", err))
+			status = "failed"
+		}
+		report.Validator = status
+	}
+
+	indexes := SheetIndexes(schema)
+	if len(indexes) == 0 {
+		return report, nil
+	}
+
+	collection := database.Collection(d.collectionName)
+	existing, err := indexNames(ctx, collection)
+	if err != nil {
+		return report, err
+	}
+
+	for _, index := range indexes {
+		name := *index.Options.Name
+		if existing[name] {
+			report.ExistingIndexes = append(report.ExistingIndexes, name)
+			continue
+		}
+
+		_, err := collection.Indexes().CreateOne(ctx, index)
+		if err != nil {
+			report.Failures = append(report.Failures, fmt.Sprintf("index %v: %v", name, err))
+			continue
+		}
+		report.CreatedIndexes = append(report.CreatedIndexes, name)
+	}
+
+	return report, nil
+}
+
+// ensureValidator creates the collection with the validator, or updates the validator of an existing collection when it differs
+func (d *CharacterDB) ensureValidator(ctx context.Context, database *mongo.Database, schema config.SchemaConfig) (string, error) {
+	validator := SheetValidator()
+
+	cur, err := database.ListCollections(ctx, bson.M{"name": d.collectionName})
+	if err != nil {
+		return "", err
+	}
+	defer cur.Close(ctx)
+
+	if !cur.Next(ctx) {
+		if err := cur.Err(); err != nil {
+			return "", err
+		}
+
+		opts := options.CreateCollection().
+			SetValidator(validator).
+			SetValidationLevel(schema.ValidationLevel).
+			SetValidationAction(schema.ValidationAction)
+
+		return "created", database.CreateCollection(ctx, d.collectionName, opts)
+	}
+
+	current := struct {
+		Options struct {
+			Validator        bson.Raw `bson:"validator"`
+			ValidationLevel  string   `bson:"validationLevel"`
+			ValidationAction string   `bson:"validationAction"`
+		} `bson:"options"`
+	}{}
+	err = cur.Decode(&current)
+	if err != nil {
+		return "", err
+	}
+
+	expected, err := bson.Marshal(validator)
+	if err != nil {
+		return "", err
+	}
+
+	if bytes.Equal(current.Options.Validator, expected) &&
+		current.Options.ValidationLevel == schema.ValidationLevel &&
+		current.Options.ValidationAction == schema.ValidationAction {
+		return "unchanged", nil
+	}
+
+	command := bson.D{
+		{Key: "collMod", Value: d.collectionName},
+		{Key: "validator", Value: validator},
+		{Key: "validationLevel", Value: schema.ValidationLevel},
+		{Key: "validationAction", Value: schema.ValidationAction},
+	}
+
+	return "updated", database.RunCommand(ctx, command).Err()
+}
+
+func indexNames(ctx context.Context, collection *mongo.Collection) (map[string]bool, error) {
+	cur, err := collection.Indexes().List(ctx)
+	if commandErr, ok := err.(mongo.CommandError); ok && commandErr.Code == namespaceNotFound {
+		return map[string]bool{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	names := map[string]bool{}
+	for cur.Next(ctx) {
+		index := struct {
+			Name string `bson:"name"`
+		}{}
+		err := cur.Decode(&index)
+		if err != nil {
+			return nil, err
+		}
+
+		names[index.Name] = true
+	}
+
+	return names, cur.Err()
+}
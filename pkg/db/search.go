@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/api"
+	"github.com/geeksheik9/sheet-CRUD/pkg/search"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//SearchForceCharacterSheets finds the force character sheets matching any word of the text through the text index,
+//ranked by mongo's text score. The other query parameters filter and page the results like GetForceCharacterSheets,
+//and the total counts every match ignoring pagination
+func (d *CharacterDB) SearchForceCharacterSheets(ctx context.Context, text string, queryParams url.Values) ([]model.SearchResult, int64, error) {
+	logrus.Debug("BEGIN - SearchForceCharacterSheets")
+
+	ctx, cancel := withTimeout(ctx, d.readTimeout)
+	defer cancel()
+
+	collection := d.client.Database(d.databaseName).Collection(d.collectionName)
+
+	pageNumber, pageCount, _, filter, err := api.BuildFilter(queryParams, api.SheetSchema)
+	if err != nil {
+		return nil, 0, translate(err)
+	}
+
+	terms := search.Terms(text)
+	if len(terms) == 0 {
+		return []model.SearchResult{}, 0, nil
+	}
+
+	// the terms are searched as plain words so that every backend treats quotes and dashes the same way
+	textFilter := bson.M{"$text": bson.M{"$search": strings.Join(terms, " ")}}
+	if filter != nil {
+		textFilter = bson.M{"$and": []bson.M{textFilter, filter}}
+	}
+
+	total, err := collection.CountDocuments(ctx, textFilter)
+	if err != nil {
+		return nil, 0, translate(err)
+	}
+
+	skip := 0
+	if pageNumber > 0 {
+		skip = (pageNumber - 1) * pageCount
+	}
+
+	score := bson.M{"$meta": "textScore"}
+	opts := options.Find().
+		SetProjection(bson.M{"score": score}).
+		SetSort(bson.D{{Key: "score", Value: score}, {Key: "_id", Value: 1}}).
+		SetSkip(int64(skip)).
+		SetLimit(int64(pageCount))
+
+	cur, err := collection.Find(ctx, textFilter, opts)
+	if err != nil {
+		return nil, 0, translate(err)
+	}
+	defer cur.Close(ctx)
+
+	results := []model.SearchResult{}
+	for cur.Next(ctx) {
+		result := model.SearchResult{}
+		err := cur.Decode(&result.Sheet)
+		if err != nil {
+			return nil, 0, translate(err)
+		}
+
+		if value, err := cur.Current.LookupErr("score"); err == nil {
+			result.Score, _ = value.DoubleOK()
+		}
+
+		results = append(results, result)
+	}
+
+	return results, total, translate(cur.Err())
+}
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrTransactionsNotSupported is returned for atomic operations against a standalone mongo, transactions need a replica set or sharded cluster
+var ErrTransactionsNotSupported error = dberr.New(dberr.ErrNotSupported, "transactions_not_supported", "atomic operations are not supported by this mongo deployment, transactions need a replica set or sharded cluster")
+
+//RunInTransaction runs fn in a mongo transaction so that either every write it makes through the context it is given is kept or,
+//when it returns an error, none are. fn may be run again when the transaction hits a transient error, so it must start over each time
+func (d *CharacterDB) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	logrus.Debug("BEGIN - RunInTransaction")
+
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+
+	supported, err := d.supportsTransactions(ctx)
+	if err != nil {
+		return translate(err)
+	}
+	if !supported {
+		return ErrTransactionsNotSupported
+	}
+
+	session, err := d.client.StartSession()
+	if err != nil {
+		return translate(err)
+	}
+	defer session.EndSession(context.Background())
+
+	_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessionContext)
+	})
+
+	return translate(err)
+}
+
+// atomically runs fn in a transaction where the deployment supports them and on its own where it doesn't,
+// for writes that fall back to undoing their own steps when a later one fails
+func (d *CharacterDB) atomically(ctx context.Context, fn func(ctx context.Context) error) error {
+	err := d.RunInTransaction(ctx, fn)
+	if err == ErrTransactionsNotSupported {
+		return fn(ctx)
+	}
+
+	return err
+}
+
+// supportsTransactions checks whether the deployment is a replica set or sharded cluster
+func (d *CharacterDB) supportsTransactions(ctx context.Context) (bool, error) {
+	ctx, cancel := withTimeout(ctx, d.readTimeout)
+	defer cancel()
+
+	result := struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}{}
+	err := d.client.Database("admin").RunCommand(ctx, bson.D{{Key: "isMaster", Value: 1}}).Decode(&result)
+	if err != nil {
+		return false, translate(err)
+	}
+
+	return result.SetName != "" || result.Msg == "isdbgrid", nil
+}
@@ -0,0 +1,110 @@
+package db
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// requiredSheetFields are the top level fields every stored sheet must have
+var requiredSheetFields = []string{"_id", "characterName", "playerName"}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	objectIDType = reflect.TypeOf(primitive.ObjectID{})
+)
+
+//SheetValidator returns the $jsonSchema validator for the sheet collection, derived from the bson tags of model.ForceCharacterSheet.
+//Properties are sorted so the same model always gives the same validator, which lets EnsureSchema tell when it changed
+func SheetValidator() bson.D {
+	schema := jsonSchema(reflect.TypeOf(model.ForceCharacterSheet{}))
+	schema = append(schema, bson.E{Key: "required", Value: requiredSheetFields})
+
+	return bson.D{{Key: "$jsonSchema", Value: schema}}
+}
+
+func jsonSchema(t reflect.Type) bson.D {
+	switch {
+	case t == objectIDType:
+		return bson.D{{Key: "bsonType", Value: "objectId"}}
+	case t == timeType:
+		return bson.D{{Key: "bsonType", Value: "date"}}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return nullable(jsonSchema(t.Elem()))
+	case reflect.Slice, reflect.Array:
+		// nil slices are stored as null by the driver
+		return bson.D{
+			{Key: "bsonType", Value: bson.A{"array", "null"}},
+			{Key: "items", Value: jsonSchema(t.Elem())},
+		}
+	case reflect.Struct:
+		return bson.D{
+			{Key: "bsonType", Value: "object"},
+			{Key: "properties", Value: properties(t)},
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return bson.D{{Key: "bsonType", Value: bson.A{"int", "long"}}}
+	case reflect.Float32, reflect.Float64:
+		return bson.D{{Key: "bsonType", Value: "double"}}
+	case reflect.Bool:
+		return bson.D{{Key: "bsonType", Value: "bool"}}
+	case reflect.String:
+		return bson.D{{Key: "bsonType", Value: "string"}}
+	}
+
+	return bson.D{}
+}
+
+func properties(t reflect.Type) bson.D {
+	props := bson.D{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		tag := strings.Split(field.Tag.Get("bson"), ",")
+		if tag[0] == "-" {
+			continue
+		}
+		if len(tag) > 1 && tag[1] == "inline" {
+			props = append(props, properties(field.Type)...)
+			continue
+		}
+
+		name := tag[0]
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		props = append(props, bson.E{Key: name, Value: jsonSchema(field.Type)})
+	}
+
+	sort.Slice(props, func(i, j int) bool { return props[i].Key < props[j].Key })
+
+	return props
+}
+
+func nullable(schema bson.D) bson.D {
+	for i, e := range schema {
+		if e.Key != "bsonType" {
+			continue
+		}
+
+		if list, ok := e.Value.(bson.A); ok {
+			schema[i].Value = append(append(bson.A{}, list...), "null")
+		} else {
+			schema[i].Value = bson.A{e.Value, "null"}
+		}
+	}
+
+	return schema
+}
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/geeksheik9/sheet-CRUD/config"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func lookupSchema(schema bson.D, path ...string) interface{} {
+	var value interface{} = schema
+	for _, key := range path {
+		doc, ok := value.(bson.D)
+		if !ok {
+			return nil
+		}
+
+		value = nil
+		for _, e := range doc {
+			if e.Key == key {
+				value = e.Value
+			}
+		}
+	}
+
+	return value
+}
+
+func TestSheetValidator(t *testing.T) {
+	schema := SheetValidator()[0].Value.(bson.D)
+
+	tests := []struct {
+		path     []string
+		expected string
+	}{
+		{[]string{"bsonType"}, "object"},
+		{[]string{"properties", "_id", "bsonType"}, "objectId"},
+		{[]string{"properties", "characterName", "bsonType"}, "string"},
+		{[]string{"properties", "forceRating", "bsonType"}, "[int long]"},
+		{[]string{"properties", "characteristics", "properties", "brawn", "bsonType"}, "[int long]"},
+		{[]string{"properties", "skills", "bsonType"}, "[array null]"},
+		{[]string{"properties", "skills", "items", "properties", "career", "bsonType"}, "bool"},
+		{[]string{"required"}, "[_id characterName playerName]"},
+	}
+
+	for _, test := range tests {
+		got := lookupSchema(schema, test.path...)
+		if fmt.Sprint(got) != test.expected {
+			t.Errorf("SheetValidator() error at %v:\n   expected: %v\n   got:      %v", test.path, test.expected, got)
+		}
+	}
+
+	first, _ := bson.Marshal(SheetValidator())
+	second, _ := bson.Marshal(SheetValidator())
+	if string(first) != string(second) {
+		t.Errorf("SheetValidator() error:\n   expected: the same validator on every call\n   got:      different validators")
+	}
+}
+
+func TestSheetIndexes(t *testing.T) {
+	indexes := SheetIndexes(config.SchemaConfig{EnsureIndexes: true, EnsureUniqueIdentity: true})
+	if len(indexes) != 5 || *indexes[2].Options.Name != TextIndex || *indexes[3].Options.Name != IdentityIndex || indexes[3].Options.Unique != nil ||
+		*indexes[4].Options.Name != LineageIndex || !*indexes[4].Options.Unique {
+		t.Errorf("SheetIndexes() error:\n   expected: two lookup indexes, the text index, the identity index and the unique lineage index\n   got:      %v", indexes)
+	}
+
+	indexes = SheetIndexes(config.SchemaConfig{EnsureIndexes: true})
+	if len(indexes) != 4 || *indexes[3].Options.Name != IdentityIndex {
+		t.Errorf("SheetIndexes() error:\n   expected: the identity index without the unique lineage index\n   got:      %v", indexes)
+	}
+
+	indexes = SheetIndexes(config.SchemaConfig{})
+	if len(indexes) != 0 {
+		t.Errorf("SheetIndexes() error:\n   expected: no indexes when disabled\n   got:      %v", indexes)
+	}
+}
@@ -0,0 +1,202 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DefaultHistory is how many recent events a Broker keeps for subscriptions to resume from
+const DefaultHistory = 1000
+
+// subscriptionBuffer is how many events a subscriber may fall behind before it is closed with ErrSlowConsumer
+const subscriptionBuffer = 256
+
+//Journal persists the events of a Broker so that their resume tokens stay valid after a restart
+type Journal interface {
+	// LoadEvents returns the journaled events in the order they were published
+	LoadEvents() ([]Event, error)
+	// AppendEvent adds a published event to the journal
+	AppendEvent(event Event) error
+	// CompactEvents replaces the journal with the events a broker still keeps
+	CompactEvents(history []Event) error
+}
+
+//Broker is the in-process event Source for backends without change streams.
+//It keeps the most recent events so that a subscription can resume after a disconnect. Without a Journal the events only live
+//as long as the process and tokens from an earlier run have expired, with one a broker picks up the stream and its tokens where it left off
+type Broker struct {
+	mu          sync.Mutex
+	stream      string
+	sequence    uint64
+	history     []Event
+	size        int
+	subscribers map[*Subscription]bool
+	journal     Journal
+	journaled   int
+}
+
+//NewBroker returns a broker that keeps the given number of recent events for resuming
+func NewBroker(history int) *Broker {
+	return &Broker{
+		stream:      primitive.NewObjectID().Hex(),
+		size:        history,
+		subscribers: map[*Subscription]bool{},
+	}
+}
+
+//NewJournaledBroker returns a broker that keeps the given number of recent events for resuming and writes every event to the journal.
+//The stream, sequence and recent events are loaded from the journal, so tokens issued before a restart can still be resumed
+func NewJournaledBroker(history int, journal Journal) (*Broker, error) {
+	b := NewBroker(history)
+	b.journal = journal
+
+	loaded, err := journal.LoadEvents()
+	if err != nil {
+		return nil, err
+	}
+	if len(loaded) == 0 {
+		return b, nil
+	}
+
+	stream, sequence, err := parseToken(loaded[len(loaded)-1].ID)
+	if err != nil {
+		return nil, fmt.Errorf("journaled event: %w", err)
+	}
+	b.stream = stream
+	b.sequence = sequence
+	b.journaled = len(loaded)
+
+	// only the events that follow each other up to the last one can be resumed from
+	for i := len(loaded) - 1; i >= 0 && len(b.history) < history; i-- {
+		eventStream, eventSequence, err := parseToken(loaded[i].ID)
+		if err != nil || eventStream != stream || eventSequence != sequence-uint64(len(b.history)) {
+			break
+		}
+		b.history = append([]Event{loaded[i]}, b.history...)
+	}
+
+	return b, nil
+}
+
+//Publish gives the event its resume token and time and delivers it to every subscription
+func (b *Broker) Publish(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.sequence++
+	event.ID = fmt.Sprintf("%v-%v", b.stream, b.sequence)
+	event.Time = time.Now().UTC()
+
+	b.history = append(b.history, event)
+	if len(b.history) > b.size {
+		b.history = b.history[len(b.history)-b.size:]
+	}
+	b.writeJournal(event)
+
+	for subscription := range b.subscribers {
+		select {
+		case subscription.events <- event:
+		default:
+			delete(b.subscribers, subscription)
+			subscription.close(ErrSlowConsumer)
+		}
+	}
+}
+
+//Subscribe streams the events after the resume token, or from now on when the token is empty, until the context is done
+func (b *Broker) Subscribe(ctx context.Context, resumeToken string) (*Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	replay, err := b.after(resumeToken)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription := newSubscription(len(replay) + subscriptionBuffer)
+	for _, event := range replay {
+		subscription.events <- event
+	}
+	b.subscribers[subscription] = true
+
+	go func() {
+		<-ctx.Done()
+
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers, subscription)
+		subscription.close(nil)
+	}()
+
+	return subscription, nil
+}
+
+// after returns the kept events that follow the resume token
+func (b *Broker) after(resumeToken string) ([]Event, error) {
+	if resumeToken == "" {
+		return nil, nil
+	}
+
+	stream, sequence, err := parseToken(resumeToken)
+	if err == nil && stream != b.stream {
+		return nil, ErrTokenExpired
+	}
+	if err != nil || sequence > b.sequence {
+		return nil, dberr.Invalid("invalid_query", "after", "invalid query: unknown resume token %q", resumeToken)
+	}
+
+	// the oldest kept event is sequence - len(history) + 1, resuming needs every event after the token
+	oldest := b.sequence - uint64(len(b.history)) + 1
+	if sequence+1 < oldest {
+		return nil, ErrTokenExpired
+	}
+
+	return append([]Event{}, b.history[len(b.history)-int(b.sequence-sequence):]...), nil
+}
+
+// writeJournal appends the event to the journal, compacting it once it holds twice the events the broker keeps.
+// A failed write is logged rather than failing the change the event describes, its token then only lasts until a restart
+func (b *Broker) writeJournal(event Event) {
+	if b.journal == nil {
+		return
+	}
+
+	err := b.journal.AppendEvent(event)
+	if err != nil {
+		logrus.Errorf("Journaling event %v: %v", event.ID, err)
+		return
+	}
+	b.journaled++
+
+	if b.journaled > 2*b.size {
+		err = b.journal.CompactEvents(b.history)
+		if err != nil {
+			logrus.Errorf("Compacting the event journal: %v", err)
+			return
+		}
+		b.journaled = len(b.history)
+	}
+}
+
+// parseToken splits a resume token into its stream and sequence number
+func parseToken(token string) (string, uint64, error) {
+	parts := strings.SplitN(token, "-", 2)
+	if len(parts) != 2 {
+		return "", 0, fmt.Errorf("malformed resume token %q", token)
+	}
+
+	sequence, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("malformed resume token %q", token)
+	}
+
+	return parts[0], sequence, nil
+}
@@ -0,0 +1,165 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func receive(t *testing.T, subscription *Subscription) Event {
+	select {
+	case event := <-subscription.Events():
+		return event
+	case <-time.After(time.Second):
+		t.Fatalf("Events() error:\n   expected: an event\n   got:      none after a second")
+	}
+
+	return Event{}
+}
+
+func TestBroker_Subscribe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := NewBroker(10)
+	subscription, err := b.Subscribe(ctx, "")
+	if err != nil {
+		t.Fatalf("Subscribe() error:\n   expected: <nil>\n   got:      %v", err)
+	}
+
+	sheetID := primitive.NewObjectID()
+	b.Publish(Event{Type: Created, SheetID: sheetID, Version: 1})
+	b.Publish(Event{Type: Deleted, SheetID: sheetID, Version: 1})
+
+	created := receive(t, subscription)
+	deleted := receive(t, subscription)
+	if created.Type != Created || deleted.Type != Deleted || created.SheetID != sheetID || created.ID == "" || created.ID == deleted.ID || created.Time.IsZero() {
+		t.Errorf("Publish() error:\n   expected: created then deleted with distinct IDs\n   got:      %+v %+v", created, deleted)
+	}
+
+	cancel()
+	select {
+	case _, open := <-subscription.Events():
+		if open || subscription.Err() != nil {
+			t.Errorf("Subscribe() cancel error:\n   expected: closed without error\n   got:      %v %v", open, subscription.Err())
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Subscribe() cancel error:\n   expected: closed\n   got:      still open after a second")
+	}
+}
+
+func TestBroker_Resume(t *testing.T) {
+	b := NewBroker(2)
+	for version := int64(1); version <= 3; version++ {
+		b.Publish(Event{Type: Updated, SheetID: primitive.NewObjectID(), Version: version})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	first, err := b.Subscribe(ctx, "")
+	if err != nil {
+		t.Fatalf("Subscribe() error:\n   expected: <nil>\n   got:      %v", err)
+	}
+	b.Publish(Event{Type: Updated, Version: 4})
+	token := receive(t, first).ID
+
+	b.Publish(Event{Type: Updated, Version: 5})
+	resumed, err := b.Subscribe(ctx, token)
+	if err != nil {
+		t.Fatalf("Subscribe() resume error:\n   expected: <nil>\n   got:      %v", err)
+	}
+	if event := receive(t, resumed); event.Version != 5 {
+		t.Errorf("Subscribe() resume error:\n   expected: version 5\n   got:      %+v", event)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "fell out of the history", token: b.stream + "-1"},
+		{name: "another stream", token: primitive.NewObjectID().Hex() + "-4"},
+	}
+
+	for _, test := range tests {
+		_, err := b.Subscribe(ctx, test.token)
+		if err != ErrTokenExpired {
+			t.Errorf("Subscribe() %v error:\n   expected: %v\n   got:      %v", test.name, ErrTokenExpired, err)
+		}
+	}
+}
+
+func TestBroker_SlowConsumer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := NewBroker(DefaultHistory)
+	subscription, _ := b.Subscribe(ctx, "")
+
+	for i := 0; i <= subscriptionBuffer; i++ {
+		b.Publish(Event{Type: Updated})
+	}
+
+	received := 0
+	for range subscription.Events() {
+		received++
+	}
+
+	if received != subscriptionBuffer || subscription.Err() != ErrSlowConsumer {
+		t.Errorf("Publish() slow consumer error:\n   expected: %v events then %v\n   got:      %v events then %v", subscriptionBuffer, ErrSlowConsumer, received, subscription.Err())
+	}
+}
+
+type memoryJournal struct {
+	events    []Event
+	compacted int
+}
+
+func (j *memoryJournal) LoadEvents() ([]Event, error) {
+	return j.events, nil
+}
+
+func (j *memoryJournal) AppendEvent(event Event) error {
+	j.events = append(j.events, event)
+	return nil
+}
+
+func (j *memoryJournal) CompactEvents(history []Event) error {
+	j.events = append([]Event{}, history...)
+	j.compacted++
+	return nil
+}
+
+func TestNewJournaledBroker(t *testing.T) {
+	journal := &memoryJournal{}
+	b, _ := NewJournaledBroker(2, journal)
+	for version := int64(1); version <= 5; version++ {
+		b.Publish(Event{Type: Updated, Version: version})
+	}
+	if journal.compacted != 1 || len(journal.events) != 2 {
+		t.Errorf("Publish() error:\n   expected: the journal compacted once it held twice the history\n   got:      %v compactions, %v events", journal.compacted, len(journal.events))
+	}
+	token := b.history[0].ID
+
+	restarted, err := NewJournaledBroker(2, journal)
+	if err != nil {
+		t.Fatalf("NewJournaledBroker() error:\n   expected: <nil>\n   got:      %v", err)
+	}
+	restarted.Publish(Event{Type: Updated, Version: 6})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	resumed, err := restarted.Subscribe(ctx, token)
+	if err != nil {
+		t.Fatalf("Subscribe() after restart error:\n   expected: <nil>\n   got:      %v", err)
+	}
+	if event := receive(t, resumed); event.Version != 5 || event.ID != b.stream+"-5" {
+		t.Errorf("Subscribe() after restart error:\n   expected: version 5 of the same stream\n   got:      %+v", event)
+	}
+	if event := receive(t, resumed); event.ID != b.stream+"-6" {
+		t.Errorf("Publish() after restart error:\n   expected: %v-6\n   got:      %+v", b.stream, event)
+	}
+}
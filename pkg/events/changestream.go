@@ -0,0 +1,150 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// changeStreamFatalError is the mongo error code for a resume token the change stream can't resume after
+	changeStreamFatalError = 280
+	// changeStreamHistoryLost is the mongo error code for a resume token that has fallen off the oplog
+	changeStreamHistoryLost = 286
+	// changeStreamNotSupported is the mongo error code for watching a standalone server
+	changeStreamNotSupported = 40573
+)
+
+// ErrChangeStreamsNotSupported is returned when subscribing to a standalone mongo, change streams need a replica set or sharded cluster
+var ErrChangeStreamsNotSupported error = dberr.New(dberr.ErrNotSupported, "events_not_supported", "events are not supported by this mongo deployment, change streams need a replica set or sharded cluster")
+
+//ChangeStream is the event Source of a mongo collection, read from its change stream.
+//Change streams need a replica set or sharded cluster, resume tokens stay valid as long as the oplog holds their change
+type ChangeStream struct {
+	collection *mongo.Collection
+}
+
+//NewChangeStream returns the event source of the collection
+func NewChangeStream(collection *mongo.Collection) *ChangeStream {
+	return &ChangeStream{collection: collection}
+}
+
+// change is the part of a change stream document an event is built from
+type change struct {
+	OperationType string              `bson:"operationType"`
+	ClusterTime   primitive.Timestamp `bson:"clusterTime"`
+	DocumentKey   struct {
+		ID primitive.ObjectID `bson:"_id"`
+	} `bson:"documentKey"`
+	FullDocument *model.ForceCharacterSheet `bson:"fullDocument"`
+}
+
+//Subscribe watches the collection for the changes after the resume token, or from now on when the token is empty, until the context is done
+func (c *ChangeStream) Subscribe(ctx context.Context, resumeToken string) (*Subscription, error) {
+	logrus.Debugf("BEGIN - ChangeStream Subscribe after: %v", resumeToken)
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"operationType": bson.M{"$in": bson.A{"insert", "update", "replace", "delete"}}}}},
+	}
+
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	if resumeToken != "" {
+		opts.SetResumeAfter(bson.M{"_data": resumeToken})
+	}
+
+	stream, err := c.collection.Watch(ctx, pipeline, opts)
+	if err != nil {
+		return nil, streamError(err)
+	}
+
+	subscription := newSubscription(subscriptionBuffer)
+
+	go func() {
+		defer stream.Close(context.Background())
+
+		for stream.Next(ctx) {
+			event, err := toEvent(stream)
+			if err != nil {
+				subscription.close(err)
+				return
+			}
+
+			select {
+			case subscription.events <- event:
+			case <-ctx.Done():
+				subscription.close(nil)
+				return
+			}
+		}
+
+		if ctx.Err() != nil {
+			subscription.close(nil)
+			return
+		}
+		subscription.close(streamError(stream.Err()))
+	}()
+
+	return subscription, nil
+}
+
+func toEvent(stream *mongo.ChangeStream) (Event, error) {
+	current := change{}
+	err := stream.Decode(&current)
+	if err != nil {
+		return Event{}, err
+	}
+
+	token, ok := stream.ResumeToken().Lookup("_data").StringValueOK()
+	if !ok {
+		return Event{}, errors.New("change stream resume token has no _data")
+	}
+
+	event := Event{
+		ID:      token,
+		SheetID: current.DocumentKey.ID,
+		Time:    time.Unix(int64(current.ClusterTime.T), 0).UTC(),
+	}
+
+	switch current.OperationType {
+	case "insert":
+		event.Type = Created
+	case "delete":
+		event.Type = Deleted
+		return event, nil
+	default:
+		event.Type = Updated
+	}
+
+	// the looked up document is missing when the sheet was deleted before the lookup ran
+	if current.FullDocument != nil {
+		event.Sheet = current.FullDocument
+		event.Version = current.FullDocument.Version
+	}
+
+	return event, nil
+}
+
+// streamError reports the errors of a resume token mongo no longer has the history for as ErrTokenExpired
+func streamError(err error) error {
+	var commandErr mongo.CommandError
+	if !errors.As(err, &commandErr) {
+		return err
+	}
+
+	switch commandErr.Code {
+	case changeStreamHistoryLost, changeStreamFatalError:
+		return ErrTokenExpired
+	case changeStreamNotSupported:
+		return ErrChangeStreamsNotSupported
+	}
+
+	return err
+}
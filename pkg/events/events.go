@@ -0,0 +1,85 @@
+// Package events publishes a resumable feed of the sheets created, updated and deleted in the live collection.
+// The mongo backend reads the feed from a change stream, so it includes changes made by other instances and tools,
+// while the file and memory backends publish to an in-process Broker as they write
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Type is the kind of change an event describes
+type Type string
+
+const (
+	// Created is published when a sheet is inserted into the live collection, including restores from the archive
+	Created Type = "created"
+	// Updated is published when a sheet of the live collection changes
+	Updated Type = "updated"
+	// Deleted is published when a sheet leaves the live collection, including moves into the archive
+	Deleted Type = "deleted"
+)
+
+// ErrTokenExpired is returned when a subscription asks to resume after an event that is no longer available
+var ErrTokenExpired = errors.New("resume token expired, the events after it are no longer available")
+
+// ErrSlowConsumer ends a subscription that fell too far behind the events, it can resume from the last event it received
+var ErrSlowConsumer = errors.New("subscription closed, the consumer fell too far behind")
+
+//Event is a change to a sheet of the live collection. The ID is the token a subscription resumes after,
+//the sheet holds the stored sheet after the change and is left out of deleted events
+type Event struct {
+	ID      string                     `json:"id"`
+	Type    Type                       `json:"type"`
+	SheetID primitive.ObjectID         `json:"sheetId"`
+	Version int64                      `json:"version,omitempty"`
+	Time    time.Time                  `json:"time"`
+	Sheet   *model.ForceCharacterSheet `json:"sheet,omitempty"`
+}
+
+//Source is a feed of sheet events that subscriptions can resume
+type Source interface {
+	// Subscribe streams the events after the resume token, or the events from now on when the token is empty,
+	// until the context is done or the feed fails
+	Subscribe(ctx context.Context, resumeToken string) (*Subscription, error)
+}
+
+//Subscription delivers the events of a Source in order. The events channel is closed when the subscription ends and Err then says why
+type Subscription struct {
+	events chan Event
+	once   sync.Once
+	mu     sync.Mutex
+	err    error
+}
+
+func newSubscription(buffer int) *Subscription {
+	return &Subscription{events: make(chan Event, buffer)}
+}
+
+// Events returns the channel the events are delivered on
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Err returns why the subscription ended, nil while it is running or when its context was done
+func (s *Subscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
+
+// close ends the subscription once, recording why
+func (s *Subscription) close(err error) {
+	s.once.Do(func() {
+		s.mu.Lock()
+		s.err = err
+		s.mu.Unlock()
+		close(s.events)
+	})
+}
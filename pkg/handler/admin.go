@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/geeksheik9/sheet-CRUD/pkg/api"
+	"github.com/geeksheik9/sheet-CRUD/pkg/backup"
+	"github.com/sirupsen/logrus"
+)
+
+// MaxRestoreSize is the largest backup bundle the restore endpoint accepts
+const MaxRestoreSize = 64 << 20
+
+//BackupForceCharacterSheets is the handler function for downloading a backup bundle of the live and archived sheets
+func (s *CharacterService) BackupForceCharacterSheets(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("BackupForceCharacterSheets invoked with url: %v", r.URL)
+
+	// the bundle is only written once every sheet was read, so failures can still get an error response
+	var bundle bytes.Buffer
+	manifest, err := backup.Write(r.Context(), s.Database, &bundle)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	filename := fmt.Sprintf("sheets-%v.tar.gz", manifest.CreatedAt.Format("20060102-150405"))
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(bundle.Bytes())
+}
+
+//RestoreForceCharacterSheets is the handler function for restoring a backup bundle sent as the request body.
+//The policy query parameter decides what happens to sheets whose ID already exists: skip (default), overwrite or new-ids
+func (s *CharacterService) RestoreForceCharacterSheets(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("RestoreForceCharacterSheets invoked with url: %v", r.URL)
+	defer r.Body.Close()
+
+	policy, err := backup.ParsePolicy(r.URL.Query().Get("policy"))
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	report, err := backup.Restore(r.Context(), s.Database, http.MaxBytesReader(w, r.Body, MaxRestoreSize), policy)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	logrus.Infof("Restored backup from %v: %v", report.Manifest.CreatedAt.Format(time.RFC3339), report)
+	api.RespondWithJSON(w, http.StatusOK, report)
+}
+
+// adminOnly lets a request through when it carries the configured admin token as a bearer token.
+// Admin routes are disabled while no token is configured
+func (s *CharacterService) adminOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.AdminToken == "" {
+			api.RespondWithError(w, http.StatusForbidden, "admin endpoints are disabled, set ADMIN_TOKEN to enable them")
+			return
+		}
+
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.AdminToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			api.RespondWithError(w, http.StatusUnauthorized, "a valid admin token is required")
+			return
+		}
+
+		next(w, r)
+	}
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/geeksheik9/sheet-CRUD/pkg/backup"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/memory"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func serveAdmin(router *mux.Router, method string, url string, token string, body []byte) *httptest.ResponseRecorder {
+	r, _ := http.NewRequest(method, url, bytes.NewBuffer(body))
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	return w
+}
+
+func TestCharacterService_BackupAndRestore(t *testing.T) {
+	source := CharacterService{Version: "test", Database: memory.New(), AdminToken: "secret"}
+	sourceRouter := source.Routes(mux.NewRouter().StrictSlash(true))
+
+	sheet := mockCharacter(primitive.NewObjectID(), "Mando", 12, 0, 1)
+	sheet.Version = 1
+	source.Database.InsertForceCharacterSheet(context.Background(), sheet)
+
+	w := serveAdmin(sourceRouter, "GET", "/admin/backup", "secret", nil)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/gzip" {
+		t.Fatalf("BackupForceCharacterSheets() error:\ngot: %v %v\nexpected: %v with a gzip bundle", w.Code, w.Header(), http.StatusOK)
+	}
+	bundle := w.Body.Bytes()
+
+	target := CharacterService{Version: "test", Database: memory.New(), AdminToken: "secret"}
+	targetRouter := target.Routes(mux.NewRouter().StrictSlash(true))
+
+	w = serveAdmin(targetRouter, "POST", "/admin/restore?policy=overwrite", "secret", bundle)
+	report := backup.RestoreReport{}
+	_ = json.NewDecoder(w.Body).Decode(&report)
+	if w.Code != http.StatusOK || report.Policy != backup.Overwrite || report.Sheets.Inserted != 1 {
+		t.Errorf("RestoreForceCharacterSheets() error:\ngot: %v %+v\nexpected: %v with one sheet inserted", w.Code, report, http.StatusOK)
+	}
+
+	if _, err := target.Database.FindForceCharacterSheetByID(context.Background(), sheet.ID, nil); err != nil {
+		t.Errorf("RestoreForceCharacterSheets() error:\ngot: %v\nexpected: the sheet restored", err)
+	}
+
+	w = serveAdmin(targetRouter, "POST", "/admin/restore?policy=merge", "secret", bundle)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("RestoreForceCharacterSheets() error:\ngot: %v\nexpected: %v", w.Code, http.StatusBadRequest)
+	}
+
+	w = serveAdmin(targetRouter, "POST", "/admin/restore", "secret", []byte("not a bundle"))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("RestoreForceCharacterSheets() error:\ngot: %v\nexpected: %v", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestCharacterService_AdminOnly(t *testing.T) {
+	tests := []struct {
+		adminToken string
+		token      string
+		expected   int
+	}{
+		{"", "", http.StatusForbidden},
+		{"", "anything", http.StatusForbidden},
+		{"secret", "", http.StatusUnauthorized},
+		{"secret", "wrong", http.StatusUnauthorized},
+		{"secret", "secret", http.StatusOK},
+	}
+
+	for _, test := range tests {
+		service := CharacterService{Version: "test", Database: memory.New(), AdminToken: test.adminToken}
+		router := service.Routes(mux.NewRouter().StrictSlash(true))
+
+		w := serveAdmin(router, "GET", "/admin/backup", test.token, nil)
+		if w.Code != test.expected {
+			t.Errorf("adminOnly() with token %q error:\ngot: %v\nexpected: %v", test.token, w.Code, test.expected)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/geeksheik9/sheet-CRUD/pkg/api"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+//GetArchivedForceCharacterSheets is the handler function for getting all archived force character sheets
+func (s *CharacterService) GetArchivedForceCharacterSheets(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("GetArchivedForceCharacterSheets invoked with url: %v", r.URL)
+
+	sheets, err := s.Database.GetArchivedForceCharacterSheets(r.Context(), r.URL.Query())
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	api.RespondWithJSON(w, http.StatusOK, sheets)
+}
+
+//FindArchivedForceCharacterSheetByID is the handler function for getting a specific archived character sheet by database ID
+func (s *CharacterService) FindArchivedForceCharacterSheetByID(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("BEGIN - FindArchivedForceCharacterSheetByID invoked with url: %v", r.URL)
+
+	vars := mux.Vars(r)
+	ID := vars["ID"]
+
+	objectID, err := api.StringToObjectID(ID)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	sheet, err := s.Database.FindArchivedForceCharacterSheetByID(r.Context(), objectID)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	api.RespondWithJSON(w, http.StatusOK, sheet)
+}
+
+//RestoreForceCharacterSheetByID is the handler function for moving an archived character sheet back into the live collection
+func (s *CharacterService) RestoreForceCharacterSheetByID(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("BEGIN - RestoreForceCharacterSheetByID invoked with url: %v", r.URL)
+
+	vars := mux.Vars(r)
+	ID := vars["ID"]
+
+	objectID, err := api.StringToObjectID(ID)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	err = s.Database.RestoreForceCharacterSheetByID(r.Context(), objectID)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	api.RespondWithJSON(w, http.StatusOK, objectID)
+}
+
+//PurgeArchivedForceCharacterSheetByID is the handler function for permanently deleting an archived character sheet
+func (s *CharacterService) PurgeArchivedForceCharacterSheetByID(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("BEGIN - PurgeArchivedForceCharacterSheetByID invoked with url: %v", r.URL)
+
+	vars := mux.Vars(r)
+	ID := vars["ID"]
+
+	objectID, err := api.StringToObjectID(ID)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	err = s.Database.PurgeArchivedForceCharacterSheetByID(r.Context(), objectID)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	api.RespondNoContent(w, http.StatusNoContent)
+}
@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/mocks"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func InitMockArchiveService(archivedToReturn *model.ArchivedForceCharacterSheet, errorToReturn error) (s CharacterService) {
+	db := mocks.MockCharacterDB{
+		ErrorToReturn:         errorToReturn,
+		ArchivedSheetToReturn: archivedToReturn,
+	}
+	if archivedToReturn != nil {
+		db.ArchivedSheetsToReturn = []model.ArchivedForceCharacterSheet{*archivedToReturn}
+	}
+
+	return CharacterService{
+		Version:  "test",
+		Database: &db,
+	}
+}
+
+func mockArchivedCharacter(id primitive.ObjectID, name string) model.ArchivedForceCharacterSheet {
+	return model.ArchivedForceCharacterSheet{
+		ForceCharacterSheet: mockCharacter(id, name, 2, 0, 5),
+		DeletedAt:           time.Now().UTC(),
+		DeletedBy:           "gm",
+	}
+}
+
+func TestCharacterService_GetArchivedForceCharacterSheets_Success(t *testing.T) {
+	id := primitive.NewObjectID()
+	sheet := mockArchivedCharacter(id, "test")
+	service := InitMockArchiveService(&sheet, nil)
+
+	r, err := http.NewRequest("GET", "/archived-force-character-sheet", nil)
+	if err != nil {
+		t.Errorf("GetArchivedForceCharacterSheets() error creating request:\ngot: %v\nexpected:<no error>", err)
+	}
+
+	w := httptest.NewRecorder()
+	router := mux.NewRouter().StrictSlash(true)
+	service.Routes(router).ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("GetArchivedForceCharacterSheets() error:\ngot: %v\nexpected: %v", w.Code, http.StatusOK)
+	}
+
+	resp := []model.ArchivedForceCharacterSheet{}
+	err = json.NewDecoder(w.Body).Decode(&resp)
+	if err != nil {
+		t.Errorf("GetArchivedForceCharacterSheets() json decode error:\ngot: %v\nexpected: <nil>", err)
+	}
+	if len(resp) != 1 || resp[0].ID != sheet.ID || resp[0].DeletedBy != sheet.DeletedBy {
+		t.Errorf("GetArchivedForceCharacterSheets() error:\n got:%v\nexpected:%v", resp, sheet)
+	}
+}
+
+func TestCharacterService_FindArchivedForceCharacterSheetByID_Success(t *testing.T) {
+	id := primitive.NewObjectID()
+	sheet := mockArchivedCharacter(id, "test")
+	service := InitMockArchiveService(&sheet, nil)
+
+	r, err := http.NewRequest("GET", "/archived-force-character-sheet/"+id.Hex(), nil)
+	if err != nil {
+		t.Errorf("FindArchivedForceCharacterSheetByID() error creating request:\ngot: %v\nexpected:<no error>", err)
+	}
+
+	w := httptest.NewRecorder()
+	router := mux.NewRouter().StrictSlash(true)
+	service.Routes(router).ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("FindArchivedForceCharacterSheetByID() error:\ngot: %v\nexpected: %v", w.Code, http.StatusOK)
+	}
+
+	resp := model.ArchivedForceCharacterSheet{}
+	err = json.NewDecoder(w.Body).Decode(&resp)
+	if err != nil {
+		t.Errorf("FindArchivedForceCharacterSheetByID() json decode error:\ngot: %v\nexpected: <nil>", err)
+	}
+	if resp.ID != sheet.ID || resp.CharacterName != sheet.CharacterName || resp.DeletedBy != sheet.DeletedBy {
+		t.Errorf("FindArchivedForceCharacterSheetByID() error:\n got:%v\nexpected:%v", resp, sheet)
+	}
+}
+
+func TestCharacterService_FindArchivedForceCharacterSheetByID_NotFound(t *testing.T) {
+	id := primitive.NewObjectID()
+	service := InitMockArchiveService(nil, dberr.ArchivedSheetNotFound(primitive.NewObjectID()))
+
+	r, err := http.NewRequest("GET", "/archived-force-character-sheet/"+id.Hex(), nil)
+	if err != nil {
+		t.Errorf("FindArchivedForceCharacterSheetByID() error creating request:\ngot: %v\nexpected:<no error>", err)
+	}
+
+	w := httptest.NewRecorder()
+	router := mux.NewRouter().StrictSlash(true)
+	service.Routes(router).ServeHTTP(w, r)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("FindArchivedForceCharacterSheetByID() error:\ngot: %v\nexpected: %v", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestCharacterService_RestoreForceCharacterSheetByID_Success(t *testing.T) {
+	id := primitive.NewObjectID()
+	service := InitMockArchiveService(nil, nil)
+
+	r, err := http.NewRequest("POST", "/archived-force-character-sheet/"+id.Hex()+"/restore", nil)
+	if err != nil {
+		t.Errorf("RestoreForceCharacterSheetByID() error creating request:\ngot: %v\nexpected:<no error>", err)
+	}
+
+	w := httptest.NewRecorder()
+	router := mux.NewRouter().StrictSlash(true)
+	service.Routes(router).ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("RestoreForceCharacterSheetByID() error:\ngot: %v\nexpected: %v", w.Code, http.StatusOK)
+	}
+}
+
+func TestCharacterService_RestoreForceCharacterSheetByID_Conflict(t *testing.T) {
+	id := primitive.NewObjectID()
+	service := InitMockArchiveService(nil, dberr.DuplicateSheet(primitive.NewObjectID()))
+
+	r, err := http.NewRequest("POST", "/archived-force-character-sheet/"+id.Hex()+"/restore", nil)
+	if err != nil {
+		t.Errorf("RestoreForceCharacterSheetByID() error creating request:\ngot: %v\nexpected:<no error>", err)
+	}
+
+	w := httptest.NewRecorder()
+	router := mux.NewRouter().StrictSlash(true)
+	service.Routes(router).ServeHTTP(w, r)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("RestoreForceCharacterSheetByID() error:\ngot: %v\nexpected: %v", w.Code, http.StatusConflict)
+	}
+}
+
+func TestCharacterService_PurgeArchivedForceCharacterSheetByID_Success(t *testing.T) {
+	id := primitive.NewObjectID()
+	service := InitMockArchiveService(nil, nil)
+
+	r, err := http.NewRequest("DELETE", "/archived-force-character-sheet/"+id.Hex(), nil)
+	if err != nil {
+		t.Errorf("PurgeArchivedForceCharacterSheetByID() error creating request:\ngot: %v\nexpected:<no error>", err)
+	}
+
+	w := httptest.NewRecorder()
+	router := mux.NewRouter().StrictSlash(true)
+	service.Routes(router).ServeHTTP(w, r)
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("PurgeArchivedForceCharacterSheetByID() error:\ngot: %v\nexpected: %v", w.Code, http.StatusNoContent)
+	}
+}
+
+func TestCharacterService_PurgeArchivedForceCharacterSheetByID_NotFound(t *testing.T) {
+	id := primitive.NewObjectID()
+	service := InitMockArchiveService(nil, dberr.ArchivedSheetNotFound(id))
+
+	r, err := http.NewRequest("DELETE", "/archived-force-character-sheet/"+id.Hex(), nil)
+	if err != nil {
+		t.Errorf("PurgeArchivedForceCharacterSheetByID() error creating request:\ngot: %v\nexpected:<no error>", err)
+	}
+
+	w := httptest.NewRecorder()
+	router := mux.NewRouter().StrictSlash(true)
+	service.Routes(router).ServeHTTP(w, r)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("PurgeArchivedForceCharacterSheetByID() error:\ngot: %v\nexpected: %v", w.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/api"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
+	"github.com/sirupsen/logrus"
+)
+
+// MaxBulkOperations is the most operations a single bulk request may carry
+const MaxBulkOperations = 500
+
+// errBulkOperationFailed stops an atomic bulk request at its first failed operation so that the transaction is rolled back
+var errBulkOperationFailed = fmt.Errorf("bulk operation failed")
+
+//BulkForceCharacterSheets is the handler function for running a batch of inserts, updates and deletes.
+//Every operation gets its own result. A request where every operation succeeded returns 200, otherwise it returns 207 Multi-Status,
+//or for an atomic request the status of the operation that failed after every change was rolled back
+func (s *CharacterService) BulkForceCharacterSheets(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("BulkForceCharacterSheets invoked with url: %v", r.URL)
+	defer r.Body.Close()
+
+	request := model.BulkRequest{}
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		api.RespondWithFailure(w, api.PayloadError(err))
+		return
+	}
+
+	if len(request.Operations) == 0 || len(request.Operations) > MaxBulkOperations {
+		api.RespondWithFailure(w, dberr.Invalid("invalid_payload", "operations", "a bulk request needs between 1 and %v operations", MaxBulkOperations))
+		return
+	}
+
+	var results []model.BulkResult
+	run := func(ctx context.Context) error {
+		results = make([]model.BulkResult, len(request.Operations))
+		for i, operation := range request.Operations {
+			results[i] = s.bulkOperation(ctx, r, operation)
+			results[i].Index = i
+
+			if request.Atomic && results[i].Error != nil {
+				return errBulkOperationFailed
+			}
+		}
+
+		return nil
+	}
+
+	if !request.Atomic {
+		run(r.Context())
+		respondWithBulkResults(w, request.Atomic, results)
+		return
+	}
+
+	err = s.Database.RunInTransaction(r.Context(), run)
+	if err != nil && err != errBulkOperationFailed {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	respondWithBulkResults(w, request.Atomic, results)
+}
+
+func respondWithBulkResults(w http.ResponseWriter, atomic bool, results []model.BulkResult) {
+	response := model.BulkResponse{Atomic: atomic, Results: results}
+	code := http.StatusOK
+
+	for i, result := range results {
+		if result.Error == nil {
+			response.Succeeded++
+			continue
+		}
+
+		response.Failed++
+		if !atomic {
+			code = http.StatusMultiStatus
+			continue
+		}
+
+		// the failed operation stopped the transaction, the ones before it were rolled back and the ones after it never ran
+		code = result.Status
+		rolledBack := api.NewProblem(http.StatusFailedDependency, "rolled_back", "rolled back")
+		notRun := api.NewProblem(http.StatusFailedDependency, "not_run", "not run")
+		for j := range results {
+			if j < i {
+				results[j].Status = http.StatusFailedDependency
+				results[j].Error = &rolledBack
+			} else if j > i {
+				results[j] = model.BulkResult{Index: j, Op: results[j].Op, ID: results[j].ID, Status: http.StatusFailedDependency, Error: &notRun}
+			}
+		}
+		response.Succeeded = 0
+		response.Failed = len(results)
+		break
+	}
+
+	api.RespondWithJSON(w, code, response)
+}
+
+// bulkOperation runs a single operation of a bulk request the same way its single sheet endpoint would
+func (s *CharacterService) bulkOperation(ctx context.Context, r *http.Request, operation model.BulkOperation) model.BulkResult {
+	result := model.BulkResult{Op: operation.Op, ID: operation.ID}
+	fail := func(err error) model.BulkResult {
+		problem := api.ProblemFor(err)
+		result.Status = problem.Status
+		result.Error = &problem
+		return result
+	}
+
+	switch operation.Op {
+	case model.BulkInsert:
+		sheet := model.ForceCharacterSheet{}
+		err := decodeSheet(bytes.NewReader(operation.Sheet), &sheet)
+		if err != nil {
+			return fail(err)
+		}
+
+		sheet = withInsertDefaults(sheet)
+		result.ID = sheet.ID.Hex()
+
+		err = s.Database.InsertForceCharacterSheet(ctx, sheet)
+		if err != nil {
+			return fail(err)
+		}
+		result.Status = http.StatusCreated
+	case model.BulkUpdate:
+		objectID, err := api.StringToObjectID(operation.ID)
+		if err != nil {
+			return fail(err)
+		}
+
+		sheet := model.ForceCharacterSheet{}
+		err = decodeSheet(bytes.NewReader(operation.Sheet), &sheet)
+		if err != nil {
+			return fail(err)
+		}
+
+		err = s.Database.UpdateForceCharacterSheetByID(ctx, sheet, objectID)
+		if err != nil {
+			return fail(err)
+		}
+		result.Status = http.StatusOK
+	case model.BulkDelete:
+		objectID, err := api.StringToObjectID(operation.ID)
+		if err != nil {
+			return fail(err)
+		}
+
+		err = s.Database.DeleteForceCharacterSheetByID(ctx, objectID, api.RequestUser(r))
+		if err != nil {
+			return fail(err)
+		}
+		result.Status = http.StatusNoContent
+	default:
+		return fail(dberr.Invalid("invalid_payload", "op", "unknown bulk operation %q, expected %v, %v or %v", operation.Op, model.BulkInsert, model.BulkUpdate, model.BulkDelete))
+	}
+
+	return result
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/memory"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func bulkOperation(t *testing.T, op string, id primitive.ObjectID, sheet *model.ForceCharacterSheet) model.BulkOperation {
+	operation := model.BulkOperation{Op: op}
+	if !id.IsZero() {
+		operation.ID = id.Hex()
+	}
+	if sheet != nil {
+		data, err := json.Marshal(sheet)
+		if err != nil {
+			t.Fatalf("json.Marshal() error:\ngot: %v\nexpected:<no error>", err)
+		}
+		operation.Sheet = data
+	}
+
+	return operation
+}
+
+func TestCharacterService_BulkForceCharacterSheets(t *testing.T) {
+	database := memory.New()
+	service := CharacterService{Version: "test", Database: database}
+	router := service.Routes(mux.NewRouter().StrictSlash(true))
+
+	kanan := mockCharacter(primitive.NewObjectID(), "Kanan", 12, 0, 2)
+	ezra := mockCharacter(primitive.NewObjectID(), "Ezra", 10, 0, 1)
+	request := model.BulkRequest{Operations: []model.BulkOperation{
+		bulkOperation(t, model.BulkInsert, primitive.NilObjectID, &kanan),
+		bulkOperation(t, model.BulkInsert, primitive.NilObjectID, &ezra),
+		bulkOperation(t, model.BulkDelete, primitive.NewObjectID(), nil),
+		{Op: "upsert"},
+	}}
+
+	w := serveMemory(t, router, "POST", "/force-character-sheet/bulk", request)
+	response := model.BulkResponse{}
+	_ = json.NewDecoder(w.Body).Decode(&response)
+	if w.Code != http.StatusMultiStatus || response.Succeeded != 2 || response.Failed != 2 {
+		t.Fatalf("BulkForceCharacterSheets() error:\ngot: %v %+v\nexpected: %v with 2 succeeded and 2 failed", w.Code, response, http.StatusMultiStatus)
+	}
+
+	statuses := []int{http.StatusCreated, http.StatusCreated, http.StatusNotFound, http.StatusBadRequest}
+	for i, result := range response.Results {
+		if result.Index != i || result.Status != statuses[i] {
+			t.Errorf("BulkForceCharacterSheets() result %v error:\ngot: %+v\nexpected: status %v", i, result, statuses[i])
+		}
+	}
+
+	kanan.Version = 1
+	kanan.Strain.Current = 0
+	hera := mockCharacter(primitive.NewObjectID(), "Hera", 14, 0, 0)
+	request = model.BulkRequest{Atomic: true, Operations: []model.BulkOperation{
+		bulkOperation(t, model.BulkUpdate, kanan.ID, &kanan),
+		bulkOperation(t, model.BulkInsert, primitive.NilObjectID, &hera),
+		bulkOperation(t, model.BulkInsert, primitive.NilObjectID, &ezra),
+		bulkOperation(t, model.BulkDelete, kanan.ID, nil),
+	}}
+
+	w = serveMemory(t, router, "POST", "/force-character-sheet/bulk", request)
+	response = model.BulkResponse{}
+	_ = json.NewDecoder(w.Body).Decode(&response)
+	if w.Code != http.StatusConflict || response.Failed != 4 || response.Results[2].Status != http.StatusConflict ||
+		response.Results[0].Status != http.StatusFailedDependency || response.Results[3].Error == nil || response.Results[3].Error.Code != "not_run" {
+		t.Fatalf("BulkForceCharacterSheets() atomic error:\ngot: %v %+v\nexpected: %v with the duplicate insert failing", w.Code, response, http.StatusConflict)
+	}
+
+	current, err := database.FindForceCharacterSheetByID(context.Background(), kanan.ID, nil)
+	if err != nil || current.Version != 1 {
+		t.Errorf("BulkForceCharacterSheets() atomic error:\ngot: %v %v\nexpected: the update rolled back to version 1", current, err)
+	}
+	if _, err := database.FindForceCharacterSheetByID(context.Background(), hera.ID, nil); err == nil {
+		t.Errorf("BulkForceCharacterSheets() atomic error:\ngot: <nil>\nexpected: the insert of Hera rolled back")
+	}
+
+	request.Operations = request.Operations[:2]
+	w = serveMemory(t, router, "POST", "/force-character-sheet/bulk", request)
+	if w.Code != http.StatusOK {
+		t.Errorf("BulkForceCharacterSheets() atomic error:\ngot: %v %v\nexpected: %v", w.Code, w.Body.String(), http.StatusOK)
+	}
+}
+
+func TestCharacterService_BulkForceCharacterSheets_BadRequest(t *testing.T) {
+	service := InitMockCharacterService(nil, nil, nil)
+	router := service.Routes(mux.NewRouter().StrictSlash(true))
+
+	for _, body := range []interface{}{"not a bulk request", model.BulkRequest{}} {
+		w := serveMemory(t, router, "POST", "/force-character-sheet/bulk", body)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("BulkForceCharacterSheets() error:\ngot: %v\nexpected: %v", w.Code, http.StatusBadRequest)
+		}
+	}
+}
@@ -1,12 +1,20 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strconv"
 
 	model "github.com/geeksheik9/sheet-CRUD/models"
 	"github.com/geeksheik9/sheet-CRUD/pkg/api"
+	"github.com/geeksheik9/sheet-CRUD/pkg/catalog"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
+	"github.com/geeksheik9/sheet-CRUD/pkg/events"
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -14,18 +22,45 @@ import (
 
 //CharacterDatabase is the interface setup for accesssing the character database
 type CharacterDatabase interface {
-	GetForceCharacterSheets(query url.Values) ([]model.ForceCharacterSheet, error)
-	FindForceCharacterSheetByID(mongoID primitive.ObjectID) (*model.ForceCharacterSheet, error)
-	UpdateForceCharacterSheetByID(sheet model.ForceCharacterSheet, mongoID primitive.ObjectID) error
-	InsertForceCharacterSheet(sheet model.ForceCharacterSheet) error
-	DeleteForceCharacterSheetByID(mongoID primitive.ObjectID) error
-	Ping() error
+	GetForceCharacterSheets(ctx context.Context, query url.Values) ([]model.ForceCharacterSheet, error)
+	CountForceCharacterSheets(ctx context.Context, query url.Values) (int64, error)
+	SearchForceCharacterSheets(ctx context.Context, text string, query url.Values) ([]model.SearchResult, int64, error)
+	FindForceCharacterSheetByID(ctx context.Context, mongoID primitive.ObjectID, projection api.Projection) (*model.ForceCharacterSheet, error)
+	FindLatestForceCharacterSheet(ctx context.Context, characterName string, playerName string, ignoreCase bool) (*model.ForceCharacterSheet, error)
+	GetForceCharacterSheetVersions(ctx context.Context, characterID primitive.ObjectID) ([]model.ForceCharacterSheet, error)
+	FindForceCharacterSheetVersion(ctx context.Context, characterID primitive.ObjectID, characterVersion int64) (*model.ForceCharacterSheet, error)
+	LevelUpForceCharacterSheet(ctx context.Context, characterID primitive.ObjectID) (*model.ForceCharacterSheet, error)
+	UpdateForceCharacterSheetByID(ctx context.Context, sheet model.ForceCharacterSheet, mongoID primitive.ObjectID) error
+	InsertSheetElement(ctx context.Context, mongoID primitive.ObjectID, array api.SheetArray, element interface{}, expected int64) (*model.ForceCharacterSheet, error)
+	UpdateSheetElement(ctx context.Context, mongoID primitive.ObjectID, array api.SheetArray, elementID primitive.ObjectID, element interface{}, expected int64) (*model.ForceCharacterSheet, error)
+	DeleteSheetElement(ctx context.Context, mongoID primitive.ObjectID, array api.SheetArray, elementID primitive.ObjectID, expected int64) (*model.ForceCharacterSheet, error)
+	ApplyHealthAction(ctx context.Context, mongoID primitive.ObjectID, action api.HealthAction, amount int64, expected int64) (*model.ForceCharacterSheet, error)
+	InsertForceCharacterSheet(ctx context.Context, sheet model.ForceCharacterSheet) error
+	DeleteForceCharacterSheetByID(ctx context.Context, mongoID primitive.ObjectID, deletedBy string) error
+	GetArchivedForceCharacterSheets(ctx context.Context, query url.Values) ([]model.ArchivedForceCharacterSheet, error)
+	FindArchivedForceCharacterSheetByID(ctx context.Context, mongoID primitive.ObjectID) (*model.ArchivedForceCharacterSheet, error)
+	InsertArchivedForceCharacterSheet(ctx context.Context, sheet model.ArchivedForceCharacterSheet) error
+	RestoreForceCharacterSheetByID(ctx context.Context, mongoID primitive.ObjectID) error
+	PurgeArchivedForceCharacterSheetByID(ctx context.Context, mongoID primitive.ObjectID) error
+	GetForceCharacterSheetRevisions(ctx context.Context, mongoID primitive.ObjectID) ([]model.SheetRevision, error)
+	FindForceCharacterSheetRevision(ctx context.Context, mongoID primitive.ObjectID, version int64) (*model.SheetRevision, error)
+	RevertForceCharacterSheetByID(ctx context.Context, mongoID primitive.ObjectID, version int64) error
+	RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+	Ping(ctx context.Context) error
 }
 
 //CharacterService is the implementation of the service to access character sheets
 type CharacterService struct {
 	Version  string
 	Database CharacterDatabase
+	// AdminToken is the bearer token the admin routes require, they are disabled when it is empty
+	AdminToken string
+	// Events is the feed of sheet changes streamed by the events route, it is not supported when nil
+	Events events.Source
+	// RequireIfMatch makes updates, patches and deletes of a sheet without an If-Match header fail with 428
+	RequireIfMatch bool
+	// Catalog prices the talents and force powers bought with XP, the built in catalog is used when nil
+	Catalog *catalog.Catalog
 }
 
 //Routes sets up the routes for the RESTful interface
@@ -55,11 +90,53 @@ func (s *CharacterService) Routes(r *mux.Router) *mux.Router {
 	// Schemes: http, https
 	//
 	// responses:
-	// 200: []ForceCharacterSheet
+	// 200: ForceCharacterSheetPage
 	// 400: description:Bad request
 	// 404: description:No records
 	// 500: description:Internal Server Error
 	r.HandleFunc("/force-character-sheet", s.GetForceCharacterSheets).Methods(http.MethodGet)
+	// swagger:route POST /force-character-sheet/bulk BulkRequest
+	//
+	// Insert, update and delete Force Character Sheets in a single request, optionally all or nothing
+	//
+	// Consumes:
+	// - application/json
+	// Schemes: http, https
+	//
+	// responses:
+	// 200: BulkResponse
+	// 207: BulkResponse
+	// 400: description:Bad request
+	// 501: description:Atomic requests not supported by the database
+	// 500: description:Internal Server Error
+	r.HandleFunc("/force-character-sheet/bulk", s.BulkForceCharacterSheets).Methods(http.MethodPost)
+	// swagger:route GET /search ForceCharacterSheet
+	//
+	// Search Force Character Sheets by character and player names, talents, force powers, weapons, gear and notable features
+	//
+	// Consumes:
+	// - application/json
+	// Schemes: http, https
+	//
+	// responses:
+	// 200: SearchPage
+	// 400: description:Bad request
+	// 500: description:Internal Server Error
+	r.HandleFunc("/search", s.SearchForceCharacterSheets).Methods(http.MethodGet)
+	// swagger:route GET /events Events
+	//
+	// Stream the created, updated and deleted sheet events as server-sent events, resuming after the Last-Event-ID header or the after query parameter
+	//
+	// Produces:
+	// - text/event-stream
+	// Schemes: http, https
+	//
+	// responses:
+	// 200: description:Event stream
+	// 410: description:Resume token expired
+	// 501: description:Events not supported by the storage backend
+	// 500: description:Internal Server Error
+	r.HandleFunc("/events", s.StreamEvents).Methods(http.MethodGet)
 	// swagger:route GET /force-character-sheet/{ID} ForceCharacterSheet
 	//
 	// Get Force Character Sheet by ID
@@ -70,13 +147,14 @@ func (s *CharacterService) Routes(r *mux.Router) *mux.Router {
 	//
 	// responses:
 	// 200: ForceCharacterSheet
+	// 304: description:Not Modified
 	// 400: description:Bad request
 	// 404: description:No records
 	// 500: description:Internal Server Error
 	r.HandleFunc("/force-character-sheet/{ID}", s.FindForceCharacterSheetByID).Methods(http.MethodGet)
-	// swagger:route GET /force-character-sheet/{name} ForceCharacterSheet
+	// swagger:route GET /user-force-character-sheet ForceCharacterSheet
 	//
-	// Get Force Character Sheet by Name
+	// Get the latest version of a Force Character Sheet by its characterName and playerName query parameters, ignoreCase=true matches the names case insensitively
 	//
 	// Consumes:
 	// - application/json
@@ -100,11 +178,33 @@ func (s *CharacterService) Routes(r *mux.Router) *mux.Router {
 	// 200: description:Success
 	// 400: description:Bad request
 	// 404: description:No records
+	// 409: ConflictResponse
+	// 412: ConflictResponse
+	// 428: description:Precondition Required
 	// 500: description:Internal Server Error
 	r.HandleFunc("/force-character-sheet/{ID}", s.UpdateForceCharacterSheetByID).Methods(http.MethodPut)
+	// swagger:route PATCH /force-character-sheet/{ID} ForceCharacterSheet
+	//
+	// Patch Force Character Sheet by ID with a JSON Merge Patch or a JSON Patch, a patch that changes nothing is not written
+	//
+	// Consumes:
+	// - application/merge-patch+json
+	// - application/json-patch+json
+	// Schemes: http, https
+	//
+	// responses:
+	// 200: ForceCharacterSheet
+	// 400: description:Bad request
+	// 404: description:No records
+	// 409: ConflictResponse
+	// 412: ConflictResponse
+	// 415: description:Unsupported media type
+	// 428: description:Precondition Required
+	// 500: description:Internal Server Error
+	r.HandleFunc("/force-character-sheet/{ID}", s.PatchForceCharacterSheetByID).Methods(http.MethodPatch)
 	// swagger:route DELETE /force-character-sheet/{ID} ForceCharacterSheet
 	//
-	// Update Force Character Sheet by ID
+	// Archive Force Character Sheet by ID
 	//
 	// Consumes:
 	// - application/json
@@ -114,8 +214,336 @@ func (s *CharacterService) Routes(r *mux.Router) *mux.Router {
 	// 204: description:No Content
 	// 400: description:Bad request
 	// 404: description:No records
+	// 412: ConflictResponse
+	// 428: description:Precondition Required
 	// 500: description:Internal Server Error
 	r.HandleFunc("/force-character-sheet/{ID}", s.DeleteForceCharacterSheetByID).Methods(http.MethodDelete)
+	// swagger:route GET /force-character-sheet/{ID}/revisions SheetRevision
+	//
+	// Get the revision history of a Force Character Sheet
+	//
+	// Consumes:
+	// - application/json
+	// Schemes: http, https
+	//
+	// responses:
+	// 200: []SheetRevision
+	// 400: description:Bad request
+	// 500: description:Internal Server Error
+	r.HandleFunc("/force-character-sheet/{ID}/revisions", s.GetForceCharacterSheetRevisions).Methods(http.MethodGet)
+	// swagger:route GET /force-character-sheet/{ID}/revisions/diff SheetRevision
+	//
+	// Diff two revisions of a Force Character Sheet using the from and to query parameters
+	//
+	// Consumes:
+	// - application/json
+	// Schemes: http, https
+	//
+	// responses:
+	// 200: RevisionDiff
+	// 400: description:Bad request
+	// 404: description:No records
+	// 500: description:Internal Server Error
+	r.HandleFunc("/force-character-sheet/{ID}/revisions/diff", s.DiffForceCharacterSheetRevisions).Methods(http.MethodGet)
+	// swagger:route GET /force-character-sheet/{ID}/revisions/{version} SheetRevision
+	//
+	// Get a specific revision of a Force Character Sheet
+	//
+	// Consumes:
+	// - application/json
+	// Schemes: http, https
+	//
+	// responses:
+	// 200: SheetRevision
+	// 400: description:Bad request
+	// 404: description:No records
+	// 500: description:Internal Server Error
+	r.HandleFunc("/force-character-sheet/{ID}/revisions/{version:[0-9]+}", s.FindForceCharacterSheetRevision).Methods(http.MethodGet)
+	// swagger:route POST /force-character-sheet/{ID}/revisions/{version}/revert SheetRevision
+	//
+	// Revert a Force Character Sheet to an earlier revision, creating a new revision
+	//
+	// Consumes:
+	// - application/json
+	// Schemes: http, https
+	//
+	// responses:
+	// 200: description:Success
+	// 400: description:Bad request
+	// 404: description:No records
+	// 409: ConflictResponse
+	// 500: description:Internal Server Error
+	r.HandleFunc("/force-character-sheet/{ID}/revisions/{version:[0-9]+}/revert", s.RevertForceCharacterSheetByID).Methods(http.MethodPost)
+	// swagger:route POST /force-character-sheet/{ID}/damage HealthState
+	//
+	// Take damage on a Force Character Sheet, the soak value is taken off the amount before it is added to the wounds
+	//
+	// Consumes:
+	// - application/json
+	// Schemes: http, https
+	//
+	// responses:
+	// 200: HealthState
+	// 400: description:Bad request
+	// 404: description:No records
+	// 500: description:Internal Server Error
+	r.HandleFunc("/force-character-sheet/{ID}/damage", s.TakeDamage).Methods(http.MethodPost)
+	// swagger:route POST /force-character-sheet/{ID}/suffer-strain HealthState
+	//
+	// Suffer strain on a Force Character Sheet
+	//
+	// Consumes:
+	// - application/json
+	// Schemes: http, https
+	//
+	// responses:
+	// 200: HealthState
+	// 400: description:Bad request
+	// 404: description:No records
+	// 500: description:Internal Server Error
+	r.HandleFunc("/force-character-sheet/{ID}/suffer-strain", s.SufferStrain).Methods(http.MethodPost)
+	// swagger:route POST /force-character-sheet/{ID}/recover-strain HealthState
+	//
+	// Recover strain on a Force Character Sheet, the strain never goes below 0
+	//
+	// Consumes:
+	// - application/json
+	// Schemes: http, https
+	//
+	// responses:
+	// 200: HealthState
+	// 400: description:Bad request
+	// 404: description:No records
+	// 500: description:Internal Server Error
+	r.HandleFunc("/force-character-sheet/{ID}/recover-strain", s.RecoverStrain).Methods(http.MethodPost)
+	// swagger:route POST /force-character-sheet/{ID}/heal HealthState
+	//
+	// Heal wounds on a Force Character Sheet, the wounds never go below 0
+	//
+	// Consumes:
+	// - application/json
+	// Schemes: http, https
+	//
+	// responses:
+	// 200: HealthState
+	// 400: description:Bad request
+	// 404: description:No records
+	// 500: description:Internal Server Error
+	r.HandleFunc("/force-character-sheet/{ID}/heal", s.HealWounds).Methods(http.MethodPost)
+	// swagger:route POST /force-character-sheet/{ID}/purchase PurchaseResult
+	//
+	// Buy a skill rank, characteristic, talent or force power upgrade with the available XP of a Force Character Sheet at the costs of the rulebook
+	//
+	// Consumes:
+	// - application/json
+	// Schemes: http, https
+	//
+	// responses:
+	// 200: PurchaseResult
+	// 400: description:Bad request
+	// 404: description:No records
+	// 409: ConflictResponse
+	// 422: description:Unprocessable Entity
+	// 500: description:Internal Server Error
+	r.HandleFunc("/force-character-sheet/{ID}/purchase", s.PurchaseAdvancement).Methods(http.MethodPost)
+	// swagger:route GET /force-character-sheet/{ID}/{array} SheetElement
+	//
+	// List the elements of an array of a Force Character Sheet: skills, weapons, talents, criticalInjuries, equipment/armor or equipment/personalGear
+	//
+	// Consumes:
+	// - application/json
+	// Schemes: http, https
+	//
+	// responses:
+	// 200: description:Success
+	// 400: description:Bad request
+	// 404: description:No records
+	// 500: description:Internal Server Error
+	r.HandleFunc("/force-character-sheet/{ID}/{array:"+sheetArrayPattern()+"}", s.GetSheetElements).Methods(http.MethodGet)
+	// swagger:route POST /force-character-sheet/{ID}/{array} SheetElement
+	//
+	// Add an element to an array of a Force Character Sheet, the element gets a new ID
+	//
+	// Consumes:
+	// - application/json
+	// Schemes: http, https
+	//
+	// responses:
+	// 201: description:Created
+	// 400: description:Bad request
+	// 404: description:No records
+	// 500: description:Internal Server Error
+	r.HandleFunc("/force-character-sheet/{ID}/{array:"+sheetArrayPattern()+"}", s.InsertSheetElement).Methods(http.MethodPost)
+	// swagger:route GET /force-character-sheet/{ID}/{array}/{elementID} SheetElement
+	//
+	// Get an element of an array of a Force Character Sheet by its ID
+	//
+	// Consumes:
+	// - application/json
+	// Schemes: http, https
+	//
+	// responses:
+	// 200: description:Success
+	// 400: description:Bad request
+	// 404: description:No records
+	// 500: description:Internal Server Error
+	r.HandleFunc("/force-character-sheet/{ID}/{array:"+sheetArrayPattern()+"}/{elementID}", s.FindSheetElement).Methods(http.MethodGet)
+	// swagger:route PUT /force-character-sheet/{ID}/{array}/{elementID} SheetElement
+	//
+	// Replace an element of an array of a Force Character Sheet by its ID
+	//
+	// Consumes:
+	// - application/json
+	// Schemes: http, https
+	//
+	// responses:
+	// 200: description:Success
+	// 400: description:Bad request
+	// 404: description:No records
+	// 500: description:Internal Server Error
+	r.HandleFunc("/force-character-sheet/{ID}/{array:"+sheetArrayPattern()+"}/{elementID}", s.UpdateSheetElement).Methods(http.MethodPut)
+	// swagger:route DELETE /force-character-sheet/{ID}/{array}/{elementID} SheetElement
+	//
+	// Remove an element of an array of a Force Character Sheet by its ID
+	//
+	// Consumes:
+	// - application/json
+	// Schemes: http, https
+	//
+	// responses:
+	// 204: description:No Content
+	// 400: description:Bad request
+	// 404: description:No records
+	// 500: description:Internal Server Error
+	r.HandleFunc("/force-character-sheet/{ID}/{array:"+sheetArrayPattern()+"}/{elementID}", s.DeleteSheetElement).Methods(http.MethodDelete)
+	// swagger:route GET /character/{characterID}/versions ForceCharacterSheet
+	//
+	// List every version of a character, the Force Character Sheets sharing its characterId in characterVersion order
+	//
+	// Consumes:
+	// - application/json
+	// Schemes: http, https
+	//
+	// responses:
+	// 200: []ForceCharacterSheet
+	// 400: description:Bad request
+	// 404: description:No records
+	// 500: description:Internal Server Error
+	r.HandleFunc("/character/{characterID}/versions", s.GetForceCharacterSheetVersions).Methods(http.MethodGet)
+	// swagger:route GET /character/{characterID}/versions/{characterVersion} ForceCharacterSheet
+	//
+	// Get the Force Character Sheet of a given version of a character
+	//
+	// Consumes:
+	// - application/json
+	// Schemes: http, https
+	//
+	// responses:
+	// 200: ForceCharacterSheet
+	// 400: description:Bad request
+	// 404: description:No records
+	// 500: description:Internal Server Error
+	r.HandleFunc("/character/{characterID}/versions/{characterVersion:[0-9]+}", s.FindForceCharacterSheetVersion).Methods(http.MethodGet)
+	// swagger:route POST /character/{characterID}/level-up ForceCharacterSheet
+	//
+	// Level up a character, creating its next version as a new Force Character Sheet copied from the latest version
+	//
+	// Consumes:
+	// - application/json
+	// Schemes: http, https
+	//
+	// responses:
+	// 201: ForceCharacterSheet
+	// 400: description:Bad request
+	// 404: description:No records
+	// 409: description:The character was leveled up at the same time
+	// 500: description:Internal Server Error
+	r.HandleFunc("/character/{characterID}/level-up", s.LevelUpForceCharacterSheet).Methods(http.MethodPost)
+	// swagger:route GET /archived-force-character-sheet ArchivedForceCharacterSheet
+	//
+	// Get Archived Force Character Sheets
+	//
+	// Consumes:
+	// - application/json
+	// Schemes: http, https
+	//
+	// responses:
+	// 200: []ArchivedForceCharacterSheet
+	// 400: description:Bad request
+	// 500: description:Internal Server Error
+	r.HandleFunc("/archived-force-character-sheet", s.GetArchivedForceCharacterSheets).Methods(http.MethodGet)
+	// swagger:route GET /archived-force-character-sheet/{ID} ArchivedForceCharacterSheet
+	//
+	// Get Archived Force Character Sheet by ID
+	//
+	// Consumes:
+	// - application/json
+	// Schemes: http, https
+	//
+	// responses:
+	// 200: ArchivedForceCharacterSheet
+	// 400: description:Bad request
+	// 404: description:No records
+	// 500: description:Internal Server Error
+	r.HandleFunc("/archived-force-character-sheet/{ID}", s.FindArchivedForceCharacterSheetByID).Methods(http.MethodGet)
+	// swagger:route POST /archived-force-character-sheet/{ID}/restore ArchivedForceCharacterSheet
+	//
+	// Restore Archived Force Character Sheet by ID
+	//
+	// Consumes:
+	// - application/json
+	// Schemes: http, https
+	//
+	// responses:
+	// 200: description:Success
+	// 400: description:Bad request
+	// 404: description:No records
+	// 409: description:Sheet already exists
+	// 500: description:Internal Server Error
+	r.HandleFunc("/archived-force-character-sheet/{ID}/restore", s.RestoreForceCharacterSheetByID).Methods(http.MethodPost)
+	// swagger:route DELETE /archived-force-character-sheet/{ID} ArchivedForceCharacterSheet
+	//
+	// Purge Archived Force Character Sheet by ID
+	//
+	// Consumes:
+	// - application/json
+	// Schemes: http, https
+	//
+	// responses:
+	// 204: description:No Content
+	// 400: description:Bad request
+	// 404: description:No records
+	// 500: description:Internal Server Error
+	r.HandleFunc("/archived-force-character-sheet/{ID}", s.PurgeArchivedForceCharacterSheetByID).Methods(http.MethodDelete)
+	// swagger:route GET /admin/backup Admin
+	//
+	// Download a backup bundle of the live and archived Force Character Sheets, requires the admin token
+	//
+	// Produces:
+	// - application/gzip
+	// Schemes: http, https
+	//
+	// responses:
+	// 200: description:Backup bundle
+	// 401: description:Missing or wrong admin token
+	// 403: description:Admin routes disabled
+	// 500: description:Internal Server Error
+	r.HandleFunc("/admin/backup", s.adminOnly(s.BackupForceCharacterSheets)).Methods(http.MethodGet)
+	// swagger:route POST /admin/restore Admin
+	//
+	// Restore a backup bundle, the policy query parameter resolves existing sheets with skip, overwrite or new-ids. Requires the admin token
+	//
+	// Consumes:
+	// - application/gzip
+	// Schemes: http, https
+	//
+	// responses:
+	// 200: RestoreReport
+	// 400: description:Bad request
+	// 401: description:Missing or wrong admin token
+	// 403: description:Admin routes disabled
+	// 500: description:Internal Server Error
+	r.HandleFunc("/admin/restore", s.adminOnly(s.RestoreForceCharacterSheets)).Methods(http.MethodPost)
 
 	fs := http.FileServer(http.Dir("./swagger-ui/"))
 	r.PathPrefix("/swagger").Handler(http.StripPrefix("/swagger", fs))
@@ -131,7 +559,7 @@ func (s *CharacterService) PingCheck(w http.ResponseWriter, r *http.Request) {
 
 func (s *CharacterService) healthCheck(database CharacterDatabase) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		dbErr := database.Ping()
+		dbErr := database.Ping(r.Context())
 		var stringDBErr string
 
 		if dbErr != nil {
@@ -142,6 +570,10 @@ func (s *CharacterService) healthCheck(database CharacterDatabase) http.Handler
 			APIVersion: s.Version,
 			DBError:    stringDBErr,
 		}
+		if cache, ok := database.(interface{ Stats() model.CacheStats }); ok {
+			stats := cache.Stats()
+			response.Cache = &stats
+		}
 
 		if dbErr != nil {
 			api.RespondWithJSON(w, http.StatusFailedDependency, response)
@@ -158,21 +590,18 @@ func (s *CharacterService) InsertForceCharacterSheet(w http.ResponseWriter, r *h
 	defer r.Body.Close()
 
 	var characterSheet model.ForceCharacterSheet
-	characterSheet.ID = primitive.NewObjectID()
 
-	err := json.NewDecoder(r.Body).Decode(&characterSheet)
+	err := decodeSheet(r.Body, &characterSheet)
 	if err != nil {
-		api.RespondWithError(w, http.StatusBadRequest, "Invalid Request Payload")
+		api.RespondWithFailure(w, err)
 		return
 	}
 
-	if characterSheet.Version == 0 {
-		characterSheet.Version = 1
-	}
+	characterSheet = withInsertDefaults(characterSheet)
 
-	err = s.Database.InsertForceCharacterSheet(characterSheet)
+	err = s.Database.InsertForceCharacterSheet(r.Context(), characterSheet)
 	if err != nil {
-		api.RespondWithJSON(w, api.CheckError(err), err.Error())
+		api.RespondWithFailure(w, err)
 		return
 	}
 
@@ -183,13 +612,61 @@ func (s *CharacterService) InsertForceCharacterSheet(w http.ResponseWriter, r *h
 func (s *CharacterService) GetForceCharacterSheets(w http.ResponseWriter, r *http.Request) {
 	logrus.Infof("GetForceCharacterSheet invoked with url: %v", r.URL)
 
-	sheets, err := s.Database.GetForceCharacterSheets(r.URL.Query())
+	query := r.URL.Query()
+	projection, err := api.ParseProjection(query.Get("fields"), api.SheetSchema)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	sheets, err := s.Database.GetForceCharacterSheets(r.Context(), query)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	total, err := s.Database.CountForceCharacterSheets(r.Context(), query)
 	if err != nil {
-		api.RespondWithError(w, api.CheckError(err), err.Error())
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	var lastID *primitive.ObjectID
+	if len(sheets) > 0 {
+		lastID = &sheets[len(sheets)-1].ID
+	}
+
+	pageNumber, pageCount, _ := api.Paging(query)
+	if pageNumber < 1 {
+		pageNumber = 1
+	}
+
+	links := api.BuildPageLinks(r.URL, len(sheets), total, lastID)
+	api.SetLinkHeader(w, links)
+
+	page := model.ForceCharacterSheetPage{
+		Items:      sheets,
+		Total:      total,
+		PageNumber: pageNumber,
+		PageCount:  pageCount,
+		Next:       links["next"],
+		Prev:       links["prev"],
+	}
+	if projection == nil {
+		api.RespondWithJSON(w, http.StatusOK, page)
 		return
 	}
 
-	api.RespondWithJSON(w, http.StatusOK, sheets)
+	items, err := api.Sparse(sheets, projection, api.SheetSchema)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	api.RespondWithJSON(w, http.StatusOK, struct {
+		model.ForceCharacterSheetPage
+		Items interface{} `json:"items"`
+	}{page, items})
 }
 
 //FindForceCharacterSheetByID is the handler function for getting a specific character sheet by database ID
@@ -201,37 +678,76 @@ func (s *CharacterService) FindForceCharacterSheetByID(w http.ResponseWriter, r
 
 	objectID, err := api.StringToObjectID(ID)
 	if err != nil {
-		api.RespondWithError(w, api.CheckError(err), err.Error())
+		api.RespondWithFailure(w, err)
 		return
 	}
 
-	sheet, err := s.Database.FindForceCharacterSheetByID(objectID)
+	projection, err := api.ParseProjection(r.URL.Query().Get("fields"), api.SheetSchema)
 	if err != nil {
-		api.RespondWithError(w, api.CheckError(err), err.Error())
+		api.RespondWithFailure(w, err)
 		return
 	}
 
-	api.RespondWithJSON(w, http.StatusOK, sheet)
-}
+	sheet, err := s.Database.FindForceCharacterSheetByID(r.Context(), objectID, projection.Including("version"))
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
 
-//FindForceCharacterSheetByName is the handler fucntion for getting a specific sheet by user name
-func (s *CharacterService) FindForceCharacterSheetByName(w http.ResponseWriter, r *http.Request) {
-	logrus.Infof("BEGIN - FindCharacterSheetByID invoked with url: %v", r.URL)
+	etag := api.SheetETag(sheet.Version, projection)
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" && api.MatchETag(ifNoneMatch, etag, true) {
+		api.RespondNotModified(w, etag)
+		return
+	}
+	w.Header().Set("ETag", etag)
 
-	sheets, err := s.Database.GetForceCharacterSheets(r.URL.Query())
+	if projection == nil {
+		api.RespondWithJSON(w, http.StatusOK, sheet)
+		return
+	}
+
+	sparse, err := api.Sparse(sheet, projection, api.SheetSchema)
 	if err != nil {
-		api.RespondWithError(w, api.CheckError(err), err.Error())
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	api.RespondWithJSON(w, http.StatusOK, sparse)
+}
+
+//FindForceCharacterSheetByName is the handler function for getting the latest version of a character by its character and player name.
+//Names match exactly unless the ignoreCase query parameter is true
+func (s *CharacterService) FindForceCharacterSheetByName(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("BEGIN - FindForceCharacterSheetByName invoked with url: %v", r.URL)
+
+	query := r.URL.Query()
+	characterName := query.Get("characterName")
+	playerName := query.Get("playerName")
+	if characterName == "" || playerName == "" {
+		field := "characterName"
+		if characterName != "" {
+			field = "playerName"
+		}
+		api.RespondWithFailure(w, dberr.Invalid("invalid_query", field, "invalid query: characterName and playerName are required"))
 		return
 	}
 
-	sheet := sheets[0]
-	if len(sheets) > 1 {
-		for _, value := range sheets {
-			if int(value.Version) > int(sheet.Version) {
-				sheet = value
-			}
+	ignoreCase := false
+	if value := query.Get("ignoreCase"); value != "" {
+		var err error
+		ignoreCase, err = strconv.ParseBool(value)
+		if err != nil {
+			api.RespondWithFailure(w, dberr.Invalid("invalid_query", "ignoreCase", "invalid query: ignoreCase must be true or false"))
+			return
 		}
 	}
+
+	sheet, err := s.Database.FindLatestForceCharacterSheet(r.Context(), characterName, playerName, ignoreCase)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
 	api.RespondWithJSON(w, http.StatusOK, sheet)
 }
 
@@ -243,45 +759,160 @@ func (s *CharacterService) UpdateForceCharacterSheetByID(w http.ResponseWriter,
 	vars := mux.Vars(r)
 	ID := vars["ID"]
 
-	objectID, err := primitive.ObjectIDFromHex(ID)
+	objectID, err := api.StringToObjectID(ID)
 	if err != nil {
-		api.RespondWithError(w, api.CheckError(err), err.Error())
+		api.RespondWithFailure(w, err)
 		return
 	}
 
 	sheet := model.ForceCharacterSheet{}
-	err = json.NewDecoder(r.Body).Decode(&sheet)
+	err = decodeSheet(r.Body, &sheet)
 	if err != nil {
-		api.RespondWithError(w, http.StatusBadRequest, err.Error())
+		api.RespondWithFailure(w, err)
 		return
 	}
 
-	err = s.Database.UpdateForceCharacterSheetByID(sheet, objectID)
+	ifMatch, err := s.ifMatch(r)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+	if ifMatch != "" {
+		current, err := s.Database.FindForceCharacterSheetByID(r.Context(), objectID, nil)
+		if err != nil {
+			api.RespondWithFailure(w, err)
+			return
+		}
+		if err = checkIfMatch(ifMatch, current); err != nil {
+			s.respondWithConflict(w, r, objectID, err)
+			return
+		}
+		// the update is checked against the version that matched, so a write landing in between fails the precondition too
+		sheet.Version = current.Version
+	}
+
+	err = s.Database.UpdateForceCharacterSheetByID(r.Context(), sheet, objectID)
+	if ifMatch != "" && dberr.Code(err) == "version_conflict" {
+		err = dberr.PreconditionFailed(objectID, ifMatch)
+	}
+	if errors.Is(err, dberr.ErrConflict) || errors.Is(err, dberr.ErrPreconditionFailed) {
+		s.respondWithConflict(w, r, objectID, err)
+		return
+	}
 	if err != nil {
-		api.RespondWithError(w, api.CheckError(err), err.Error())
+		api.RespondWithFailure(w, err)
 		return
 	}
 
+	setETag(w, sheet.Version+1)
 	api.RespondWithJSON(w, http.StatusOK, objectID)
 }
 
-//DeleteForceCharacterSheetByID is the handler function for deleting a specific character sheet by database ID
+// respondWithConflict responds with a conflict or failed precondition and the currently stored sheet so the client can merge its changes
+func (s *CharacterService) respondWithConflict(w http.ResponseWriter, r *http.Request, objectID primitive.ObjectID, err error) {
+	current, findErr := s.Database.FindForceCharacterSheetByID(r.Context(), objectID, nil)
+	if findErr != nil {
+		logrus.Warnf("Could not load current sheet %v for conflict response: %v", objectID.Hex(), findErr)
+		current = nil
+	}
+
+	if current != nil {
+		setETag(w, current.Version)
+	}
+
+	problem := api.ProblemFor(err)
+	api.RespondWithProblem(w, problem.Status, model.ConflictResponse{
+		Problem: problem,
+		Current: current,
+	})
+}
+
+//DeleteForceCharacterSheetByID is the handler function for archiving a specific character sheet by database ID
 func (s *CharacterService) DeleteForceCharacterSheetByID(w http.ResponseWriter, r *http.Request) {
-	logrus.Infof("BEGIN - UpdateCharacterSheetByID invoked with url: %v", r.URL)
+	logrus.Infof("BEGIN - DeleteCharacterSheetByID invoked with url: %v", r.URL)
 
 	vars := mux.Vars(r)
 	ID := vars["ID"]
 
-	objectID, err := primitive.ObjectIDFromHex(ID)
+	objectID, err := api.StringToObjectID(ID)
 	if err != nil {
-		api.RespondWithError(w, api.CheckError(err), err.Error())
+		api.RespondWithFailure(w, err)
 		return
 	}
 
-	err = s.Database.DeleteForceCharacterSheetByID(objectID)
+	ifMatch, err := s.ifMatch(r)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+	if ifMatch != "" {
+		current, err := s.Database.FindForceCharacterSheetByID(r.Context(), objectID, nil)
+		if err != nil {
+			api.RespondWithFailure(w, err)
+			return
+		}
+		if err = checkIfMatch(ifMatch, current); err != nil {
+			s.respondWithConflict(w, r, objectID, err)
+			return
+		}
+	}
+
+	err = s.Database.DeleteForceCharacterSheetByID(r.Context(), objectID, api.RequestUser(r))
 	if err != nil {
-		api.RespondWithError(w, api.CheckError(err), err.Error())
+		api.RespondWithFailure(w, err)
+		return
 	}
 
 	api.RespondNoContent(w, http.StatusNoContent)
 }
+
+// withInsertDefaults gives a new sheet an ID and its first version when the client didn't send them
+func withInsertDefaults(sheet model.ForceCharacterSheet) model.ForceCharacterSheet {
+	if sheet.ID.IsZero() {
+		sheet.ID = primitive.NewObjectID()
+	}
+
+	if sheet.Version == 0 {
+		sheet.Version = 1
+	}
+
+	return sheet
+}
+
+// legacySheetFields are the JSON names sheets used before the model tags were corrected.
+// They are still accepted on input so that older clients don't silently drop those fields
+type legacySheetFields struct {
+	Wound                *model.Amount               `json:"wound"`
+	CharcaterDescription *model.CharacterDescription `json:"charcaterDescription"`
+}
+
+// decodeSheet decodes a sheet from a request body, filling fields sent under their legacy names when the current name is absent
+func decodeSheet(body io.Reader, sheet *model.ForceCharacterSheet) error {
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return api.PayloadError(err)
+	}
+
+	err = json.Unmarshal(data, sheet)
+	if err != nil {
+		return api.PayloadError(err)
+	}
+
+	current := struct {
+		Wounds               json.RawMessage `json:"wounds"`
+		CharacterDescription json.RawMessage `json:"characterDescription"`
+	}{}
+	legacy := legacySheetFields{}
+	if json.Unmarshal(data, &current) != nil || json.Unmarshal(data, &legacy) != nil {
+		return nil
+	}
+
+	if current.Wounds == nil && legacy.Wound != nil {
+		sheet.Wounds = *legacy.Wound
+	}
+	if current.CharacterDescription == nil && legacy.CharcaterDescription != nil {
+		sheet.CharacterDescription = *legacy.CharcaterDescription
+	}
+
+	return nil
+}
@@ -2,13 +2,17 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/api"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
 	"github.com/geeksheik9/sheet-CRUD/pkg/db/mocks"
 	"github.com/gorilla/mux"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -67,14 +71,65 @@ func TestCharacterService_GetForceCharacterSheets_Success(t *testing.T) {
 		t.Errorf("GetForceCharacterSheets() error:\ngot: %v\nexpected: %v", w.Code, http.StatusOK)
 	}
 
-	resp := []model.ForceCharacterSheet{}
+	resp := model.ForceCharacterSheetPage{}
 	err = json.NewDecoder(w.Body).Decode(&resp)
 	if err != nil {
 		t.Errorf("GetForceCharacterSheets() json decode error:\ngot: %v\nexpected: <nil>", err)
 	}
-	if resp[0].ID != sheet.ID || resp[0].CharacterName != sheet.CharacterName || resp[0].PlayerName != sheet.PlayerName ||
-		resp[0].Wounds.Threshold != sheet.Wounds.Threshold || resp[0].Wounds.Current != sheet.Wounds.Current || resp[0].ForceRating != sheet.ForceRating {
-		t.Errorf("GetForceCharacterSheets() error:\n got:%v\nexpected:%v", resp[0], sheet)
+	if resp.Total != 1 || resp.PageNumber != 1 || len(resp.Items) != 1 {
+		t.Fatalf("GetForceCharacterSheets() error:\ngot: %+v\nexpected: one sheet on page 1", resp)
+	}
+	item := resp.Items[0]
+	if item.ID != sheet.ID || item.CharacterName != sheet.CharacterName || item.PlayerName != sheet.PlayerName ||
+		item.Wounds.Threshold != sheet.Wounds.Threshold || item.Wounds.Current != sheet.Wounds.Current || item.ForceRating != sheet.ForceRating {
+		t.Errorf("GetForceCharacterSheets() error:\n got:%v\nexpected:%v", item, sheet)
+	}
+	if w.Header().Get("Link") == "" {
+		t.Errorf("GetForceCharacterSheets() error:\ngot: no Link header\nexpected: pagination links")
+	}
+}
+
+func TestCharacterService_GetForceCharacterSheets_Fields(t *testing.T) {
+	id := primitive.NewObjectID()
+	sheet := mockCharacter(id, "test", 2, 0, 5)
+	service := InitMockCharacterService(mockCharacters(sheet), nil, nil)
+
+	r, err := http.NewRequest("GET", "/force-character-sheet?fields=characterName,playerName", nil)
+	if err != nil {
+		t.Errorf("GetForceCharacterSheets() error creating request:\ngot: %v\nexpected:<no error>", err)
+	}
+
+	w := httptest.NewRecorder()
+	router := mux.NewRouter().StrictSlash(true)
+	service.Routes(router).ServeHTTP(w, r)
+
+	resp := struct {
+		Items []map[string]interface{} `json:"items"`
+		Total int64                    `json:"total"`
+	}{}
+	err = json.NewDecoder(w.Body).Decode(&resp)
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("GetForceCharacterSheets() error:\ngot: %v %v\nexpected: %v", w.Code, err, http.StatusOK)
+	}
+	if resp.Total != 1 || len(resp.Items) != 1 || len(resp.Items[0]) != 3 || resp.Items[0]["characterName"] != "test" {
+		t.Errorf("GetForceCharacterSheets() error:\ngot: %v\nexpected: items with only _id, characterName and playerName", resp)
+	}
+}
+
+func TestCharacterService_GetForceCharacterSheets_BadCursor(t *testing.T) {
+	service := InitMockCharacterService(nil, nil, nil)
+
+	r, err := http.NewRequest("GET", "/force-character-sheet?after=bad", nil)
+	if err != nil {
+		t.Errorf("GetForceCharacterSheets() error creating request:\ngot: %v\nexpected:<no error>", err)
+	}
+
+	w := httptest.NewRecorder()
+	router := mux.NewRouter().StrictSlash(true)
+	service.Routes(router).ServeHTTP(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("GetForceCharacterSheets() error:\ngot: %v\nexpected: %v", w.Code, http.StatusBadRequest)
 	}
 }
 
@@ -123,6 +178,47 @@ func TestCharacterService_FindForceCharacterSheetByID_Success(t *testing.T) {
 	}
 }
 
+func TestCharacterService_FindForceCharacterSheetByID_Fields(t *testing.T) {
+	id := primitive.NewObjectID()
+	sheet := mockCharacter(id, "test", 2, 0, 5)
+	service := InitMockCharacterService(nil, &sheet, nil)
+
+	r, err := http.NewRequest("GET", "/force-character-sheet/"+id.Hex()+"?fields=characterName,wounds.current", nil)
+	if err != nil {
+		t.Errorf("FindForceCharacterSheetByID() error creating request:\ngot: %v\nexpected:<no error>", err)
+	}
+
+	w := httptest.NewRecorder()
+	router := mux.NewRouter().StrictSlash(true)
+	service.Routes(router).ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("FindForceCharacterSheetByID() error:\ngot:%v\nexpected:%v", w.Code, http.StatusOK)
+	}
+
+	expected := `{"_id":"` + id.Hex() + `","characterName":"test","wounds":{"current":0}}`
+	if body := strings.TrimSpace(w.Body.String()); body != expected {
+		t.Errorf("FindForceCharacterSheetByID() error:\ngot: %v\nexpected: %v", body, expected)
+	}
+}
+
+func TestCharacterService_FindForceCharacterSheetByID_BadFields(t *testing.T) {
+	id := primitive.NewObjectID()
+	sheet := mockCharacter(id, "test", 2, 0, 5)
+	service := InitMockCharacterService(nil, &sheet, nil)
+
+	r, err := http.NewRequest("GET", "/force-character-sheet/"+id.Hex()+"?fields=characterName,-talents", nil)
+	if err != nil {
+		t.Errorf("FindForceCharacterSheetByID() error creating request:\ngot: %v\nexpected:<no error>", err)
+	}
+
+	w := httptest.NewRecorder()
+	router := mux.NewRouter().StrictSlash(true)
+	service.Routes(router).ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("FindForceCharacterSheetByID() error:\ngot:%v\nexpected:%v", w.Code, http.StatusBadRequest)
+	}
+}
+
 func TestCharacterService_FindForceCharacterSheetByID_DBError(t *testing.T) {
 	id := primitive.NewObjectID()
 	service := InitMockCharacterService(nil, nil, errors.New("test error"))
@@ -141,6 +237,24 @@ func TestCharacterService_FindForceCharacterSheetByID_DBError(t *testing.T) {
 	}
 }
 
+func TestCharacterService_FindForceCharacterSheetByID_Timeout(t *testing.T) {
+	id := primitive.NewObjectID()
+	service := InitMockCharacterService(nil, nil, context.DeadlineExceeded)
+
+	r, err := http.NewRequest("GET", "/force-character-sheet/"+id.Hex(), nil)
+	if err != nil {
+		t.Errorf("FindForceCharacterSheetByID() error creating request:\ngot: %v\nexpected:<no error>", err)
+	}
+
+	w := httptest.NewRecorder()
+	router := mux.NewRouter().StrictSlash(true)
+	service.Routes(router).ServeHTTP(w, r)
+
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("FindForceCharacterSheetByID() error:\ngot: %v\nexpected: %v", w.Code, http.StatusGatewayTimeout)
+	}
+}
+
 func TestCharacterService_FindForceCharacterSheetByID_BadID(t *testing.T) {
 	id := "this is a bad id"
 	service := InitMockCharacterService(nil, nil, nil)
@@ -154,8 +268,62 @@ func TestCharacterService_FindForceCharacterSheetByID_BadID(t *testing.T) {
 	router := mux.NewRouter().StrictSlash(true)
 	service.Routes(router).ServeHTTP(w, r)
 
-	if w.Code != http.StatusInternalServerError {
-		t.Errorf("FindForceCharacterSheetByID() error:\ngot: %v\nexpected: %v", w.Code, http.StatusInternalServerError)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("FindForceCharacterSheetByID() error:\ngot: %v\nexpected: %v", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestCharacterService_FindForceCharacterSheetByName_Success(t *testing.T) {
+	sheet := mockCharacter(primitive.NewObjectID(), "test", 2, 0, 5)
+	sheet.Version = 3
+	service := InitMockCharacterService(nil, &sheet, nil)
+
+	r, err := http.NewRequest("GET", "/user-force-character-sheet?characterName=test&playerName=test&ignoreCase=true", nil)
+	if err != nil {
+		t.Errorf("FindForceCharacterSheetByName() error creating request:\ngot: %v\nexpected:<no error>", err)
+	}
+
+	w := httptest.NewRecorder()
+	router := mux.NewRouter().StrictSlash(true)
+	service.Routes(router).ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("FindForceCharacterSheetByName() error:\ngot: %v\nexpected: %v", w.Code, http.StatusOK)
+	}
+
+	resp := model.ForceCharacterSheet{}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.ID != sheet.ID || resp.Version != 3 {
+		t.Errorf("FindForceCharacterSheetByName() error:\ngot: %v\nexpected: %v", resp, sheet)
+	}
+}
+
+func TestCharacterService_FindForceCharacterSheetByName_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		dbErr    error
+		expected int
+	}{
+		{name: "no player name", query: "characterName=test", expected: http.StatusBadRequest},
+		{name: "no character name", query: "playerName=test", expected: http.StatusBadRequest},
+		{name: "bad ignoreCase", query: "characterName=test&playerName=test&ignoreCase=maybe", expected: http.StatusBadRequest},
+		{name: "not found", query: "characterName=test&playerName=test", dbErr: dberr.LatestNotFound("test", "test"), expected: http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		service := InitMockCharacterService(nil, nil, test.dbErr)
+
+		r, err := http.NewRequest("GET", "/user-force-character-sheet?"+test.query, nil)
+		if err != nil {
+			t.Errorf("FindForceCharacterSheetByName() %v error creating request:\ngot: %v\nexpected:<no error>", test.name, err)
+		}
+
+		w := httptest.NewRecorder()
+		router := mux.NewRouter().StrictSlash(true)
+		service.Routes(router).ServeHTTP(w, r)
+		if w.Code != test.expected {
+			t.Errorf("FindForceCharacterSheetByName() %v error:\ngot: %v\nexpected: %v", test.name, w.Code, test.expected)
+		}
 	}
 }
 
@@ -179,6 +347,64 @@ func TestCharacterService_UpdateForceCharacterSheetByID_Success(t *testing.T) {
 	}
 }
 
+func TestCharacterService_UpdateForceCharacterSheetByID_IncrementsVersion(t *testing.T) {
+	id := primitive.NewObjectID()
+	stored := mockCharacter(id, "test", 2, 0, 5)
+	stored.Version = 3
+	service := InitMockCharacterService(nil, &stored, nil)
+
+	sheet := mockCharacter(id, "test", 2, 1, 5)
+	sheet.Version = 3
+	request, _ := json.Marshal(sheet)
+
+	r, err := http.NewRequest("PUT", "/force-character-sheet/"+id.Hex(), bytes.NewBuffer(request))
+	if err != nil {
+		t.Errorf("UpdateForceCharacterSheetByID() error creating request:\ngot: %v\nexpected:<no error>", err)
+	}
+
+	w := httptest.NewRecorder()
+	router := mux.NewRouter().StrictSlash(true)
+	service.Routes(router).ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("UpdateForceCharacterSheetByID() error:\ngot:%v\nexpected:%v", w.Code, http.StatusOK)
+	}
+	if stored.Version != 4 || stored.Wounds.Current != 1 {
+		t.Errorf("UpdateForceCharacterSheetByID() error:\ngot version: %v wounds: %v\nexpected version: 4 wounds: 1", stored.Version, stored.Wounds.Current)
+	}
+}
+
+func TestCharacterService_UpdateForceCharacterSheetByID_StaleVersion(t *testing.T) {
+	id := primitive.NewObjectID()
+	stored := mockCharacter(id, "test", 2, 0, 5)
+	stored.Version = 4
+	service := InitMockCharacterService(nil, &stored, nil)
+
+	sheet := mockCharacter(id, "test", 2, 1, 5)
+	sheet.Version = 3
+	request, _ := json.Marshal(sheet)
+
+	r, err := http.NewRequest("PUT", "/force-character-sheet/"+id.Hex(), bytes.NewBuffer(request))
+	if err != nil {
+		t.Errorf("UpdateForceCharacterSheetByID() error creating request:\ngot: %v\nexpected:<no error>", err)
+	}
+
+	w := httptest.NewRecorder()
+	router := mux.NewRouter().StrictSlash(true)
+	service.Routes(router).ServeHTTP(w, r)
+	if w.Code != http.StatusConflict {
+		t.Errorf("UpdateForceCharacterSheetByID() error:\ngot:%v\nexpected:%v", w.Code, http.StatusConflict)
+	}
+
+	resp := model.ConflictResponse{}
+	err = json.NewDecoder(w.Body).Decode(&resp)
+	if err != nil {
+		t.Errorf("UpdateForceCharacterSheetByID() json decode error:\ngot: %v\nexpected: <nil>", err)
+	}
+	if resp.Current == nil || resp.Current.Version != 4 || resp.Current.Wounds.Current != 0 {
+		t.Errorf("UpdateForceCharacterSheetByID() error:\ngot current: %v\nexpected: %v", resp.Current, stored)
+	}
+}
+
 func TestCharacterService_UpdateForceCharacterSheetByID_DBError(t *testing.T) {
 	id := primitive.NewObjectID()
 	sheet := mockCharacter(id, "test", 2, 0, 5)
@@ -216,8 +442,8 @@ func TestCharacterService_UpdateForceCharacterSheetByID_BadID(t *testing.T) {
 	router := mux.NewRouter().StrictSlash(true)
 	service.Routes(router).ServeHTTP(w, r)
 
-	if w.Code != http.StatusInternalServerError {
-		t.Errorf("FindForceCharacterSheetByID() error:\ngot: %v\nexpected: %v", w.Code, http.StatusInternalServerError)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("FindForceCharacterSheetByID() error:\ngot: %v\nexpected: %v", w.Code, http.StatusBadRequest)
 	}
 }
 
@@ -301,6 +527,28 @@ func TestCharacterService_InsertForceCharacterSheet_BadJSON(t *testing.T) {
 	}
 }
 
+func TestCharacterService_InsertForceCharacterSheet_Problem(t *testing.T) {
+	service := InitMockCharacterService(nil, nil, nil)
+
+	r, err := http.NewRequest("POST", "/force-character-sheet", bytes.NewBufferString(`{"characterName":"Hera","availableXP":"lots"}`))
+	if err != nil {
+		t.Errorf("InsertForceCharacterSheet() error creating request:\ngot: %v\nexpected:<no error>", err)
+	}
+
+	w := httptest.NewRecorder()
+	router := mux.NewRouter().StrictSlash(true)
+	service.Routes(router).ServeHTTP(w, r)
+
+	problem := model.Problem{}
+	json.NewDecoder(w.Body).Decode(&problem)
+	if w.Code != http.StatusBadRequest || w.Header().Get("Content-Type") != api.ProblemContentType {
+		t.Errorf("InsertForceCharacterSheet() error:\ngot: %v %v\nexpected: %v %v", w.Code, w.Header().Get("Content-Type"), http.StatusBadRequest, api.ProblemContentType)
+	}
+	if problem.Code != "invalid_payload" || len(problem.Violations) != 1 || problem.Violations[0].Field != "availableXP" {
+		t.Errorf("InsertForceCharacterSheet() error:\ngot: %+v\nexpected: an invalid_payload problem with a violation of availableXP", problem)
+	}
+}
+
 func TestCharacterService_DeleteForceCharacterSheetByID_Success(t *testing.T) {
 	id := primitive.NewObjectID()
 	service := InitMockCharacterService(nil, nil, nil)
@@ -349,8 +597,8 @@ func TestCharacterService_DeleteForceCharacterSheetByID_BadID(t *testing.T) {
 	router := mux.NewRouter().StrictSlash(true)
 	service.Routes(router).ServeHTTP(w, r)
 
-	if w.Code != http.StatusInternalServerError {
-		t.Errorf("FindForceCharacterSheetByID() error:\ngot: %v\nexpected: %v", w.Code, http.StatusInternalServerError)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("FindForceCharacterSheetByID() error:\ngot: %v\nexpected: %v", w.Code, http.StatusBadRequest)
 	}
 }
 
@@ -397,3 +645,20 @@ func TestCharacterService_HealthCheck_error(t *testing.T) {
 		t.Errorf("Ping() error:\ngot: %v\n expected: %v", w.Code, http.StatusFailedDependency)
 	}
 }
+
+func TestDecodeSheet_LegacyFields(t *testing.T) {
+	body := `{"characterName":"test","wound":{"threshold":12,"current":3},"charcaterDescription":{"hair":"brown"}}`
+
+	sheet := model.ForceCharacterSheet{}
+	err := decodeSheet(strings.NewReader(body), &sheet)
+	if err != nil || sheet.Wounds.Current != 3 || sheet.CharacterDescription.Hair != "brown" {
+		t.Errorf("decodeSheet() error:\ngot: %+v %v\nexpected: legacy wound and charcaterDescription decoded", sheet, err)
+	}
+
+	body = `{"wounds":{"current":1},"wound":{"current":3}}`
+	sheet = model.ForceCharacterSheet{}
+	err = decodeSheet(strings.NewReader(body), &sheet)
+	if err != nil || sheet.Wounds.Current != 1 {
+		t.Errorf("decodeSheet() error:\ngot: %v %v\nexpected: wounds preferred over wound", sheet.Wounds, err)
+	}
+}
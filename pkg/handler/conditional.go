@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/api"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ifMatch returns the If-Match header of a write to a sheet, a write without one fails with PreconditionRequiredError when RequireIfMatch is set
+func (s *CharacterService) ifMatch(r *http.Request) (string, error) {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" && s.RequireIfMatch {
+		return "", dberr.PreconditionRequired()
+	}
+
+	return ifMatch, nil
+}
+
+// checkIfMatch returns PreconditionFailedError when an If-Match header doesn't match the ETag of the sheet, an empty header always matches
+func checkIfMatch(ifMatch string, sheet *model.ForceCharacterSheet) error {
+	if ifMatch == "" || api.MatchETag(ifMatch, api.SheetETag(sheet.Version, nil), false) {
+		return nil
+	}
+
+	return dberr.PreconditionFailed(sheet.ID, ifMatch)
+}
+
+// expectedVersion returns the If-Match header of a write to a sheet and the version of the sheet it matches, or 0 without a header so the write
+// applies to whatever version it finds. The write is passed the version so that one landing in between fails the precondition too
+func (s *CharacterService) expectedVersion(r *http.Request, mongoID primitive.ObjectID) (string, int64, error) {
+	ifMatch, err := s.ifMatch(r)
+	if err != nil || ifMatch == "" {
+		return ifMatch, 0, err
+	}
+
+	current, err := s.Database.FindForceCharacterSheetByID(r.Context(), mongoID, nil)
+	if err != nil {
+		return ifMatch, 0, err
+	}
+
+	return ifMatch, current.Version, checkIfMatch(ifMatch, current)
+}
+
+// respondWithWriteFailure responds to a failed write to a sheet. A version conflict of a write with an If-Match header is a failed precondition,
+// conflicts and failed preconditions are answered with the current sheet
+func (s *CharacterService) respondWithWriteFailure(w http.ResponseWriter, r *http.Request, mongoID primitive.ObjectID, ifMatch string, err error) {
+	if ifMatch != "" && dberr.Code(err) == "version_conflict" {
+		err = dberr.PreconditionFailed(mongoID, ifMatch)
+	}
+	if errors.Is(err, dberr.ErrConflict) || errors.Is(err, dberr.ErrPreconditionFailed) {
+		s.respondWithConflict(w, r, mongoID, err)
+		return
+	}
+
+	api.RespondWithFailure(w, err)
+}
+
+// setETag sets the ETag header to the tag of the whole sheet at a version
+func setETag(w http.ResponseWriter, version int64) {
+	w.Header().Set("ETag", api.SheetETag(version, nil))
+}
@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/api"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/memory"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func serveConditional(t *testing.T, router *mux.Router, method string, url string, header http.Header, body string) *httptest.ResponseRecorder {
+	r, err := http.NewRequest(method, url, bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatalf("%v %v error creating request:\ngot: %v\nexpected:<no error>", method, url, err)
+	}
+	for key, values := range header {
+		r.Header[key] = values
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	return w
+}
+
+func servePatchIfMatch(t *testing.T, router *mux.Router, id primitive.ObjectID, ifMatch string, patch string) *httptest.ResponseRecorder {
+	header := http.Header{"Content-Type": {api.MergePatchContentType}}
+	if ifMatch != "" {
+		header.Set("If-Match", ifMatch)
+	}
+
+	return serveConditional(t, router, "PATCH", "/force-character-sheet/"+id.Hex(), header, patch)
+}
+
+func TestCharacterService_FindForceCharacterSheetByID_IfNoneMatch(t *testing.T) {
+	router, id := patchService(t)
+	url := "/force-character-sheet/" + id.Hex()
+
+	w := serveConditional(t, router, "GET", url, nil, "")
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"1"` {
+		t.Fatalf("FindForceCharacterSheetByID() error:\ngot: %v %v\nexpected: %v \"1\"", w.Code, w.Header().Get("ETag"), http.StatusOK)
+	}
+
+	w = serveConditional(t, router, "GET", url, http.Header{"If-None-Match": {`W/"1"`}}, "")
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 || w.Header().Get("ETag") != `"1"` {
+		t.Errorf("FindForceCharacterSheetByID() not modified error:\ngot: %v %v %q\nexpected: %v \"1\" without a body", w.Code, w.Header().Get("ETag"), w.Body.String(), http.StatusNotModified)
+	}
+
+	w = serveConditional(t, router, "GET", url+"?fields=characterName", http.Header{"If-None-Match": {`"1"`}}, "")
+	sparse := map[string]interface{}{}
+	_ = json.Unmarshal(w.Body.Bytes(), &sparse)
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag == `"1"` || etag != api.SheetETag(1, api.Projection{"_id": 1, "characterName": 1}) || sparse["version"] != nil {
+		t.Errorf("FindForceCharacterSheetByID() projection error:\ngot: %v %v %v\nexpected: %v with an ETag of its own and no version", w.Code, etag, sparse, http.StatusOK)
+	}
+
+	servePatchIfMatch(t, router, id, "", `{"wounds":{"current":1}}`)
+	w = serveConditional(t, router, "GET", url, http.Header{"If-None-Match": {`"1"`}}, "")
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"2"` {
+		t.Errorf("FindForceCharacterSheetByID() after update error:\ngot: %v %v\nexpected: %v \"2\"", w.Code, w.Header().Get("ETag"), http.StatusOK)
+	}
+}
+
+func TestCharacterService_UpdateForceCharacterSheetByID_IfMatch(t *testing.T) {
+	router, id := patchService(t)
+	url := "/force-character-sheet/" + id.Hex()
+
+	sheet := mockCharacter(id, "Mando", 12, 3, 1)
+	body, _ := json.Marshal(sheet)
+
+	w := serveConditional(t, router, "PUT", url, http.Header{"If-Match": {`"7"`}}, string(body))
+	conflict := model.ConflictResponse{}
+	_ = json.Unmarshal(w.Body.Bytes(), &conflict)
+	if w.Code != http.StatusPreconditionFailed || conflict.Code != "precondition_failed" || conflict.Current == nil || w.Header().Get("ETag") != `"1"` {
+		t.Errorf("UpdateForceCharacterSheetByID() stale If-Match error:\ngot: %v %+v %v\nexpected: %v precondition_failed with the current sheet", w.Code, conflict, w.Header().Get("ETag"), http.StatusPreconditionFailed)
+	}
+
+	w = serveConditional(t, router, "PUT", url, http.Header{"If-Match": {`"1"`}}, string(body))
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"2"` {
+		t.Errorf("UpdateForceCharacterSheetByID() If-Match error:\ngot: %v %v\nexpected: %v \"2\"", w.Code, w.Header().Get("ETag"), http.StatusOK)
+	}
+
+	w = servePatchIfMatch(t, router, id, `"1"`, `{"wounds":{"current":4}}`)
+	if w.Code != http.StatusPreconditionFailed {
+		t.Errorf("PatchForceCharacterSheetByID() stale If-Match error:\ngot: %v\nexpected: %v", w.Code, http.StatusPreconditionFailed)
+	}
+
+	w = servePatchIfMatch(t, router, id, `"2"`, `{"wounds":{"current":4}}`)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"3"` {
+		t.Errorf("PatchForceCharacterSheetByID() If-Match error:\ngot: %v %v\nexpected: %v \"3\"", w.Code, w.Header().Get("ETag"), http.StatusOK)
+	}
+
+	w = serveConditional(t, router, "DELETE", url, http.Header{"If-Match": {`"2"`}}, "")
+	if w.Code != http.StatusPreconditionFailed {
+		t.Errorf("DeleteForceCharacterSheetByID() stale If-Match error:\ngot: %v\nexpected: %v", w.Code, http.StatusPreconditionFailed)
+	}
+
+	w = serveConditional(t, router, "DELETE", url, http.Header{"If-Match": {`"3"`}}, "")
+	if w.Code != http.StatusNoContent {
+		t.Errorf("DeleteForceCharacterSheetByID() If-Match error:\ngot: %v\nexpected: %v", w.Code, http.StatusNoContent)
+	}
+}
+
+func TestCharacterService_RequireIfMatch(t *testing.T) {
+	service := CharacterService{
+		Version:        "test",
+		Database:       memory.New(),
+		RequireIfMatch: true,
+	}
+	router := service.Routes(mux.NewRouter().StrictSlash(true))
+
+	sheet := mockCharacter(primitive.NewObjectID(), "Mando", 12, 0, 1)
+	w := serveMemory(t, router, "POST", "/force-character-sheet", sheet)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("InsertForceCharacterSheet() error:\ngot: %v\nexpected: %v", w.Code, http.StatusCreated)
+	}
+
+	url := "/force-character-sheet/" + sheet.ID.Hex()
+	body, _ := json.Marshal(sheet)
+	for _, w := range []*httptest.ResponseRecorder{
+		serveConditional(t, router, "PUT", url, nil, string(body)),
+		servePatchIfMatch(t, router, sheet.ID, "", `{"wounds":{"current":4}}`),
+		serveConditional(t, router, "DELETE", url, nil, ""),
+		serveConditional(t, router, "POST", url+"/weapons", nil, `{"name":"Blaster"}`),
+		serveConditional(t, router, "POST", url+"/damage", nil, `{"amount":5}`),
+		serveConditional(t, router, "POST", url+"/purchase", nil, `{"type":"skill","name":"Cool"}`),
+	} {
+		problem := model.Problem{}
+		_ = json.Unmarshal(w.Body.Bytes(), &problem)
+		if w.Code != http.StatusPreconditionRequired || problem.Code != "precondition_required" {
+			t.Errorf("RequireIfMatch error:\ngot: %v %v\nexpected: %v precondition_required", w.Code, problem.Code, http.StatusPreconditionRequired)
+		}
+	}
+
+	w = serveConditional(t, router, "DELETE", url, http.Header{"If-Match": {"*"}}, "")
+	if w.Code != http.StatusNoContent {
+		t.Errorf("DeleteForceCharacterSheetByID() If-Match * error:\ngot: %v\nexpected: %v", w.Code, http.StatusNoContent)
+	}
+}
@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/geeksheik9/sheet-CRUD/pkg/api"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// sheetArrayPattern matches the route names of the sheet arrays, see api.SheetArrays
+func sheetArrayPattern() string {
+	names := []string{}
+	for _, array := range api.SheetArrays {
+		names = append(names, array.Name)
+	}
+
+	return strings.Join(names, "|")
+}
+
+//GetSheetElements is the handler function for listing the elements of an array of a specific character sheet
+func (s *CharacterService) GetSheetElements(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("BEGIN - GetSheetElements invoked with url: %v", r.URL)
+
+	objectID, array, err := sheetArrayVars(r)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	sheet, err := s.Database.FindForceCharacterSheetByID(r.Context(), objectID, nil)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	setETag(w, sheet.Version)
+	api.RespondWithJSON(w, http.StatusOK, array.Elements(*sheet))
+}
+
+//FindSheetElement is the handler function for getting an element of an array of a specific character sheet by its ID
+func (s *CharacterService) FindSheetElement(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("BEGIN - FindSheetElement invoked with url: %v", r.URL)
+
+	objectID, array, err := sheetArrayVars(r)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	elementID, err := api.StringToObjectID(mux.Vars(r)["elementID"])
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	sheet, err := s.Database.FindForceCharacterSheetByID(r.Context(), objectID, nil)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	element, found := array.FindElement(*sheet, elementID)
+	if !found {
+		api.RespondWithFailure(w, dberr.ElementNotFound(objectID, array.Name, elementID))
+		return
+	}
+
+	setETag(w, sheet.Version)
+	api.RespondWithJSON(w, http.StatusOK, element)
+}
+
+//InsertSheetElement is the handler function for adding an element to an array of a specific character sheet.
+//The element gets a new ID whatever ID the body holds
+func (s *CharacterService) InsertSheetElement(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("BEGIN - InsertSheetElement invoked with url: %v", r.URL)
+	defer r.Body.Close()
+
+	objectID, array, err := sheetArrayVars(r)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	element, err := decodeElement(r, array, primitive.NewObjectID())
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	ifMatch, version, err := s.expectedVersion(r, objectID)
+	if err != nil {
+		s.respondWithWriteFailure(w, r, objectID, ifMatch, err)
+		return
+	}
+
+	sheet, err := s.Database.InsertSheetElement(r.Context(), objectID, array, element, version)
+	if err != nil {
+		s.respondWithWriteFailure(w, r, objectID, ifMatch, err)
+		return
+	}
+
+	setETag(w, sheet.Version)
+	api.RespondWithJSON(w, http.StatusCreated, element)
+}
+
+//UpdateSheetElement is the handler function for replacing an element of an array of a specific character sheet by its ID
+func (s *CharacterService) UpdateSheetElement(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("BEGIN - UpdateSheetElement invoked with url: %v", r.URL)
+	defer r.Body.Close()
+
+	objectID, array, err := sheetArrayVars(r)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	elementID, err := api.StringToObjectID(mux.Vars(r)["elementID"])
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	element, err := decodeElement(r, array, elementID)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	ifMatch, version, err := s.expectedVersion(r, objectID)
+	if err != nil {
+		s.respondWithWriteFailure(w, r, objectID, ifMatch, err)
+		return
+	}
+
+	sheet, err := s.Database.UpdateSheetElement(r.Context(), objectID, array, elementID, element, version)
+	if err != nil {
+		s.respondWithWriteFailure(w, r, objectID, ifMatch, err)
+		return
+	}
+
+	setETag(w, sheet.Version)
+	api.RespondWithJSON(w, http.StatusOK, element)
+}
+
+//DeleteSheetElement is the handler function for removing an element of an array of a specific character sheet by its ID
+func (s *CharacterService) DeleteSheetElement(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("BEGIN - DeleteSheetElement invoked with url: %v", r.URL)
+
+	objectID, array, err := sheetArrayVars(r)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	elementID, err := api.StringToObjectID(mux.Vars(r)["elementID"])
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	ifMatch, version, err := s.expectedVersion(r, objectID)
+	if err != nil {
+		s.respondWithWriteFailure(w, r, objectID, ifMatch, err)
+		return
+	}
+
+	sheet, err := s.Database.DeleteSheetElement(r.Context(), objectID, array, elementID, version)
+	if err != nil {
+		s.respondWithWriteFailure(w, r, objectID, ifMatch, err)
+		return
+	}
+
+	setETag(w, sheet.Version)
+	api.RespondNoContent(w, http.StatusNoContent)
+}
+
+// sheetArrayVars returns the sheet ID and the sheet array named by the route
+func sheetArrayVars(r *http.Request) (primitive.ObjectID, api.SheetArray, error) {
+	vars := mux.Vars(r)
+
+	objectID, err := api.StringToObjectID(vars["ID"])
+	if err != nil {
+		return objectID, api.SheetArray{}, err
+	}
+
+	array, found := api.FindSheetArray(vars["array"])
+	if !found {
+		return objectID, array, dberr.Invalid("invalid_query", "array", "invalid query: %q is not an array of a sheet", vars["array"])
+	}
+
+	return objectID, array, nil
+}
+
+// decodeElement decodes an element of the array from a request body and gives it the ID
+func decodeElement(r *http.Request, array api.SheetArray, elementID primitive.ObjectID) (interface{}, error) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, api.PayloadError(err)
+	}
+
+	element := array.NewElement()
+	err = json.Unmarshal(data, element)
+	if err != nil {
+		return nil, api.PayloadError(err)
+	}
+	api.SetElementID(element, elementID)
+
+	return element, nil
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/memory"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCharacterService_SheetElements(t *testing.T) {
+	router, id := patchService(t)
+	url := "/force-character-sheet/" + id.Hex() + "/weapons"
+
+	w := serveMemory(t, router, "POST", url, model.Weapons{ID: id, Name: "Blaster Pistol", Damage: "6"})
+	weapon := model.Weapons{}
+	_ = json.Unmarshal(w.Body.Bytes(), &weapon)
+	if w.Code != http.StatusCreated || weapon.ID.IsZero() || weapon.ID == id || weapon.Name != "Blaster Pistol" || w.Header().Get("ETag") != `"2"` {
+		t.Errorf("InsertSheetElement() error:\ngot: %v %+v %v\nexpected: %v a Blaster Pistol with a new ID at \"2\"", w.Code, weapon, w.Header().Get("ETag"), http.StatusCreated)
+	}
+
+	w = serveMemory(t, router, "GET", url+"/"+weapon.ID.Hex(), nil)
+	found := model.Weapons{}
+	_ = json.Unmarshal(w.Body.Bytes(), &found)
+	if w.Code != http.StatusOK || found != weapon {
+		t.Errorf("FindSheetElement() error:\ngot: %v %+v\nexpected: %v %+v", w.Code, found, http.StatusOK, weapon)
+	}
+
+	w = serveMemory(t, router, "PUT", url+"/"+weapon.ID.Hex(), model.Weapons{Name: "Heavy Blaster Pistol", Damage: "7"})
+	updated := model.Weapons{}
+	_ = json.Unmarshal(w.Body.Bytes(), &updated)
+	if w.Code != http.StatusOK || updated.ID != weapon.ID || updated.Damage != "7" || w.Header().Get("ETag") != `"3"` {
+		t.Errorf("UpdateSheetElement() error:\ngot: %v %+v %v\nexpected: %v damage 7 with the same ID at \"3\"", w.Code, updated, w.Header().Get("ETag"), http.StatusOK)
+	}
+
+	w = serveMemory(t, router, "GET", url, nil)
+	weapons := []model.Weapons{}
+	_ = json.Unmarshal(w.Body.Bytes(), &weapons)
+	if w.Code != http.StatusOK || len(weapons) != 1 || weapons[0] != updated {
+		t.Errorf("GetSheetElements() error:\ngot: %v %+v\nexpected: %v [%+v]", w.Code, weapons, http.StatusOK, updated)
+	}
+
+	w = serveMemory(t, router, "DELETE", url+"/"+weapon.ID.Hex(), nil)
+	if w.Code != http.StatusNoContent || w.Header().Get("ETag") != `"4"` {
+		t.Errorf("DeleteSheetElement() error:\ngot: %v %v\nexpected: %v \"4\"", w.Code, w.Header().Get("ETag"), http.StatusNoContent)
+	}
+
+	for _, method := range []string{"GET", "PUT", "DELETE"} {
+		w = serveMemory(t, router, method, url+"/"+weapon.ID.Hex(), model.Weapons{})
+		problem := model.Problem{}
+		_ = json.Unmarshal(w.Body.Bytes(), &problem)
+		if w.Code != http.StatusNotFound || problem.Code != "element_not_found" {
+			t.Errorf("%v removed element error:\ngot: %v %v\nexpected: %v element_not_found", method, w.Code, problem.Code, http.StatusNotFound)
+		}
+	}
+
+	w = serveMemory(t, router, "GET", "/force-character-sheet/"+primitive.NewObjectID().Hex()+"/weapons", nil)
+	problem := model.Problem{}
+	_ = json.Unmarshal(w.Body.Bytes(), &problem)
+	if w.Code != http.StatusNotFound || problem.Code != "sheet_not_found" {
+		t.Errorf("GetSheetElements() missing sheet error:\ngot: %v %v\nexpected: %v sheet_not_found", w.Code, problem.Code, http.StatusNotFound)
+	}
+
+	w = serveMemory(t, router, "GET", url+"/nope", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("FindSheetElement() bad ID error:\ngot: %v\nexpected: %v", w.Code, http.StatusBadRequest)
+	}
+
+	w = serveMemory(t, router, "POST", url, []string{"not", "a", "weapon"})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("InsertSheetElement() bad payload error:\ngot: %v\nexpected: %v", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestCharacterService_InsertSheetElement_Concurrent(t *testing.T) {
+	router, id := patchService(t)
+	url := "/force-character-sheet/" + id.Hex() + "/equipment/personalGear"
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			serveMemory(t, router, "POST", url, model.Gear{Gear: "Stimpack"})
+		}()
+	}
+	wg.Wait()
+
+	w := serveMemory(t, router, "GET", url, nil)
+	gear := []model.Gear{}
+	_ = json.Unmarshal(w.Body.Bytes(), &gear)
+	if w.Code != http.StatusOK || len(gear) != 10 || w.Header().Get("ETag") != `"11"` {
+		t.Errorf("InsertSheetElement() concurrent error:\ngot: %v %v items at %v\nexpected: %v 10 items at \"11\"", w.Code, len(gear), w.Header().Get("ETag"), http.StatusOK)
+	}
+}
+
+func TestCharacterService_GetSheetElements_DoesNotWrite(t *testing.T) {
+	database := memory.New()
+	service := CharacterService{Version: "test", Database: database}
+	router := service.Routes(mux.NewRouter().StrictSlash(true))
+
+	w := serveMemory(t, router, "POST", "/force-character-sheet", mockCharacter(primitive.NilObjectID, "Mando", 12, 0, 1))
+	var id primitive.ObjectID
+	_ = json.NewDecoder(w.Body).Decode(&id)
+
+	// a weapon stored before elements had IDs
+	_, err := database.UpdateDocuments(context.Background(), db.SheetsCollection, bson.M{"_id": id}, bson.M{"$set": bson.M{"weapons": bson.A{bson.M{"name": "Amban Rifle"}}}})
+	if err != nil {
+		t.Fatalf("UpdateDocuments() error:\ngot: %v\nexpected: <nil>", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		w = serveMemory(t, router, "GET", "/force-character-sheet/"+id.Hex()+"/weapons", nil)
+		weapons := []model.Weapons{}
+		_ = json.Unmarshal(w.Body.Bytes(), &weapons)
+		if w.Code != http.StatusOK || len(weapons) != 1 || !weapons[0].ID.IsZero() || w.Header().Get("ETag") != `"1"` {
+			t.Errorf("GetSheetElements() error:\ngot: %v %+v at %v\nexpected: %v the rifle as stored at \"1\"", w.Code, weapons, w.Header().Get("ETag"), http.StatusOK)
+		}
+	}
+}
+
+func TestCharacterService_SheetElements_IfMatch(t *testing.T) {
+	router, id := patchService(t)
+	url := "/force-character-sheet/" + id.Hex() + "/weapons"
+
+	w := serveConditional(t, router, "POST", url, http.Header{"If-Match": {`"1"`}}, `{"name":"Blaster Pistol"}`)
+	weapon := model.Weapons{}
+	_ = json.Unmarshal(w.Body.Bytes(), &weapon)
+	if w.Code != http.StatusCreated || w.Header().Get("ETag") != `"2"` {
+		t.Errorf("InsertSheetElement() If-Match error:\ngot: %v %v\nexpected: %v \"2\"", w.Code, w.Header().Get("ETag"), http.StatusCreated)
+	}
+
+	for _, method := range []string{"POST", "PUT", "DELETE"} {
+		target := url
+		if method != "POST" {
+			target += "/" + weapon.ID.Hex()
+		}
+
+		w = serveConditional(t, router, method, target, http.Header{"If-Match": {`"1"`}}, `{"name":"Heavy Blaster Pistol"}`)
+		conflict := model.ConflictResponse{}
+		_ = json.Unmarshal(w.Body.Bytes(), &conflict)
+		if w.Code != http.StatusPreconditionFailed || conflict.Code != "precondition_failed" || conflict.Current == nil || w.Header().Get("ETag") != `"2"` {
+			t.Errorf("%v element stale If-Match error:\ngot: %v %+v %v\nexpected: %v precondition_failed with the current sheet", method, w.Code, conflict, w.Header().Get("ETag"), http.StatusPreconditionFailed)
+		}
+	}
+
+	w = serveConditional(t, router, "DELETE", url+"/"+weapon.ID.Hex(), http.Header{"If-Match": {`"2"`}}, "")
+	if w.Code != http.StatusNoContent || w.Header().Get("ETag") != `"3"` {
+		t.Errorf("DeleteSheetElement() If-Match error:\ngot: %v %v\nexpected: %v \"3\"", w.Code, w.Header().Get("ETag"), http.StatusNoContent)
+	}
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/api"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
+	"github.com/geeksheik9/sheet-CRUD/pkg/events"
+	"github.com/sirupsen/logrus"
+)
+
+// EventsHeartbeat is how often the event stream sends a comment so that proxies keep an idle connection open
+var EventsHeartbeat = 15 * time.Second
+
+//StreamEvents is the handler function for the server-sent event stream of sheet changes.
+//A client resumes after the last event it received with the Last-Event-ID header or the after query parameter
+func (s *CharacterService) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("StreamEvents invoked with url: %v", r.URL)
+
+	if s.Events == nil {
+		api.RespondWithFailure(w, dberr.New(dberr.ErrNotSupported, "events_not_supported", "events are not supported by this storage backend"))
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		api.RespondWithError(w, http.StatusInternalServerError, "streaming is not supported by the connection")
+		return
+	}
+
+	resumeToken := r.Header.Get("Last-Event-ID")
+	if resumeToken == "" {
+		resumeToken = r.URL.Query().Get("after")
+	}
+
+	subscription, err := s.Events.Subscribe(r.Context(), resumeToken)
+	if errors.Is(err, events.ErrTokenExpired) {
+		api.RespondWithProblem(w, http.StatusGone, api.NewProblem(http.StatusGone, "resume_token_expired", err.Error()))
+		return
+	}
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(EventsHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case event, open := <-subscription.Events():
+			if !open {
+				if err := subscription.Err(); err != nil {
+					logrus.Warnf("Event stream ended: %v", err)
+					data, _ := json.Marshal(streamProblem(err))
+					fmt.Fprintf(w, "event: error\ndata: %s\n\n", data)
+					flusher.Flush()
+				}
+				return
+			}
+
+			data, err := json.Marshal(event)
+			if err != nil {
+				logrus.Errorf("Error marshalling event %v: %v", event.ID, err)
+				return
+			}
+			fmt.Fprintf(w, "id: %v\nevent: %v\ndata: %s\n\n", event.ID, event.Type, data)
+			flusher.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// streamProblem returns the problem sent as the error event that ends a stream. The feed errors a client can act on keep their detail,
+// a done context is reported by its kind alone and anything else goes through api.ProblemFor, which logs it and keeps its text from the client
+func streamProblem(err error) model.Problem {
+	switch {
+	case errors.Is(err, events.ErrTokenExpired):
+		return api.NewProblem(http.StatusGone, "resume_token_expired", err.Error())
+	case errors.Is(err, events.ErrSlowConsumer):
+		return api.NewProblem(http.StatusServiceUnavailable, "slow_consumer", err.Error())
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return api.NewProblem(api.CheckError(err), "", "")
+	}
+
+	return api.ProblemFor(err)
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/geeksheik9/sheet-CRUD/pkg/events"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCharacterService_StreamEvents_Resume(t *testing.T) {
+	broker := events.NewBroker(events.DefaultHistory)
+	service := InitMockCharacterService(nil, nil, nil)
+	service.Events = broker
+
+	ctx, cancel := context.WithCancel(context.Background())
+	subscription, _ := broker.Subscribe(ctx, "")
+	sheetID := primitive.NewObjectID()
+	broker.Publish(events.Event{Type: events.Created, SheetID: sheetID, Version: 1})
+	broker.Publish(events.Event{Type: events.Deleted, SheetID: sheetID, Version: 1})
+	first := <-subscription.Events()
+	second := <-subscription.Events()
+	cancel()
+
+	r, err := http.NewRequest("GET", "/events", nil)
+	if err != nil {
+		t.Errorf("StreamEvents() error creating request:\ngot: %v\nexpected:<no error>", err)
+	}
+	r.Header.Set("Last-Event-ID", first.ID)
+	// a done context ends the stream once the events after the token are replayed
+	r = r.WithContext(ctx)
+
+	w := httptest.NewRecorder()
+	router := mux.NewRouter().StrictSlash(true)
+	service.Routes(router).ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/event-stream" {
+		t.Errorf("StreamEvents() error:\ngot: %v %v\nexpected: %v text/event-stream", w.Code, w.Header().Get("Content-Type"), http.StatusOK)
+	}
+
+	body := w.Body.String()
+	expected := fmt.Sprintf("id: %v\nevent: deleted\ndata: ", second.ID)
+	if !strings.HasPrefix(body, expected) || strings.Contains(body, first.ID+"\n") {
+		t.Errorf("StreamEvents() error:\ngot: %q\nexpected: only the deleted event starting %q", body, expected)
+	}
+}
+
+func TestCharacterService_StreamEvents_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   events.Source
+		token    string
+		expected int
+	}{
+		{name: "no event source", expected: http.StatusNotImplemented},
+		{name: "expired token", source: events.NewBroker(1), token: primitive.NewObjectID().Hex() + "-1", expected: http.StatusGone},
+	}
+
+	for _, test := range tests {
+		service := InitMockCharacterService(nil, nil, nil)
+		service.Events = test.source
+
+		r, err := http.NewRequest("GET", "/events?after="+test.token, nil)
+		if err != nil {
+			t.Errorf("StreamEvents() %v error creating request:\ngot: %v\nexpected:<no error>", test.name, err)
+		}
+
+		w := httptest.NewRecorder()
+		router := mux.NewRouter().StrictSlash(true)
+		service.Routes(router).ServeHTTP(w, r)
+
+		if w.Code != test.expected {
+			t.Errorf("StreamEvents() %v error:\ngot: %v\nexpected: %v", test.name, w.Code, test.expected)
+		}
+	}
+}
+
+func TestStreamProblem(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+		detail bool
+	}{
+		{events.ErrSlowConsumer, http.StatusServiceUnavailable, "slow_consumer", true},
+		{events.ErrTokenExpired, http.StatusGone, "resume_token_expired", true},
+		{fmt.Errorf("change stream: %w", context.DeadlineExceeded), http.StatusGatewayTimeout, "timeout", false},
+		{errors.New("connection(db:27017) incomplete read of message header"), http.StatusInternalServerError, "internal_error", true},
+	}
+
+	for _, test := range tests {
+		problem := streamProblem(test.err)
+		if problem.Status != test.status || problem.Code != test.code || (problem.Detail != "") != test.detail || strings.Contains(problem.Detail, "27017") {
+			t.Errorf("streamProblem(%v) error:\ngot: %+v\nexpected: %v %v without the error text", test.err, problem, test.status, test.code)
+		}
+	}
+}
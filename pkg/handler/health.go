@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/api"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+//TakeDamage is the handler function for a character taking damage, the soak value of the sheet is taken off before it is added to the wounds
+func (s *CharacterService) TakeDamage(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("BEGIN - TakeDamage invoked with url: %v", r.URL)
+	s.applyHealthAction(w, r, api.TakeDamage)
+}
+
+//SufferStrain is the handler function for a character suffering strain
+func (s *CharacterService) SufferStrain(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("BEGIN - SufferStrain invoked with url: %v", r.URL)
+	s.applyHealthAction(w, r, api.SufferStrain)
+}
+
+//RecoverStrain is the handler function for a character recovering strain, the strain never goes below 0
+func (s *CharacterService) RecoverStrain(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("BEGIN - RecoverStrain invoked with url: %v", r.URL)
+	s.applyHealthAction(w, r, api.RecoverStrain)
+}
+
+//HealWounds is the handler function for a character healing wounds, the wounds never go below 0
+func (s *CharacterService) HealWounds(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("BEGIN - HealWounds invoked with url: %v", r.URL)
+	s.applyHealthAction(w, r, api.HealWounds)
+}
+
+// applyHealthAction applies the action of the amount in the request body and responds with the health of the sheet after it
+func (s *CharacterService) applyHealthAction(w http.ResponseWriter, r *http.Request, action api.HealthAction) {
+	defer r.Body.Close()
+
+	objectID, err := api.StringToObjectID(mux.Vars(r)["ID"])
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	request := model.HealthActionRequest{}
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		api.RespondWithFailure(w, api.PayloadError(err))
+		return
+	}
+	if request.Amount < 0 {
+		api.RespondWithFailure(w, api.HealthAmountError(request.Amount))
+		return
+	}
+
+	ifMatch, version, err := s.expectedVersion(r, objectID)
+	if err != nil {
+		s.respondWithWriteFailure(w, r, objectID, ifMatch, err)
+		return
+	}
+
+	sheet, err := s.Database.ApplyHealthAction(r.Context(), objectID, action, request.Amount, version)
+	if err != nil {
+		s.respondWithWriteFailure(w, r, objectID, ifMatch, err)
+		return
+	}
+
+	setETag(w, sheet.Version)
+	api.RespondWithJSON(w, http.StatusOK, api.HealthState(*sheet, action, request.Amount))
+}
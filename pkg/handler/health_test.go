@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/api"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func healthService(t *testing.T) (*mux.Router, primitive.ObjectID) {
+	router, id := patchService(t)
+
+	w, _ := servePatch(t, router, id, api.MergePatchContentType, `{"soakValue":3,"strain":{"threshold":10}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PatchForceCharacterSheetByID() error:\ngot: %v\nexpected: %v", w.Code, http.StatusOK)
+	}
+
+	return router, id
+}
+
+func TestCharacterService_HealthActions(t *testing.T) {
+	router, id := healthService(t)
+	url := "/force-character-sheet/" + id.Hex()
+
+	tests := []struct {
+		name     string
+		action   string
+		amount   int64
+		wounds   int64
+		strain   int64
+		version  int64
+		soaked   int64
+		wounded  bool
+		strained bool
+	}{
+		{"damage over soak", "/damage", 5, 2, 0, 3, 3, false, false},
+		{"damage soaked", "/damage", 2, 2, 0, 3, 2, false, false},
+		{"damage over threshold", "/damage", 14, 13, 0, 4, 3, true, false},
+		{"heal past 0", "/heal", 20, 0, 0, 5, 0, false, false},
+		{"heal at 0", "/heal", 1, 0, 0, 5, 0, false, false},
+		{"suffer strain over threshold", "/suffer-strain", 11, 0, 11, 6, 0, false, true},
+		{"recover strain", "/recover-strain", 4, 0, 7, 7, 0, false, false},
+	}
+
+	for _, test := range tests {
+		w := serveMemory(t, router, "POST", url+test.action, model.HealthActionRequest{Amount: test.amount})
+		state := model.HealthState{}
+		_ = json.Unmarshal(w.Body.Bytes(), &state)
+		if w.Code != http.StatusOK || state.ID != id || state.Wounds.Current != test.wounds || state.Strain.Current != test.strain || state.Version != test.version ||
+			state.Soaked != test.soaked || state.WoundThresholdExceeded != test.wounded || state.StrainThresholdExceeded != test.strained {
+			t.Errorf("%v error:\ngot: %v %+v\nexpected: %v %+v", test.name, w.Code, state, http.StatusOK, test)
+		}
+		if w.Header().Get("ETag") != api.SheetETag(test.version, nil) {
+			t.Errorf("%v ETag error:\ngot: %v\nexpected: %v", test.name, w.Header().Get("ETag"), api.SheetETag(test.version, nil))
+		}
+	}
+
+	w := serveMemory(t, router, "POST", url+"/damage", model.HealthActionRequest{Amount: -1})
+	problem := model.Problem{}
+	_ = json.Unmarshal(w.Body.Bytes(), &problem)
+	if w.Code != http.StatusBadRequest || len(problem.Violations) != 1 || problem.Violations[0].Field != "amount" {
+		t.Errorf("TakeDamage() negative amount error:\ngot: %v %+v\nexpected: %v a violation of amount", w.Code, problem, http.StatusBadRequest)
+	}
+
+	w = serveMemory(t, router, "POST", url+"/heal", "lots")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("HealWounds() bad payload error:\ngot: %v\nexpected: %v", w.Code, http.StatusBadRequest)
+	}
+
+	w = serveMemory(t, router, "POST", "/force-character-sheet/"+primitive.NewObjectID().Hex()+"/damage", model.HealthActionRequest{Amount: 5})
+	if w.Code != http.StatusNotFound {
+		t.Errorf("TakeDamage() missing sheet error:\ngot: %v\nexpected: %v", w.Code, http.StatusNotFound)
+	}
+
+	w = serveMemory(t, router, "GET", url+"/revisions", nil)
+	revisions := []model.SheetRevision{}
+	_ = json.Unmarshal(w.Body.Bytes(), &revisions)
+	if len(revisions) != 7 || revisions[2].Summary != "took 5 damage" || revisions[6].Summary != "recovered 4 strain" {
+		t.Errorf("GetForceCharacterSheetRevisions() error:\ngot: %+v\nexpected: a revision for every action that changed the sheet", revisions)
+	}
+}
+
+func TestCharacterService_TakeDamage_Concurrent(t *testing.T) {
+	router, id := healthService(t)
+	url := "/force-character-sheet/" + id.Hex() + "/damage"
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			serveMemory(t, router, "POST", url, model.HealthActionRequest{Amount: 4})
+		}()
+	}
+	wg.Wait()
+
+	w := serveMemory(t, router, "POST", url, model.HealthActionRequest{Amount: 0})
+	state := model.HealthState{}
+	_ = json.Unmarshal(w.Body.Bytes(), &state)
+	if w.Code != http.StatusOK || state.Wounds.Current != 10 || state.Version != 12 || state.WoundThresholdExceeded {
+		t.Errorf("TakeDamage() concurrent error:\ngot: %v %+v\nexpected: %v 10 wounds at version 12", w.Code, state, http.StatusOK)
+	}
+}
+
+func TestCharacterService_HealthActions_IfMatch(t *testing.T) {
+	router, id := healthService(t)
+	url := "/force-character-sheet/" + id.Hex()
+
+	w := serveConditional(t, router, "POST", url+"/suffer-strain", http.Header{"If-Match": {`"1"`}}, `{"amount":2}`)
+	conflict := model.ConflictResponse{}
+	_ = json.Unmarshal(w.Body.Bytes(), &conflict)
+	if w.Code != http.StatusPreconditionFailed || conflict.Code != "precondition_failed" || conflict.Current == nil || conflict.Current.Strain.Current != 0 {
+		t.Errorf("SufferStrain() stale If-Match error:\ngot: %v %+v\nexpected: %v precondition_failed with the unchanged sheet", w.Code, conflict, http.StatusPreconditionFailed)
+	}
+
+	w = serveConditional(t, router, "POST", url+"/suffer-strain", http.Header{"If-Match": {`"2"`}}, `{"amount":2}`)
+	state := model.HealthState{}
+	_ = json.Unmarshal(w.Body.Bytes(), &state)
+	if w.Code != http.StatusOK || state.Strain.Current != 2 || w.Header().Get("ETag") != `"3"` {
+		t.Errorf("SufferStrain() If-Match error:\ngot: %v %+v %v\nexpected: %v 2 strain at \"3\"", w.Code, state, w.Header().Get("ETag"), http.StatusOK)
+	}
+}
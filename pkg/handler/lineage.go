@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/geeksheik9/sheet-CRUD/pkg/api"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+//GetForceCharacterSheetVersions is the handler function for listing every version of a character
+func (s *CharacterService) GetForceCharacterSheetVersions(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("BEGIN - GetForceCharacterSheetVersions invoked with url: %v", r.URL)
+
+	characterID, err := api.StringToObjectID(mux.Vars(r)["characterID"])
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	sheets, err := s.Database.GetForceCharacterSheetVersions(r.Context(), characterID)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	api.RespondWithJSON(w, http.StatusOK, sheets)
+}
+
+//FindForceCharacterSheetVersion is the handler function for getting the sheet of a given version of a character
+func (s *CharacterService) FindForceCharacterSheetVersion(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("BEGIN - FindForceCharacterSheetVersion invoked with url: %v", r.URL)
+
+	vars := mux.Vars(r)
+	characterID, err := api.StringToObjectID(vars["characterID"])
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	characterVersion, err := strconv.ParseInt(vars["characterVersion"], 10, 64)
+	if err != nil {
+		api.RespondWithFailure(w, dberr.Invalid("invalid_id", "characterVersion", "%q is not a character version", vars["characterVersion"]))
+		return
+	}
+
+	sheet, err := s.Database.FindForceCharacterSheetVersion(r.Context(), characterID, characterVersion)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	api.RespondWithJSON(w, http.StatusOK, sheet)
+}
+
+//LevelUpForceCharacterSheet is the handler function for leveling up a character.
+//The next version is created as a new sheet copied from the latest one, which is kept as the snapshot of the session it was played in
+func (s *CharacterService) LevelUpForceCharacterSheet(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("BEGIN - LevelUpForceCharacterSheet invoked with url: %v", r.URL)
+
+	characterID, err := api.StringToObjectID(mux.Vars(r)["characterID"])
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	sheet, err := s.Database.LevelUpForceCharacterSheet(r.Context(), characterID)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	api.RespondWithJSON(w, http.StatusCreated, sheet)
+}
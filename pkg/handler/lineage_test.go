@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/memory"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCharacterService_LevelUpForceCharacterSheet(t *testing.T) {
+	service := CharacterService{
+		Version:  "test",
+		Database: memory.New(),
+	}
+	router := service.Routes(mux.NewRouter().StrictSlash(true))
+
+	w := serveMemory(t, router, "POST", "/force-character-sheet", mockCharacter(primitive.NilObjectID, "Kanan", 12, 0, 1))
+	var characterID primitive.ObjectID
+	_ = json.NewDecoder(w.Body).Decode(&characterID)
+
+	w = serveMemory(t, router, "POST", "/character/"+characterID.Hex()+"/level-up", nil)
+	next := model.ForceCharacterSheet{}
+	_ = json.NewDecoder(w.Body).Decode(&next)
+	if w.Code != http.StatusCreated || next.CharacterID != characterID || next.CharacterVersion != 2 {
+		t.Fatalf("LevelUpForceCharacterSheet() error:\ngot: %v %+v\nexpected: %v version 2 of %v", w.Code, next, http.StatusCreated, characterID)
+	}
+
+	w = serveMemory(t, router, "GET", "/character/"+characterID.Hex()+"/versions", nil)
+	versions := []model.ForceCharacterSheet{}
+	_ = json.NewDecoder(w.Body).Decode(&versions)
+	if w.Code != http.StatusOK || len(versions) != 2 || versions[0].ID != characterID || versions[1].ID != next.ID {
+		t.Errorf("GetForceCharacterSheetVersions() error:\ngot: %v %v\nexpected: %v versions 1 and 2", w.Code, versions, http.StatusOK)
+	}
+
+	w = serveMemory(t, router, "GET", "/character/"+characterID.Hex()+"/versions/2", nil)
+	version := model.ForceCharacterSheet{}
+	_ = json.NewDecoder(w.Body).Decode(&version)
+	if w.Code != http.StatusOK || version.ID != next.ID {
+		t.Errorf("FindForceCharacterSheetVersion() error:\ngot: %v %v\nexpected: %v %v", w.Code, version.ID, http.StatusOK, next.ID)
+	}
+
+	tests := []struct {
+		method   string
+		url      string
+		expected int
+	}{
+		{"GET", "/character/" + characterID.Hex() + "/versions/3", http.StatusNotFound},
+		{"GET", "/character/" + primitive.NewObjectID().Hex() + "/versions", http.StatusNotFound},
+		{"GET", "/character/bad/versions", http.StatusBadRequest},
+		{"POST", "/character/" + primitive.NewObjectID().Hex() + "/level-up", http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		w := serveMemory(t, router, test.method, test.url, nil)
+		if w.Code != test.expected {
+			t.Errorf("%v %v error:\ngot: %v\nexpected: %v", test.method, test.url, w.Code, test.expected)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/memory"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var _ CharacterDatabase = memory.New()
+
+func serveMemory(t *testing.T, router *mux.Router, method string, url string, body interface{}) *httptest.ResponseRecorder {
+	var payload []byte
+	if body != nil {
+		payload, _ = json.Marshal(body)
+	}
+
+	r, err := http.NewRequest(method, url, bytes.NewBuffer(payload))
+	if err != nil {
+		t.Fatalf("%v %v error creating request:\ngot: %v\nexpected:<no error>", method, url, err)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	return w
+}
+
+func TestCharacterService_MemoryBackend_EndToEnd(t *testing.T) {
+	service := CharacterService{
+		Version:  "test",
+		Database: memory.New(),
+	}
+	router := service.Routes(mux.NewRouter().StrictSlash(true))
+
+	sheet := mockCharacter(primitive.NilObjectID, "Mando", 12, 0, 1)
+	w := serveMemory(t, router, "POST", "/force-character-sheet", sheet)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("InsertForceCharacterSheet() error:\ngot: %v\nexpected: %v", w.Code, http.StatusCreated)
+	}
+
+	var id primitive.ObjectID
+	_ = json.NewDecoder(w.Body).Decode(&id)
+
+	w = serveMemory(t, router, "GET", "/force-character-sheet?characterName=Mando", nil)
+	page := model.ForceCharacterSheetPage{}
+	_ = json.NewDecoder(w.Body).Decode(&page)
+	sheets := page.Items
+	if w.Code != http.StatusOK || len(sheets) != 1 || sheets[0].ID != id || sheets[0].Version != 1 {
+		t.Fatalf("GetForceCharacterSheets() error:\ngot: %v %v\nexpected: one sheet with ID %v", w.Code, sheets, id)
+	}
+
+	update := sheets[0]
+	update.Wounds.Current = 4
+	w = serveMemory(t, router, "PUT", "/force-character-sheet/"+id.Hex(), update)
+	if w.Code != http.StatusOK {
+		t.Fatalf("UpdateForceCharacterSheetByID() error:\ngot: %v\nexpected: %v", w.Code, http.StatusOK)
+	}
+
+	w = serveMemory(t, router, "PUT", "/force-character-sheet/"+id.Hex(), update)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("UpdateForceCharacterSheetByID() stale error:\ngot: %v\nexpected: %v", w.Code, http.StatusConflict)
+	}
+
+	w = serveMemory(t, router, "DELETE", "/force-character-sheet/"+id.Hex(), nil)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("DeleteForceCharacterSheetByID() error:\ngot: %v\nexpected: %v", w.Code, http.StatusNoContent)
+	}
+
+	w = serveMemory(t, router, "GET", "/force-character-sheet/"+id.Hex(), nil)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("FindForceCharacterSheetByID() after delete error:\ngot: %v\nexpected: %v", w.Code, http.StatusNotFound)
+	}
+
+	w = serveMemory(t, router, "POST", "/archived-force-character-sheet/"+id.Hex()+"/restore", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("RestoreForceCharacterSheetByID() error:\ngot: %v\nexpected: %v", w.Code, http.StatusOK)
+	}
+
+	w = serveMemory(t, router, "GET", "/force-character-sheet/"+id.Hex(), nil)
+	restored := model.ForceCharacterSheet{}
+	_ = json.NewDecoder(w.Body).Decode(&restored)
+	if w.Code != http.StatusOK || restored.Wounds.Current != 4 || restored.Version != 2 {
+		t.Fatalf("FindForceCharacterSheetByID() after restore error:\ngot: %v %v\nexpected: wounds 4 at version 2", w.Code, restored)
+	}
+}
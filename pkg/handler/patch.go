@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"mime"
+	"net/http"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/api"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxMutateAttempts is how many times a change to a stored sheet is read and applied again when another write got in first
+const MaxMutateAttempts = 5
+
+//PatchForceCharacterSheetByID is the handler function for patching a specific character sheet by database ID.
+//The body is a JSON Merge Patch or a JSON Patch picked by the Content-Type, and is applied to the stored sheet.
+//A version in the patched sheet or an If-Match header must match the stored version, and a patch that changes nothing writes nothing
+func (s *CharacterService) PatchForceCharacterSheetByID(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("BEGIN - PatchForceCharacterSheetByID invoked with url: %v", r.URL)
+	defer r.Body.Close()
+
+	vars := mux.Vars(r)
+	ID := vars["ID"]
+
+	objectID, err := api.StringToObjectID(ID)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	parse := api.ParseMergePatch
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case api.MergePatchContentType:
+	case api.JSONPatchContentType:
+		parse = api.ParseJSONPatch
+	default:
+		api.RespondWithError(w, http.StatusUnsupportedMediaType, "PATCH requires a Content-Type of "+api.MergePatchContentType+" or "+api.JSONPatchContentType)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		api.RespondWithFailure(w, api.PayloadError(err))
+		return
+	}
+
+	patch, err := parse(body)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	ifMatch, err := s.ifMatch(r)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	sheet, err := s.mutateSheet(r.Context(), objectID, func(sheet *model.ForceCharacterSheet) error {
+		if err := checkIfMatch(ifMatch, sheet); err != nil {
+			return err
+		}
+		return patchSheet(sheet, patch)
+	})
+	if errors.Is(err, dberr.ErrConflict) || errors.Is(err, dberr.ErrPreconditionFailed) {
+		s.respondWithConflict(w, r, objectID, err)
+		return
+	}
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	setETag(w, sheet.Version)
+	api.RespondWithJSON(w, http.StatusOK, sheet)
+}
+
+// patchSheet applies a patch to the JSON form of a sheet. The patch may test the ID, version and lineage of the sheet but not change them,
+// except for the version which, when changed, is taken as the version the client expected to patch
+func patchSheet(sheet *model.ForceCharacterSheet, patch api.Patch) error {
+	doc, err := json.Marshal(sheet)
+	if err != nil {
+		return err
+	}
+
+	doc, err = patch(doc)
+	if err != nil {
+		return err
+	}
+
+	patched := model.ForceCharacterSheet{}
+	err = decodeSheet(bytes.NewReader(doc), &patched)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case patched.ID != sheet.ID:
+		return dberr.Invalid("invalid_patch", "_id", "the ID of a sheet can't be patched")
+	case patched.CharacterID != sheet.CharacterID:
+		return dberr.Invalid("invalid_patch", "characterId", "the character of a sheet can't be patched")
+	case patched.CharacterVersion != sheet.CharacterVersion:
+		return dberr.Invalid("invalid_patch", "characterVersion", "the character version of a sheet can't be patched")
+	case patched.Version != sheet.Version:
+		return dberr.VersionConflict(sheet.ID, patched.Version, sheet.Version)
+	}
+
+	*sheet = patched
+
+	return nil
+}
+
+// mutateSheet applies fn to the stored sheet and saves the result with an update checked against the version that was read, so the change is
+// made against the sheet as it is stored. When another write lands in between, the sheet is read and fn applied again, up to MaxMutateAttempts.
+// A change that leaves the sheet as it was writes nothing. The sheet is returned as it is stored afterwards
+func (s *CharacterService) mutateSheet(ctx context.Context, mongoID primitive.ObjectID, fn func(sheet *model.ForceCharacterSheet) error) (*model.ForceCharacterSheet, error) {
+	var conflict error
+	for attempt := 0; attempt < MaxMutateAttempts; attempt++ {
+		current, err := s.Database.FindForceCharacterSheetByID(ctx, mongoID, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		before, err := json.Marshal(current)
+		if err != nil {
+			return nil, err
+		}
+
+		// fn works on its own copy so that a failed or repeated attempt never sees a half applied change
+		sheet := model.ForceCharacterSheet{}
+		err = json.Unmarshal(before, &sheet)
+		if err != nil {
+			return nil, err
+		}
+
+		err = fn(&sheet)
+		if err != nil {
+			return nil, err
+		}
+
+		sheet.ID = mongoID
+		sheet.Version = current.Version
+		after, err := json.Marshal(sheet)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Equal(before, after) {
+			return current, nil
+		}
+
+		conflict = s.Database.UpdateForceCharacterSheetByID(ctx, sheet, mongoID)
+		if conflict == nil {
+			sheet.Version++
+			return &sheet, nil
+		}
+		if dberr.Code(conflict) != "version_conflict" {
+			return nil, conflict
+		}
+
+		logrus.Debugf("Sheet %v changed while it was being updated, attempt %v of %v", mongoID.Hex(), attempt+1, MaxMutateAttempts)
+	}
+
+	return nil, conflict
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/api"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/memory"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func servePatch(t *testing.T, router *mux.Router, id primitive.ObjectID, contentType string, patch string) (*httptest.ResponseRecorder, model.ForceCharacterSheet) {
+	r, err := http.NewRequest("PATCH", "/force-character-sheet/"+id.Hex(), bytes.NewBufferString(patch))
+	if err != nil {
+		t.Fatalf("PATCH error creating request:\ngot: %v\nexpected:<no error>", err)
+	}
+	r.Header.Set("Content-Type", contentType)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	sheet := model.ForceCharacterSheet{}
+	_ = json.Unmarshal(w.Body.Bytes(), &sheet)
+
+	return w, sheet
+}
+
+func patchService(t *testing.T) (*mux.Router, primitive.ObjectID) {
+	service := CharacterService{
+		Version:  "test",
+		Database: memory.New(),
+	}
+	router := service.Routes(mux.NewRouter().StrictSlash(true))
+
+	w := serveMemory(t, router, "POST", "/force-character-sheet", mockCharacter(primitive.NilObjectID, "Mando", 12, 0, 1))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("InsertForceCharacterSheet() error:\ngot: %v\nexpected: %v", w.Code, http.StatusCreated)
+	}
+
+	var id primitive.ObjectID
+	_ = json.NewDecoder(w.Body).Decode(&id)
+
+	return router, id
+}
+
+func TestCharacterService_PatchForceCharacterSheetByID_MergePatch(t *testing.T) {
+	router, id := patchService(t)
+
+	w, sheet := servePatch(t, router, id, api.MergePatchContentType+"; charset=utf-8", `{"wounds":{"current":5}}`)
+	if w.Code != http.StatusOK || sheet.Wounds.Current != 5 || sheet.Wounds.Threshold != 12 || sheet.Version != 2 {
+		t.Errorf("PatchForceCharacterSheetByID() error:\ngot: %v %+v version %v\nexpected: %v wounds 5 of 12 at version 2", w.Code, sheet.Wounds, sheet.Version, http.StatusOK)
+	}
+
+	w, sheet = servePatch(t, router, id, api.MergePatchContentType, `{"wounds":{"current":5}}`)
+	if w.Code != http.StatusOK || sheet.Version != 2 {
+		t.Errorf("PatchForceCharacterSheetByID() no-op error:\ngot: %v version %v\nexpected: %v version 2", w.Code, sheet.Version, http.StatusOK)
+	}
+
+	w, _ = servePatch(t, router, id, api.MergePatchContentType, `{"version":1,"wounds":{"current":7}}`)
+	conflict := model.ConflictResponse{}
+	_ = json.Unmarshal(w.Body.Bytes(), &conflict)
+	if w.Code != http.StatusConflict || conflict.Code != "version_conflict" || conflict.Current == nil || conflict.Current.Wounds.Current != 5 {
+		t.Errorf("PatchForceCharacterSheetByID() stale version error:\ngot: %v %+v\nexpected: %v version_conflict with the current sheet", w.Code, conflict, http.StatusConflict)
+	}
+
+	w, _ = servePatch(t, router, id, api.MergePatchContentType, `{"_id":"5f3c6b1b9d1b2c3d4e5f6a7b"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("PatchForceCharacterSheetByID() ID change error:\ngot: %v\nexpected: %v", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestCharacterService_PatchForceCharacterSheetByID_JSONPatch(t *testing.T) {
+	router, id := patchService(t)
+
+	w, sheet := servePatch(t, router, id, api.JSONPatchContentType, `[{"op":"test","path":"/version","value":1},{"op":"replace","path":"/wounds/current","value":3}]`)
+	if w.Code != http.StatusOK || sheet.Wounds.Current != 3 || sheet.Version != 2 {
+		t.Errorf("PatchForceCharacterSheetByID() error:\ngot: %v wounds %v version %v\nexpected: %v wounds 3 version 2", w.Code, sheet.Wounds.Current, sheet.Version, http.StatusOK)
+	}
+
+	w, _ = servePatch(t, router, id, api.JSONPatchContentType, `[{"op":"test","path":"/wounds/current","value":0},{"op":"replace","path":"/wounds/current","value":4}]`)
+	conflict := model.ConflictResponse{}
+	_ = json.Unmarshal(w.Body.Bytes(), &conflict)
+	if w.Code != http.StatusConflict || conflict.Code != "patch_test_failed" || conflict.Current == nil || conflict.Current.Wounds.Current != 3 {
+		t.Errorf("PatchForceCharacterSheetByID() failed test error:\ngot: %v %+v\nexpected: %v patch_test_failed with the current sheet", w.Code, conflict, http.StatusConflict)
+	}
+
+	w, _ = servePatch(t, router, id, api.JSONPatchContentType, `[{"op":"remove","path":"/missing"}]`)
+	if w.Code != http.StatusConflict {
+		t.Errorf("PatchForceCharacterSheetByID() missing path error:\ngot: %v\nexpected: %v", w.Code, http.StatusConflict)
+	}
+
+	w, _ = servePatch(t, router, id, api.JSONPatchContentType, `[{"op":"explode","path":"/wounds"}]`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("PatchForceCharacterSheetByID() invalid patch error:\ngot: %v\nexpected: %v", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestCharacterService_PatchForceCharacterSheetByID_Errors(t *testing.T) {
+	router, id := patchService(t)
+
+	w, _ := servePatch(t, router, id, "application/json", `{"wounds":{"current":5}}`)
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("PatchForceCharacterSheetByID() media type error:\ngot: %v\nexpected: %v", w.Code, http.StatusUnsupportedMediaType)
+	}
+
+	w, _ = servePatch(t, router, primitive.NewObjectID(), api.MergePatchContentType, `{"wounds":{"current":5}}`)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("PatchForceCharacterSheetByID() missing sheet error:\ngot: %v\nexpected: %v", w.Code, http.StatusNotFound)
+	}
+
+	w, _ = servePatch(t, router, id, api.MergePatchContentType, `{"wounds":{"current":"five"}}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("PatchForceCharacterSheetByID() payload error:\ngot: %v\nexpected: %v", w.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/api"
+	"github.com/geeksheik9/sheet-CRUD/pkg/catalog"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+//PurchaseAdvancement is the handler function for buying a skill rank, characteristic, talent or force power upgrade with the XP of a specific character sheet.
+//The purchase is checked against and applied to the stored sheet with an update checked against its version, so two purchases can't spend the same XP
+func (s *CharacterService) PurchaseAdvancement(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("BEGIN - PurchaseAdvancement invoked with url: %v", r.URL)
+	defer r.Body.Close()
+
+	objectID, err := api.StringToObjectID(mux.Vars(r)["ID"])
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	purchase := model.PurchaseRequest{}
+	err = json.NewDecoder(r.Body).Decode(&purchase)
+	if err != nil {
+		api.RespondWithFailure(w, api.PayloadError(err))
+		return
+	}
+
+	err = api.ValidatePurchase(purchase)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	ifMatch, err := s.ifMatch(r)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	var result model.PurchaseResult
+	sheet, err := s.mutateSheet(r.Context(), objectID, func(sheet *model.ForceCharacterSheet) (err error) {
+		if err := checkIfMatch(ifMatch, sheet); err != nil {
+			return err
+		}
+		result, err = api.Purchase(sheet, purchase, s.purchaseCatalog())
+		return err
+	})
+	if errors.Is(err, dberr.ErrConflict) || errors.Is(err, dberr.ErrPreconditionFailed) {
+		s.respondWithConflict(w, r, objectID, err)
+		return
+	}
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	result.Sheet = *sheet
+	result.AvailableXP = sheet.AvailableXP
+
+	setETag(w, sheet.Version)
+	api.RespondWithJSON(w, http.StatusOK, result)
+}
+
+// purchaseCatalog returns the catalog purchases are priced from, the built in one when the service has none
+func (s *CharacterService) purchaseCatalog() *catalog.Catalog {
+	if s.Catalog == nil {
+		return catalog.Default()
+	}
+
+	return s.Catalog
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/api"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func purchaseService(t *testing.T) (*mux.Router, primitive.ObjectID) {
+	router, id := patchService(t)
+
+	w, _ := servePatch(t, router, id, api.MergePatchContentType, `{"availableXP":30,"skills":[{"name":"Athletics","career":true,"level":1},{"name":"Cool"}]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PatchForceCharacterSheetByID() error:\ngot: %v\nexpected: %v", w.Code, http.StatusOK)
+	}
+
+	return router, id
+}
+
+func TestCharacterService_PurchaseAdvancement(t *testing.T) {
+	router, id := purchaseService(t)
+	url := "/force-character-sheet/" + id.Hex() + "/purchase"
+
+	w := serveMemory(t, router, "POST", url, model.PurchaseRequest{Type: model.PurchaseSkill, Name: "Cool"})
+	result := model.PurchaseResult{}
+	_ = json.Unmarshal(w.Body.Bytes(), &result)
+	if w.Code != http.StatusOK || result.Cost != 10 || result.Rank != 1 || result.AvailableXP != 20 || result.Sheet.Skills[1].Level != 1 || result.Sheet.AvailableXP != 20 {
+		t.Errorf("PurchaseAdvancement() error:\ngot: %v %+v\nexpected: %v Cool at rank 1 for 10 XP leaving 20", w.Code, result, http.StatusOK)
+	}
+	if w.Header().Get("ETag") != api.SheetETag(result.Sheet.Version, nil) {
+		t.Errorf("PurchaseAdvancement() ETag error:\ngot: %v\nexpected: %v", w.Header().Get("ETag"), api.SheetETag(result.Sheet.Version, nil))
+	}
+
+	w = serveMemory(t, router, "POST", url, model.PurchaseRequest{Type: model.PurchaseTalent, Name: "Dedication"})
+	problem := model.Problem{}
+	_ = json.Unmarshal(w.Body.Bytes(), &problem)
+	if w.Code != http.StatusUnprocessableEntity || problem.Code != "insufficient_xp" {
+		t.Errorf("PurchaseAdvancement() insufficient XP error:\ngot: %v %+v\nexpected: %v insufficient_xp", w.Code, problem, http.StatusUnprocessableEntity)
+	}
+
+	w = serveMemory(t, router, "POST", url, model.PurchaseRequest{Type: model.PurchaseTalent, Name: "Force Choke"})
+	problem = model.Problem{}
+	_ = json.Unmarshal(w.Body.Bytes(), &problem)
+	if w.Code != http.StatusUnprocessableEntity || problem.Code != "not_in_catalog" {
+		t.Errorf("PurchaseAdvancement() unpriced talent error:\ngot: %v %+v\nexpected: %v not_in_catalog", w.Code, problem, http.StatusUnprocessableEntity)
+	}
+
+	w = serveMemory(t, router, "POST", url, model.PurchaseRequest{Type: "level", Name: "Athletics"})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("PurchaseAdvancement() bad type error:\ngot: %v\nexpected: %v", w.Code, http.StatusBadRequest)
+	}
+
+	w = serveMemory(t, router, "POST", "/force-character-sheet/"+primitive.NewObjectID().Hex()+"/purchase", model.PurchaseRequest{Type: model.PurchaseSkill, Name: "Cool"})
+	if w.Code != http.StatusNotFound {
+		t.Errorf("PurchaseAdvancement() missing sheet error:\ngot: %v\nexpected: %v", w.Code, http.StatusNotFound)
+	}
+
+	w = serveMemory(t, router, "GET", "/force-character-sheet/"+id.Hex(), nil)
+	sheet := model.ForceCharacterSheet{}
+	_ = json.Unmarshal(w.Body.Bytes(), &sheet)
+	if sheet.AvailableXP != 20 || sheet.Skills[1].Level != 1 {
+		t.Errorf("PurchaseAdvancement() stored error:\ngot: %v XP %+v\nexpected: 20 XP and Cool at rank 1", sheet.AvailableXP, sheet.Skills[1])
+	}
+}
+
+func TestCharacterService_PurchaseAdvancement_Concurrent(t *testing.T) {
+	router, id := purchaseService(t)
+	url := "/force-character-sheet/" + id.Hex() + "/purchase"
+
+	var bought int64
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := serveMemory(t, router, "POST", url, model.PurchaseRequest{Type: model.PurchaseTalent, Name: "Grit"})
+			if w.Code == http.StatusOK {
+				atomic.AddInt64(&bought, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	w := serveMemory(t, router, "GET", "/force-character-sheet/"+id.Hex(), nil)
+	sheet := model.ForceCharacterSheet{}
+	_ = json.Unmarshal(w.Body.Bytes(), &sheet)
+	if bought == 0 || bought > 6 || sheet.AvailableXP != 30-5*bought || int64(len(sheet.Talents)) != bought {
+		t.Errorf("PurchaseAdvancement() concurrent error:\ngot: %v bought, %v XP left and %v talents\nexpected: every XP spent once", bought, sheet.AvailableXP, len(sheet.Talents))
+	}
+}
+
+func TestCharacterService_PurchaseAdvancement_IfMatch(t *testing.T) {
+	router, id := purchaseService(t)
+	url := "/force-character-sheet/" + id.Hex() + "/purchase"
+
+	w := serveConditional(t, router, "POST", url, http.Header{"If-Match": {`"1"`}}, `{"type":"skill","name":"Cool"}`)
+	conflict := model.ConflictResponse{}
+	_ = json.Unmarshal(w.Body.Bytes(), &conflict)
+	if w.Code != http.StatusPreconditionFailed || conflict.Code != "precondition_failed" || conflict.Current == nil || conflict.Current.AvailableXP != 30 {
+		t.Errorf("PurchaseAdvancement() stale If-Match error:\ngot: %v %+v\nexpected: %v precondition_failed with 30 XP left", w.Code, conflict, http.StatusPreconditionFailed)
+	}
+
+	w = serveConditional(t, router, "POST", url, http.Header{"If-Match": {`"2"`}}, `{"type":"skill","name":"Cool"}`)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"3"` {
+		t.Errorf("PurchaseAdvancement() If-Match error:\ngot: %v %v\nexpected: %v \"3\"", w.Code, w.Header().Get("ETag"), http.StatusOK)
+	}
+}
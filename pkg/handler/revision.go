@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/api"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+//GetForceCharacterSheetRevisions is the handler function for listing the revision history of a character sheet
+func (s *CharacterService) GetForceCharacterSheetRevisions(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("BEGIN - GetForceCharacterSheetRevisions invoked with url: %v", r.URL)
+
+	vars := mux.Vars(r)
+	ID := vars["ID"]
+
+	objectID, err := api.StringToObjectID(ID)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	revisions, err := s.Database.GetForceCharacterSheetRevisions(r.Context(), objectID)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	api.RespondWithJSON(w, http.StatusOK, revisions)
+}
+
+//FindForceCharacterSheetRevision is the handler function for getting a specific revision of a character sheet
+func (s *CharacterService) FindForceCharacterSheetRevision(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("BEGIN - FindForceCharacterSheetRevision invoked with url: %v", r.URL)
+
+	vars := mux.Vars(r)
+	ID := vars["ID"]
+
+	objectID, err := api.StringToObjectID(ID)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	version, err := strconv.ParseInt(vars["version"], 10, 64)
+	if err != nil {
+		api.RespondWithFailure(w, dberr.Invalid("invalid_id", "version", "%q is not a revision version", vars["version"]))
+		return
+	}
+
+	revision, err := s.Database.FindForceCharacterSheetRevision(r.Context(), objectID, version)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	api.RespondWithJSON(w, http.StatusOK, revision)
+}
+
+//DiffForceCharacterSheetRevisions is the handler function for comparing two revisions of a character sheet field by field
+func (s *CharacterService) DiffForceCharacterSheetRevisions(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("BEGIN - DiffForceCharacterSheetRevisions invoked with url: %v", r.URL)
+
+	vars := mux.Vars(r)
+	ID := vars["ID"]
+
+	objectID, err := api.StringToObjectID(ID)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	from, err := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+	if err != nil {
+		api.RespondWithFailure(w, dberr.Invalid("invalid_query", "from", "from must be a revision version"))
+		return
+	}
+
+	to, err := strconv.ParseInt(r.URL.Query().Get("to"), 10, 64)
+	if err != nil {
+		api.RespondWithFailure(w, dberr.Invalid("invalid_query", "to", "to must be a revision version"))
+		return
+	}
+
+	fromRevision, err := s.Database.FindForceCharacterSheetRevision(r.Context(), objectID, from)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	toRevision, err := s.Database.FindForceCharacterSheetRevision(r.Context(), objectID, to)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	if fromRevision.Sheet == nil {
+		api.RespondWithFailure(w, dberr.RevisionNotFound(objectID, from))
+		return
+	}
+	if toRevision.Sheet == nil {
+		api.RespondWithFailure(w, dberr.RevisionNotFound(objectID, to))
+		return
+	}
+
+	changes, err := api.Diff(fromRevision.Sheet, toRevision.Sheet)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	api.RespondWithJSON(w, http.StatusOK, model.RevisionDiff{
+		SheetID: objectID,
+		From:    from,
+		To:      to,
+		Changes: changes,
+	})
+}
+
+//RevertForceCharacterSheetByID is the handler function for reverting a character sheet to an earlier revision
+func (s *CharacterService) RevertForceCharacterSheetByID(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("BEGIN - RevertForceCharacterSheetByID invoked with url: %v", r.URL)
+
+	vars := mux.Vars(r)
+	ID := vars["ID"]
+
+	objectID, err := api.StringToObjectID(ID)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	version, err := strconv.ParseInt(vars["version"], 10, 64)
+	if err != nil {
+		api.RespondWithFailure(w, dberr.Invalid("invalid_id", "version", "%q is not a revision version", vars["version"]))
+		return
+	}
+
+	err = s.Database.RevertForceCharacterSheetByID(r.Context(), objectID, version)
+	if errors.Is(err, dberr.ErrConflict) {
+		s.respondWithConflict(w, r, objectID, err)
+		return
+	}
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	api.RespondWithJSON(w, http.StatusOK, objectID)
+}
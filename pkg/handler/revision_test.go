@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/mocks"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func InitMockRevisionService(revisionsToReturn []model.SheetRevision, errorToReturn error) (s CharacterService) {
+	db := mocks.MockCharacterDB{
+		RevisionsToReturn: revisionsToReturn,
+		ErrorToReturn:     errorToReturn,
+	}
+
+	return CharacterService{
+		Version:  "test",
+		Database: &db,
+	}
+}
+
+func mockRevisions(id primitive.ObjectID) []model.SheetRevision {
+	first := mockCharacter(id, "test", 10, 0, 1)
+	first.AvailableXP = 25
+	first.Version = 1
+	second := mockCharacter(id, "test", 10, 3, 1)
+	second.AvailableXP = 10
+	second.Version = 2
+
+	return []model.SheetRevision{
+		{ID: primitive.NewObjectID(), SheetID: id, Version: 1, Timestamp: time.Now().UTC(), Summary: "created", Sheet: &first},
+		{ID: primitive.NewObjectID(), SheetID: id, Version: 2, Timestamp: time.Now().UTC(), Summary: "updated availableXP, wounds", Sheet: &second},
+	}
+}
+
+func TestCharacterService_GetForceCharacterSheetRevisions_Success(t *testing.T) {
+	id := primitive.NewObjectID()
+	service := InitMockRevisionService(mockRevisions(id), nil)
+
+	r, err := http.NewRequest("GET", "/force-character-sheet/"+id.Hex()+"/revisions", nil)
+	if err != nil {
+		t.Errorf("GetForceCharacterSheetRevisions() error creating request:\ngot: %v\nexpected:<no error>", err)
+	}
+
+	w := httptest.NewRecorder()
+	router := mux.NewRouter().StrictSlash(true)
+	service.Routes(router).ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("GetForceCharacterSheetRevisions() error:\ngot: %v\nexpected: %v", w.Code, http.StatusOK)
+	}
+
+	resp := []model.SheetRevision{}
+	err = json.NewDecoder(w.Body).Decode(&resp)
+	if err != nil {
+		t.Errorf("GetForceCharacterSheetRevisions() json decode error:\ngot: %v\nexpected: <nil>", err)
+	}
+	if len(resp) != 2 || resp[1].Summary != "updated availableXP, wounds" {
+		t.Errorf("GetForceCharacterSheetRevisions() error:\ngot: %v\nexpected: 2 revisions", resp)
+	}
+}
+
+func TestCharacterService_FindForceCharacterSheetRevision_Success(t *testing.T) {
+	id := primitive.NewObjectID()
+	service := InitMockRevisionService(mockRevisions(id), nil)
+
+	r, err := http.NewRequest("GET", "/force-character-sheet/"+id.Hex()+"/revisions/2", nil)
+	if err != nil {
+		t.Errorf("FindForceCharacterSheetRevision() error creating request:\ngot: %v\nexpected:<no error>", err)
+	}
+
+	w := httptest.NewRecorder()
+	router := mux.NewRouter().StrictSlash(true)
+	service.Routes(router).ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("FindForceCharacterSheetRevision() error:\ngot: %v\nexpected: %v", w.Code, http.StatusOK)
+	}
+
+	resp := model.SheetRevision{}
+	err = json.NewDecoder(w.Body).Decode(&resp)
+	if err != nil {
+		t.Errorf("FindForceCharacterSheetRevision() json decode error:\ngot: %v\nexpected: <nil>", err)
+	}
+	if resp.Version != 2 || resp.Sheet == nil || resp.Sheet.AvailableXP != 10 {
+		t.Errorf("FindForceCharacterSheetRevision() error:\ngot: %v\nexpected: version 2", resp)
+	}
+}
+
+func TestCharacterService_DiffForceCharacterSheetRevisions_Success(t *testing.T) {
+	id := primitive.NewObjectID()
+	service := InitMockRevisionService(mockRevisions(id), nil)
+
+	r, err := http.NewRequest("GET", "/force-character-sheet/"+id.Hex()+"/revisions/diff?from=1&to=2", nil)
+	if err != nil {
+		t.Errorf("DiffForceCharacterSheetRevisions() error creating request:\ngot: %v\nexpected:<no error>", err)
+	}
+
+	w := httptest.NewRecorder()
+	router := mux.NewRouter().StrictSlash(true)
+	service.Routes(router).ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("DiffForceCharacterSheetRevisions() error:\ngot: %v\nexpected: %v", w.Code, http.StatusOK)
+	}
+
+	resp := model.RevisionDiff{}
+	err = json.NewDecoder(w.Body).Decode(&resp)
+	if err != nil {
+		t.Errorf("DiffForceCharacterSheetRevisions() json decode error:\ngot: %v\nexpected: <nil>", err)
+	}
+	if len(resp.Changes) != 2 || resp.Changes[0].Path != "availableXP" || resp.Changes[1].Path != "wounds.current" {
+		t.Errorf("DiffForceCharacterSheetRevisions() error:\ngot: %v\nexpected: availableXP and wounds.current changes", resp.Changes)
+	}
+}
+
+func TestCharacterService_DiffForceCharacterSheetRevisions_BadVersion(t *testing.T) {
+	id := primitive.NewObjectID()
+	service := InitMockRevisionService(mockRevisions(id), nil)
+
+	r, err := http.NewRequest("GET", "/force-character-sheet/"+id.Hex()+"/revisions/diff?from=first&to=2", nil)
+	if err != nil {
+		t.Errorf("DiffForceCharacterSheetRevisions() error creating request:\ngot: %v\nexpected:<no error>", err)
+	}
+
+	w := httptest.NewRecorder()
+	router := mux.NewRouter().StrictSlash(true)
+	service.Routes(router).ServeHTTP(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("DiffForceCharacterSheetRevisions() error:\ngot: %v\nexpected: %v", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestCharacterService_RevertForceCharacterSheetByID_Success(t *testing.T) {
+	id := primitive.NewObjectID()
+	service := InitMockRevisionService(mockRevisions(id), nil)
+
+	r, err := http.NewRequest("POST", "/force-character-sheet/"+id.Hex()+"/revisions/1/revert", nil)
+	if err != nil {
+		t.Errorf("RevertForceCharacterSheetByID() error creating request:\ngot: %v\nexpected:<no error>", err)
+	}
+
+	w := httptest.NewRecorder()
+	router := mux.NewRouter().StrictSlash(true)
+	service.Routes(router).ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("RevertForceCharacterSheetByID() error:\ngot: %v\nexpected: %v", w.Code, http.StatusOK)
+	}
+}
+
+func TestCharacterService_RevertForceCharacterSheetByID_NotFound(t *testing.T) {
+	id := primitive.NewObjectID()
+	service := InitMockRevisionService(nil, dberr.RevisionNotFound(primitive.NewObjectID(), 1))
+
+	r, err := http.NewRequest("POST", "/force-character-sheet/"+id.Hex()+"/revisions/7/revert", nil)
+	if err != nil {
+		t.Errorf("RevertForceCharacterSheetByID() error creating request:\ngot: %v\nexpected:<no error>", err)
+	}
+
+	w := httptest.NewRecorder()
+	router := mux.NewRouter().StrictSlash(true)
+	service.Routes(router).ServeHTTP(w, r)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("RevertForceCharacterSheetByID() error:\ngot: %v\nexpected: %v", w.Code, http.StatusNotFound)
+	}
+}
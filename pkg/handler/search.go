@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/geeksheik9/sheet-CRUD/pkg/api"
+	"github.com/geeksheik9/sheet-CRUD/pkg/db/dberr"
+	"github.com/geeksheik9/sheet-CRUD/pkg/search"
+	"github.com/sirupsen/logrus"
+)
+
+//SearchForceCharacterSheets is the handler function for full text search across force character sheets.
+//The q query parameter holds the words to search for, results are ranked by score and carry highlighted snippets
+func (s *CharacterService) SearchForceCharacterSheets(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("SearchForceCharacterSheets invoked with url: %v", r.URL)
+
+	query := r.URL.Query()
+	text := query.Get("q")
+	terms := search.Terms(text)
+	if len(terms) == 0 {
+		api.RespondWithFailure(w, dberr.Invalid("invalid_query", "q", "invalid query: q must contain a word to search for"))
+		return
+	}
+	if query.Get("after") != "" || query.Get("sort") != "" {
+		field := "sort"
+		if query.Get("after") != "" {
+			field = "after"
+		}
+		api.RespondWithFailure(w, dberr.Invalid("invalid_query", field, "invalid query: search results are ranked by score and can't use after or sort"))
+		return
+	}
+
+	filters := url.Values{}
+	for key, values := range query {
+		if key != "q" {
+			filters[key] = values
+		}
+	}
+
+	results, total, err := s.Database.SearchForceCharacterSheets(r.Context(), text, filters)
+	if err != nil {
+		api.RespondWithFailure(w, err)
+		return
+	}
+
+	for i := range results {
+		results[i].Highlights = search.Highlight(results[i].Sheet, terms)
+	}
+
+	pageNumber, pageCount, _ := api.Paging(query)
+	if pageNumber < 1 {
+		pageNumber = 1
+	}
+
+	links := api.BuildPageLinks(r.URL, len(results), total, nil)
+	api.SetLinkHeader(w, links)
+
+	api.RespondWithJSON(w, http.StatusOK, model.SearchPage{
+		Query:      strings.Join(terms, " "),
+		Items:      results,
+		Total:      total,
+		PageNumber: pageNumber,
+		PageCount:  pageCount,
+		Next:       links["next"],
+		Prev:       links["prev"],
+	})
+}
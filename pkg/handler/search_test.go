@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCharacterService_SearchForceCharacterSheets_Success(t *testing.T) {
+	id := primitive.NewObjectID()
+	sheet := mockCharacter(id, "Kanan Jarrus", 2, 0, 5)
+	service := InitMockCharacterService(mockCharacters(sheet), nil, nil)
+
+	r, err := http.NewRequest("GET", "/search?q=kanan&playerName[like]=kanan", nil)
+	if err != nil {
+		t.Errorf("SearchForceCharacterSheets() error creating request:\ngot: %v\nexpected:<no error>", err)
+	}
+
+	w := httptest.NewRecorder()
+	router := mux.NewRouter().StrictSlash(true)
+	service.Routes(router).ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("SearchForceCharacterSheets() error:\ngot: %v\nexpected: %v", w.Code, http.StatusOK)
+	}
+
+	page := model.SearchPage{}
+	json.Unmarshal(w.Body.Bytes(), &page)
+	if page.Total != 1 || len(page.Items) != 1 || page.Query != "kanan" {
+		t.Errorf("SearchForceCharacterSheets() error:\ngot: %+v\nexpected: one result for kanan", page)
+	}
+
+	expected := []model.SearchHighlight{
+		{Field: "characterName", Snippet: "<em>Kanan</em> Jarrus"},
+		{Field: "playerName", Snippet: "<em>Kanan</em> Jarrus"},
+	}
+	if len(page.Items) == 1 && (len(page.Items[0].Highlights) != 2 || page.Items[0].Highlights[0] != expected[0] || page.Items[0].Highlights[1] != expected[1]) {
+		t.Errorf("SearchForceCharacterSheets() error:\ngot: %v\nexpected: %v", page.Items[0].Highlights, expected)
+	}
+}
+
+func TestCharacterService_SearchForceCharacterSheets_BadQuery(t *testing.T) {
+	service := InitMockCharacterService(nil, nil, nil)
+
+	for _, target := range []string{"/search", "/search?q=%20-%20", "/search?q=kanan&sort=-characterName", "/search?q=kanan&unknown=1"} {
+		r, err := http.NewRequest("GET", target, nil)
+		if err != nil {
+			t.Errorf("SearchForceCharacterSheets() error creating request:\ngot: %v\nexpected:<no error>", err)
+		}
+
+		w := httptest.NewRecorder()
+		router := mux.NewRouter().StrictSlash(true)
+		service.Routes(router).ServeHTTP(w, r)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("SearchForceCharacterSheets() %v error:\ngot: %v\nexpected: %v", target, w.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestCharacterService_SearchForceCharacterSheets_DBError(t *testing.T) {
+	service := InitMockCharacterService(nil, nil, errors.New("test error"))
+
+	r, err := http.NewRequest("GET", "/search?q=kanan", nil)
+	if err != nil {
+		t.Errorf("SearchForceCharacterSheets() error creating request:\ngot: %v\nexpected:<no error>", err)
+	}
+
+	w := httptest.NewRecorder()
+	router := mux.NewRouter().StrictSlash(true)
+	service.Routes(router).ServeHTTP(w, r)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("SearchForceCharacterSheets() error:\ngot: %v\nexpected: %v", w.Code, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,229 @@
+// Package search ranks and highlights character sheets matching a free text search.
+// Backends without mongo use it to match sheets, and every backend uses it to build the highlighted snippets
+package search
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+)
+
+// SnippetLength is the most characters of context kept on each side of the first match in a highlight
+const SnippetLength = 40
+
+// Field is a part of a sheet that search looks in and how much a match there counts towards the rank
+type Field struct {
+	Path   string
+	Weight int
+	Values func(sheet model.ForceCharacterSheet) []string
+}
+
+//Fields are the searched fields of a sheet, keyed by the same path in the bson and JSON forms.
+//The mongo text index is built from them, so their paths and weights are also what mongo ranks on
+var Fields = []Field{
+	{Path: "characterName", Weight: 10, Values: func(s model.ForceCharacterSheet) []string { return []string{s.CharacterName} }},
+	{Path: "playerName", Weight: 5, Values: func(s model.ForceCharacterSheet) []string { return []string{s.PlayerName} }},
+	{Path: "talents.name", Weight: 3, Values: func(s model.ForceCharacterSheet) []string {
+		values := []string{}
+		for _, talent := range s.Talents {
+			values = append(values, talent.Name)
+		}
+		return values
+	}},
+	{Path: "talents.forcePower.name", Weight: 3, Values: func(s model.ForceCharacterSheet) []string {
+		values := []string{}
+		for _, talent := range s.Talents {
+			for _, power := range talent.ForcePower {
+				values = append(values, power.Name)
+			}
+		}
+		return values
+	}},
+	{Path: "weapons.name", Weight: 2, Values: func(s model.ForceCharacterSheet) []string {
+		values := []string{}
+		for _, weapon := range s.Weapons {
+			values = append(values, weapon.Name)
+		}
+		return values
+	}},
+	{Path: "equipment.armor.gear", Weight: 1, Values: func(s model.ForceCharacterSheet) []string {
+		return gear(s.Equipment.Armor)
+	}},
+	{Path: "equipment.personalGear.gear", Weight: 1, Values: func(s model.ForceCharacterSheet) []string {
+		return gear(s.Equipment.PersonalGear)
+	}},
+	{Path: "characterDescription.notableFeatures", Weight: 1, Values: func(s model.ForceCharacterSheet) []string {
+		return []string{s.CharacterDescription.NotableFeatures}
+	}},
+}
+
+func gear(items []model.Gear) []string {
+	values := []string{}
+	for _, item := range items {
+		values = append(values, item.Gear)
+	}
+
+	return values
+}
+
+//Terms splits a search into its distinct lower case words. A sheet matches a search when it matches any of the terms
+func Terms(text string) []string {
+	terms := []string{}
+	seen := map[string]bool{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), isSeparator) {
+		if seen[word] {
+			continue
+		}
+		seen[word] = true
+		terms = append(terms, word)
+	}
+
+	return terms
+}
+
+//Score ranks how well a sheet matches the terms, a sheet that doesn't match scores 0.
+//Every matching word counts the weight of its field, damped by the length of the text it is in like a mongo text score
+func Score(sheet model.ForceCharacterSheet, terms []string) float64 {
+	stems := stemAll(terms)
+	score := 0.0
+
+	for _, field := range Fields {
+		for _, value := range field.Values(sheet) {
+			words := strings.FieldsFunc(strings.ToLower(value), isSeparator)
+			matched := 0
+			for _, word := range words {
+				if stems[stem(word)] {
+					matched++
+				}
+			}
+
+			if matched > 0 {
+				score += float64(field.Weight*matched) * (0.5 + 0.5/float64(len(words)))
+			}
+		}
+	}
+
+	return score
+}
+
+//Highlight returns a snippet of every searched field value that matches the terms, with the matching words wrapped in <em> tags
+func Highlight(sheet model.ForceCharacterSheet, terms []string) []model.SearchHighlight {
+	stems := stemAll(terms)
+	highlights := []model.SearchHighlight{}
+
+	for _, field := range Fields {
+		for _, value := range field.Values(sheet) {
+			if snippet, ok := snippet(value, stems); ok {
+				highlights = append(highlights, model.SearchHighlight{Field: field.Path, Snippet: snippet})
+			}
+		}
+	}
+
+	return highlights
+}
+
+//Rank orders search results by descending score, breaking ties by sheet ID so that pages are stable
+func Rank(results []model.SearchResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Sheet.ID.Hex() < results[j].Sheet.ID.Hex()
+	})
+}
+
+func snippet(value string, stems map[string]bool) (string, bool) {
+	text := []rune(value)
+	var builder strings.Builder
+	first, last := -1, -1
+
+	for start := 0; start < len(text); {
+		if isSeparator(text[start]) {
+			start++
+			continue
+		}
+
+		end := start
+		for end < len(text) && !isSeparator(text[end]) {
+			end++
+		}
+
+		if stems[stem(strings.ToLower(string(text[start:end])))] {
+			if first < 0 {
+				first = start
+			}
+			last = end
+		}
+		start = end
+	}
+
+	if first < 0 {
+		return "", false
+	}
+
+	// keep the context to whole words
+	from := first - SnippetLength
+	if from < 0 {
+		from = 0
+	}
+	for from > 0 && !isSeparator(text[from-1]) {
+		from++
+	}
+	to := last + SnippetLength
+	if to > len(text) {
+		to = len(text)
+	}
+	for to < len(text) && !isSeparator(text[to]) {
+		to--
+	}
+
+	if from > 0 {
+		builder.WriteString("…")
+	}
+	for start := from; start < to; {
+		end := start
+		for end < to && isSeparator(text[end]) == isSeparator(text[start]) {
+			end++
+		}
+
+		word := string(text[start:end])
+		if !isSeparator(text[start]) && stems[stem(strings.ToLower(word))] {
+			builder.WriteString("<em>" + word + "</em>")
+		} else {
+			builder.WriteString(word)
+		}
+		start = end
+	}
+	if to < len(text) {
+		builder.WriteString("…")
+	}
+
+	return builder.String(), true
+}
+
+func stemAll(terms []string) map[string]bool {
+	stems := map[string]bool{}
+	for _, term := range terms {
+		stems[stem(term)] = true
+	}
+
+	return stems
+}
+
+// stem strips plural endings so that e.g. blaster and blasters match, close to mongo's stemming
+func stem(word string) string {
+	switch {
+	case len(word) > 4 && strings.HasSuffix(word, "ies"):
+		return word[:len(word)-3] + "y"
+	case len(word) > 3 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss"):
+		return word[:len(word)-1]
+	}
+
+	return word
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
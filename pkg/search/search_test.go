@@ -0,0 +1,63 @@
+package search
+
+import (
+	"fmt"
+	"testing"
+
+	model "github.com/geeksheik9/sheet-CRUD/models"
+)
+
+func TestTerms(t *testing.T) {
+	terms := Terms(`  "Lightsaber", lightsaber -Force  push!`)
+	expected := "[lightsaber force push]"
+	if fmt.Sprint(terms) != expected {
+		t.Errorf("Terms() error:\n   expected: %v\n   got:      %v", expected, terms)
+	}
+}
+
+func TestScore(t *testing.T) {
+	named := model.ForceCharacterSheet{CharacterName: "Blaster Jones"}
+	armed := model.ForceCharacterSheet{
+		CharacterName: "Mando",
+		Weapons:       []model.Weapons{{Name: "Heavy blaster pistol"}},
+	}
+	unarmed := model.ForceCharacterSheet{CharacterName: "Grogu"}
+
+	terms := Terms("blasters")
+	if Score(named, terms) <= Score(armed, terms) {
+		t.Errorf("Score() error:\n   expected: a character name match to outrank a weapon match\n   got:      %v <= %v", Score(named, terms), Score(armed, terms))
+	}
+	if score := Score(unarmed, terms); score != 0 {
+		t.Errorf("Score() error:\n   expected: 0\n   got:      %v", score)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	sheet := model.ForceCharacterSheet{
+		Talents: []model.Talents{{Name: "Force Rating", ForcePower: []model.ForcePower{{Name: "Move"}}}},
+		CharacterDescription: model.CharacterDescription{
+			NotableFeatures: "A long scar runs across the left side of the face, earned while escaping the Force sensitive purge on Kamino",
+		},
+	}
+
+	highlights := Highlight(sheet, Terms("force"))
+	expected := []model.SearchHighlight{
+		{Field: "talents.name", Snippet: "<em>Force</em> Rating"},
+		{Field: "characterDescription.notableFeatures", Snippet: "…of the face, earned while escaping the <em>Force</em> sensitive purge on Kamino"},
+	}
+	if fmt.Sprint(highlights) != fmt.Sprint(expected) {
+		t.Errorf("Highlight() error:\n   expected: %v\n   got:      %v", expected, highlights)
+	}
+}
+
+func TestRank(t *testing.T) {
+	results := []model.SearchResult{
+		{Sheet: model.ForceCharacterSheet{CharacterName: "low"}, Score: 1},
+		{Sheet: model.ForceCharacterSheet{CharacterName: "high"}, Score: 3},
+	}
+
+	Rank(results)
+	if results[0].Sheet.CharacterName != "high" {
+		t.Errorf("Rank() error:\n   expected: high first\n   got:      %v", results)
+	}
+}